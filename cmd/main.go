@@ -1,15 +1,25 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/izzanzahrial/blog-api-echo/pkg/comment"
 	"github.com/izzanzahrial/blog-api-echo/pkg/elastic"
+	"github.com/izzanzahrial/blog-api-echo/pkg/favourite"
 	"github.com/izzanzahrial/blog-api-echo/pkg/handler"
+	"github.com/izzanzahrial/blog-api-echo/pkg/mailer"
 	"github.com/izzanzahrial/blog-api-echo/pkg/postgre"
 	"github.com/izzanzahrial/blog-api-echo/pkg/posting"
 	redisDB "github.com/izzanzahrial/blog-api-echo/pkg/redis"
 	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+	"github.com/izzanzahrial/blog-api-echo/pkg/search"
+	"github.com/izzanzahrial/blog-api-echo/pkg/taxonomy"
 	"github.com/izzanzahrial/blog-api-echo/pkg/user"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
@@ -18,28 +28,142 @@ import (
 )
 
 var (
-	redisHost     = os.Getenv("redisHost")
-	redisPass     = os.Getenv("redisPass")
-	esAddresses   = os.Getenv("esAddresses")
-	esUsername    = os.Getenv("esUsername")
-	esPassword    = os.Getenv("esPassword")
+	redisHost   = os.Getenv("redisHost")
+	redisPass   = os.Getenv("redisPass")
+	esAddresses = os.Getenv("esAddresses")
+	esUsername  = os.Getenv("esUsername")
+	esPassword  = os.Getenv("esPassword")
+	// name of the search index, it's read and written through <esIndex>_alias so cmd/reindex can swap it
+	esIndex       = os.Getenv("esIndex")
 	jwtSignMethod = os.Getenv("jwtSignMethod")
 	jwtSignKey    = os.Getenv("jwtSignKey")
-	echoAddress   = os.Getenv("echoAddress")
+	// how long the access token and the refresh token last, e.g. "10m", default to 15 minutes and 30 days
+	accessTokenTTL  = os.Getenv("accessTokenTTL")
+	refreshTokenTTL = os.Getenv("refreshTokenTTL")
+	echoAddress     = os.Getenv("echoAddress")
+	// how often the scheduler look for due post, e.g. "30s", default to a minute
+	publishInterval = os.Getenv("publishInterval")
+	// how often the purger look for expired deleted post, default to an hour
+	purgeInterval = os.Getenv("purgeInterval")
+	// how long a deleted post stay in the trash, e.g. "720h", default to 30 days
+	trashRetention = os.Getenv("trashRetention")
+	// how often the relay deliver the outbox to the searcher and redis, default to 5 seconds
+	relayInterval = os.Getenv("relayInterval")
+	// how many time an outbox event is tried before it's dead lettered, default to 10
+	relayMaxAttempts = os.Getenv("relayMaxAttempts")
+	// where the published post is searched, "elastic", "postgres" or "memory", default to elastic.
+	// "memory" is single instance and for development only, it's refused with the postgres storage
+	searchBackend = os.Getenv("searchBackend")
+	// how the mail is sent, "smtp" or "log", default to log which write the mail to mailLogPath, or stdout
+	mailerBackend = os.Getenv("mailerBackend")
+	mailLogPath   = os.Getenv("mailLogPath")
+	smtpHost      = os.Getenv("smtpHost")
+	smtpPort      = os.Getenv("smtpPort")
+	smtpUsername  = os.Getenv("smtpUsername")
+	smtpPassword  = os.Getenv("smtpPassword")
+	mailFrom      = os.Getenv("mailFrom")
+	// the page of the front end that the password reset link open, the token is added as the token query param
+	passwordResetURL = os.Getenv("passwordResetURL")
+	// how long the password reset link last, default to an hour
+	passwordResetTTL = os.Getenv("passwordResetTTL")
+	// the verify endpoint of the api that the email verification link open, e.g. https://example.com/api/v1/user/verify
+	emailVerifyURL = os.Getenv("emailVerifyURL")
+	// how long the email verification link last and how often another one can be asked for,
+	// default to a day and a minute
+	emailVerifyTTL       = os.Getenv("emailVerifyTTL")
+	verifyResendInterval = os.Getenv("verifyResendInterval")
+	// "true" keep the user whose email isn't verified from publishing posts
+	requireVerifiedToPublish = os.Getenv("requireVerifiedToPublish")
+
+	// "memory" keep everything in memory so the app boot without postgres, redis and elasticsearch,
+	// what's stored is lost on restart
+	storageFlag = flag.String("storage", "postgres", "where the data is stored, \"postgres\" or \"memory\". "+
+		"memory is for development only and a single instance, every instance would have its own posts and search")
 )
 
+// storage is where the services keep their data
+type storage struct {
+	DB                repository.DB
+	Cache             redisDB.Cache
+	Post              repository.Post
+	Outbox            repository.OutboxRepository
+	Favourite         repository.FavouriteRepository
+	Tag               repository.TagRepository
+	Category          repository.CategoryRepository
+	Comment           repository.CommentRepository
+	User              repository.UserRepository
+	RefreshToken      repository.RefreshTokenRepository
+	Role              repository.RoleRepository
+	PasswordReset     repository.PasswordResetRepository
+	EmailVerification repository.EmailVerificationRepository
+	Searcher          search.Searcher
+}
+
 func main() {
+	flag.Parse()
+
 	validator := validator.New()
-	postgreDB, _ := postgre.NewPostgreDatabase()
-	redis := redisDB.NewRedis(redisHost, redisPass)
-	es := elastic.NewElastic(esUsername, esPassword, esAddresses)
+	st := newStorage(*storageFlag)
+
+	favouriteService := favourite.NewService(st.Favourite, st.Post, st.DB, st.Cache)
+	favouriteHandler := handler.NewFavouriteHandler(favouriteService)
+
+	transactor := repository.NewTransactor(st.DB)
+
+	postService := posting.NewService(st.Post, st.Outbox, transactor, validator, st.Cache, st.Searcher)
+	postHandler := handler.NewPostHandler(postService, favouriteService)
+
+	interval, err := time.ParseDuration(publishInterval)
+	if err != nil {
+		interval = time.Minute
+	}
+	scheduler := posting.NewScheduler(st.Post, st.Outbox, st.DB, interval)
+	go scheduler.Start(context.Background())
+
+	relayEvery, err := time.ParseDuration(relayInterval)
+	if err != nil {
+		relayEvery = 5 * time.Second
+	}
+	relay := posting.NewRelay(st.Post, st.Outbox, st.DB, st.Cache, st.Searcher, relayEvery)
+	if attempts, err := strconv.Atoi(relayMaxAttempts); err == nil && attempts > 0 {
+		relay.MaxAttempts = attempts
+	}
+	go relay.Start(context.Background())
+
+	purgeEvery, err := time.ParseDuration(purgeInterval)
+	if err != nil {
+		purgeEvery = time.Hour
+	}
+	retention, err := time.ParseDuration(trashRetention)
+	if err != nil {
+		retention = 30 * 24 * time.Hour
+	}
+	purger := posting.NewPurger(st.Post, st.DB, st.Cache, purgeEvery, retention)
+	go purger.Start(context.Background())
 
-	postRepository := repository.NewPostgre()
-	postService := posting.NewService(postRepository, postgreDB, validator, redis, es)
-	postHandler := handler.NewPostHandler(postService)
+	taxonomyService := taxonomy.NewService(st.Tag, st.Category, st.DB, validator)
+	taxonomyHandler := handler.NewTaxonomyHandler(taxonomyService)
 
-	userRepository := repository.NewUserPostgreRepository()
-	userService := user.NewUserService(userRepository, postgreDB, validator)
+	commentService := comment.NewService(st.Comment, st.Post, st.Outbox, st.DB, validator)
+	commentHandler := handler.NewCommentHandler(commentService)
+
+	// an unset or invalid ttl fall back to the default of NewUserService
+	accessTTL, _ := time.ParseDuration(accessTokenTTL)
+	refreshTTL, _ := time.ParseDuration(refreshTokenTTL)
+	resetTTL, _ := time.ParseDuration(passwordResetTTL)
+	verifyTTL, _ := time.ParseDuration(emailVerifyTTL)
+	resendInterval, _ := time.ParseDuration(verifyResendInterval)
+	userService := user.NewUserService(st.User, st.Favourite, st.RefreshToken, st.Role, st.PasswordReset, st.EmailVerification, transactor, validator, st.Cache, newMailer(), user.TokenConfig{
+		SigningMethod:  jwtSignMethod,
+		SigningKey:     []byte(jwtSignKey),
+		AccessTTL:      accessTTL,
+		RefreshTTL:     refreshTTL,
+		ResetTTL:       resetTTL,
+		ResetURL:       passwordResetURL,
+		VerifyTTL:      verifyTTL,
+		VerifyURL:      emailVerifyURL,
+		ResendInterval: resendInterval,
+	})
 	userHandler := handler.NewUserHandler(userService)
 
 	jwtConfig := middleware.JWTConfig{
@@ -48,23 +172,220 @@ func main() {
 		SigningKey:    []byte(jwtSignKey),
 	}
 
+	// optionalJWTConfig let anonymous request through, the handler decide what they can see
+	optionalJWTConfig := jwtConfig
+	optionalJWTConfig.ContinueOnIgnoredError = true
+	optionalJWTConfig.ErrorHandlerWithContext = func(err error, c echo.Context) error {
+		return nil
+	}
+
+	// the token revoked by a logout is refused even though its signature is still valid
+	denylist := user.NewDenylist(st.Cache, accessTTL)
+	auth := handler.JWTWithDenylist(jwtConfig, denylist)
+	optionalAuth := handler.JWTWithDenylist(optionalJWTConfig, denylist)
+
+	// the policy only check the post that's published, a draft can be written before the email is verified
+	createPost := []echo.MiddlewareFunc{auth, handler.RequirePermission(repository.PermissionPostCreate)}
+	publishPost := []echo.MiddlewareFunc{auth}
+	if verified, _ := strconv.ParseBool(requireVerifiedToPublish); verified {
+		createPost = append(createPost, handler.RequireVerified(handler.PublishingPost))
+		publishPost = append(publishPost, handler.RequireVerified(nil))
+	}
+
 	e := echo.New()
 	p := e.Group("/api/v1/posts")
 
-	p.POST("", postHandler.Create)
-	p.GET("", postHandler.FindRecent)
-	p.GET("/:postid", postHandler.FindByID)
-	p.PUT("/:postid", postHandler.Update, middleware.JWTWithConfig(jwtConfig))
-	p.DELETE("/:postid", postHandler.Delete, middleware.JWTWithConfig(jwtConfig))
-	p.GET("/:result", postHandler.FindByTitleContent)
+	p.POST("", postHandler.Create, createPost...)
+	p.GET("", postHandler.FindRecent, optionalAuth)
+	p.GET("/search", postHandler.FindByTitleContent, optionalAuth)
+	p.GET("/suggest", postHandler.Suggest)
+	p.GET("/trash", postHandler.FindTrash, auth)
+	p.GET("/slug/:slug", postHandler.FindBySlug, optionalAuth)
+	p.GET("/:postid", postHandler.FindByID, optionalAuth)
+	p.PUT("/:postid", postHandler.Update, auth)
+	p.DELETE("/:postid", postHandler.Delete, auth)
+	p.POST("/:postid/restore", postHandler.Undelete, auth)
+	p.POST("/:postid/publish", postHandler.Publish, publishPost...)
+	p.DELETE("/:postid/publish", postHandler.Unpublish, auth)
+	p.POST("/:postid/archive", postHandler.Archive, auth)
+	p.PUT("/:postid/schedule", postHandler.Schedule, publishPost...)
+	p.DELETE("/:postid/schedule", postHandler.Unschedule, auth)
+	p.GET("/:postid/related", postHandler.Related)
+	p.GET("/:postid/revisions", postHandler.FindRevisions, auth)
+	p.GET("/:postid/revisions/diff", postHandler.DiffRevisions, auth)
+	p.POST("/:postid/revisions/:revisionid/restore", postHandler.Restore, auth)
+	p.GET("/:postid/comments", commentHandler.FindThreads)
+	p.POST("/:postid/comments", commentHandler.Create, auth)
+	p.POST("/:postid/favourite", favouriteHandler.Favourite, auth)
+	p.DELETE("/:postid/favourite", favouriteHandler.Unfavourite, auth)
+
+	cm := e.Group("/api/v1/comments")
+
+	cm.PUT("/:commentid", commentHandler.Update, auth)
+	cm.DELETE("/:commentid", commentHandler.Delete, auth)
+
+	// the tags and categories are shared by every post, only the editor and admin change them
+	manageTaxonomy := []echo.MiddlewareFunc{auth, handler.RequirePermission(repository.PermissionTaxonomyManage)}
+
+	t := e.Group("/api/v1/tags")
+
+	t.GET("", taxonomyHandler.FindTags)
+	t.POST("", taxonomyHandler.CreateTag, manageTaxonomy...)
+	t.PUT("/:tagid", taxonomyHandler.UpdateTag, manageTaxonomy...)
+	t.DELETE("/:tagid", taxonomyHandler.DeleteTag, manageTaxonomy...)
+
+	c := e.Group("/api/v1/categories")
+
+	c.GET("", taxonomyHandler.FindCategories)
+	c.POST("", taxonomyHandler.CreateCategory, manageTaxonomy...)
+	c.PUT("/:categoryid", taxonomyHandler.UpdateCategory, manageTaxonomy...)
+	c.DELETE("/:categoryid", taxonomyHandler.DeleteCategory, manageTaxonomy...)
 
 	u := e.Group("/api/v1/user")
 
 	u.POST("", userHandler.Create)
-	u.PUT("", userHandler.UpdateUser, middleware.JWTWithConfig(jwtConfig))
-	u.DELETE("", userHandler.Delete, middleware.JWTWithConfig(jwtConfig))
+	u.PUT("", userHandler.UpdateUser, auth)
+	u.DELETE("", userHandler.Delete, auth)
 	u.POST("/login", userHandler.Login)
-	u.PUT("/password", userHandler.UpdatePassword, middleware.JWTWithConfig(jwtConfig))
+	u.POST("/token/refresh", userHandler.Refresh)
+	u.POST("/logout", userHandler.Logout, auth)
+	u.POST("/logout/all", userHandler.LogoutAll, auth)
+	u.PUT("/password", userHandler.UpdatePassword, auth)
+	u.POST("/password/forgot", userHandler.ForgotPassword)
+	u.POST("/password/reset", userHandler.ResetPassword)
+	u.GET("/verify", userHandler.VerifyEmail)
+	u.POST("/verify/resend", userHandler.ResendVerification, auth)
+	u.GET("/favourites", favouriteHandler.FindByUser, auth)
+
+	a := e.Group("/api/v1/admin", auth, handler.RequirePermission(repository.PermissionUserManage))
+
+	a.GET("/users", userHandler.FindUsers)
+	a.PUT("/users/:userid/role", userHandler.ChangeRole)
+	a.POST("/users/:userid/suspend", userHandler.Suspend)
+	a.DELETE("/users/:userid/suspend", userHandler.Unsuspend)
 
 	e.Logger.Fatal(e.Start(echoAddress))
 }
+
+// newStorage build the repositories of the storage, the memory storage search in memory
+// unless another searchBackend is asked for
+func newStorage(name string) storage {
+	switch name {
+	case "memory":
+		store := repository.NewMemoryStore()
+		st := storage{
+			DB:                store,
+			Cache:             redisDB.NewMemory(),
+			Post:              repository.NewPostMemory(store),
+			Outbox:            repository.NewOutboxMemory(store),
+			Favourite:         repository.NewFavouriteMemory(store),
+			Tag:               repository.NewTagMemory(store),
+			Category:          repository.NewCategoryMemory(store),
+			Comment:           repository.NewCommentMemory(store),
+			User:              repository.NewUserMemory(store),
+			RefreshToken:      repository.NewRefreshTokenMemory(store),
+			Role:              repository.NewRoleMemory(store),
+			PasswordReset:     repository.NewPasswordResetMemory(store),
+			EmailVerification: repository.NewEmailVerificationMemory(store),
+		}
+		if searchBackend == "" {
+			searchBackend = search.BackendMemory
+		}
+		st.Searcher = newSearcher(st.Post, st.DB)
+		return st
+	case "postgres":
+	default:
+		log.Fatalf("unknown storage: %s", name)
+	}
+
+	postgreDB, _ := postgre.NewPostgreDatabase()
+	st := storage{
+		DB:                repository.NewSQLDB(postgreDB),
+		Cache:             redisDB.NewRedis(redisHost, redisPass),
+		Post:              repository.NewPostgre(),
+		Outbox:            repository.NewOutboxPostgre(),
+		Favourite:         repository.NewFavouritePostgre(),
+		Tag:               repository.NewTagPostgre(),
+		Category:          repository.NewCategoryPostgre(),
+		Comment:           repository.NewCommentPostgre(),
+		User:              repository.NewUserPostgreRepository(),
+		RefreshToken:      repository.NewRefreshTokenPostgre(),
+		Role:              repository.NewRolePostgre(),
+		PasswordReset:     repository.NewPasswordResetPostgre(),
+		EmailVerification: repository.NewEmailVerificationPostgre(),
+	}
+
+	// the relays of the replicas share the outbox and each deliver only part of it, so a memory searcher
+	// would only see the changes of its own relay and every replica would search different stale posts
+	if searchBackend == search.BackendMemory {
+		log.Fatalf("the memory search backend is single instance and can't run on the postgres storage, use %q or %q",
+			search.BackendElastic, search.BackendPostgres)
+	}
+	st.Searcher = newSearcher(st.Post, st.DB)
+	return st
+}
+
+// newMailer build the mailer of mailerBackend, the log mailer append to mailLogPath
+// so the link of the mail can be followed without an smtp server
+func newMailer() mailer.Mailer {
+	switch mailerBackend {
+	case mailer.BackendSMTP:
+		if smtpPort == "" {
+			smtpPort = "587"
+		}
+		return mailer.NewSMTP(smtpHost, smtpPort, smtpUsername, smtpPassword, mailFrom)
+	case "", mailer.BackendLog:
+	default:
+		log.Fatalf("unknown mailer backend: %s", mailerBackend)
+	}
+
+	if mailLogPath == "" {
+		return mailer.NewLog(os.Stdout)
+	}
+
+	f, err := os.OpenFile(mailLogPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		log.Fatalf("failed to open the mail log %s: %v", mailLogPath, err)
+	}
+	return mailer.NewLog(f)
+}
+
+// newSearcher build the searcher of searchBackend, elasticsearch fall back to postgres
+// whenever it's unavailable so the post can still be searched
+func newSearcher(rp repository.Post, db search.DBtx) search.Searcher {
+	switch searchBackend {
+	case search.BackendPostgres:
+		return search.NewPostgres(rp, db)
+	case search.BackendMemory:
+		// the post is only kept in memory, it's loaded once and then kept up to date by the relay of this
+		// process, so it's only right with the memory storage and a single instance
+		m := search.NewMemory()
+		if err := m.Load(context.Background(), rp, db); err != nil {
+			log.Fatalf("failed to load the posts into the memory searcher: %v", err)
+		}
+		return m
+	case "", search.BackendElastic:
+	default:
+		log.Fatalf("unknown search backend: %s", searchBackend)
+	}
+
+	es, err := elastic.NewElastic(esUsername, esPassword, esAddresses)
+	if err != nil {
+		log.Fatalf("failed to create the elasticsearch client: %v", err)
+	}
+	if esIndex == "" {
+		esIndex = "posts"
+	}
+	es.Index = elastic.AliasOf(esIndex)
+
+	// a mapping that drifted from the one in pkg/elastic is only fixed by cmd/reindex
+	drift, err := es.CheckMapping(context.Background())
+	if err != nil {
+		log.Printf("failed to check the mapping of search index %s: %v", es.Index, err)
+	}
+	for _, d := range drift {
+		log.Printf("search index mapping drift: %s, run cmd/reindex to apply the current mapping", d)
+	}
+
+	return search.NewFallback(search.NewElastic(es), search.NewPostgres(rp, db))
+}
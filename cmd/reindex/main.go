@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+
+	"github.com/izzanzahrial/blog-api-echo/pkg/elastic"
+	"github.com/izzanzahrial/blog-api-echo/pkg/postgre"
+	"github.com/izzanzahrial/blog-api-echo/pkg/posting"
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+)
+
+var (
+	esAddresses = os.Getenv("esAddresses")
+	esUsername  = os.Getenv("esUsername")
+	esPassword  = os.Getenv("esPassword")
+	esIndex     = os.Getenv("esIndex")
+)
+
+// reindex rebuild the search index from postgres, run it again with the same checkpoint to resume
+func main() {
+	if esIndex == "" {
+		esIndex = "posts"
+	}
+
+	index := flag.String("index", esIndex, "name of the index, the application read it through <index>_alias")
+	batch := flag.Int("batch", 500, "how many post is sent in one bulk request")
+	checkpoint := flag.String("checkpoint", "reindex.checkpoint.json", "file the progress is saved in, empty to disable resume")
+	flag.Parse()
+
+	postgreDB, _ := postgre.NewPostgreDatabase()
+	es, err := elastic.NewElastic(esUsername, esPassword, esAddresses)
+	if err != nil {
+		log.Fatalf("failed to create the elasticsearch client: %v", err)
+	}
+
+	reindexer := posting.NewReindexer(repository.NewPostgre(), repository.NewSQLDB(postgreDB), es, *checkpoint)
+	reindexer.BatchSize = *batch
+	reindexer.Progress = func(indexed int64, total int64) {
+		percent := 100.0
+		if total > 0 {
+			percent = float64(indexed) / float64(total) * 100
+		}
+		log.Printf("indexed %d/%d posts (%.1f%%)", indexed, total, percent)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	cp, err := reindexer.Run(ctx, *index)
+	if err != nil {
+		log.Fatalf("reindex into %s stopped after post %d: %v", cp.Index, cp.LastID, err)
+	}
+
+	log.Printf("reindexed %d posts into %s, %s now point at it", cp.Indexed, cp.Index, cp.Alias)
+}
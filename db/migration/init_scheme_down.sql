@@ -1 +1,18 @@
-DROP TABLE IF EXISTS posts;
\ No newline at end of file
+DROP TABLE IF EXISTS email_verifications;
+DROP TABLE IF EXISTS password_resets;
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS favourites;
+DROP TABLE IF EXISTS comments;
+DROP TABLE IF EXISTS post_categories;
+DROP TABLE IF EXISTS categories;
+DROP TABLE IF EXISTS post_tags;
+DROP TABLE IF EXISTS tags;
+DROP TABLE IF EXISTS post_revisions;
+DROP TABLE IF EXISTS outbox;
+DROP TABLE IF EXISTS post_slugs;
+DROP TABLE IF EXISTS posts;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS permissions;
+DROP TABLE IF EXISTS roles;
+DROP EXTENSION IF EXISTS pg_trgm;
@@ -1,11 +1,70 @@
+-- every user has a single role, what the role is permitted is embedded in the access token,
+-- see repository.RoleAuthor for the roles and repository.PermissionPostCreate for the permissions
+CREATE TABLE roles (
+    role_id SERIAL PRIMARY KEY,
+    name VARCHAR (32) NOT NULL UNIQUE
+);
+
+CREATE TABLE permissions (
+    permission_id SERIAL PRIMARY KEY,
+    name VARCHAR (64) NOT NULL UNIQUE
+);
+
+CREATE TABLE role_permissions (
+    role_id INTEGER NOT NULL REFERENCES roles (role_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    permission_id INTEGER NOT NULL REFERENCES permissions (permission_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission_id)
+);
+
+INSERT INTO roles (name) VALUES ('reader'), ('author'), ('editor'), ('admin');
+INSERT INTO permissions (name) VALUES ('post:create'), ('post:edit:any'), ('post:delete:any'), ('user:manage'), ('taxonomy:manage');
+INSERT INTO role_permissions (role_id, permission_id)
+    SELECT r.role_id, p.permission_id FROM roles r, permissions p WHERE
+        (r.name = 'author' AND p.name = 'post:create') OR
+        (r.name = 'editor' AND p.name IN ('post:create', 'post:edit:any', 'taxonomy:manage')) OR
+        r.name = 'admin';
+
+-- suspended user can't login or refresh their token
+CREATE TABLE users (
+    user_id serial PRIMARY KEY,
+    email TEXT NOT NULL UNIQUE,
+    username TEXT NOT NULL UNIQUE,
+    name VARCHAR (255) NOT NULL,
+    password VARCHAR (255) NOT NULL,
+    role VARCHAR (32) NOT NULL DEFAULT 'author' REFERENCES roles (name) ON UPDATE CASCADE,
+    suspended_at TIMESTAMP,
+    -- when the user followed the link mailed to their email, NULL until then
+    verified_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_users_lower_email ON users(LOWER(email));
+CREATE UNIQUE INDEX idx_users_username ON users(LOWER(username));
+
+CREATE INDEX idx_user ON users(user_id, password);
+
 CREATE TABLE posts (
     post_id SERIAL PRIMARY KEY,
     title VARCHAR (255) NOT NULL,
+    -- url friendly title, see posting.Slugify
+    slug VARCHAR (255) NOT NULL UNIQUE,
     short_desc TEXT NOT NULL,
     content TEXT NOT NULL,
-    created_at TIMESTAMP NOT NULL
+    created_at TIMESTAMP NOT NULL,
+    -- only published post is visible to everyone, draft is only visible to the author
+    status VARCHAR (16) NOT NULL DEFAULT 'draft' CHECK (status IN ('draft', 'published', 'archived')),
+    -- draft with publish_at will be published by the scheduler once it's due
+    publish_at TIMESTAMP,
+    -- deleted post stay in the trash until it's purged, every query but the trash one skip it
+    deleted_at TIMESTAMP,
+    -- the user that wrote the post, only the author can update or delete it
+    author_id INTEGER NOT NULL REFERENCES users (user_id) ON UPDATE CASCADE ON DELETE CASCADE
 );
 
+CREATE INDEX idx_post_author ON posts(author_id);
+CREATE INDEX idx_post_status_created ON posts(status, created_at DESC);
+CREATE INDEX idx_post_publish_at ON posts(publish_at) WHERE status = 'draft' AND publish_at IS NOT NULL;
+CREATE INDEX idx_post_deleted_at ON posts(author_id, deleted_at) WHERE deleted_at IS NOT NULL;
+
 -- tsvector = values that stored in ordered list of distinct words
 -- setweight = to weight the value of the data
 -- coalesce = function that will return the first value that's not null 
@@ -13,35 +72,157 @@ CREATE TABLE posts (
 -- full text search postgres = https://blog.crunchydata.com/blog/postgres-full-text-search-a-search-engine-in-a-database
 ALTER TABLE posts ADD COLUMN ts_title_content tsvector GENERATED ALWAYS AS (
     setweight(to_tsvector('english', title), 'A') ||
-    setweight(to_tsvector('english', content), 'B') STORED;
-)
+    setweight(to_tsvector('english', content), 'B')) STORED;
 -- if title and content can be null use this
 -- ALTER TABLE posts ADD COLUMN ts_title_content tsvector GENERATED ALWAYS AS (
 --     setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
---     setweight(to_tsvector('english', coalesce(content, '')), 'B') STORED;
--- )
+--     setweight(to_tsvector('english', coalesce(content, '')), 'B')) STORED;
 
 -- in postgress its better to use GIN index for full text search
 -- https://www.postgresql.org/docs/current/textsearch-indexes.html
-CREATE INDEX ts_index ON posts USING GIN (ts_title_content)
+CREATE INDEX ts_index ON posts USING GIN (ts_title_content);
 
 CREATE INDEX idx_post_title ON posts(title);
 
-CREATE TABLE users (
-    user_id serial PRIMARY KEY,
-    email TEXT NOT NULL UNIQUE,
-    username TEXT NOT NULL UNIQUE,
-    name VARCHAR (255) NOT NULL,
-    password VARCHAR (255) NOT NULL
+-- trigram index back the title suggestion when elasticsearch is unavailable
+-- https://www.postgresql.org/docs/current/pgtrgm.html
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX idx_post_title_trgm ON posts USING GIN (title gin_trgm_ops);
+
+-- change of a post waiting to be delivered to elasticsearch and redis by the relay,
+-- post_id isn't a foreign key since the event must outlive a purged post
+CREATE TABLE outbox (
+    event_id BIGSERIAL PRIMARY KEY,
+    post_id INTEGER NOT NULL,
+    slug VARCHAR (255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    last_error TEXT NOT NULL DEFAULT '',
+    -- event that ran out of attempts is dead lettered and left for inspection
+    dead_at TIMESTAMP
 );
 
-CREATE UNIQUE INDEX idx_users_lower_email ON users(LOWER(email));
-CREATE UNIQUE INDEX idx_users_username ON users(LOWER(username));
+CREATE INDEX idx_outbox_pending ON outbox(next_attempt_at) WHERE dead_at IS NULL;
 
-CREATE INDEX idx_user ON users(user_id, password);
+-- old slugs of a post, so old link can be redirected to the current slug
+CREATE TABLE post_slugs (
+    slug VARCHAR (255) PRIMARY KEY,
+    post_id INTEGER NOT NULL REFERENCES posts (post_id) ON UPDATE CASCADE ON DELETE CASCADE
+);
 
+CREATE INDEX idx_post_slugs_post ON post_slugs(post_id);
+
+-- full snapshot of a post on every create, update and restore, the latest one is the current post
+CREATE TABLE post_revisions (
+    revision_id SERIAL PRIMARY KEY,
+    post_id INTEGER NOT NULL REFERENCES posts (post_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    editor_id INTEGER REFERENCES users (user_id) ON UPDATE CASCADE ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL,
+    title VARCHAR (255) NOT NULL,
+    short_desc TEXT NOT NULL,
+    content TEXT NOT NULL,
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    categories TEXT[] NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX idx_post_revisions_post ON post_revisions(post_id, revision_id DESC);
+
+-- tag name is lowercase and unique, see taxonomy.NormalizeName
+CREATE TABLE tags (
+    tag_id SERIAL PRIMARY KEY,
+    name VARCHAR (64) NOT NULL UNIQUE
+);
+
+CREATE TABLE post_tags (
+    post_id INTEGER NOT NULL REFERENCES posts (post_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tags (tag_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    PRIMARY KEY (post_id, tag_id)
+);
+
+CREATE INDEX idx_post_tags_tag ON post_tags(tag_id);
+
+-- categories is a tree, top level category doesn't have a parent
+CREATE TABLE categories (
+    category_id SERIAL PRIMARY KEY,
+    name VARCHAR (64) NOT NULL UNIQUE,
+    parent_id INTEGER REFERENCES categories (category_id) ON UPDATE CASCADE ON DELETE SET NULL
+);
+
+CREATE INDEX idx_categories_parent ON categories(parent_id);
+
+CREATE TABLE post_categories (
+    post_id INTEGER NOT NULL REFERENCES posts (post_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    category_id INTEGER NOT NULL REFERENCES categories (category_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    PRIMARY KEY (post_id, category_id)
+);
+
+CREATE INDEX idx_post_categories_category ON post_categories(category_id);
+
+-- comment without parent is the start of a thread, deleting a post or a comment delete its replies
+CREATE TABLE comments (
+    comment_id SERIAL PRIMARY KEY,
+    post_id INTEGER NOT NULL REFERENCES posts (post_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    parent_id INTEGER REFERENCES comments (comment_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    author_id INTEGER NOT NULL REFERENCES users (user_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_comments_post_thread ON comments(post_id, created_at) WHERE parent_id IS NULL;
+CREATE INDEX idx_comments_parent ON comments(parent_id);
+
+-- post bookmarked by the user, the count is cached in redis, see caching.FavouriteKey
 CREATE TABLE favourites (
+    user_id INTEGER NOT NULL,
+    post_id INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
     FOREIGN KEY (user_id) REFERENCES users (user_id) ON UPDATE CASCADE ON DELETE CASCADE,
     FOREIGN KEY (post_id) REFERENCES posts (post_id) ON UPDATE CASCADE ON DELETE CASCADE,
     CONSTRAINT favourites_pkey PRIMARY KEY (user_id, post_id) -- explicit pk
-)
\ No newline at end of file
+);
+
+CREATE INDEX idx_favourites_post ON favourites(post_id);
+CREATE INDEX idx_favourites_user_created ON favourites(user_id, created_at DESC);
+
+-- refresh token of a login, only the sha256 of the token is stored. The token is replaced on every refresh,
+-- the replaced tokens of a login share its family_id so a replayed token revoke the whole family
+CREATE TABLE refresh_tokens (
+    refresh_token_id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users (user_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    family_id TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX idx_refresh_tokens_family ON refresh_tokens(family_id);
+CREATE INDEX idx_refresh_tokens_user ON refresh_tokens(user_id);
+
+-- token of a password reset link, only the sha256 of the token is stored. Resetting the password
+-- use up every token of the user
+CREATE TABLE password_resets (
+    password_reset_id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users (user_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP
+);
+
+CREATE INDEX idx_password_resets_user ON password_resets(user_id);
+
+-- token of an email verification link, only the sha256 of the token is stored
+CREATE TABLE email_verifications (
+    email_verification_id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users (user_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP
+);
+
+CREATE INDEX idx_email_verifications_user ON email_verifications(user_id);
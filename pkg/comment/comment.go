@@ -0,0 +1,17 @@
+package comment
+
+import "github.com/izzanzahrial/blog-api-echo/pkg/repository"
+
+type Comment struct {
+	PostID int64 `json:"post_id" validate:"required"`
+	// ParentID is the comment that is replied to, nil start a new thread
+	ParentID *int64 `json:"parent_id"`
+	AuthorID int64  `json:"author_id" validate:"required"`
+	Content  string `json:"content" validate:"required,max=5000"`
+}
+
+// Thread is a comment with its replies, used to return the whole thread
+type Thread struct {
+	repository.Comment
+	Replies []*Thread `json:"replies"`
+}
@@ -0,0 +1,270 @@
+package comment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/izzanzahrial/blog-api-echo/pkg/posting"
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+	"github.com/stretchr/testify/mock"
+)
+
+var (
+	ErrCommentIsntValidate = errors.New("comment data from handler isn't validate")
+	ErrNotCommentAuthor    = errors.New("user isn't the author of the comment")
+	ErrParentNotInPost     = errors.New("replied comment doesn't belong to the post")
+)
+
+type Service interface {
+	Create(ctx context.Context, c Comment) (repository.Comment, error)
+	Update(ctx context.Context, userID int64, id int64, content string) (repository.Comment, error)
+	Delete(ctx context.Context, userID int64, id int64) error
+	FindThreads(ctx context.Context, postID int64, from int, size int) ([]*Thread, error)
+}
+
+type MockService struct {
+	mock.Mock
+}
+
+func (m *MockService) Create(ctx context.Context, c Comment) (repository.Comment, error) {
+	args := m.Called(ctx, c)
+	return args.Get(0).(repository.Comment), args.Error(1)
+}
+
+func (m *MockService) Update(ctx context.Context, userID int64, id int64, content string) (repository.Comment, error) {
+	args := m.Called(ctx, userID, id, content)
+	return args.Get(0).(repository.Comment), args.Error(1)
+}
+
+func (m *MockService) Delete(ctx context.Context, userID int64, id int64) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+func (m *MockService) FindThreads(ctx context.Context, postID int64, from int, size int) ([]*Thread, error) {
+	args := m.Called(ctx, postID, from, size)
+	return args.Get(0).([]*Thread), args.Error(1)
+}
+
+type DBtx interface {
+	Begin() (repository.Tx, error)
+}
+
+type service struct {
+	Repository     repository.CommentRepository
+	PostRepository repository.Post
+	Outbox         repository.OutboxRepository
+	DB             DBtx
+	Validate       *validator.Validate
+}
+
+func NewService(rp repository.CommentRepository, prp repository.Post, ob repository.OutboxRepository, db DBtx, val *validator.Validate) Service {
+	return &service{
+		Repository:     rp,
+		PostRepository: prp,
+		Outbox:         ob,
+		DB:             db,
+		Validate:       val,
+	}
+}
+
+// Create add the comment to a published post, a reply must be in the same post as the comment it replies to
+func (cs *service) Create(ctx context.Context, c Comment) (repository.Comment, error) {
+	c.Content = strings.TrimSpace(c.Content)
+	if err := cs.Validate.Struct(c); err != nil {
+		return repository.Comment{}, fmt.Errorf("failed to validate: %v because %w", c, ErrCommentIsntValidate)
+	}
+
+	tx, err := cs.DB.Begin()
+	if err != nil {
+		return repository.Comment{}, fmt.Errorf("failed to begin transaction for: %v because %w", c, err)
+	}
+	defer tx.Rollback()
+
+	post, err := cs.PostRepository.FindByID(ctx, tx, c.PostID)
+	if err != nil {
+		return repository.Comment{}, err
+	}
+
+	if post.Status != repository.StatusPublished {
+		return repository.Comment{}, fmt.Errorf("failed to comment post: %d because %w", c.PostID, repository.ErrPostNotFound)
+	}
+
+	if c.ParentID != nil {
+		parent, err := cs.Repository.FindByID(ctx, tx, *c.ParentID)
+		if err != nil {
+			return repository.Comment{}, err
+		}
+		if parent.PostID != c.PostID {
+			return repository.Comment{}, ErrParentNotInPost
+		}
+	}
+
+	comment, err := cs.Repository.Create(ctx, tx, repository.Comment{
+		PostID:    c.PostID,
+		ParentID:  c.ParentID,
+		Author:    repository.Author{ID: c.AuthorID},
+		Content:   c.Content,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return repository.Comment{}, err
+	}
+
+	// the indexed and cached post carry the comment count
+	if err := posting.Sync(ctx, cs.Outbox, tx, post.ID, post.Slug); err != nil {
+		return repository.Comment{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return repository.Comment{}, fmt.Errorf("failed to commit transaction: %v because %w", comment, err)
+	}
+
+	return comment, nil
+}
+
+func (cs *service) Update(ctx context.Context, userID int64, id int64, content string) (repository.Comment, error) {
+	content = strings.TrimSpace(content)
+	if err := cs.Validate.Var(content, "required,max=5000"); err != nil {
+		return repository.Comment{}, fmt.Errorf("failed to validate comment: %d because %w", id, ErrCommentIsntValidate)
+	}
+
+	tx, err := cs.DB.Begin()
+	if err != nil {
+		return repository.Comment{}, fmt.Errorf("failed to begin transaction for comment: %d because %w", id, err)
+	}
+	defer tx.Rollback()
+
+	comment, err := cs.Repository.FindByID(ctx, tx, id)
+	if err != nil {
+		return repository.Comment{}, err
+	}
+
+	if comment.Author.ID != userID {
+		return repository.Comment{}, ErrNotCommentAuthor
+	}
+
+	comment.Content = content
+	comment.UpdatedAt = time.Now()
+
+	comment, err = cs.Repository.Update(ctx, tx, comment)
+	if err != nil {
+		return repository.Comment{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return repository.Comment{}, fmt.Errorf("failed to commit transaction: %v because %w", comment, err)
+	}
+
+	return comment, nil
+}
+
+// Delete remove the comment with all of its replies
+func (cs *service) Delete(ctx context.Context, userID int64, id int64) error {
+	tx, err := cs.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for comment: %d because %w", id, err)
+	}
+	defer tx.Rollback()
+
+	comment, err := cs.Repository.FindByID(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	if comment.Author.ID != userID {
+		return ErrNotCommentAuthor
+	}
+
+	if err := cs.Repository.Delete(ctx, tx, id); err != nil {
+		return err
+	}
+
+	post, err := cs.PostRepository.FindByID(ctx, tx, comment.PostID)
+	if err != nil {
+		return err
+	}
+
+	if post.Status == repository.StatusPublished {
+		if err := posting.Sync(ctx, cs.Outbox, tx, post.ID, post.Slug); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction for comment: %d because %w", id, err)
+	}
+
+	return nil
+}
+
+// FindThreads return a page of the threads of a published post, each with all of its replies
+func (cs *service) FindThreads(ctx context.Context, postID int64, from int, size int) ([]*Thread, error) {
+	tx, err := cs.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction for comments of post: %d because %w", postID, err)
+	}
+	defer tx.Rollback()
+
+	post, err := cs.PostRepository.FindByID(ctx, tx, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	if post.Status != repository.StatusPublished {
+		return nil, fmt.Errorf("failed to find comments of post: %d because %w", postID, repository.ErrPostNotFound)
+	}
+
+	roots, err := cs.Repository.FindThreads(ctx, tx, postID, from, size)
+	if err != nil {
+		return nil, err
+	}
+
+	rootIDs := make([]int64, 0, len(roots))
+	for _, root := range roots {
+		rootIDs = append(rootIDs, root.ID)
+	}
+
+	replies := []repository.Comment{}
+	if len(rootIDs) > 0 {
+		replies, err = cs.Repository.FindReplies(ctx, tx, rootIDs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction for comments of post: %d because %w", postID, err)
+	}
+
+	return buildThreads(roots, replies), nil
+}
+
+// buildThreads nest the replies under the comment they reply to, keeping the order of the slices
+func buildThreads(roots []repository.Comment, replies []repository.Comment) []*Thread {
+	nodes := make(map[int64]*Thread, len(roots)+len(replies))
+	threads := make([]*Thread, 0, len(roots))
+	for _, root := range roots {
+		node := &Thread{Comment: root, Replies: []*Thread{}}
+		nodes[root.ID] = node
+		threads = append(threads, node)
+	}
+
+	for _, reply := range replies {
+		nodes[reply.ID] = &Thread{Comment: reply, Replies: []*Thread{}}
+	}
+
+	for _, reply := range replies {
+		if reply.ParentID == nil || nodes[*reply.ParentID] == nil {
+			continue
+		}
+		parent := nodes[*reply.ParentID]
+		parent.Replies = append(parent.Replies, nodes[reply.ID])
+	}
+
+	return threads
+}
@@ -0,0 +1,43 @@
+package comment
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildThreads(t *testing.T) {
+	first := int64(1)
+	reply := int64(3)
+	roots := []repository.Comment{
+		{ID: 1, PostID: 1, Content: "first"},
+		{ID: 2, PostID: 1, Content: "second"},
+	}
+	replies := []repository.Comment{
+		{ID: 3, PostID: 1, ParentID: &first, Content: "reply"},
+		{ID: 4, PostID: 1, ParentID: &reply, Content: "reply of reply"},
+		{ID: 5, PostID: 1, ParentID: &first, Content: "another reply"},
+	}
+
+	threads := buildThreads(roots, replies)
+
+	assert.Len(t, threads, 2)
+	assert.Equal(t, "first", threads[0].Content)
+	assert.Len(t, threads[0].Replies, 2)
+	assert.Equal(t, "reply", threads[0].Replies[0].Content)
+	assert.Equal(t, "reply of reply", threads[0].Replies[0].Replies[0].Content)
+	assert.Equal(t, "another reply", threads[0].Replies[1].Content)
+	assert.Empty(t, threads[1].Replies)
+}
+
+func TestServiceCreateEmptyComment(t *testing.T) {
+	service := NewService(new(repository.MockCommentPostgre), new(repository.MockPostingPostgre), new(repository.MockOutboxPostgre), nil, validator.New())
+
+	_, err := service.Create(context.Background(), Comment{PostID: 1, AuthorID: 1, Content: "   "})
+
+	assert.True(t, errors.Is(err, ErrCommentIsntValidate))
+}
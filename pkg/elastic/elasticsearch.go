@@ -6,9 +6,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/elastic/go-elasticsearch/v8/esapi"
@@ -19,22 +19,44 @@ import (
 type SearchResults struct {
 	Total int         `json:"total"`
 	Hits  []*Document `json:"hits"`
+	// Facets and DidYouMean is only filled by FindByTitleContent
+	Facets     *repository.Facets `json:"facets,omitempty"`
+	DidYouMean string             `json:"did_you_mean,omitempty"`
 }
 
 type Document struct {
-	ID      int    `json:"id"`
-	Title   string `json:"title"`
-	Content string `json:"content"`
+	ID           int               `json:"id"`
+	Title        string            `json:"title"`
+	Slug         string            `json:"slug"`
+	ShortDesc    string            `json:"short_desc"`
+	Content      string            `json:"content"`
+	CreatedAt    time.Time         `json:"created_at"`
+	Status       string            `json:"status"`
+	Author       repository.Author `json:"author"`
+	Tags         []string          `json:"tags"`
+	Categories   []string          `json:"categories"`
+	CommentCount int64             `json:"comment_count"`
+	// Score and Highlights are filled from the search hit, they aren't part of the source
+	Score      float64             `json:"score"`
+	Highlights map[string][]string `json:"highlights,omitempty"`
 }
 
 type ElasticDB interface {
 	CreateIndex(index string) error
+	EnsureIndex(ctx context.Context, index string) error
+	Bulk(ctx context.Context, index string, posts []repository.PostData) error
+	SwapAlias(ctx context.Context, alias string, index string) ([]string, error)
+	DeleteIndices(ctx context.Context, indices []string) error
 	Insert(ctx context.Context, post repository.PostData) error
 	Update(ctx context.Context, post repository.PostData) error
+	Upsert(ctx context.Context, post repository.PostData) error
 	Delete(ctx context.Context, id string) error
 	FindByID(ctx context.Context, id string) (repository.PostData, error)
-	FindByTitleContent(ctx context.Context, query string, from int, size int) (*SearchResults, error)
-	FindByRecent(ctx context.Context, from int, size int) (*SearchResults, error)
+	FindBySlug(ctx context.Context, slug string) (repository.PostData, error)
+	FindByTitleContent(ctx context.Context, query repository.SearchQuery, filter repository.PostFilter, from int, size int) (*SearchResults, error)
+	FindByRecent(ctx context.Context, filter repository.PostFilter, from int, size int) (*SearchResults, error)
+	Suggest(ctx context.Context, prefix string, size int) ([]repository.Suggestion, error)
+	Related(ctx context.Context, id int64, size int) ([]repository.PostData, error)
 }
 
 type MockElastic struct {
@@ -46,11 +68,46 @@ func (me *MockElastic) CreateIndex(index string) error {
 	return args.Error(0)
 }
 
+func (me *MockElastic) EnsureIndex(ctx context.Context, index string) error {
+	args := me.Called(ctx, index)
+	return args.Error(0)
+}
+
+func (me *MockElastic) Bulk(ctx context.Context, index string, posts []repository.PostData) error {
+	args := me.Called(ctx, index, posts)
+	return args.Error(0)
+}
+
+func (me *MockElastic) SwapAlias(ctx context.Context, alias string, index string) ([]string, error) {
+	args := me.Called(ctx, alias, index)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (me *MockElastic) DeleteIndices(ctx context.Context, indices []string) error {
+	args := me.Called(ctx, indices)
+	return args.Error(0)
+}
+
+func (me *MockElastic) Suggest(ctx context.Context, prefix string, size int) ([]repository.Suggestion, error) {
+	args := me.Called(ctx, prefix, size)
+	return args.Get(0).([]repository.Suggestion), args.Error(1)
+}
+
+func (me *MockElastic) Related(ctx context.Context, id int64, size int) ([]repository.PostData, error) {
+	args := me.Called(ctx, id, size)
+	return args.Get(0).([]repository.PostData), args.Error(1)
+}
+
 func (me *MockElastic) Insert(ctx context.Context, post repository.PostData) error {
 	args := me.Called(ctx, post)
 	return args.Error(0)
 }
 
+func (me *MockElastic) Upsert(ctx context.Context, post repository.PostData) error {
+	args := me.Called(ctx, post)
+	return args.Error(0)
+}
+
 func (me *MockElastic) Update(ctx context.Context, post repository.PostData) error {
 	args := me.Called(ctx, post)
 	return args.Error(0)
@@ -66,13 +123,18 @@ func (me *MockElastic) FindByID(ctx context.Context, id string) (repository.Post
 	return args.Get(0).(repository.PostData), args.Error(1)
 }
 
-func (me *MockElastic) FindByTitleContent(ctx context.Context, query string, from int, size int) (*SearchResults, error) {
-	args := me.Called(ctx, query, from, size)
+func (me *MockElastic) FindBySlug(ctx context.Context, slug string) (repository.PostData, error) {
+	args := me.Called(ctx, slug)
+	return args.Get(0).(repository.PostData), args.Error(1)
+}
+
+func (me *MockElastic) FindByTitleContent(ctx context.Context, query repository.SearchQuery, filter repository.PostFilter, from int, size int) (*SearchResults, error) {
+	args := me.Called(ctx, query, filter, from, size)
 	return args.Get(0).(*SearchResults), args.Error(1)
 }
 
-func (me *MockElastic) FindByRecent(ctx context.Context, from int, size int) (*SearchResults, error) {
-	args := me.Called(ctx, from, size)
+func (me *MockElastic) FindByRecent(ctx context.Context, filter repository.PostFilter, from int, size int) (*SearchResults, error) {
+	args := me.Called(ctx, filter, from, size)
 	return args.Get(0).(*SearchResults), args.Error(1)
 }
 
@@ -82,9 +144,9 @@ type Elastic struct {
 	Alias  string
 }
 
-func NewElastic(username, password string, addresses ...string) *Elastic {
+func NewElastic(username, password string, addresses ...string) (*Elastic, error) {
 	if len(addresses)%2 == 0 {
-		log.Fatalf("don't use even number for creating elasticsearch node, you create : %d", len(addresses))
+		return nil, fmt.Errorf("don't use even number for creating elasticsearch node, you create : %d", len(addresses))
 	}
 
 	cfg := elasticsearch.Config{
@@ -95,17 +157,22 @@ func NewElastic(username, password string, addresses ...string) *Elastic {
 
 	es, err := elasticsearch.NewClient(cfg)
 	if err != nil {
-		log.Fatalf("error creating client : %s", err)
+		return nil, fmt.Errorf("error creating client : %w", err)
 	}
 
 	return &Elastic{
 		Client: es,
-	}
+	}, nil
+}
+
+// AliasOf return the alias the application read and write the index through
+func AliasOf(index string) string {
+	return index + "_alias"
 }
 
 func (e *Elastic) CreateIndex(index string) error {
 	e.Index = index
-	e.Alias = index + "_alias"
+	e.Alias = AliasOf(index)
 
 	res, err := e.Client.Indices.Exists([]string{e.Index})
 	if err != nil {
@@ -116,7 +183,7 @@ func (e *Elastic) CreateIndex(index string) error {
 		return fmt.Errorf("error index existence response: %s", res.String())
 	}
 
-	res, err = e.Client.Indices.Create(e.Index)
+	res, err = e.Client.Indices.Create(e.Index, e.Client.Indices.Create.WithBody(strings.NewReader(postIndex)))
 	if err != nil {
 		return fmt.Errorf("cannot create index: %w", err)
 	}
@@ -135,6 +202,164 @@ func (e *Elastic) CreateIndex(index string) error {
 	return nil
 }
 
+// EnsureIndex create the index unless it's already there, so an interrupted reindex can carry on with it
+func (e *Elastic) EnsureIndex(ctx context.Context, index string) error {
+	res, err := e.Client.Indices.Exists([]string{index}, e.Client.Indices.Exists.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("cannot check index existense: %w", err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode == 200 {
+		return nil
+	}
+
+	res, err = e.Client.Indices.Create(index,
+		e.Client.Indices.Create.WithContext(ctx),
+		e.Client.Indices.Create.WithBody(strings.NewReader(postIndex)),
+	)
+	if err != nil {
+		return fmt.Errorf("cannot create index: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("error index creation response: %s", res.String())
+	}
+
+	return nil
+}
+
+// Bulk index the posts into the index with a single request, indexing a post again replace it
+func (e *Elastic) Bulk(ctx context.Context, index string, posts []repository.PostData) error {
+	var body bytes.Buffer
+	for _, post := range posts {
+		meta := fmt.Sprintf(`{"index":{"_id":"%d"}}`, post.ID)
+		doc, err := json.Marshal(post)
+		if err != nil {
+			return fmt.Errorf("failed to marshal: %w", err)
+		}
+
+		body.WriteString(meta + "\n")
+		body.Write(doc)
+		body.WriteString("\n")
+	}
+
+	res, err := e.Client.Bulk(&body, e.Client.Bulk.WithContext(ctx), e.Client.Bulk.WithIndex(index))
+	if err != nil {
+		return fmt.Errorf("failed to bulk index documents: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("failed because there's an error in response: %s", res.String())
+	}
+
+	var r struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			ID     string `json:"_id"`
+			Status int    `json:"status"`
+			Error  struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return fmt.Errorf("failed to decode the bulk response: %w", err)
+	}
+
+	if !r.Errors {
+		return nil
+	}
+
+	// a bulk request succeed as a whole even when some of the documents failed
+	var failed []string
+	for _, item := range r.Items {
+		for _, result := range item {
+			if result.Status > 299 {
+				failed = append(failed, fmt.Sprintf("%s: [%s] %s", result.ID, result.Error.Type, result.Error.Reason))
+			}
+		}
+	}
+
+	return fmt.Errorf("failed to bulk index %d documents: %s", len(failed), strings.Join(failed, ", "))
+}
+
+// SwapAlias point the alias at the index and away from every other index in one atomic request,
+// it return the indices the alias was pointing at before
+func (e *Elastic) SwapAlias(ctx context.Context, alias string, index string) ([]string, error) {
+	res, err := e.Client.Indices.GetAlias(e.Client.Indices.GetAlias.WithContext(ctx), e.Client.Indices.GetAlias.WithName(alias))
+	if err != nil {
+		return nil, fmt.Errorf("cannot get index alias: %w", err)
+	}
+	defer res.Body.Close()
+
+	var previous []string
+	if res.StatusCode != 404 {
+		if res.IsError() {
+			return nil, fmt.Errorf("error index alias response: %s", res.String())
+		}
+
+		var indices map[string]interface{}
+		if err := json.NewDecoder(res.Body).Decode(&indices); err != nil {
+			return nil, fmt.Errorf("failed to decode the alias response: %w", err)
+		}
+		for name := range indices {
+			if name != index {
+				previous = append(previous, name)
+			}
+		}
+	}
+
+	actions := []map[string]interface{}{
+		{"add": map[string]string{"index": index, "alias": alias}},
+	}
+	for _, name := range previous {
+		actions = append(actions, map[string]interface{}{"remove": map[string]string{"index": name, "alias": alias}})
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"actions": actions})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal: %w", err)
+	}
+
+	res, err = e.Client.Indices.UpdateAliases(bytes.NewReader(body), e.Client.Indices.UpdateAliases.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("cannot swap index alias: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("error index alias swap response: %s", res.String())
+	}
+
+	return previous, nil
+}
+
+// DeleteIndices delete the indices, a missing index isn't an error
+func (e *Elastic) DeleteIndices(ctx context.Context, indices []string) error {
+	if len(indices) == 0 {
+		return nil
+	}
+
+	res, err := e.Client.Indices.Delete(indices,
+		e.Client.Indices.Delete.WithContext(ctx),
+		e.Client.Indices.Delete.WithIgnoreUnavailable(true),
+	)
+	if err != nil {
+		return fmt.Errorf("cannot delete indices: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("error index deletion response: %s", res.String())
+	}
+
+	return nil
+}
+
 func (e *Elastic) Insert(ctx context.Context, post repository.PostData) error {
 	body, err := json.Marshal(post)
 	if err != nil {
@@ -185,6 +410,33 @@ func (e *Elastic) Update(ctx context.Context, post repository.PostData) error {
 	return nil
 }
 
+// Upsert index the whole post, replacing the document if it's already there
+func (e *Elastic) Upsert(ctx context.Context, post repository.PostData) error {
+	body, err := json.Marshal(post)
+	if err != nil {
+		return fmt.Errorf("failed to marshal: %w", err)
+	}
+
+	req := esapi.IndexRequest{
+		Index:      e.Index,
+		DocumentID: strconv.Itoa(int(post.ID)),
+		Body:       bytes.NewReader(body),
+	}
+
+	res, err := req.Do(ctx, e.Client)
+	if err != nil {
+		return fmt.Errorf("failed to index document: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("failed because there's an error in response: %s", res.String())
+	}
+
+	return nil
+}
+
+// Delete remove the document, deleting a missing document isn't an error
 func (e *Elastic) Delete(ctx context.Context, postID string) error {
 	req := esapi.DeleteRequest{
 		Index:      e.Index,
@@ -197,7 +449,7 @@ func (e *Elastic) Delete(ctx context.Context, postID string) error {
 	}
 	defer res.Body.Close()
 
-	if res.IsError() {
+	if res.IsError() && res.StatusCode != 404 {
 		return fmt.Errorf("failed because there's an error in response: %s", res.String())
 	}
 
@@ -237,13 +489,46 @@ func (e *Elastic) FindByID(ctx context.Context, id string) (repository.PostData,
 	return post, nil
 }
 
-func (e *Elastic) FindByTitleContent(ctx context.Context, query string, from int, size int) (*SearchResults, error) {
+func (e *Elastic) FindBySlug(ctx context.Context, slug string) (repository.PostData, error) {
+	res, err := e.Client.Search(
+		e.Client.Search.WithContext(ctx),
+		e.Client.Search.WithIndex(e.Index),
+		e.Client.Search.WithBody(strings.NewReader(fmt.Sprintf(searchSlug, jsonString(slug)))),
+	)
+	if err != nil {
+		return repository.PostData{}, fmt.Errorf("failed to find the post by slug: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return repository.PostData{}, fmt.Errorf("failed because there's an error in response: %s", res.String())
+	}
+
+	var r struct {
+		Hits struct {
+			Hits []struct {
+				Source repository.PostData `json:"_source"`
+			}
+		}
+	}
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return repository.PostData{}, fmt.Errorf("failed to decode the result body: %w", err)
+	}
+
+	if len(r.Hits.Hits) < 1 {
+		return repository.PostData{}, fmt.Errorf("failed to find the post by slug: %s because %w", slug, repository.ErrPostNotFound)
+	}
+
+	return r.Hits.Hits[0].Source, nil
+}
+
+func (e *Elastic) FindByTitleContent(ctx context.Context, query repository.SearchQuery, filter repository.PostFilter, from int, size int) (*SearchResults, error) {
 	var results SearchResults
 
 	res, err := e.Client.Search(
 		e.Client.Search.WithContext(ctx),
 		e.Client.Search.WithIndex(e.Index),
-		e.Client.Search.WithBody(e.BuildBody(from, size, query)),
+		e.Client.Search.WithBody(e.BuildBody(from, size, query, filter)),
 		e.Client.Search.WithTrackTotalHits(true),
 	)
 	if err != nil {
@@ -266,10 +551,25 @@ func (e *Elastic) FindByTitleContent(ctx context.Context, query string, from int
 				Value int
 			}
 			Hits []struct {
-				ID     string          `json:"_id"`
-				Source json.RawMessage `json:"_source"`
+				ID        string              `json:"_id"`
+				Score     float64             `json:"_score"`
+				Source    json.RawMessage     `json:"_source"`
+				Highlight map[string][]string `json:"highlight"`
 			}
 		}
+		Suggest map[string][]struct {
+			Text    string `json:"text"`
+			Options []struct {
+				Text string `json:"text"`
+			} `json:"options"`
+		} `json:"suggest"`
+		Aggregations map[string]struct {
+			Buckets []struct {
+				Key         interface{} `json:"key"`
+				KeyAsString string      `json:"key_as_string"`
+				DocCount    int64       `json:"doc_count"`
+			} `json:"buckets"`
+		} `json:"aggregations"`
 	}
 
 	var r envelopeResponse
@@ -279,6 +579,36 @@ func (e *Elastic) FindByTitleContent(ctx context.Context, query string, from int
 
 	results.Total = r.Hits.Total.Value
 
+	// the suggester only correct the word that isn't in the index, the best option come first
+	if results.Total == 0 {
+		corrections := map[string]string{}
+		for _, entry := range r.Suggest[spellSuggester] {
+			if len(entry.Options) > 0 {
+				corrections[entry.Text] = entry.Options[0].Text
+			}
+		}
+		results.DidYouMean = query.Corrected(corrections)
+	}
+
+	// the date histogram key is the epoch millis, its formatted month is in key_as_string
+	if len(r.Aggregations) > 0 {
+		results.Facets = &repository.Facets{}
+		for name, dest := range map[string]*[]repository.FacetBucket{
+			"tags":    &results.Facets.Tags,
+			"authors": &results.Facets.Authors,
+			"months":  &results.Facets.Months,
+		} {
+			*dest = []repository.FacetBucket{}
+			for _, bucket := range r.Aggregations[name].Buckets {
+				key := bucket.KeyAsString
+				if key == "" {
+					key = fmt.Sprint(bucket.Key)
+				}
+				*dest = append(*dest, repository.FacetBucket{Key: key, Count: bucket.DocCount})
+			}
+		}
+	}
+
 	if len(r.Hits.Hits) < 1 {
 		results.Hits = []*Document{}
 		return &results, nil
@@ -294,6 +624,8 @@ func (e *Elastic) FindByTitleContent(ctx context.Context, query string, from int
 		if err := json.Unmarshal(hit.Source, &doc); err != nil {
 			return &results, err
 		}
+		doc.Score = hit.Score
+		doc.Highlights = hit.Highlight
 
 		results.Hits = append(results.Hits, &doc)
 	}
@@ -302,13 +634,13 @@ func (e *Elastic) FindByTitleContent(ctx context.Context, query string, from int
 }
 
 // TODO : implement find by recent elastic
-func (e *Elastic) FindByRecent(ctx context.Context, from int, size int) (*SearchResults, error) {
+func (e *Elastic) FindByRecent(ctx context.Context, filter repository.PostFilter, from int, size int) (*SearchResults, error) {
 	var results SearchResults
 
 	res, err := e.Client.Search(
 		e.Client.Search.WithContext(ctx),
 		e.Client.Search.WithIndex(e.Index),
-		e.Client.Search.WithBody(e.BuildBody(from, size, "")),
+		e.Client.Search.WithBody(e.BuildBody(from, size, repository.SearchQuery{}, filter)),
 		e.Client.Search.WithTrackTotalHits(true),
 	)
 	if err != nil {
@@ -366,15 +698,87 @@ func (e *Elastic) FindByRecent(ctx context.Context, from int, size int) (*Search
 	return &results, nil
 }
 
-func (e *Elastic) BuildBody(from int, size int, query string) io.Reader {
+// Suggest complete the prefix with the title of published posts through the edge ngram of the title
+func (e *Elastic) Suggest(ctx context.Context, prefix string, size int) ([]repository.Suggestion, error) {
+	res, err := e.Client.Search(
+		e.Client.Search.WithContext(ctx),
+		e.Client.Search.WithIndex(e.Index),
+		e.Client.Search.WithBody(strings.NewReader(fmt.Sprintf(searchSuggest, size, jsonString(prefix)))),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to suggest title: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("failed because there's an error in response: %s", res.String())
+	}
+
+	var r struct {
+		Hits struct {
+			Hits []struct {
+				Source repository.Suggestion `json:"_source"`
+			}
+		}
+	}
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return nil, fmt.Errorf("failed to decode the result body: %w", err)
+	}
+
+	suggestions := []repository.Suggestion{}
+	for _, hit := range r.Hits.Hits {
+		suggestions = append(suggestions, hit.Source)
+	}
+
+	return suggestions, nil
+}
+
+// Related find the published posts that look like the post, by the words and tags they share
+func (e *Elastic) Related(ctx context.Context, id int64, size int) ([]repository.PostData, error) {
+	res, err := e.Client.Search(
+		e.Client.Search.WithContext(ctx),
+		e.Client.Search.WithIndex(e.Index),
+		e.Client.Search.WithBody(strings.NewReader(fmt.Sprintf(searchRelated, size, jsonString(strconv.FormatInt(id, 10))))),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find post related to post: %d because %w", id, err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("failed because there's an error in response: %s", res.String())
+	}
+
+	var r struct {
+		Hits struct {
+			Hits []struct {
+				Source repository.PostData `json:"_source"`
+			}
+		}
+	}
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return nil, fmt.Errorf("failed to decode the result body: %w", err)
+	}
+
+	posts := []repository.PostData{}
+	for _, hit := range r.Hits.Hits {
+		posts = append(posts, hit.Source)
+	}
+
+	return posts, nil
+}
+
+func (e *Elastic) BuildBody(from int, size int, query repository.SearchQuery, filter repository.PostFilter) io.Reader {
 	var body strings.Builder
 
 	body.WriteString("{\n")
 
-	if query == "" {
-		body.WriteString(searchRecet)
+	if query.Empty() {
+		body.WriteString(fmt.Sprintf(searchRecet, buildFilter(filter), from, size))
 	} else {
-		body.WriteString(fmt.Sprintf(searchMatch, from, size, query))
+		must, mustNot := buildQuery(query)
+		body.WriteString(fmt.Sprintf(searchMatch, from, size, must, mustNot, buildFilter(filter), repository.FacetSize,
+			jsonString(strings.Join(query.Words(), " ")), jsonString(spellSuggester)))
 	}
 
 	body.WriteString("\n}")
@@ -382,8 +786,116 @@ func (e *Elastic) BuildBody(from int, size int, query string) io.Reader {
 	return strings.NewReader(body.String())
 }
 
-const searchRecet = `"query": { 
-							"match_all": {}
+// searchFields is the field a clause is matched against, by the field it's limited to
+var searchFields = map[string][]string{
+	"":        {"title^2", "short_desc", "content"},
+	"title":   {"title"},
+	"content": {"content"},
+}
+
+// buildQuery return the bool must and must_not clauses of the query, every clause is a multi_match
+// so nothing the user typed is read as query syntax by elasticsearch
+func buildQuery(query repository.SearchQuery) (string, string) {
+	must := []interface{}{}
+	mustNot := []interface{}{}
+
+	for _, group := range query.Groups {
+		clauses := make([]interface{}, 0, len(group.Clauses))
+		for _, clause := range group.Clauses {
+			clauses = append(clauses, matchClause(clause, query.Mode))
+		}
+
+		switch {
+		case group.Exclude:
+			mustNot = append(mustNot, clauses...)
+		case len(clauses) == 1:
+			must = append(must, clauses[0])
+		default:
+			must = append(must, map[string]interface{}{
+				"bool": map[string]interface{}{"should": clauses, "minimum_should_match": 1},
+			})
+		}
+	}
+
+	mustBody, _ := json.Marshal(must)
+	mustNotBody, _ := json.Marshal(mustNot)
+	return string(mustBody), string(mustNotBody)
+}
+
+// matchClause match a phrase word for word, and a word by itself with a typo allowed in the fuzzy mode
+func matchClause(clause repository.QueryClause, mode string) map[string]interface{} {
+	match := map[string]interface{}{
+		"query":  clause.Text,
+		"fields": searchFields[clause.Field],
+	}
+
+	if clause.Phrase {
+		match["type"] = "phrase"
+	} else if mode == repository.QueryModeFuzzy {
+		match["fuzziness"] = "AUTO"
+	}
+
+	return map[string]interface{}{"multi_match": match}
+}
+
+// buildFilter return the bool filter clauses, only published post is returned unless the filter ask otherwise
+// even though draft and archived post shouldn't be in the index, in case the index is out of sync
+func buildFilter(filter repository.PostFilter) string {
+	status := filter.Status
+	if status == "" {
+		status = repository.StatusPublished
+	}
+
+	clauses := []interface{}{
+		term("status", status),
+	}
+
+	if filter.Tag != "" {
+		clauses = append(clauses, term("tags", filter.Tag))
+	}
+
+	if filter.Category != "" {
+		clauses = append(clauses, term("categories", filter.Category))
+	}
+
+	if filter.Author != "" {
+		clauses = append(clauses, term("author.username", filter.Author))
+	}
+
+	if filter.From != nil || filter.To != nil {
+		createdAt := map[string]interface{}{}
+		if filter.From != nil {
+			createdAt["gte"] = filter.From
+		}
+		if filter.To != nil {
+			createdAt["lt"] = filter.To
+		}
+		clauses = append(clauses, map[string]interface{}{
+			"range": map[string]interface{}{"created_at": createdAt},
+		})
+	}
+
+	body, _ := json.Marshal(clauses)
+	return string(body)
+}
+
+// jsonString quote the string for a JSON body, %q quote it the Go way and a control character
+// or invalid UTF-8 become an escape JSON doesn't have
+func jsonString(s string) string {
+	body, _ := json.Marshal(s)
+	return string(body)
+}
+
+func term(field string, value interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"term": map[string]interface{}{field: value},
+	}
+}
+
+const searchRecet = `"query": {
+							"bool": {
+									"filter": %s
+							}
 					},
 					"from": %d,
 					"size": %d,
@@ -391,16 +903,83 @@ const searchRecet = `"query": {
 							{"created_at": {"order": "desc"}}
 					]`
 
-const searchMatch = `"query": {
-							"from": %d,
-							"size": %d,
-							"multi_match": {
-										"query": %q,
-										"fields": [
-												"title^2",
-												"short_desc",
-												"content"
-										],
-										"type": "phrase"
-									}
-								}`
+// spellSuggester is the name of the term suggester of searchMatch
+const spellSuggester = "did_you_mean"
+
+const searchMatch = `"from": %d,
+					"size": %d,
+					"query": {
+							"bool": {
+									"must": %s,
+									"must_not": %s,
+									"filter": %s
+							}
+					},
+					"highlight": {
+							"pre_tags": ["<mark>"],
+							"post_tags": ["</mark>"],
+							"fields": {
+									"title": {"number_of_fragments": 0},
+									"content": {"fragment_size": 150, "number_of_fragments": 3}
+							}
+					},
+					"aggs": {
+							"tags": {"terms": {"field": "tags", "size": %[6]d}},
+							"authors": {"terms": {"field": "author.username", "size": %[6]d}},
+							"months": {"date_histogram": {"field": "created_at", "calendar_interval": "month", "format": "yyyy-MM", "min_doc_count": 1}}
+					},
+					"suggest": {
+							"text": %[7]s,
+							%[8]s: {"term": {"field": "spell", "suggest_mode": "missing", "sort": "score"}}
+					}`
+
+const searchSuggest = `{
+					"size": %d,
+					"_source": ["id", "title", "slug"],
+					"query": {
+							"bool": {
+									"must": {
+											"match": {
+													"title.autocomplete": {"query": %s, "operator": "and"}
+											}
+									},
+									"filter": [
+											{"term": {"status": "published"}}
+									]
+							}
+					}
+}`
+
+// searchRelated use the indexed post itself as the example, a blog is small so a word used once
+// in a single other post is still worth matching
+const searchRelated = `{
+					"size": %d,
+					"query": {
+							"bool": {
+									"must": {
+											"more_like_this": {
+													"fields": ["title", "short_desc", "content", "tags"],
+													"like": [{"_id": %s}],
+													"min_term_freq": 1,
+													"min_doc_freq": 1,
+													"max_query_terms": 50
+											}
+									},
+									"filter": [
+											{"term": {"status": "published"}}
+									]
+							}
+					}
+}`
+
+const searchSlug = `{
+					"size": 1,
+					"query": {
+							"bool": {
+									"filter": [
+											{"term": {"slug": %s}},
+											{"term": {"status": "published"}}
+									]
+							}
+					}
+}`
@@ -0,0 +1,63 @@
+package elastic
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildQuery(t *testing.T) {
+	query, err := repository.ParseQuery(`gorutine OR title:channel -"java thread"`, repository.QueryModeFuzzy)
+	assert.NoError(t, err)
+
+	must, mustNot := buildQuery(query)
+	assert.JSONEq(t, `[{"bool": {"minimum_should_match": 1, "should": [
+		{"multi_match": {"query": "gorutine", "fields": ["title^2", "short_desc", "content"], "fuzziness": "AUTO"}},
+		{"multi_match": {"query": "channel", "fields": ["title"], "fuzziness": "AUTO"}}
+	]}}]`, must)
+	assert.JSONEq(t, `[
+		{"multi_match": {"query": "java thread", "fields": ["title^2", "short_desc", "content"], "type": "phrase"}}
+	]`, mustNot)
+}
+
+func TestRequestBody(t *testing.T) {
+	// a control character and invalid UTF-8 are escaped the JSON way
+	value := "go\a\x00\xff"
+
+	subtest := []struct {
+		name string
+		body string
+	}{
+		{
+			name: "Suggest",
+			body: fmt.Sprintf(searchSuggest, 5, jsonString(value)),
+		},
+		{
+			name: "Related",
+			body: fmt.Sprintf(searchRelated, 5, jsonString("42")),
+		},
+		{
+			name: "Slug",
+			body: fmt.Sprintf(searchSlug, jsonString(value)),
+		},
+	}
+
+	query, err := repository.ParseQuery(value, repository.QueryModeFuzzy)
+	assert.NoError(t, err)
+	search, err := io.ReadAll((&Elastic{}).BuildBody(0, 10, query, repository.PostFilter{}))
+	assert.NoError(t, err)
+	subtest = append(subtest, struct {
+		name string
+		body string
+	}{name: "Search", body: string(search)})
+
+	for _, test := range subtest {
+		t.Run(test.name, func(t *testing.T) {
+			assert.True(t, json.Valid([]byte(test.body)), test.body)
+		})
+	}
+}
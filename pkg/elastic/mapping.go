@@ -0,0 +1,146 @@
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// MappingVersion is stored in the _meta of the index, bump it whenever postIndex change
+// and run cmd/reindex so the new mapping is applied
+const MappingVersion = 2
+
+// postIndex is the settings and mapping of the post index, text is analyzed in english with stemming
+// and stopwords, title.keyword is used for exact match and title.autocomplete for search as you type,
+// the text is also copied unstemmed into spell for the spelling suggestion,
+// field that isn't listed is kept in the source but not indexed
+const postIndex = `{
+	"settings": {
+		"analysis": {
+			"filter": {
+				"english_stop": {"type": "stop", "stopwords": "_english_"},
+				"english_stemmer": {"type": "stemmer", "language": "english"},
+				"english_possessive_stemmer": {"type": "stemmer", "language": "possessive_english"},
+				"autocomplete_edge_ngram": {"type": "edge_ngram", "min_gram": 2, "max_gram": 20}
+			},
+			"analyzer": {
+				"english_text": {
+					"tokenizer": "standard",
+					"filter": ["english_possessive_stemmer", "lowercase", "english_stop", "english_stemmer"]
+				},
+				"autocomplete": {
+					"tokenizer": "standard",
+					"filter": ["lowercase", "autocomplete_edge_ngram"]
+				},
+				"autocomplete_search": {
+					"tokenizer": "standard",
+					"filter": ["lowercase"]
+				}
+			}
+		}
+	},
+	"mappings": {
+		"_meta": {"version": 2},
+		"dynamic": "false",
+		"properties": {
+			"id": {"type": "long"},
+			"title": {
+				"type": "text",
+				"analyzer": "english_text",
+				"copy_to": ["spell"],
+				"fields": {
+					"keyword": {"type": "keyword", "ignore_above": 256},
+					"autocomplete": {"type": "text", "analyzer": "autocomplete", "search_analyzer": "autocomplete_search"}
+				}
+			},
+			"slug": {"type": "keyword"},
+			"short_desc": {"type": "text", "analyzer": "english_text", "copy_to": ["spell"]},
+			"content": {"type": "text", "analyzer": "english_text", "copy_to": ["spell"]},
+			"spell": {"type": "text", "analyzer": "standard"},
+			"created_at": {"type": "date"},
+			"status": {"type": "keyword"},
+			"publish_at": {"type": "date"},
+			"deleted_at": {"type": "date"},
+			"author": {
+				"properties": {
+					"id": {"type": "long"},
+					"username": {"type": "keyword"},
+					"name": {"type": "text", "fields": {"keyword": {"type": "keyword", "ignore_above": 256}}}
+				}
+			},
+			"tags": {"type": "keyword"},
+			"categories": {"type": "keyword"},
+			"comment_count": {"type": "long"}
+		}
+	}
+}`
+
+type indexDefinition struct {
+	Settings map[string]interface{} `json:"settings"`
+	Mappings map[string]interface{} `json:"mappings"`
+}
+
+// CheckMapping compare the index behind e.Index with postIndex and return every difference,
+// an index created before the mapping was changed need a reindex
+func (e *Elastic) CheckMapping(ctx context.Context) ([]string, error) {
+	var expected indexDefinition
+	if err := json.Unmarshal([]byte(postIndex), &expected); err != nil {
+		return nil, fmt.Errorf("failed to decode the post index definition: %w", err)
+	}
+
+	res, err := e.Client.Indices.Get([]string{e.Index}, e.Client.Indices.Get.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("cannot get index: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("error index response: %s", res.String())
+	}
+
+	// the index is keyed by its own name even when it's asked through the alias
+	var indices map[string]struct {
+		Settings struct {
+			Index map[string]interface{} `json:"index"`
+		} `json:"settings"`
+		Mappings map[string]interface{} `json:"mappings"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&indices); err != nil {
+		return nil, fmt.Errorf("failed to decode the index response: %w", err)
+	}
+
+	var drift []string
+	for name, actual := range indices {
+		analysis, _ := actual.Settings.Index["analysis"].(map[string]interface{})
+		drift = append(drift, definitionDrift(name+".settings.analysis", expected.Settings["analysis"], analysis)...)
+		drift = append(drift, definitionDrift(name+".mappings", expected.Mappings, actual.Mappings)...)
+	}
+	sort.Strings(drift)
+
+	return drift, nil
+}
+
+// definitionDrift list every setting of expected that actual is missing or set differently,
+// value is compared by how it's printed since the index return number and boolean as string
+func definitionDrift(path string, expected interface{}, actual interface{}) []string {
+	expectedMap, ok := expected.(map[string]interface{})
+	if !ok {
+		if fmt.Sprint(expected) != fmt.Sprint(actual) {
+			return []string{fmt.Sprintf("%s is %v, want %v", path, actual, expected)}
+		}
+		return nil
+	}
+
+	actualMap, ok := actual.(map[string]interface{})
+	if !ok {
+		return []string{fmt.Sprintf("%s is missing", path)}
+	}
+
+	var drift []string
+	for key, value := range expectedMap {
+		drift = append(drift, definitionDrift(path+"."+key, value, actualMap[key])...)
+	}
+
+	return drift
+}
@@ -0,0 +1,57 @@
+package elastic
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostIndexVersion(t *testing.T) {
+	var definition indexDefinition
+	assert.NoError(t, json.Unmarshal([]byte(postIndex), &definition))
+
+	meta := definition.Mappings["_meta"].(map[string]interface{})
+	assert.Equal(t, float64(MappingVersion), meta["version"])
+}
+
+func TestDefinitionDrift(t *testing.T) {
+	var expected indexDefinition
+	assert.NoError(t, json.Unmarshal([]byte(postIndex), &expected))
+
+	// the index echo the mapping back with number and boolean as string
+	var same map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"analysis": {
+			"filter": {
+				"english_stop": {"type": "stop", "stopwords": "_english_"},
+				"english_stemmer": {"type": "stemmer", "language": "english"},
+				"english_possessive_stemmer": {"type": "stemmer", "language": "possessive_english"},
+				"autocomplete_edge_ngram": {"type": "edge_ngram", "min_gram": "2", "max_gram": "20"}
+			},
+			"analyzer": {
+				"english_text": {"tokenizer": "standard", "filter": ["english_possessive_stemmer", "lowercase", "english_stop", "english_stemmer"]},
+				"autocomplete": {"tokenizer": "standard", "filter": ["lowercase", "autocomplete_edge_ngram"]},
+				"autocomplete_search": {"tokenizer": "standard", "filter": ["lowercase"]}
+			}
+		}
+	}`), &same))
+	assert.Empty(t, definitionDrift("posts", expected.Settings["analysis"], same["analysis"]))
+
+	// an index created with dynamic mapping guess text with a keyword subfield and no _meta
+	var guessed map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"properties": {
+			"slug": {"type": "text", "fields": {"keyword": {"type": "keyword", "ignore_above": 256}}}
+		}
+	}`), &guessed))
+
+	drift := definitionDrift("posts", map[string]interface{}{
+		"_meta":      map[string]interface{}{"version": float64(1)},
+		"properties": map[string]interface{}{"slug": map[string]interface{}{"type": "keyword"}},
+	}, guessed)
+	assert.ElementsMatch(t, []string{
+		"posts._meta is missing",
+		"posts.properties.slug.type is text, want keyword",
+	}, drift)
+}
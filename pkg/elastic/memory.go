@@ -0,0 +1,339 @@
+package elastic
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+)
+
+// Memory keep the indices in memory instead of elasticsearch, an alias point at a single index like AliasOf
+// does for the application. It search with repository.SearchPosts so it's only meant for test and deployment
+// with a handful of posts
+type Memory struct {
+	Index string
+
+	mu      sync.RWMutex
+	indices map[string]map[int64]repository.PostData
+	aliases map[string]string
+}
+
+// NewMemory return a Memory with the posts index already created, read and written through its alias
+func NewMemory() *Memory {
+	m := &Memory{
+		indices: map[string]map[int64]repository.PostData{},
+		aliases: map[string]string{},
+	}
+	m.CreateIndex("posts")
+	m.Index = AliasOf("posts")
+
+	return m
+}
+
+func (m *Memory) CreateIndex(index string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.indices[index]; ok {
+		return fmt.Errorf("index %s already exists", index)
+	}
+
+	m.Index = index
+	m.indices[index] = map[int64]repository.PostData{}
+	m.aliases[AliasOf(index)] = index
+
+	return nil
+}
+
+// EnsureIndex create the index unless it's already there
+func (m *Memory) EnsureIndex(ctx context.Context, index string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.indices[index]; !ok {
+		m.indices[index] = map[int64]repository.PostData{}
+	}
+
+	return nil
+}
+
+// Bulk index the posts into the index, indexing a post again replace it
+func (m *Memory) Bulk(ctx context.Context, index string, posts []repository.PostData) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	docs, err := m.index(index)
+	if err != nil {
+		return err
+	}
+
+	for _, post := range posts {
+		docs[post.ID] = post
+	}
+
+	return nil
+}
+
+// SwapAlias point the alias at the index and return the index it was pointing at before
+func (m *Memory) SwapAlias(ctx context.Context, alias string, index string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.indices[index]; !ok {
+		return nil, fmt.Errorf("index %s doesn't exist", index)
+	}
+
+	var previous []string
+	if name, ok := m.aliases[alias]; ok && name != index {
+		previous = append(previous, name)
+	}
+	m.aliases[alias] = index
+
+	return previous, nil
+}
+
+// DeleteIndices delete the indices and the alias pointing at them, a missing index isn't an error
+func (m *Memory) DeleteIndices(ctx context.Context, indices []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, index := range indices {
+		delete(m.indices, index)
+		for alias, name := range m.aliases {
+			if name == index {
+				delete(m.aliases, alias)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (m *Memory) Insert(ctx context.Context, post repository.PostData) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	docs, err := m.index(m.Index)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := docs[post.ID]; ok {
+		return fmt.Errorf("failed to create document: %d because it already exists", post.ID)
+	}
+	docs[post.ID] = post
+
+	return nil
+}
+
+func (m *Memory) Update(ctx context.Context, post repository.PostData) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	docs, err := m.index(m.Index)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := docs[post.ID]; !ok {
+		return fmt.Errorf("failed to update document: %d because %w", post.ID, repository.ErrPostNotFound)
+	}
+	docs[post.ID] = post
+
+	return nil
+}
+
+// Upsert index the whole post, replacing the document if it's already there
+func (m *Memory) Upsert(ctx context.Context, post repository.PostData) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	docs, err := m.index(m.Index)
+	if err != nil {
+		return err
+	}
+	docs[post.ID] = post
+
+	return nil
+}
+
+// Delete remove the document, deleting a missing document isn't an error
+func (m *Memory) Delete(ctx context.Context, postID string) error {
+	id, err := strconv.Atoi(postID)
+	if err != nil {
+		return fmt.Errorf("failed to delete document: %s because %w", postID, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	docs, err := m.index(m.Index)
+	if err != nil {
+		return err
+	}
+	delete(docs, int64(id))
+
+	return nil
+}
+
+func (m *Memory) FindByID(ctx context.Context, id string) (repository.PostData, error) {
+	postID, err := strconv.Atoi(id)
+	if err != nil {
+		return repository.PostData{}, fmt.Errorf("failed to find the post by id: %s because %w", id, err)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	docs, err := m.index(m.Index)
+	if err != nil {
+		return repository.PostData{}, err
+	}
+
+	post, ok := docs[int64(postID)]
+	if !ok {
+		return repository.PostData{}, fmt.Errorf("failed to find the post by id: %s because %w", id, repository.ErrPostNotFound)
+	}
+
+	return post, nil
+}
+
+func (m *Memory) FindBySlug(ctx context.Context, slug string) (repository.PostData, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	docs, err := m.index(m.Index)
+	if err != nil {
+		return repository.PostData{}, err
+	}
+
+	for _, post := range docs {
+		if post.Slug == slug {
+			return post, nil
+		}
+	}
+
+	return repository.PostData{}, fmt.Errorf("failed to find the post by slug: %s because %w", slug, repository.ErrPostNotFound)
+}
+
+func (m *Memory) FindByTitleContent(ctx context.Context, query repository.SearchQuery, filter repository.PostFilter, from int, size int) (*SearchResults, error) {
+	posts, err := m.posts()
+	if err != nil {
+		return &SearchResults{}, err
+	}
+
+	result := repository.SearchPosts(posts, query, filter, from, size)
+	results := SearchResults{
+		Total:      int(result.Total),
+		Hits:       []*Document{},
+		Facets:     result.Facets,
+		DidYouMean: result.DidYouMean,
+	}
+	for _, hit := range result.Hits {
+		doc := postToDocument(hit.PostData)
+		doc.Score = hit.Score
+		results.Hits = append(results.Hits, doc)
+	}
+
+	return &results, nil
+}
+
+func (m *Memory) FindByRecent(ctx context.Context, filter repository.PostFilter, from int, size int) (*SearchResults, error) {
+	posts, err := m.posts()
+	if err != nil {
+		return &SearchResults{}, err
+	}
+
+	results := SearchResults{Hits: []*Document{}}
+	for _, post := range posts {
+		if filter.Match(post) {
+			results.Total++
+		}
+	}
+	for _, post := range repository.RecentPosts(posts, filter, from, size) {
+		results.Hits = append(results.Hits, postToDocument(post))
+	}
+
+	return &results, nil
+}
+
+func (m *Memory) Suggest(ctx context.Context, prefix string, size int) ([]repository.Suggestion, error) {
+	posts, err := m.posts()
+	if err != nil {
+		return nil, err
+	}
+
+	return repository.SuggestPosts(posts, prefix, size), nil
+}
+
+func (m *Memory) Related(ctx context.Context, id int64, size int) ([]repository.PostData, error) {
+	m.mu.RLock()
+	docs, err := m.index(m.Index)
+	source, ok := docs[id]
+	m.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return []repository.PostData{}, nil
+	}
+
+	posts, err := m.posts()
+	if err != nil {
+		return nil, err
+	}
+
+	return repository.RelatedPosts(source, posts, size), nil
+}
+
+// index return the documents of the index or of the index the alias point at, mu must be held
+func (m *Memory) index(name string) (map[int64]repository.PostData, error) {
+	if index, ok := m.aliases[name]; ok {
+		name = index
+	}
+
+	docs, ok := m.indices[name]
+	if !ok {
+		return nil, fmt.Errorf("index %s doesn't exist", name)
+	}
+
+	return docs, nil
+}
+
+// posts return a copy of every document of m.Index
+func (m *Memory) posts() ([]repository.PostData, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	docs, err := m.index(m.Index)
+	if err != nil {
+		return nil, err
+	}
+
+	posts := make([]repository.PostData, 0, len(docs))
+	for _, post := range docs {
+		posts = append(posts, post)
+	}
+
+	return posts, nil
+}
+
+// postToDocument turn the post into the source of an elasticsearch hit
+func postToDocument(post repository.PostData) *Document {
+	return &Document{
+		ID:           int(post.ID),
+		Title:        post.Title,
+		Slug:         post.Slug,
+		ShortDesc:    post.ShortDesc,
+		Content:      post.Content,
+		CreatedAt:    post.CreatedAt,
+		Status:       post.Status,
+		Author:       post.Author,
+		Tags:         post.Tags,
+		Categories:   post.Categories,
+		CommentCount: post.CommentCount,
+	}
+}
@@ -0,0 +1,249 @@
+package favourite
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	caching "github.com/izzanzahrial/blog-api-echo/pkg/redis"
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+	"github.com/stretchr/testify/mock"
+)
+
+// countTTL is how long a favourite count live in the cache
+const countTTL = time.Duration(3600) * time.Second
+
+type Service interface {
+	Favourite(ctx context.Context, userID int64, postID int64) error
+	Unfavourite(ctx context.Context, userID int64, postID int64) error
+	FindByUser(ctx context.Context, userID int64, from int, size int) ([]repository.PostData, error)
+	Annotate(ctx context.Context, viewerID int64, posts []repository.PostData) error
+}
+
+type MockService struct {
+	mock.Mock
+}
+
+func (m *MockService) Favourite(ctx context.Context, userID int64, postID int64) error {
+	args := m.Called(ctx, userID, postID)
+	return args.Error(0)
+}
+
+func (m *MockService) Unfavourite(ctx context.Context, userID int64, postID int64) error {
+	args := m.Called(ctx, userID, postID)
+	return args.Error(0)
+}
+
+func (m *MockService) FindByUser(ctx context.Context, userID int64, from int, size int) ([]repository.PostData, error) {
+	args := m.Called(ctx, userID, from, size)
+	return args.Get(0).([]repository.PostData), args.Error(1)
+}
+
+func (m *MockService) Annotate(ctx context.Context, viewerID int64, posts []repository.PostData) error {
+	args := m.Called(ctx, viewerID, posts)
+	return args.Error(0)
+}
+
+type DBtx interface {
+	Begin() (repository.Tx, error)
+}
+
+type service struct {
+	Repository     repository.FavouriteRepository
+	PostRepository repository.Post
+	DB             DBtx
+	Cache          caching.Cache
+}
+
+func NewService(rp repository.FavouriteRepository, prp repository.Post, db DBtx, cache caching.Cache) Service {
+	return &service{
+		Repository:     rp,
+		PostRepository: prp,
+		DB:             db,
+		Cache:          cache,
+	}
+}
+
+// Favourite bookmark a published post for the user
+func (fs *service) Favourite(ctx context.Context, userID int64, postID int64) error {
+	tx, err := fs.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for favourite post: %d because %w", postID, err)
+	}
+	defer tx.Rollback()
+
+	if err := fs.PostRepository.LockByID(ctx, tx, postID); err != nil {
+		return err
+	}
+
+	post, err := fs.PostRepository.FindByID(ctx, tx, postID)
+	if err != nil {
+		return err
+	}
+
+	if post.Status != repository.StatusPublished {
+		return fmt.Errorf("failed to favourite post: %d because %w", postID, repository.ErrPostNotFound)
+	}
+
+	if err := fs.Repository.Add(ctx, tx, userID, postID); err != nil {
+		return err
+	}
+
+	if err := fs.cacheCount(ctx, tx, postID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		fs.forget(postID)
+		return fmt.Errorf("failed to commit transaction for favourite post: %d because %w", postID, err)
+	}
+
+	return nil
+}
+
+func (fs *service) Unfavourite(ctx context.Context, userID int64, postID int64) error {
+	tx, err := fs.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for unfavourite post: %d because %w", postID, err)
+	}
+	defer tx.Rollback()
+
+	if err := fs.PostRepository.LockByID(ctx, tx, postID); err != nil {
+		return err
+	}
+
+	if err := fs.Repository.Remove(ctx, tx, userID, postID); err != nil {
+		return err
+	}
+
+	if err := fs.cacheCount(ctx, tx, postID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		fs.forget(postID)
+		return fmt.Errorf("failed to commit transaction for unfavourite post: %d because %w", postID, err)
+	}
+
+	return nil
+}
+
+// FindByUser return a page of the user favourites, the latest favourite first
+func (fs *service) FindByUser(ctx context.Context, userID int64, from int, size int) ([]repository.PostData, error) {
+	tx, err := fs.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction for favourites of user: %d because %w", userID, err)
+	}
+	defer tx.Rollback()
+
+	posts, err := fs.Repository.FindByUser(ctx, tx, userID, from, size)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction for favourites of user: %d because %w", userID, err)
+	}
+
+	if err := fs.Annotate(ctx, userID, posts); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// Annotate fill the favourite count from the cache, counting the missing one in the repository,
+// and whether the viewer favourited the post, viewerID is 0 for anonymous user. A count read here
+// only fill a missing key, the count a favourite or unfavourite cached meanwhile is newer
+func (fs *service) Annotate(ctx context.Context, viewerID int64, posts []repository.PostData) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(posts))
+	counts := make(map[int64]int64, len(posts))
+	var missed []int64
+	for _, post := range posts {
+		ids = append(ids, post.ID)
+
+		val, err := fs.Cache.Get(context.Background(), caching.FavouriteKey(post.ID)).Result()
+		if err != nil {
+			missed = append(missed, post.ID)
+			continue
+		}
+
+		count, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			missed = append(missed, post.ID)
+			continue
+		}
+		counts[post.ID] = count
+	}
+
+	favourited := map[int64]bool{}
+	if len(missed) > 0 || viewerID != 0 {
+		tx, err := fs.DB.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction for favourites of posts: %v because %w", ids, err)
+		}
+		defer tx.Rollback()
+
+		if len(missed) > 0 {
+			found, err := fs.Repository.Count(ctx, tx, missed)
+			if err != nil {
+				return err
+			}
+
+			// a post without favourite is cached too, or it would be counted on every view
+			for _, id := range missed {
+				counts[id] = found[id]
+				fs.Cache.SetNX(context.Background(), caching.FavouriteKey(id), found[id], countTTL)
+			}
+		}
+
+		if viewerID != 0 {
+			favourited, err = fs.Repository.FavouritedBy(ctx, tx, viewerID, ids)
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit transaction for favourites of posts: %v because %w", ids, err)
+		}
+	}
+
+	for i := range posts {
+		posts[i].FavouriteCount = counts[posts[i].ID]
+		posts[i].FavouritedByMe = favourited[posts[i].ID]
+	}
+
+	return nil
+}
+
+// cacheCount cache the count the transaction see before it's committed. The post is locked, so the favourite
+// and unfavourite of the post cache their count one at a time in the order they commit
+func (fs *service) cacheCount(ctx context.Context, tx repository.Tx, postID int64) error {
+	counts, err := fs.Repository.Count(ctx, tx, []int64{postID})
+	if err != nil {
+		return err
+	}
+
+	op1 := fs.Cache.Set(context.Background(), caching.FavouriteKey(postID), counts[postID], countTTL)
+	if err := op1.Err(); err != nil {
+		return fmt.Errorf("failed to cache favourite count of post: %d because %w", postID, err)
+	}
+
+	return nil
+}
+
+// forget drop the cached count of a change that wasn't committed, so it's counted again on the next read
+func (fs *service) forget(postID int64) error {
+	op1 := fs.Cache.Del(context.Background(), caching.FavouriteKey(postID))
+	if err := op1.Err(); err != nil {
+		return fmt.Errorf("failed to remove cached favourite count of post: %d because %w", postID, err)
+	}
+
+	return nil
+}
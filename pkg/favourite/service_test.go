@@ -0,0 +1,79 @@
+package favourite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+	redisDB "github.com/izzanzahrial/blog-api-echo/pkg/redis"
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestServiceAnnotateCached(t *testing.T) {
+	mockRepo := new(repository.MockFavouritePostgre)
+	mockRedis := new(redisDB.MockRedis)
+
+	// every count is cached and the viewer is anonymous, so the repository isn't touched
+	service := NewService(mockRepo, new(repository.MockPostingPostgre), nil, mockRedis)
+
+	mockRedis.On("Get", mock.Anything, "favourites1").Return(redis.NewStringResult("3", nil)).Once()
+	mockRedis.On("Get", mock.Anything, "favourites2").Return(redis.NewStringResult("0", nil)).Once()
+
+	posts := []repository.PostData{{ID: 1, FavouritedByMe: true}, {ID: 2}}
+	err := service.Annotate(context.Background(), 0, posts)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), posts[0].FavouriteCount)
+	assert.False(t, posts[0].FavouritedByMe)
+	assert.Equal(t, int64(0), posts[1].FavouriteCount)
+	mockRedis.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "Count")
+}
+
+func TestServiceAnnotateCacheZero(t *testing.T) {
+	mockRepo := new(repository.MockFavouritePostgre)
+	cache := redisDB.NewMemory()
+	service := NewService(mockRepo, new(repository.MockPostingPostgre), repository.NewMemoryStore(), cache)
+
+	// the repository leave out the post nobody favourited, it's still cached as 0
+	mockRepo.On("Count", mock.Anything, mock.Anything, []int64{1}).Return(map[int64]int64{}, nil).Once()
+
+	for i := 0; i < 2; i++ {
+		posts := []repository.PostData{{ID: 1}}
+		assert.NoError(t, service.Annotate(context.Background(), 0, posts))
+		assert.Equal(t, int64(0), posts[0].FavouriteCount)
+	}
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestServiceFavouriteDuringAnnotate(t *testing.T) {
+	mockRepo := new(repository.MockFavouritePostgre)
+	mockPost := new(repository.MockPostingPostgre)
+	cache := redisDB.NewMemory()
+	service := NewService(mockRepo, mockPost, repository.NewMemoryStore(), cache)
+
+	post := repository.PostData{ID: 1, Status: repository.StatusPublished}
+	mockPost.On("LockByID", mock.Anything, mock.Anything, int64(1)).Return(nil).Once()
+	mockPost.On("FindByID", mock.Anything, mock.Anything, int64(1)).Return(post, nil).Once()
+	mockRepo.On("Add", mock.Anything, mock.Anything, int64(2), int64(1)).Return(nil).Once()
+
+	// the view count the post before the favourite is committed, the favourite cache its count
+	// before the view fill the missing key with the older count
+	mockRepo.On("Count", mock.Anything, mock.Anything, []int64{1}).Return(map[int64]int64{1: 0}, nil).Once().Run(func(args mock.Arguments) {
+		mockRepo.On("Count", mock.Anything, mock.Anything, []int64{1}).Return(map[int64]int64{1: 1}, nil).Once()
+		assert.NoError(t, service.Favourite(context.Background(), 2, 1))
+	})
+
+	posts := []repository.PostData{{ID: 1}}
+	assert.NoError(t, service.Annotate(context.Background(), 0, posts))
+
+	count, err := cache.Get(context.Background(), redisDB.FavouriteKey(1)).Result()
+	assert.NoError(t, err)
+	assert.Equal(t, "1", count)
+
+	mockRepo.AssertExpectations(t)
+	mockPost.AssertExpectations(t)
+}
@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/izzanzahrial/blog-api-echo/pkg/comment"
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+	"github.com/labstack/echo/v4"
+)
+
+// defaultThreadSize is how many threads is returned when the size query param is empty
+const defaultThreadSize = 20
+
+type commentHandler struct {
+	Service comment.Service
+}
+
+func NewCommentHandler(cs comment.Service) CommentHandler {
+	return &commentHandler{
+		Service: cs,
+	}
+}
+
+func (ch *commentHandler) Create(c echo.Context) error {
+	claims, err := jwtClaims(c)
+	if err != nil {
+		return echo.ErrUnauthorized
+	}
+
+	postID, err := strconv.Atoi(c.Param("postid"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	newComment := comment.Comment{
+		PostID:   int64(postID),
+		AuthorID: claims.ID,
+		Content:  c.FormValue("content"),
+	}
+
+	if parent := c.FormValue("parent_id"); parent != "" {
+		parentID, err := strconv.ParseInt(parent, 10, 64)
+		if err != nil {
+			return echo.ErrBadRequest
+		}
+		newComment.ParentID = &parentID
+	}
+
+	commentResponse, err := ch.Service.Create(c.Request().Context(), newComment)
+	if err != nil {
+		return commentError(err)
+	}
+
+	webResponse := webResponse{
+		Code:    http.StatusCreated,
+		Message: http.StatusText(http.StatusCreated),
+		Data:    commentResponse,
+	}
+
+	return c.JSON(http.StatusCreated, webResponse)
+}
+
+func (ch *commentHandler) Update(c echo.Context) error {
+	claims, err := jwtClaims(c)
+	if err != nil {
+		return echo.ErrUnauthorized
+	}
+
+	id, err := strconv.Atoi(c.Param("commentid"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	commentResponse, err := ch.Service.Update(c.Request().Context(), claims.ID, int64(id), c.FormValue("content"))
+	if err != nil {
+		return commentError(err)
+	}
+
+	webResponse := webResponse{
+		Code:    http.StatusAccepted,
+		Message: http.StatusText(http.StatusAccepted),
+		Data:    commentResponse,
+	}
+
+	return c.JSON(http.StatusAccepted, webResponse)
+}
+
+func (ch *commentHandler) Delete(c echo.Context) error {
+	claims, err := jwtClaims(c)
+	if err != nil {
+		return echo.ErrUnauthorized
+	}
+
+	id, err := strconv.Atoi(c.Param("commentid"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	if err := ch.Service.Delete(c.Request().Context(), claims.ID, int64(id)); err != nil {
+		return commentError(err)
+	}
+
+	webResponse := webResponse{
+		Code:    http.StatusOK,
+		Message: http.StatusText(http.StatusOK),
+	}
+
+	return c.JSON(http.StatusOK, webResponse)
+}
+
+// FindThreads paginate the threads of the post with the "from" and "size" query param
+func (ch *commentHandler) FindThreads(c echo.Context) error {
+	postID, err := strconv.Atoi(c.Param("postid"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	from, size, err := queryPage(c, defaultThreadSize)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	threads, err := ch.Service.FindThreads(c.Request().Context(), int64(postID), from, size)
+	if err != nil {
+		return commentError(err)
+	}
+
+	webResponse := webResponse{
+		Code:    http.StatusOK,
+		Message: http.StatusText(http.StatusOK),
+		Data:    threads,
+	}
+
+	return c.JSON(http.StatusOK, webResponse)
+}
+
+// queryPage read the optional "from" and "size" query param
+func queryPage(c echo.Context, defaultSize int) (int, int, error) {
+	from, size := 0, defaultSize
+
+	if str := c.QueryParam("from"); str != "" {
+		n, err := strconv.Atoi(str)
+		if err != nil || n < 0 {
+			return 0, 0, echo.ErrBadRequest
+		}
+		from = n
+	}
+
+	if str := c.QueryParam("size"); str != "" {
+		n, err := strconv.Atoi(str)
+		if err != nil || n <= 0 {
+			return 0, 0, echo.ErrBadRequest
+		}
+		size = n
+	}
+
+	return from, size, nil
+}
+
+func commentError(err error) error {
+	switch {
+	case errors.Is(err, comment.ErrCommentIsntValidate), errors.Is(err, comment.ErrParentNotInPost):
+		return echo.ErrBadRequest
+	case errors.Is(err, comment.ErrNotCommentAuthor):
+		return echo.ErrForbidden
+	case errors.Is(err, repository.ErrCommentNotFound), errors.Is(err, repository.ErrPostNotFound):
+		return echo.ErrNotFound
+	default:
+		return echo.ErrInternalServerError
+	}
+}
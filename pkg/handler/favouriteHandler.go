@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/izzanzahrial/blog-api-echo/pkg/favourite"
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+	"github.com/labstack/echo/v4"
+)
+
+// defaultFavouriteSize is how many favourites is returned when the size query param is empty
+const defaultFavouriteSize = 20
+
+type favouriteHandler struct {
+	Service favourite.Service
+}
+
+func NewFavouriteHandler(fs favourite.Service) FavouriteHandler {
+	return &favouriteHandler{
+		Service: fs,
+	}
+}
+
+func (fh *favouriteHandler) Favourite(c echo.Context) error {
+	claims, err := jwtClaims(c)
+	if err != nil {
+		return echo.ErrUnauthorized
+	}
+
+	postID, err := strconv.Atoi(c.Param("postid"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	if err := fh.Service.Favourite(c.Request().Context(), claims.ID, int64(postID)); err != nil {
+		return favouriteError(err)
+	}
+
+	webResponse := webResponse{
+		Code:    http.StatusOK,
+		Message: http.StatusText(http.StatusOK),
+	}
+
+	return c.JSON(http.StatusOK, webResponse)
+}
+
+func (fh *favouriteHandler) Unfavourite(c echo.Context) error {
+	claims, err := jwtClaims(c)
+	if err != nil {
+		return echo.ErrUnauthorized
+	}
+
+	postID, err := strconv.Atoi(c.Param("postid"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	if err := fh.Service.Unfavourite(c.Request().Context(), claims.ID, int64(postID)); err != nil {
+		return favouriteError(err)
+	}
+
+	webResponse := webResponse{
+		Code:    http.StatusOK,
+		Message: http.StatusText(http.StatusOK),
+	}
+
+	return c.JSON(http.StatusOK, webResponse)
+}
+
+// FindByUser paginate the favourites of the logged in user with the "from" and "size" query param
+func (fh *favouriteHandler) FindByUser(c echo.Context) error {
+	claims, err := jwtClaims(c)
+	if err != nil {
+		return echo.ErrUnauthorized
+	}
+
+	from, size, err := queryPage(c, defaultFavouriteSize)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	posts, err := fh.Service.FindByUser(c.Request().Context(), claims.ID, from, size)
+	if err != nil {
+		return favouriteError(err)
+	}
+
+	webResponse := webResponse{
+		Code:    http.StatusOK,
+		Message: http.StatusText(http.StatusOK),
+		Data:    posts,
+	}
+
+	return c.JSON(http.StatusOK, webResponse)
+}
+
+func favouriteError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrPostNotFound):
+		return echo.ErrNotFound
+	default:
+		return echo.ErrInternalServerError
+	}
+}
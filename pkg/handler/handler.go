@@ -3,20 +3,60 @@ package handler
 import (
 	"errors"
 
+	"github.com/golang-jwt/jwt"
+	"github.com/izzanzahrial/blog-api-echo/pkg/user"
 	"github.com/labstack/echo/v4"
 )
 
 var (
 	ErrFailedToDecodeBody = errors.New("decoder failed to decode the body request")
+	ErrMissingJWTClaims   = errors.New("request doesn't carry valid jwt claims")
 )
 
 type PostHandler interface {
 	Create(c echo.Context) error
 	Update(c echo.Context) error
 	Delete(c echo.Context) error
+	Undelete(c echo.Context) error
+	FindTrash(c echo.Context) error
+	Publish(c echo.Context) error
+	Unpublish(c echo.Context) error
+	Archive(c echo.Context) error
+	Schedule(c echo.Context) error
+	Unschedule(c echo.Context) error
+	FindRevisions(c echo.Context) error
+	DiffRevisions(c echo.Context) error
+	Restore(c echo.Context) error
 	FindByID(c echo.Context) error
+	FindBySlug(c echo.Context) error
 	FindByTitleContent(c echo.Context) error
 	FindRecent(c echo.Context) error
+	Suggest(c echo.Context) error
+	Related(c echo.Context) error
+}
+
+type TaxonomyHandler interface {
+	CreateTag(c echo.Context) error
+	UpdateTag(c echo.Context) error
+	DeleteTag(c echo.Context) error
+	FindTags(c echo.Context) error
+	CreateCategory(c echo.Context) error
+	UpdateCategory(c echo.Context) error
+	DeleteCategory(c echo.Context) error
+	FindCategories(c echo.Context) error
+}
+
+type CommentHandler interface {
+	Create(c echo.Context) error
+	Update(c echo.Context) error
+	Delete(c echo.Context) error
+	FindThreads(c echo.Context) error
+}
+
+type FavouriteHandler interface {
+	Favourite(c echo.Context) error
+	Unfavourite(c echo.Context) error
+	FindByUser(c echo.Context) error
 }
 
 type UserHandler interface {
@@ -25,6 +65,17 @@ type UserHandler interface {
 	UpdatePassword(c echo.Context) error
 	Delete(c echo.Context) error
 	Login(c echo.Context) error
+	Refresh(c echo.Context) error
+	Logout(c echo.Context) error
+	LogoutAll(c echo.Context) error
+	FindUsers(c echo.Context) error
+	ChangeRole(c echo.Context) error
+	Suspend(c echo.Context) error
+	Unsuspend(c echo.Context) error
+	ForgotPassword(c echo.Context) error
+	ResetPassword(c echo.Context) error
+	VerifyEmail(c echo.Context) error
+	ResendVerification(c echo.Context) error
 }
 
 type webResponse struct {
@@ -32,3 +83,18 @@ type webResponse struct {
 	Message string      `json:"message"`
 	Data    interface{} `json:"data"`
 }
+
+// jwtClaims return the claims that the jwt middleware put in the context
+func jwtClaims(c echo.Context) (*user.JWTClaims, error) {
+	token, ok := c.Get("user").(*jwt.Token)
+	if !ok {
+		return nil, ErrMissingJWTClaims
+	}
+
+	claims, ok := token.Claims.(*user.JWTClaims)
+	if !ok {
+		return nil, ErrMissingJWTClaims
+	}
+
+	return claims, nil
+}
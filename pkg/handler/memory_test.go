@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/golang-jwt/jwt"
+	"github.com/izzanzahrial/blog-api-echo/pkg/favourite"
+	"github.com/izzanzahrial/blog-api-echo/pkg/posting"
+	caching "github.com/izzanzahrial/blog-api-echo/pkg/redis"
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+	"github.com/izzanzahrial/blog-api-echo/pkg/search"
+	"github.com/izzanzahrial/blog-api-echo/pkg/user"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+const testSignKey = "secret"
+
+// memoryApp serve the post routes of cmd/main.go over the memory storage,
+// the relay is run by the test instead of in the background
+type memoryApp struct {
+	e     *echo.Echo
+	store *repository.MemoryStore
+	relay *posting.Relay
+}
+
+func newMemoryApp() *memoryApp {
+	store := repository.NewMemoryStore()
+	cache := caching.NewMemory()
+	searcher := search.NewMemory()
+	postRepository := repository.NewPostMemory(store)
+	outboxRepository := repository.NewOutboxMemory(store)
+
+	favouriteService := favourite.NewService(repository.NewFavouriteMemory(store), postRepository, store, cache)
+	postService := posting.NewService(postRepository, outboxRepository, repository.NewTransactor(store), validator.New(), cache, searcher)
+	h := NewPostHandler(postService, favouriteService)
+
+	jwtConfig := middleware.JWTConfig{
+		Claims:        &user.JWTClaims{},
+		SigningMethod: middleware.AlgorithmHS256,
+		SigningKey:    []byte(testSignKey),
+	}
+	optionalJWTConfig := jwtConfig
+	optionalJWTConfig.ContinueOnIgnoredError = true
+	optionalJWTConfig.ErrorHandlerWithContext = func(err error, c echo.Context) error {
+		return nil
+	}
+
+	e := echo.New()
+	p := e.Group("/api/v1/posts")
+	p.POST("", h.Create, middleware.JWTWithConfig(jwtConfig))
+	p.GET("/search", h.FindByTitleContent, middleware.JWTWithConfig(optionalJWTConfig))
+	p.GET("/slug/:slug", h.FindBySlug, middleware.JWTWithConfig(optionalJWTConfig))
+	p.GET("/:postid", h.FindByID, middleware.JWTWithConfig(optionalJWTConfig))
+	p.DELETE("/:postid", h.Delete, middleware.JWTWithConfig(jwtConfig))
+	p.POST("/:postid/publish", h.Publish, middleware.JWTWithConfig(jwtConfig))
+
+	return &memoryApp{
+		e:     e,
+		store: store,
+		relay: posting.NewRelay(postRepository, outboxRepository, store, cache, searcher, time.Second),
+	}
+}
+
+// createUser store the user straight in the repository and return the token to act as them
+func (app *memoryApp) createUser(t *testing.T, username string) (repository.User, string) {
+	tx, err := app.store.Begin()
+	assert.NoError(t, err)
+
+	u, err := repository.NewUserMemory(app.store).Create(context.Background(), tx, repository.User{
+		Email:    username + "@example.com",
+		Username: username,
+		Name:     username,
+		Password: "password",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, tx.Commit())
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &user.JWTClaims{
+		ID:       u.ID,
+		Email:    u.Email,
+		Username: u.Username,
+		Name:     u.Name,
+	}).SignedString([]byte(testSignKey))
+	assert.NoError(t, err)
+
+	return u, token
+}
+
+// do serve the request and decode the data of the response into data, unless it's nil
+func (app *memoryApp) do(t *testing.T, method string, target string, token string, form url.Values, data interface{}) int {
+	req := httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	if token != "" {
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	app.e.ServeHTTP(rec, req)
+
+	if data != nil && rec.Code < http.StatusMultipleChoices {
+		response := struct {
+			Data interface{} `json:"data"`
+		}{Data: data}
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Errorf("Failed to unmarshal data to webresponse")
+		}
+	}
+
+	return rec.Code
+}
+
+func TestMemoryPostLifecycle(t *testing.T) {
+	app := newMemoryApp()
+	author, token := app.createUser(t, "izzan")
+	_, otherToken := app.createUser(t, "other")
+
+	form := url.Values{}
+	form.Set("title", "Routing With Echo")
+	form.Set("short_desc", "group and middleware")
+	form.Set("content", "echo make routing simple")
+	form.Set("tags", "go")
+
+	var created repository.PostData
+	code := app.do(t, http.MethodPost, "/api/v1/posts", token, form, &created)
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, "routing-with-echo", created.Slug)
+	assert.Equal(t, author.Username, created.Author.Username)
+
+	idURL := "/api/v1/posts/" + strconv.FormatInt(created.ID, 10)
+
+	// the draft is only visible to its author
+	assert.Equal(t, http.StatusNotFound, app.do(t, http.MethodGet, idURL, "", nil, nil))
+	assert.Equal(t, http.StatusFound, app.do(t, http.MethodGet, idURL, token, nil, nil))
+	assert.Equal(t, http.StatusForbidden, app.do(t, http.MethodPost, idURL+"/publish", otherToken, nil, nil))
+	assert.Equal(t, http.StatusOK, app.do(t, http.MethodPost, idURL+"/publish", token, nil, nil))
+
+	var found repository.PostData
+	code = app.do(t, http.MethodGet, "/api/v1/posts/slug/"+created.Slug, "", nil, &found)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, created.ID, found.ID)
+	assert.Equal(t, repository.StatusPublished, found.Status)
+	assert.Equal(t, []string{"go"}, found.Tags)
+
+	// the search index only see the post once the relay delivered the outbox
+	delivered, err := app.relay.Deliver(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+
+	var result repository.SearchResult
+	code = app.do(t, http.MethodGet, "/api/v1/posts/search?query=echo&from=0&size=10", "", nil, &result)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, int64(1), result.Total)
+	if assert.Len(t, result.Hits, 1) {
+		assert.Equal(t, created.ID, result.Hits[0].ID)
+	}
+
+	// deleting the post withdraw it from the search and the cache on the next delivery
+	assert.Equal(t, http.StatusOK, app.do(t, http.MethodDelete, idURL, token, nil, nil))
+
+	_, err = app.relay.Deliver(context.Background())
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusNotFound, app.do(t, http.MethodGet, idURL, "", nil, nil))
+
+	result = repository.SearchResult{}
+	code = app.do(t, http.MethodGet, "/api/v1/posts/search?query=routing&from=0&size=10", "", nil, &result)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, int64(0), result.Total)
+}
+
+func TestMemoryRollback(t *testing.T) {
+	app := newMemoryApp()
+	author, _ := app.createUser(t, "izzan")
+	posts := repository.NewPostMemory(app.store)
+	ctx := context.Background()
+
+	tx, err := app.store.Begin()
+	assert.NoError(t, err)
+	post, err := posts.Create(ctx, tx, repository.PostData{Title: "Draft", Slug: "draft", Author: repository.Author{ID: author.ID}})
+	assert.NoError(t, err)
+	assert.NoError(t, posts.SetTags(ctx, tx, post.ID, []string{"go"}))
+	assert.NoError(t, tx.Rollback())
+
+	tx, err = app.store.Begin()
+	assert.NoError(t, err)
+	defer tx.Rollback()
+
+	_, err = posts.FindByID(ctx, tx, post.ID)
+	assert.ErrorIs(t, err, repository.ErrPostNotFound)
+
+	tags, err := repository.NewTagMemory(app.store).FindAll(ctx, tx)
+	assert.NoError(t, err)
+	assert.Empty(t, tags)
+}
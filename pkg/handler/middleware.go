@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"github.com/izzanzahrial/blog-api-echo/pkg/user"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// JWTWithDenylist is middleware.JWTWithConfig that also refuse the token revoked by a logout,
+// with ContinueOnIgnoredError the request carrying a revoked token go on as an anonymous one
+func JWTWithDenylist(config middleware.JWTConfig, denylist *user.Denylist) echo.MiddlewareFunc {
+	jwtMiddleware := middleware.JWTWithConfig(config)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return jwtMiddleware(func(c echo.Context) error {
+			claims, err := jwtClaims(c)
+			if err != nil {
+				return next(c)
+			}
+
+			revoked, err := denylist.IsRevoked(c.Request().Context(), claims)
+			if err != nil {
+				return echo.ErrInternalServerError
+			}
+
+			if revoked {
+				if !config.ContinueOnIgnoredError {
+					return echo.ErrUnauthorized
+				}
+				c.Set("user", nil)
+			}
+
+			return next(c)
+		})
+	}
+}
+
+// RequirePermission refuse the request of a user whose role wasn't permitted the permission,
+// it must come after the jwt middleware
+func RequirePermission(permission string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			claims, err := jwtClaims(c)
+			if err != nil {
+				return echo.ErrUnauthorized
+			}
+
+			if !claims.Can(permission) {
+				return echo.ErrForbidden
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// RequireVerified refuse the request of a user whose email isn't verified, only the request reported by when
+// is checked unless when is nil. It must come after the jwt middleware
+func RequireVerified(when func(c echo.Context) bool) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if when != nil && !when(c) {
+				return next(c)
+			}
+
+			claims, err := jwtClaims(c)
+			if err != nil {
+				return echo.ErrUnauthorized
+			}
+
+			if !claims.Verified {
+				return echo.ErrForbidden
+			}
+
+			return next(c)
+		}
+	}
+}
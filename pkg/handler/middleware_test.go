@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	caching "github.com/izzanzahrial/blog-api-echo/pkg/redis"
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+	"github.com/izzanzahrial/blog-api-echo/pkg/user"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJWTWithDenylist(t *testing.T) {
+	denylist := user.NewDenylist(caching.NewMemory(), time.Hour)
+
+	jwtConfig := middleware.JWTConfig{
+		Claims:        &user.JWTClaims{},
+		SigningMethod: middleware.AlgorithmHS256,
+		SigningKey:    []byte(testSignKey),
+	}
+	optionalJWTConfig := jwtConfig
+	optionalJWTConfig.ContinueOnIgnoredError = true
+	optionalJWTConfig.ErrorHandlerWithContext = func(err error, c echo.Context) error {
+		return nil
+	}
+
+	// the handler answer with the id of the user, 0 for anonymous
+	whoami := func(c echo.Context) error {
+		claims, err := jwtClaims(c)
+		if err != nil {
+			return c.JSON(http.StatusOK, 0)
+		}
+		return c.JSON(http.StatusOK, claims.ID)
+	}
+
+	e := echo.New()
+	e.GET("/required", whoami, JWTWithDenylist(jwtConfig, denylist))
+	e.GET("/optional", whoami, JWTWithDenylist(optionalJWTConfig, denylist))
+
+	claims := &user.JWTClaims{
+		ID: 1,
+		StandardClaims: jwt.StandardClaims{
+			Id:        "jti",
+			IssuedAt:  time.Now().Unix(),
+			ExpiresAt: time.Now().Add(time.Hour).Unix(),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testSignKey))
+	assert.NoError(t, err)
+
+	do := func(target string) (int, string) {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code, rec.Body.String()
+	}
+
+	code, body := do("/required")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "1\n", body)
+
+	assert.NoError(t, denylist.RevokeToken(context.Background(), claims))
+
+	code, _ = do("/required")
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	// the revoked token is ignored like an invalid one
+	code, body = do("/optional")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "0\n", body)
+}
+
+func TestRequirePermission(t *testing.T) {
+	jwtConfig := middleware.JWTConfig{
+		Claims:        &user.JWTClaims{},
+		SigningMethod: middleware.AlgorithmHS256,
+		SigningKey:    []byte(testSignKey),
+	}
+
+	e := echo.New()
+	e.DELETE("/posts", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}, middleware.JWTWithConfig(jwtConfig), RequirePermission(repository.PermissionPostDeleteAny))
+
+	subtest := []struct {
+		name        string
+		role        string
+		permissions []string
+		expected    int
+	}{
+		{
+			name:        "Editor",
+			role:        repository.RoleEditor,
+			permissions: []string{repository.PermissionPostCreate, repository.PermissionPostEditAny},
+			expected:    http.StatusForbidden,
+		},
+		{
+			name:        "Admin",
+			role:        repository.RoleAdmin,
+			permissions: []string{repository.PermissionPostDeleteAny},
+			expected:    http.StatusOK,
+		},
+	}
+
+	for _, test := range subtest {
+		t.Run(test.name, func(t *testing.T) {
+			token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &user.JWTClaims{
+				ID:          1,
+				Role:        test.role,
+				Permissions: test.permissions,
+			}).SignedString([]byte(testSignKey))
+			assert.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodDelete, "/posts", nil)
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, test.expected, rec.Code)
+		})
+	}
+}
+
+func TestRequireVerified(t *testing.T) {
+	jwtConfig := middleware.JWTConfig{
+		Claims:        &user.JWTClaims{},
+		SigningMethod: middleware.AlgorithmHS256,
+		SigningKey:    []byte(testSignKey),
+	}
+
+	e := echo.New()
+	e.POST("/posts", func(c echo.Context) error {
+		return c.NoContent(http.StatusCreated)
+	}, middleware.JWTWithConfig(jwtConfig), RequireVerified(PublishingPost))
+
+	subtest := []struct {
+		name     string
+		verified bool
+		status   string
+		expected int
+	}{
+		{
+			name:     "Unverified Draft",
+			status:   repository.StatusDraft,
+			expected: http.StatusCreated,
+		},
+		{
+			name:     "Unverified Published",
+			status:   repository.StatusPublished,
+			expected: http.StatusForbidden,
+		},
+		{
+			name:     "Verified Published",
+			verified: true,
+			status:   repository.StatusPublished,
+			expected: http.StatusCreated,
+		},
+	}
+
+	for _, test := range subtest {
+		t.Run(test.name, func(t *testing.T) {
+			token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &user.JWTClaims{
+				ID:       1,
+				Verified: test.verified,
+			}).SignedString([]byte(testSignKey))
+			assert.NoError(t, err)
+
+			form := url.Values{"status": {test.status}}
+			req := httptest.NewRequest(http.MethodPost, "/posts", strings.NewReader(form.Encode()))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, test.expected, rec.Code)
+		})
+	}
+}
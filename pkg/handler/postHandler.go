@@ -2,29 +2,64 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"net/url"
+	"path"
 	"strconv"
+	"time"
 
+	"github.com/go-playground/validator/v10"
+	"github.com/izzanzahrial/blog-api-echo/pkg/favourite"
 	"github.com/izzanzahrial/blog-api-echo/pkg/posting"
 	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+	"github.com/izzanzahrial/blog-api-echo/pkg/taxonomy"
+	"github.com/izzanzahrial/blog-api-echo/pkg/user"
 	"github.com/labstack/echo/v4"
 )
 
+// Title suggestion size, the front end show a handful of titles under the search box
+const (
+	defaultSuggestSize = 5
+	maxSuggestSize     = 10
+)
+
+// defaultRelatedSize is how many related posts is shown under a post, it can't go over posting.MaxRelated
+const defaultRelatedSize = 5
+
 type postHandler struct {
-	Service posting.Service
+	Service   posting.Service
+	Favourite favourite.Service
 }
 
-func NewPostHandler(ps posting.Service) PostHandler {
+func NewPostHandler(ps posting.Service, fs favourite.Service) PostHandler {
 	return &postHandler{
-		Service: ps,
+		Service:   ps,
+		Favourite: fs,
 	}
 }
 
 func (ph *postHandler) Create(c echo.Context) error {
+	claims, err := jwtClaims(c)
+	if err != nil {
+		return echo.ErrUnauthorized
+	}
+
 	var post posting.PostData
+	post.AuthorID = claims.ID
 	post.Title = c.FormValue("title")
 	post.ShortDesc = c.FormValue("short_desc")
 	post.Content = c.FormValue("content")
+	post.Status = c.FormValue("status")
+	post.Tags, post.Categories = formTaxonomy(c)
+
+	if publishAt := c.FormValue("publish_at"); publishAt != "" {
+		t, err := time.Parse(time.RFC3339, publishAt)
+		if err != nil {
+			return echo.ErrBadRequest
+		}
+		post.PublishAt = &t
+	}
 
 	// defer c.Request().Body.Close()
 
@@ -37,7 +72,7 @@ func (ph *postHandler) Create(c echo.Context) error {
 
 	postResponse, err := ph.Service.Create(ctx, post)
 	if err != nil {
-		return echo.ErrInternalServerError
+		return postError(err)
 	}
 
 	webResponse := webResponse{
@@ -49,11 +84,21 @@ func (ph *postHandler) Create(c echo.Context) error {
 	return c.JSON(http.StatusCreated, webResponse)
 }
 
+// PublishingPost report whether the created post is published now or scheduled to be
+func PublishingPost(c echo.Context) bool {
+	return c.FormValue("status") == repository.StatusPublished || c.FormValue("publish_at") != ""
+}
+
 func (ph *postHandler) Update(c echo.Context) error {
-	strID := c.FormValue("id")
+	claims, err := jwtClaims(c)
+	if err != nil {
+		return echo.ErrUnauthorized
+	}
+
+	strID := c.Param("postid")
 	updatedID, err := strconv.Atoi(strID)
 	if err != nil {
-		return echo.ErrInternalServerError
+		return echo.ErrBadRequest
 	}
 
 	var post repository.PostData
@@ -61,11 +106,12 @@ func (ph *postHandler) Update(c echo.Context) error {
 	post.Title = c.FormValue("title")
 	post.ShortDesc = c.FormValue("short_desc")
 	post.Content = c.FormValue("content")
+	post.Tags, post.Categories = formTaxonomy(c)
 
 	ctx := context.Background()
 
-	if err = ph.Service.Update(ctx, post); err != nil {
-		return echo.ErrInternalServerError
+	if err = ph.Service.Update(ctx, postActor(claims), post); err != nil {
+		return postError(err)
 	}
 
 	webResponse := webResponse{
@@ -78,38 +124,236 @@ func (ph *postHandler) Update(c echo.Context) error {
 }
 
 func (ph *postHandler) Delete(c echo.Context) error {
-	strID := c.FormValue("id")
+	claims, err := jwtClaims(c)
+	if err != nil {
+		return echo.ErrUnauthorized
+	}
+
+	strID := c.Param("postid")
 	id, err := strconv.Atoi(strID)
 	if err != nil {
-		return echo.ErrInternalServerError
+		return echo.ErrBadRequest
 	}
 
 	ctx := context.Background()
 
-	if err := ph.Service.Delete(ctx, int64(id)); err != nil {
-		return echo.ErrInternalServerError
+	if err := ph.Service.Delete(ctx, postActor(claims), int64(id)); err != nil {
+		return postError(err)
+	}
+
+	webResponse := webResponse{
+		Code:    http.StatusOK,
+		Message: http.StatusText(http.StatusOK),
+	}
+
+	return c.JSON(http.StatusOK, webResponse)
+}
+
+// Undelete take the post out of the trash
+func (ph *postHandler) Undelete(c echo.Context) error {
+	return ph.changeStatus(c, ph.Service.Undelete)
+}
+
+// defaultTrashSize is how many deleted posts is returned when the size query param is empty
+const defaultTrashSize = 20
+
+// FindTrash paginate the deleted posts of the logged in user with the "from" and "size" query param
+func (ph *postHandler) FindTrash(c echo.Context) error {
+	claims, err := jwtClaims(c)
+	if err != nil {
+		return echo.ErrUnauthorized
+	}
+
+	from, size, err := queryPage(c, defaultTrashSize)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	posts, err := ph.Service.FindTrash(context.Background(), claims.ID, from, size)
+	if err != nil {
+		return postError(err)
 	}
 
 	webResponse := webResponse{
 		Code:    http.StatusOK,
 		Message: http.StatusText(http.StatusOK),
+		Data:    posts,
+	}
+
+	return c.JSON(http.StatusOK, webResponse)
+}
+
+func (ph *postHandler) Publish(c echo.Context) error {
+	return ph.changeStatus(c, ph.Service.Publish)
+}
+
+func (ph *postHandler) Unpublish(c echo.Context) error {
+	return ph.changeStatus(c, ph.Service.Unpublish)
+}
+
+func (ph *postHandler) Archive(c echo.Context) error {
+	return ph.changeStatus(c, ph.Service.Archive)
+}
+
+func (ph *postHandler) Schedule(c echo.Context) error {
+	publishAt, err := time.Parse(time.RFC3339, c.FormValue("publish_at"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	return ph.changeStatus(c, func(ctx context.Context, actor posting.Actor, id int64) (repository.PostData, error) {
+		return ph.Service.Schedule(ctx, actor, id, &publishAt)
+	})
+}
+
+func (ph *postHandler) Unschedule(c echo.Context) error {
+	return ph.changeStatus(c, func(ctx context.Context, actor posting.Actor, id int64) (repository.PostData, error) {
+		return ph.Service.Schedule(ctx, actor, id, nil)
+	})
+}
+
+func (ph *postHandler) changeStatus(c echo.Context, change func(ctx context.Context, actor posting.Actor, id int64) (repository.PostData, error)) error {
+	claims, err := jwtClaims(c)
+	if err != nil {
+		return echo.ErrUnauthorized
+	}
+
+	strID := c.Param("postid")
+	id, err := strconv.Atoi(strID)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	postResponse, err := change(context.Background(), postActor(claims), int64(id))
+	if err != nil {
+		return postError(err)
+	}
+
+	webResponse := webResponse{
+		Code:    http.StatusOK,
+		Message: http.StatusText(http.StatusOK),
+		Data:    postResponse,
+	}
+
+	return c.JSON(http.StatusOK, webResponse)
+}
+
+func (ph *postHandler) FindRevisions(c echo.Context) error {
+	claims, err := jwtClaims(c)
+	if err != nil {
+		return echo.ErrUnauthorized
+	}
+
+	id, err := strconv.Atoi(c.Param("postid"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	revisions, err := ph.Service.FindRevisions(context.Background(), postActor(claims), int64(id))
+	if err != nil {
+		return postError(err)
+	}
+
+	webResponse := webResponse{
+		Code:    http.StatusOK,
+		Message: http.StatusText(http.StatusOK),
+		Data:    revisions,
+	}
+
+	return c.JSON(http.StatusOK, webResponse)
+}
+
+// DiffRevisions compare the "from" and "to" revision query param, "mode" is unified by default
+func (ph *postHandler) DiffRevisions(c echo.Context) error {
+	claims, err := jwtClaims(c)
+	if err != nil {
+		return echo.ErrUnauthorized
+	}
+
+	id, err := strconv.Atoi(c.Param("postid"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	from, err := strconv.ParseInt(c.QueryParam("from"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	to, err := strconv.ParseInt(c.QueryParam("to"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	mode := c.QueryParam("mode")
+	if mode == "" {
+		mode = posting.DiffModeUnified
+	}
+
+	diff, err := ph.Service.DiffRevisions(context.Background(), postActor(claims), int64(id), from, to, mode)
+	if err != nil {
+		return postError(err)
+	}
+
+	webResponse := webResponse{
+		Code:    http.StatusOK,
+		Message: http.StatusText(http.StatusOK),
+		Data:    diff,
+	}
+
+	return c.JSON(http.StatusOK, webResponse)
+}
+
+func (ph *postHandler) Restore(c echo.Context) error {
+	claims, err := jwtClaims(c)
+	if err != nil {
+		return echo.ErrUnauthorized
+	}
+
+	id, err := strconv.Atoi(c.Param("postid"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	revisionID, err := strconv.ParseInt(c.Param("revisionid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	postResponse, err := ph.Service.Restore(context.Background(), postActor(claims), int64(id), revisionID)
+	if err != nil {
+		return postError(err)
+	}
+
+	webResponse := webResponse{
+		Code:    http.StatusOK,
+		Message: http.StatusText(http.StatusOK),
+		Data:    postResponse,
 	}
 
 	return c.JSON(http.StatusOK, webResponse)
 }
 
 func (ph *postHandler) FindByID(c echo.Context) error {
-	strID := c.Param("id")
+	strID := c.Param("postid")
 	id, err := strconv.Atoi(strID)
 	if err != nil {
-		return echo.ErrInternalServerError
+		return echo.ErrBadRequest
 	}
 
+	// the route is public, anonymous viewer only see published post
+	viewerID := viewer(c)
+
 	ctx := context.Background()
 
-	postResponse, err := ph.Service.FindByID(ctx, int64(id))
+	postResponse, err := ph.Service.FindByID(ctx, viewerID, int64(id))
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError)
+		return postError(err)
+	}
+
+	postResponse, err = ph.annotate(ctx, viewerID, postResponse)
+	if err != nil {
+		return echo.ErrInternalServerError
 	}
 
 	webResponse := webResponse{
@@ -121,12 +365,46 @@ func (ph *postHandler) FindByID(c echo.Context) error {
 	return c.JSON(http.StatusFound, webResponse)
 }
 
-func (ph *postHandler) FindByTitleContent(c echo.Context) error {
-	var posts []repository.PostData
+func (ph *postHandler) FindBySlug(c echo.Context) error {
+	slug := c.Param("slug")
 
+	viewerID := viewer(c)
+
+	postResponse, err := ph.Service.FindBySlug(context.Background(), viewerID, slug)
+	if err != nil {
+		var moved *posting.SlugMovedError
+		if errors.As(err, &moved) {
+			location := path.Join(path.Dir(c.Request().URL.Path), url.PathEscape(moved.Slug))
+			return c.Redirect(http.StatusMovedPermanently, location)
+		}
+		return postError(err)
+	}
+
+	postResponse, err = ph.annotate(context.Background(), viewerID, postResponse)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	webResponse := webResponse{
+		Code:    http.StatusOK,
+		Message: http.StatusText(http.StatusOK),
+		Data:    postResponse,
+	}
+
+	return c.JSON(http.StatusOK, webResponse)
+}
+
+// FindByTitleContent search the posts, the response carry the total hit count, every hit its score
+// and highlighted fragments, the facet counts to narrow the search by tag, author or month
+// and a corrected query when nothing is found. The "mode" query param is fuzzy, exact or phrase
+func (ph *postHandler) FindByTitleContent(c echo.Context) error {
 	ctx := context.Background()
 
-	query := c.QueryParam("query")
+	query, err := repository.ParseQuery(c.QueryParam("query"), c.QueryParam("mode"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
 	from, err := strconv.Atoi(c.QueryParam("from"))
 	if err != nil {
 		return echo.ErrInternalServerError
@@ -137,15 +415,33 @@ func (ph *postHandler) FindByTitleContent(c echo.Context) error {
 		return echo.ErrInternalServerError
 	}
 
-	posts, err = ph.Service.FindByTitleContent(ctx, query, from, size)
+	filter, err := queryFilter(c)
+	if err != nil {
+		return err
+	}
+
+	result, err := ph.Service.FindByTitleContent(ctx, query, filter, from, size)
 	if err != nil {
 		return echo.ErrInternalServerError
 	}
 
+	posts := make([]repository.PostData, len(result.Hits))
+	for i, hit := range result.Hits {
+		posts[i] = hit.PostData
+	}
+
+	if err := ph.Favourite.Annotate(ctx, viewer(c), posts); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	for i := range result.Hits {
+		result.Hits[i].PostData = posts[i]
+	}
+
 	webResponse := webResponse{
 		Code:    http.StatusFound,
 		Message: http.StatusText(http.StatusFound),
-		Data:    posts,
+		Data:    result,
 	}
 
 	return c.JSON(http.StatusOK, webResponse)
@@ -166,11 +462,20 @@ func (ph *postHandler) FindRecent(c echo.Context) error {
 		return echo.ErrInternalServerError
 	}
 
-	posts, err = ph.Service.FindRecent(ctx, from, size)
+	filter, err := queryFilter(c)
+	if err != nil {
+		return err
+	}
+
+	posts, err = ph.Service.FindRecent(ctx, filter, from, size)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError)
 	}
 
+	if err := ph.Favourite.Annotate(ctx, viewer(c), posts); err != nil {
+		return echo.ErrInternalServerError
+	}
+
 	webResponse := webResponse{
 		Code:    http.StatusFound,
 		Message: http.StatusText(http.StatusFound),
@@ -179,3 +484,171 @@ func (ph *postHandler) FindRecent(c echo.Context) error {
 
 	return c.JSON(http.StatusFound, webResponse)
 }
+
+// viewer return the id of the logged in user, 0 for anonymous user
+func viewer(c echo.Context) int64 {
+	if claims, err := jwtClaims(c); err == nil {
+		return claims.ID
+	}
+	return 0
+}
+
+// annotate fill the favourite count and flag of a single post
+func (ph *postHandler) annotate(ctx context.Context, viewerID int64, post repository.PostData) (repository.PostData, error) {
+	posts := []repository.PostData{post}
+	if err := ph.Favourite.Annotate(ctx, viewerID, posts); err != nil {
+		return post, err
+	}
+
+	return posts[0], nil
+}
+
+// formTaxonomy read the repeated "tags" and "categories" form value
+func formTaxonomy(c echo.Context) ([]string, []string) {
+	form, err := c.FormParams()
+	if err != nil {
+		return nil, nil
+	}
+
+	return form["tags"], form["categories"]
+}
+
+// Suggest complete the "q" query param with published titles, "size" default to 5 and can't go over 10
+func (ph *postHandler) Suggest(c echo.Context) error {
+	size := defaultSuggestSize
+	if str := c.QueryParam("size"); str != "" {
+		n, err := strconv.Atoi(str)
+		if err != nil || n <= 0 {
+			return echo.ErrBadRequest
+		}
+		size = n
+	}
+	if size > maxSuggestSize {
+		size = maxSuggestSize
+	}
+
+	suggestions, err := ph.Service.Suggest(c.Request().Context(), c.QueryParam("q"), size)
+	if err != nil {
+		return postError(err)
+	}
+
+	webResponse := webResponse{
+		Code:    http.StatusOK,
+		Message: http.StatusText(http.StatusOK),
+		Data:    suggestions,
+	}
+
+	return c.JSON(http.StatusOK, webResponse)
+}
+
+// filterDateLayout is the layout of the "since" and "until" query param
+const filterDateLayout = "2006-01-02"
+
+// Related return the published posts similar to the post, "size" default to 5 and can't go over posting.MaxRelated
+func (ph *postHandler) Related(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("postid"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	size := defaultRelatedSize
+	if str := c.QueryParam("size"); str != "" {
+		n, err := strconv.Atoi(str)
+		if err != nil || n <= 0 {
+			return echo.ErrBadRequest
+		}
+		size = n
+	}
+	if size > posting.MaxRelated {
+		size = posting.MaxRelated
+	}
+
+	posts, err := ph.Service.Related(c.Request().Context(), int64(id), size)
+	if err != nil {
+		return postError(err)
+	}
+
+	webResponse := webResponse{
+		Code:    http.StatusOK,
+		Message: http.StatusText(http.StatusOK),
+		Data:    posts,
+	}
+
+	return c.JSON(http.StatusOK, webResponse)
+}
+
+// queryFilter read the optional "tag", "category", "author", "since", "until" and "status" query param,
+// both date are inclusive. Anyone can look for published post but the other status is limited
+// to the logged in author's own post
+func queryFilter(c echo.Context) (repository.PostFilter, error) {
+	filter := repository.PostFilter{
+		Tag:      taxonomy.NormalizeName(c.QueryParam("tag")),
+		Category: c.QueryParam("category"),
+		Author:   c.QueryParam("author"),
+		Status:   c.QueryParam("status"),
+	}
+
+	if since := c.QueryParam("since"); since != "" {
+		date, err := time.Parse(filterDateLayout, since)
+		if err != nil {
+			return filter, echo.ErrBadRequest
+		}
+		filter.From = &date
+	}
+
+	if until := c.QueryParam("until"); until != "" {
+		date, err := time.Parse(filterDateLayout, until)
+		if err != nil {
+			return filter, echo.ErrBadRequest
+		}
+		// the whole day is included, so the filter stop at the start of the next one
+		date = date.Add(24 * time.Hour)
+		filter.To = &date
+	}
+
+	switch filter.Status {
+	case "", repository.StatusPublished:
+	case repository.StatusDraft, repository.StatusArchived:
+		claims, err := jwtClaims(c)
+		if err != nil {
+			return filter, echo.ErrUnauthorized
+		}
+		if filter.Author != "" && filter.Author != claims.Username {
+			return filter, echo.ErrForbidden
+		}
+		filter.Author = claims.Username
+	default:
+		return filter, echo.ErrBadRequest
+	}
+
+	return filter, nil
+}
+
+// postActor is the logged in user acting on a post with the permissions of their role
+func postActor(claims *user.JWTClaims) posting.Actor {
+	return posting.Actor{
+		ID:        claims.ID,
+		EditAny:   claims.Can(repository.PermissionPostEditAny),
+		DeleteAny: claims.Can(repository.PermissionPostDeleteAny),
+	}
+}
+
+// postError translate error from the posting service into http error
+func postError(err error) error {
+	var invalid validator.ValidationErrors
+
+	switch {
+	case errors.As(err, &invalid):
+		return echo.ErrBadRequest
+	case errors.Is(err, posting.ErrNotPostAuthor):
+		return echo.ErrForbidden
+	case errors.Is(err, posting.ErrPostNotFound), errors.Is(err, repository.ErrPostNotFound), errors.Is(err, repository.ErrRevisionNotFound):
+		return echo.ErrNotFound
+	case errors.Is(err, posting.ErrPostAlreadyPublished):
+		return echo.NewHTTPError(http.StatusConflict)
+	case errors.Is(err, repository.ErrCategoryNotFound), errors.Is(err, posting.ErrUnknownDiffMode), errors.Is(err, posting.ErrPublishAtPast):
+		return echo.ErrBadRequest
+	default:
+		return echo.ErrInternalServerError
+	}
+}
@@ -3,6 +3,7 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -10,64 +11,93 @@ import (
 	"testing"
 	"time"
 
+	"github.com/go-playground/validator/v10"
+	"github.com/golang-jwt/jwt"
+	"github.com/izzanzahrial/blog-api-echo/pkg/favourite"
 	"github.com/izzanzahrial/blog-api-echo/pkg/posting"
 	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+	"github.com/izzanzahrial/blog-api-echo/pkg/user"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 )
 
+// withClaims mimic what the jwt middleware put in the context
+func withClaims(c echo.Context, id int64) {
+	c.Set("user", &jwt.Token{Claims: &user.JWTClaims{ID: id}})
+}
+
 func TestHandlerCreate(t *testing.T) {
 	mockService := new(posting.MockService)
 	e := echo.New()
+	createdAt := time.Now().UTC()
 
 	subtest := []struct {
 		status       bool
 		name         string
-		post         repository.PostData
+		post         posting.PostData
 		ctx          context.Context
-		expectedData webResponse
-		expectedErr  error
+		err          error
+		expectedData repository.PostData
+		expectedCode int
 	}{
 		{
 			status: true,
 			name:   "Succesful Handler Create Post",
-			post: repository.PostData{
-				ID:        1,
+			post: posting.PostData{
 				Title:     "Test title",
 				ShortDesc: "Test short description",
 				Content:   "Test content",
-				CreatedAt: time.Now(),
+				AuthorID:  1,
 			},
 			ctx: context.Background(),
-			expectedData: webResponse{
-				Code:    http.StatusCreated,
-				Message: http.StatusText(http.StatusCreated),
-				Data: repository.PostData{
-					ID:        1,
-					Title:     "Test title",
-					ShortDesc: "Test short description",
-					Content:   "Test content",
-					CreatedAt: time.Now(),
-				},
+			expectedData: repository.PostData{
+				ID:        1,
+				Title:     "Test title",
+				ShortDesc: "Test short description",
+				Content:   "Test content",
+				CreatedAt: createdAt,
+				Author:    repository.Author{ID: 1},
 			},
-			expectedErr: nil,
+			expectedCode: http.StatusCreated,
 		},
 		{
 			status: false,
 			name:   "Failed Handler Create Post",
-			post: repository.PostData{
-				ID:        2,
+			post: posting.PostData{
 				Title:     "Test title",
 				ShortDesc: "Test short description",
 				Content:   "Test content",
+				AuthorID:  2,
 			},
-			ctx: context.Background(),
-			expectedData: webResponse{
-				Code:    http.StatusInternalServerError,
-				Message: http.StatusText(http.StatusAccepted),
-				Data:    echo.ErrInternalServerError,
+			ctx:          context.Background(),
+			err:          repository.ErrFailedToCreatePost,
+			expectedCode: http.StatusInternalServerError,
+		},
+		{
+			status: false,
+			name:   "Invalid Post",
+			post: posting.PostData{
+				Title:     "Test title",
+				ShortDesc: "Test short description",
+				Content:   "Test content",
+				AuthorID:  3,
 			},
-			expectedErr: echo.ErrInternalServerError,
+			ctx:          context.Background(),
+			err:          fmt.Errorf("failed to validate because %w", validator.New().Struct(posting.PostData{Status: "unknown"})),
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			status: false,
+			name:   "Published And Scheduled",
+			post: posting.PostData{
+				Title:     "Test title",
+				ShortDesc: "Test short description",
+				Content:   "Test content",
+				AuthorID:  4,
+			},
+			ctx:          context.Background(),
+			err:          posting.ErrPostAlreadyPublished,
+			expectedCode: http.StatusConflict,
 		},
 	}
 
@@ -82,28 +112,204 @@ func TestHandlerCreate(t *testing.T) {
 			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
 			rec := httptest.NewRecorder()
 			c := e.NewContext(req, rec)
-			h := NewPostHandler(mockService)
+			withClaims(c, test.post.AuthorID)
+			h := NewPostHandler(mockService, new(favourite.MockService))
 
 			switch test.status {
 			case true:
-				mockService.On("Create", test.ctx, test.post).Return(test.post, nil).Once()
+				mockService.On("Create", test.ctx, test.post).Return(test.expectedData, nil).Once()
 			case false:
-				mockService.On("Create", test.ctx, test.post).Return(repository.PostData{}, repository.ErrFailedToCreatePost).Once()
+				mockService.On("Create", test.ctx, test.post).Return(repository.PostData{}, test.err).Once()
 			}
 
-			h.Create(c)
-
-			var data webResponse
-			dataByte := rec.Body.Bytes()
-			err := json.Unmarshal(dataByte, &data)
+			err := h.Create(c)
 			if err != nil {
-				t.Errorf("Failed to unmarshal data to webresponse")
+				e.HTTPErrorHandler(err, c)
 			}
 
-			assert.Equal(t, test.expectedData, data)
-			assert.Equal(t, test.expectedErr, rec.Code)
+			assert.Equal(t, test.expectedCode, rec.Code)
+
+			if test.status {
+				var data struct {
+					Code int                 `json:"code"`
+					Data repository.PostData `json:"data"`
+				}
+				if err := json.Unmarshal(rec.Body.Bytes(), &data); err != nil {
+					t.Errorf("Failed to unmarshal data to webresponse")
+				}
+				assert.Equal(t, test.expectedCode, data.Code)
+				assert.Equal(t, test.expectedData, data.Data)
+			}
 
 			mockService.AssertExpectations(t)
 		})
 	}
 }
+
+func TestHandlerCreateWithoutClaims(t *testing.T) {
+	mockService := new(posting.MockService)
+	e := echo.New()
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	h := NewPostHandler(mockService, new(favourite.MockService))
+
+	err := h.Create(c)
+	assert.Equal(t, echo.ErrUnauthorized, err)
+	mockService.AssertNotCalled(t, "Create")
+}
+
+func TestHandlerUpdateNotAuthor(t *testing.T) {
+	mockService := new(posting.MockService)
+	e := echo.New()
+
+	f := make(url.Values)
+	f.Set("title", "Test title")
+	f.Set("short_desc", "Test short description")
+	f.Set("content", "Test content")
+
+	req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("postid")
+	c.SetParamValues("1")
+	withClaims(c, 2)
+	h := NewPostHandler(mockService, new(favourite.MockService))
+
+	post := repository.PostData{
+		ID:        1,
+		Title:     "Test title",
+		ShortDesc: "Test short description",
+		Content:   "Test content",
+	}
+	mockService.On("Update", context.Background(), posting.Actor{ID: 2}, post).Return(posting.ErrNotPostAuthor).Once()
+
+	err := h.Update(c)
+	assert.Equal(t, echo.ErrForbidden, err)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandlerFindByIDAnonymous(t *testing.T) {
+	mockService := new(posting.MockService)
+	e := echo.New()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("postid")
+	c.SetParamValues("1")
+	h := NewPostHandler(mockService, new(favourite.MockService))
+
+	mockService.On("FindByID", context.Background(), int64(0), int64(1)).Return(repository.PostData{}, posting.ErrPostNotFound).Once()
+
+	err := h.FindByID(c)
+	assert.Equal(t, echo.ErrNotFound, err)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandlerFindBySlugRedirect(t *testing.T) {
+	mockService := new(posting.MockService)
+	e := echo.New()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/posts/slug/old-title", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("slug")
+	c.SetParamValues("old-title")
+	h := NewPostHandler(mockService, new(favourite.MockService))
+
+	mockService.On("FindBySlug", context.Background(), int64(0), "old-title").Return(
+		repository.PostData{}, &posting.SlugMovedError{Slug: "new-title"}).Once()
+
+	err := h.FindBySlug(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusMovedPermanently, rec.Code)
+	assert.Equal(t, "/api/v1/posts/slug/new-title", rec.Header().Get(echo.HeaderLocation))
+	mockService.AssertExpectations(t)
+}
+
+func TestHandlerSuggestSizeIsCapped(t *testing.T) {
+	mockService := new(posting.MockService)
+	e := echo.New()
+
+	req := httptest.NewRequest(http.MethodGet, "/?q=go&size=50", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	h := NewPostHandler(mockService, new(favourite.MockService))
+
+	suggestions := []repository.Suggestion{{ID: 1, Title: "Go Concurrency", Slug: "go-concurrency"}}
+	mockService.On("Suggest", req.Context(), "go", maxSuggestSize).Return(suggestions, nil).Once()
+
+	err := h.Suggest(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"slug":"go-concurrency"`)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandlerFindByTitleContentFilter(t *testing.T) {
+	e := echo.New()
+
+	subtest := []struct {
+		name         string
+		query        string
+		username     string
+		expectedCode int
+	}{
+		{name: "Draft Without Claims", query: "status=draft", expectedCode: http.StatusUnauthorized},
+		{name: "Draft Of Another Author", query: "status=draft&author=other", username: "izzan", expectedCode: http.StatusForbidden},
+		{name: "Unknown Status", query: "status=deleted", username: "izzan", expectedCode: http.StatusBadRequest},
+		{name: "Invalid Date", query: "since=01-05-2022", expectedCode: http.StatusBadRequest},
+		{name: "Unknown Mode", query: "mode=regex", expectedCode: http.StatusBadRequest},
+	}
+
+	for _, test := range subtest {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/?query=echo&from=0&size=10&"+test.query, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			if test.username != "" {
+				c.Set("user", &jwt.Token{Claims: &user.JWTClaims{ID: 1, Username: test.username}})
+			}
+			h := NewPostHandler(new(posting.MockService), new(favourite.MockService))
+
+			err := h.FindByTitleContent(c)
+			assert.Equal(t, test.expectedCode, err.(*echo.HTTPError).Code)
+		})
+	}
+}
+
+func TestHandlerFindByTitleContentOwnDraft(t *testing.T) {
+	mockService := new(posting.MockService)
+	mockFavourite := new(favourite.MockService)
+	e := echo.New()
+
+	req := httptest.NewRequest(http.MethodGet, "/?query=echo&from=0&size=10&status=draft&until=2022-05-31", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set("user", &jwt.Token{Claims: &user.JWTClaims{ID: 1, Username: "izzan"}})
+	h := NewPostHandler(mockService, mockFavourite)
+
+	// the author is taken from the claims and the whole last day is included
+	to := time.Date(2022, time.June, 1, 0, 0, 0, 0, time.UTC)
+	filter := repository.PostFilter{Author: "izzan", Status: repository.StatusDraft, To: &to}
+	result := repository.SearchResult{
+		Total:  1,
+		Hits:   []repository.SearchHit{{PostData: repository.PostData{ID: 3}}},
+		Facets: &repository.Facets{Tags: []repository.FacetBucket{{Key: "go", Count: 1}}},
+	}
+	query := repository.SearchQuery{
+		Text:   "echo",
+		Mode:   repository.QueryModeFuzzy,
+		Groups: []repository.QueryGroup{{Clauses: []repository.QueryClause{{Text: "echo"}}}},
+	}
+	mockService.On("FindByTitleContent", context.Background(), query, filter, 0, 10).Return(result, nil).Once()
+	mockFavourite.On("Annotate", context.Background(), int64(1), []repository.PostData{{ID: 3}}).Return(nil).Once()
+
+	err := h.FindByTitleContent(c)
+	assert.NoError(t, err)
+	assert.Contains(t, rec.Body.String(), `"facets":{"tags":[{"key":"go","count":1}]`)
+	mockService.AssertExpectations(t)
+}
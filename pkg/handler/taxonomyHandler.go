@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+	"github.com/izzanzahrial/blog-api-echo/pkg/taxonomy"
+	"github.com/labstack/echo/v4"
+)
+
+type taxonomyHandler struct {
+	Service taxonomy.Service
+}
+
+func NewTaxonomyHandler(ts taxonomy.Service) TaxonomyHandler {
+	return &taxonomyHandler{
+		Service: ts,
+	}
+}
+
+func (th *taxonomyHandler) CreateTag(c echo.Context) error {
+	tag := taxonomy.Tag{Name: c.FormValue("name")}
+
+	tagResponse, err := th.Service.CreateTag(c.Request().Context(), tag)
+	if err != nil {
+		return taxonomyError(err)
+	}
+
+	webResponse := webResponse{
+		Code:    http.StatusCreated,
+		Message: http.StatusText(http.StatusCreated),
+		Data:    tagResponse,
+	}
+
+	return c.JSON(http.StatusCreated, webResponse)
+}
+
+func (th *taxonomyHandler) UpdateTag(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("tagid"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	tag := taxonomy.Tag{Name: c.FormValue("name")}
+
+	tagResponse, err := th.Service.UpdateTag(c.Request().Context(), int64(id), tag)
+	if err != nil {
+		return taxonomyError(err)
+	}
+
+	webResponse := webResponse{
+		Code:    http.StatusAccepted,
+		Message: http.StatusText(http.StatusAccepted),
+		Data:    tagResponse,
+	}
+
+	return c.JSON(http.StatusAccepted, webResponse)
+}
+
+func (th *taxonomyHandler) DeleteTag(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("tagid"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	if err := th.Service.DeleteTag(c.Request().Context(), int64(id)); err != nil {
+		return taxonomyError(err)
+	}
+
+	webResponse := webResponse{
+		Code:    http.StatusOK,
+		Message: http.StatusText(http.StatusOK),
+	}
+
+	return c.JSON(http.StatusOK, webResponse)
+}
+
+// FindTags return every tag with its published post count, for tag cloud
+func (th *taxonomyHandler) FindTags(c echo.Context) error {
+	tags, err := th.Service.FindTags(c.Request().Context())
+	if err != nil {
+		return taxonomyError(err)
+	}
+
+	webResponse := webResponse{
+		Code:    http.StatusOK,
+		Message: http.StatusText(http.StatusOK),
+		Data:    tags,
+	}
+
+	return c.JSON(http.StatusOK, webResponse)
+}
+
+func (th *taxonomyHandler) CreateCategory(c echo.Context) error {
+	category, err := formCategory(c)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	categoryResponse, err := th.Service.CreateCategory(c.Request().Context(), category)
+	if err != nil {
+		return taxonomyError(err)
+	}
+
+	webResponse := webResponse{
+		Code:    http.StatusCreated,
+		Message: http.StatusText(http.StatusCreated),
+		Data:    categoryResponse,
+	}
+
+	return c.JSON(http.StatusCreated, webResponse)
+}
+
+func (th *taxonomyHandler) UpdateCategory(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("categoryid"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	category, err := formCategory(c)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	categoryResponse, err := th.Service.UpdateCategory(c.Request().Context(), int64(id), category)
+	if err != nil {
+		return taxonomyError(err)
+	}
+
+	webResponse := webResponse{
+		Code:    http.StatusAccepted,
+		Message: http.StatusText(http.StatusAccepted),
+		Data:    categoryResponse,
+	}
+
+	return c.JSON(http.StatusAccepted, webResponse)
+}
+
+func (th *taxonomyHandler) DeleteCategory(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("categoryid"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	if err := th.Service.DeleteCategory(c.Request().Context(), int64(id)); err != nil {
+		return taxonomyError(err)
+	}
+
+	webResponse := webResponse{
+		Code:    http.StatusOK,
+		Message: http.StatusText(http.StatusOK),
+	}
+
+	return c.JSON(http.StatusOK, webResponse)
+}
+
+// FindCategories return the categories as a tree
+func (th *taxonomyHandler) FindCategories(c echo.Context) error {
+	categories, err := th.Service.FindCategories(c.Request().Context())
+	if err != nil {
+		return taxonomyError(err)
+	}
+
+	webResponse := webResponse{
+		Code:    http.StatusOK,
+		Message: http.StatusText(http.StatusOK),
+		Data:    categories,
+	}
+
+	return c.JSON(http.StatusOK, webResponse)
+}
+
+// formCategory read the category name and the optional parent id
+func formCategory(c echo.Context) (taxonomy.Category, error) {
+	category := taxonomy.Category{Name: c.FormValue("name")}
+
+	if strParent := c.FormValue("parent_id"); strParent != "" {
+		parentID, err := strconv.ParseInt(strParent, 10, 64)
+		if err != nil {
+			return category, err
+		}
+		category.ParentID = &parentID
+	}
+
+	return category, nil
+}
+
+// taxonomyError translate error from the taxonomy service into http error
+func taxonomyError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrTagNotFound), errors.Is(err, repository.ErrCategoryNotFound):
+		return echo.ErrNotFound
+	case errors.Is(err, taxonomy.ErrTaxonomyIsntValidate), errors.Is(err, repository.ErrCategoryCycle):
+		return echo.ErrBadRequest
+	default:
+		return echo.ErrInternalServerError
+	}
+}
@@ -2,7 +2,9 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/golang-jwt/jwt"
 	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
@@ -29,14 +31,19 @@ func (us *userHandler) Create(c echo.Context) error {
 	// 	return echo.NewHTTPError(http.StatusBadRequest)
 	// }
 
-	var user user.User
-	user.Name = c.FormValue("name")
-	user.Password = c.FormValue("password")
-	if password2 := c.FormValue("password2"); password2 != user.Password {
+	var newUser user.User
+	newUser.Email = c.FormValue("email")
+	newUser.Username = c.FormValue("username")
+	newUser.Name = c.FormValue("name")
+	newUser.Password = c.FormValue("password")
+	if password2 := c.FormValue("password2"); password2 != newUser.Password {
 		return echo.ErrBadRequest
 	}
 
-	userResponse, err := us.UserService.Create(c.Request().Context(), user)
+	userResponse, err := us.UserService.Create(c.Request().Context(), newUser)
+	if errors.Is(err, user.ErrUserIsntValidate) {
+		return echo.ErrBadRequest
+	}
 	if err != nil {
 		return echo.ErrInternalServerError
 	}
@@ -61,17 +68,14 @@ func (us *userHandler) UpdateUser(c echo.Context) error {
 	userClaims := c.Get("user").(*jwt.Token)
 	claims := userClaims.Claims.(*user.JWTClaims)
 
+	// the user is always the one logged in, whatever the email in the form is
 	user := repository.User{
 		ID:       claims.ID,
-		Email:    claims.Email,
-		Username: claims.Username,
-		Name:     claims.Name,
+		Email:    c.FormValue("email"),
+		Username: c.FormValue("username"),
+		Name:     c.FormValue("name"),
 	}
 
-	user.Email = c.FormValue("email")
-	user.Username = c.FormValue("username")
-	user.Name = c.FormValue("name")
-
 	userResponse, err := us.UserService.UpdateUser(context.Background(), user)
 	if err != nil {
 		return echo.ErrInternalServerError
@@ -170,7 +174,10 @@ func (us *userHandler) Login(c echo.Context) error {
 	emailOrUname := c.FormValue("email_or_username")
 	password := c.FormValue("password")
 
-	userResponse, token, err := us.UserService.Login(context.Background(), emailOrUname, password)
+	userResponse, tokens, err := us.UserService.Login(context.Background(), emailOrUname, password)
+	if errors.Is(err, user.ErrUserSuspended) {
+		return echo.ErrForbidden
+	}
 	if err != nil {
 		return echo.ErrUnauthorized
 	}
@@ -179,8 +186,255 @@ func (us *userHandler) Login(c echo.Context) error {
 	webResponse := webResponse{
 		Code:    http.StatusFound,
 		Message: http.StatusText(http.StatusFound),
-		Data:    []interface{}{userResponse, token},
+		Data:    []interface{}{userResponse, tokens},
+	}
+
+	return c.JSON(http.StatusOK, webResponse)
+}
+
+// Refresh rotate the refresh token, a token that was already used revoke every token of its login
+func (us *userHandler) Refresh(c echo.Context) error {
+	refreshToken := c.FormValue("refresh_token")
+	if refreshToken == "" {
+		return echo.ErrBadRequest
+	}
+
+	tokens, err := us.UserService.Refresh(c.Request().Context(), refreshToken)
+	if errors.Is(err, user.ErrInvalidRefreshToken) || errors.Is(err, user.ErrRefreshTokenReused) {
+		return echo.ErrUnauthorized
+	}
+	if errors.Is(err, user.ErrUserSuspended) {
+		return echo.ErrForbidden
+	}
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	webResponse := webResponse{
+		Code:    http.StatusOK,
+		Message: http.StatusText(http.StatusOK),
+		Data:    tokens,
 	}
 
 	return c.JSON(http.StatusOK, webResponse)
 }
+
+// Logout revoke the token of the request and the refresh token of its login
+func (us *userHandler) Logout(c echo.Context) error {
+	claims, err := jwtClaims(c)
+	if err != nil {
+		return echo.ErrUnauthorized
+	}
+
+	if err := us.UserService.Logout(c.Request().Context(), claims); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	webResponse := webResponse{
+		Code:    http.StatusOK,
+		Message: http.StatusText(http.StatusOK),
+	}
+
+	return c.JSON(http.StatusOK, webResponse)
+}
+
+// LogoutAll revoke every token of the user on every device
+func (us *userHandler) LogoutAll(c echo.Context) error {
+	claims, err := jwtClaims(c)
+	if err != nil {
+		return echo.ErrUnauthorized
+	}
+
+	if err := us.UserService.LogoutAll(c.Request().Context(), claims.ID); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	webResponse := webResponse{
+		Code:    http.StatusOK,
+		Message: http.StatusText(http.StatusOK),
+	}
+
+	return c.JSON(http.StatusOK, webResponse)
+}
+
+// defaultUserSize is how many users is returned when the size query param is empty
+const defaultUserSize = 50
+
+// FindUsers paginate every user with the "from" and "size" query param, for the admin
+func (us *userHandler) FindUsers(c echo.Context) error {
+	from, size, err := queryPage(c, defaultUserSize)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	users, err := us.UserService.FindUsers(c.Request().Context(), from, size)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	webResponse := webResponse{
+		Code:    http.StatusOK,
+		Message: http.StatusText(http.StatusOK),
+		Data:    users,
+	}
+
+	return c.JSON(http.StatusOK, webResponse)
+}
+
+// ChangeRole give the user the role of the "role" form value
+func (us *userHandler) ChangeRole(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("userid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	userResponse, err := us.UserService.ChangeRole(c.Request().Context(), id, c.FormValue("role"))
+	if err != nil {
+		return userError(err)
+	}
+
+	webResponse := webResponse{
+		Code:    http.StatusOK,
+		Message: http.StatusText(http.StatusOK),
+		Data:    userResponse,
+	}
+
+	return c.JSON(http.StatusOK, webResponse)
+}
+
+// Suspend log the user out and refuse their login
+func (us *userHandler) Suspend(c echo.Context) error {
+	return us.suspend(c, us.UserService.Suspend)
+}
+
+// Unsuspend lift the suspension of the user
+func (us *userHandler) Unsuspend(c echo.Context) error {
+	return us.suspend(c, us.UserService.Unsuspend)
+}
+
+func (us *userHandler) suspend(c echo.Context, change func(ctx context.Context, id int64) (repository.User, error)) error {
+	id, err := strconv.ParseInt(c.Param("userid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	userResponse, err := change(c.Request().Context(), id)
+	if err != nil {
+		return userError(err)
+	}
+
+	webResponse := webResponse{
+		Code:    http.StatusOK,
+		Message: http.StatusText(http.StatusOK),
+		Data:    userResponse,
+	}
+
+	return c.JSON(http.StatusOK, webResponse)
+}
+
+func userError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrUserNotFound):
+		return echo.ErrNotFound
+	case errors.Is(err, repository.ErrRoleNotFound):
+		return echo.ErrBadRequest
+	default:
+		return echo.ErrInternalServerError
+	}
+}
+
+// ForgotPassword mail the reset link to the "email" form value, it's accepted whether or not the email
+// has an account
+func (us *userHandler) ForgotPassword(c echo.Context) error {
+	email := c.FormValue("email")
+	if email == "" {
+		return echo.ErrBadRequest
+	}
+
+	if err := us.UserService.ForgotPassword(c.Request().Context(), email); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	webResponse := webResponse{
+		Code:    http.StatusAccepted,
+		Message: http.StatusText(http.StatusAccepted),
+	}
+
+	return c.JSON(http.StatusAccepted, webResponse)
+}
+
+// ResetPassword change the password with the "token" of the mailed link, every session of the user is logged out
+func (us *userHandler) ResetPassword(c echo.Context) error {
+	token := c.FormValue("token")
+	password := c.FormValue("password")
+	if token == "" || password == "" {
+		return echo.ErrBadRequest
+	}
+	if password2 := c.FormValue("password2"); password2 != password {
+		return echo.ErrBadRequest
+	}
+
+	err := us.UserService.ResetPassword(c.Request().Context(), token, password)
+	if errors.Is(err, user.ErrInvalidResetToken) {
+		return echo.ErrBadRequest
+	}
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	webResponse := webResponse{
+		Code:    http.StatusOK,
+		Message: http.StatusText(http.StatusOK),
+	}
+
+	return c.JSON(http.StatusOK, webResponse)
+}
+
+// VerifyEmail verify the email with the "token" query param of the mailed link
+func (us *userHandler) VerifyEmail(c echo.Context) error {
+	token := c.QueryParam("token")
+	if token == "" {
+		return echo.ErrBadRequest
+	}
+
+	userResponse, err := us.UserService.VerifyEmail(c.Request().Context(), token)
+	if errors.Is(err, user.ErrInvalidVerifyToken) {
+		return echo.ErrBadRequest
+	}
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	webResponse := webResponse{
+		Code:    http.StatusOK,
+		Message: http.StatusText(http.StatusOK),
+		Data:    userResponse,
+	}
+
+	return c.JSON(http.StatusOK, webResponse)
+}
+
+// ResendVerification mail the logged in user another verification link
+func (us *userHandler) ResendVerification(c echo.Context) error {
+	claims, err := jwtClaims(c)
+	if err != nil {
+		return echo.ErrUnauthorized
+	}
+
+	err = us.UserService.ResendVerification(c.Request().Context(), claims.ID)
+	switch {
+	case errors.Is(err, user.ErrVerifyRateLimited):
+		return echo.ErrTooManyRequests
+	case errors.Is(err, user.ErrEmailAlreadyVerified):
+		return echo.NewHTTPError(http.StatusConflict)
+	case err != nil:
+		return userError(err)
+	}
+
+	webResponse := webResponse{
+		Code:    http.StatusAccepted,
+		Message: http.StatusText(http.StatusAccepted),
+	}
+
+	return c.JSON(http.StatusAccepted, webResponse)
+}
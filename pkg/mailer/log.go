@@ -0,0 +1,35 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Log write the mail to w instead of sending it, so the link of a mail can be followed in local development
+type Log struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewLog(w io.Writer) *Log {
+	return &Log{
+		w: w,
+	}
+}
+
+func (l *Log) Send(ctx context.Context, msg Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, err := fmt.Fprintf(l.w, "To: %s\nSubject: %s\n\n%s\n\n", msg.To, msg.Subject, msg.Body); err != nil {
+		return fmt.Errorf("failed to write mail to %s because %w", msg.To, err)
+	}
+
+	return nil
+}
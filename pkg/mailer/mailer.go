@@ -0,0 +1,47 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/stretchr/testify/mock"
+)
+
+var ErrInvalidHeader = errors.New("the recipient or subject of the mail contain a line break")
+
+// Mailer send the mail of the app, like the link of a password reset
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Mailer backend, the log only write the mail down and is meant for local development
+const (
+	BackendSMTP = "smtp"
+	BackendLog  = "log"
+)
+
+// Message is a plain text mail
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// validate refuse the header that would let the recipient or subject inject another header
+func (m Message) validate() error {
+	if strings.ContainsAny(m.To, "\r\n") || strings.ContainsAny(m.Subject, "\r\n") {
+		return ErrInvalidHeader
+	}
+
+	return nil
+}
+
+type MockMailer struct {
+	mock.Mock
+}
+
+func (m *MockMailer) Send(ctx context.Context, msg Message) error {
+	args := m.Called(ctx, msg)
+	return args.Error(0)
+}
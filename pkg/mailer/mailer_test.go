@@ -0,0 +1,69 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/smtp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogSend(t *testing.T) {
+	var buf bytes.Buffer
+	err := NewLog(&buf).Send(context.Background(), Message{
+		To:      "izzan@example.com",
+		Subject: "Reset your password",
+		Body:    "https://example.com/reset?token=abc",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "To: izzan@example.com\nSubject: Reset your password\n\nhttps://example.com/reset?token=abc\n\n", buf.String())
+}
+
+func TestSMTPSend(t *testing.T) {
+	s := NewSMTP("localhost", "25", "", "", "blog@example.com")
+	assert.Equal(t, "localhost:25", s.Addr)
+	assert.Nil(t, s.Auth)
+
+	var sent []byte
+	var to []string
+	s.send = func(addr string, a smtp.Auth, from string, rcpt []string, msg []byte) error {
+		to = rcpt
+		sent = msg
+		return nil
+	}
+
+	err := s.Send(context.Background(), Message{
+		To:      "izzan@example.com",
+		Subject: "Reset your password",
+		Body:    "Hi,\nfollow the link",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"izzan@example.com"}, to)
+	assert.Equal(t, "From: blog@example.com\r\n"+
+		"To: izzan@example.com\r\n"+
+		"Subject: Reset your password\r\n"+
+		"MIME-Version: 1.0\r\n"+
+		"Content-Type: text/plain; charset=\"utf-8\"\r\n"+
+		"\r\n"+
+		"Hi,\r\nfollow the link", string(sent))
+
+	errSend := errors.New("connection refused")
+	s.send = func(addr string, a smtp.Auth, from string, rcpt []string, msg []byte) error {
+		return errSend
+	}
+	assert.ErrorIs(t, s.Send(context.Background(), Message{To: "izzan@example.com"}), errSend)
+}
+
+func TestSendInvalidHeader(t *testing.T) {
+	msg := Message{
+		To:      "izzan@example.com\r\nBcc: someone@example.com",
+		Subject: "Reset your password",
+	}
+
+	var buf bytes.Buffer
+	assert.ErrorIs(t, NewLog(&buf).Send(context.Background(), msg), ErrInvalidHeader)
+	assert.Empty(t, buf.String())
+	assert.ErrorIs(t, NewSMTP("localhost", "25", "", "", "blog@example.com").Send(context.Background(), msg), ErrInvalidHeader)
+}
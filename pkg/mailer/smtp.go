@@ -0,0 +1,58 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+)
+
+// SMTP send the mail through an smtp server, the server is authenticated with PLAIN when a username is given
+type SMTP struct {
+	Addr string
+	From string
+	Auth smtp.Auth
+	// send is smtp.SendMail, it's replaced in test
+	send func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+func NewSMTP(host string, port string, username string, password string, from string) *SMTP {
+	s := &SMTP{
+		Addr: net.JoinHostPort(host, port),
+		From: from,
+		send: smtp.SendMail,
+	}
+	if username != "" {
+		s.Auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return s
+}
+
+// Send doesn't stop when the ctx is done, net/smtp doesn't take a ctx
+func (s *SMTP) Send(ctx context.Context, msg Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+
+	if err := s.send(s.Addr, s.Auth, s.From, []string{msg.To}, s.format(msg)); err != nil {
+		return fmt.Errorf("failed to send mail to %s because %w", msg.To, err)
+	}
+
+	return nil
+}
+
+// format write the message as a mail with crlf line endings
+func (s *SMTP) format(msg Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", s.From)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+
+	return []byte(b.String())
+}
@@ -0,0 +1,21 @@
+package posting
+
+import "github.com/izzanzahrial/blog-api-echo/pkg/repository"
+
+// Actor is the user changing a post, they can always change their own post while EditAny and DeleteAny
+// let them edit and delete the post of other authors
+type Actor struct {
+	ID        int64
+	EditAny   bool
+	DeleteAny bool
+}
+
+// CanEdit report whether the actor can update the post or restore one of its revisions
+func (a Actor) CanEdit(post repository.PostData) bool {
+	return post.Author.ID == a.ID || a.EditAny
+}
+
+// CanDelete report whether the actor can move the post to the trash
+func (a Actor) CanDelete(post repository.PostData) bool {
+	return post.Author.ID == a.ID || a.DeleteAny
+}
@@ -0,0 +1,265 @@
+package posting
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+)
+
+// Diff mode of DiffRevisions
+const (
+	DiffModeUnified = "unified"
+	DiffModeWord    = "word"
+)
+
+// Diff operation, a token is either kept, inserted by the newer revision or deleted from the older one
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+// unifiedContext is how many unchanged lines surround a change in the unified diff
+const unifiedContext = 3
+
+// maxDiffCells bound the lcs table, bigger input is diffed as a whole replacement
+const maxDiffCells = 4_000_000
+
+type DiffOp struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// RevisionDiff is the change from one revision to another, Unified is set in unified mode
+// and the per field word diff in word mode
+type RevisionDiff struct {
+	From      int64    `json:"from"`
+	To        int64    `json:"to"`
+	Mode      string   `json:"mode"`
+	Unified   string   `json:"unified,omitempty"`
+	Title     []DiffOp `json:"title,omitempty"`
+	ShortDesc []DiffOp `json:"short_desc,omitempty"`
+	Content   []DiffOp `json:"content,omitempty"`
+}
+
+// diffRevisions compare the two revisions in the given mode
+func diffRevisions(from, to repository.Revision, mode string) (RevisionDiff, error) {
+	result := RevisionDiff{From: from.ID, To: to.ID, Mode: mode}
+
+	switch mode {
+	case DiffModeUnified:
+		result.Unified = unifiedDiff(
+			fmt.Sprintf("revision %d", from.ID), fmt.Sprintf("revision %d", to.ID),
+			revisionText(from), revisionText(to))
+	case DiffModeWord:
+		result.Title = wordDiff(from.Title, to.Title)
+		result.ShortDesc = wordDiff(from.ShortDesc, to.ShortDesc)
+		result.Content = wordDiff(from.Content, to.Content)
+	default:
+		return RevisionDiff{}, ErrUnknownDiffMode
+	}
+
+	return result, nil
+}
+
+// revisionText lay the revision out as text so every field show up in the unified diff
+func revisionText(r repository.Revision) string {
+	str := strings.Builder{}
+	str.WriteString("Title: " + r.Title + "\n")
+	str.WriteString("Short description: " + r.ShortDesc + "\n")
+	str.WriteString("Tags: " + strings.Join(r.Tags, ", ") + "\n")
+	str.WriteString("Categories: " + strings.Join(r.Categories, ", ") + "\n")
+	str.WriteString("\n")
+	str.WriteString(r.Content)
+
+	return str.String()
+}
+
+// unifiedDiff render the line diff of a and b like diff -u
+func unifiedDiff(fromName, toName string, a, b string) string {
+	ops := diffTokens(strings.Split(a, "\n"), strings.Split(b, "\n"))
+
+	var changes []int
+	for i, op := range ops {
+		if op.Op != DiffEqual {
+			changes = append(changes, i)
+		}
+	}
+
+	if len(changes) == 0 {
+		return ""
+	}
+
+	str := strings.Builder{}
+	str.WriteString("--- " + fromName + "\n")
+	str.WriteString("+++ " + toName + "\n")
+
+	for i := 0; i < len(changes); {
+		// a hunk keep going while the next change is close enough to share the context
+		j := i
+		for j+1 < len(changes) && changes[j+1]-changes[j]-1 <= 2*unifiedContext {
+			j++
+		}
+
+		start := changes[i] - unifiedContext
+		if start < 0 {
+			start = 0
+		}
+		end := changes[j] + unifiedContext + 1
+		if end > len(ops) {
+			end = len(ops)
+		}
+		writeHunk(&str, ops, start, end)
+
+		i = j + 1
+	}
+
+	return str.String()
+}
+
+// writeHunk write the header and lines of ops[start:end]
+func writeHunk(str *strings.Builder, ops []DiffOp, start, end int) {
+	var aLine, bLine int
+	for _, op := range ops[:start] {
+		if op.Op != DiffInsert {
+			aLine++
+		}
+		if op.Op != DiffDelete {
+			bLine++
+		}
+	}
+
+	var aCount, bCount int
+	lines := strings.Builder{}
+	for _, op := range ops[start:end] {
+		switch op.Op {
+		case DiffEqual:
+			aCount++
+			bCount++
+			lines.WriteString(" " + op.Text + "\n")
+		case DiffDelete:
+			aCount++
+			lines.WriteString("-" + op.Text + "\n")
+		case DiffInsert:
+			bCount++
+			lines.WriteString("+" + op.Text + "\n")
+		}
+	}
+
+	fmt.Fprintf(str, "@@ -%s +%s @@\n", hunkRange(aLine, aCount), hunkRange(bLine, bCount))
+	str.WriteString(lines.String())
+}
+
+// hunkRange format the 1-based range of the hunk, an empty range point at the line before it
+func hunkRange(before, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", before+1)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
+
+var wordPattern = regexp.MustCompile(`\s+|\S+`)
+
+// wordDiff diff the words of a and b, whitespace is kept so the text can be rebuilt,
+// adjacent tokens with the same operation are merged
+func wordDiff(a, b string) []DiffOp {
+	ops := diffTokens(wordPattern.FindAllString(a, -1), wordPattern.FindAllString(b, -1))
+
+	merged := []DiffOp{}
+	for _, op := range ops {
+		if n := len(merged); n > 0 && merged[n-1].Op == op.Op {
+			merged[n-1].Text += op.Text
+			continue
+		}
+		merged = append(merged, op)
+	}
+
+	return merged
+}
+
+// diffTokens return the shortest edit from a to b with the longest common subsequence,
+// the deletion come before the insertion of the same change
+func diffTokens(a, b []string) []DiffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]DiffOp, 0, len(a)+len(b))
+	for _, token := range a[:prefix] {
+		ops = append(ops, DiffOp{Op: DiffEqual, Text: token})
+	}
+
+	ops = append(ops, lcsDiff(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+
+	for _, token := range a[len(a)-suffix:] {
+		ops = append(ops, DiffOp{Op: DiffEqual, Text: token})
+	}
+
+	return ops
+}
+
+func lcsDiff(a, b []string) []DiffOp {
+	ops := make([]DiffOp, 0, len(a)+len(b))
+
+	if len(a)*len(b) > maxDiffCells {
+		for _, token := range a {
+			ops = append(ops, DiffOp{Op: DiffDelete, Text: token})
+		}
+		for _, token := range b {
+			ops = append(ops, DiffOp{Op: DiffInsert, Text: token})
+		}
+		return ops
+	}
+
+	// lcs[i][j] is the length of the common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, DiffOp{Op: DiffEqual, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, DiffOp{Op: DiffDelete, Text: a[i]})
+			i++
+		default:
+			ops = append(ops, DiffOp{Op: DiffInsert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, DiffOp{Op: DiffDelete, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, DiffOp{Op: DiffInsert, Text: b[j]})
+	}
+
+	return ops
+}
@@ -0,0 +1,37 @@
+package posting
+
+import (
+	"testing"
+
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWordDiff(t *testing.T) {
+	ops := wordDiff("the quick brown fox", "the slow brown fox jumps")
+
+	assert.Equal(t, []DiffOp{
+		{Op: DiffEqual, Text: "the "},
+		{Op: DiffDelete, Text: "quick"},
+		{Op: DiffInsert, Text: "slow"},
+		{Op: DiffEqual, Text: " brown fox"},
+		{Op: DiffInsert, Text: " jumps"},
+	}, ops)
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	from := repository.Revision{ID: 1, Title: "Go", Content: "one\ntwo\nthree"}
+	to := repository.Revision{ID: 2, Title: "Go", Content: "one\n2\nthree"}
+
+	diff, err := diffRevisions(from, to, DiffModeUnified)
+	assert.NoError(t, err)
+	assert.Equal(t, "--- revision 1\n+++ revision 2\n"+
+		"@@ -4,5 +4,5 @@\n Categories: \n \n one\n-two\n+2\n three\n", diff.Unified)
+
+	diff, err = diffRevisions(from, from, DiffModeUnified)
+	assert.NoError(t, err)
+	assert.Empty(t, diff.Unified)
+
+	_, err = diffRevisions(from, to, "side-by-side")
+	assert.Equal(t, ErrUnknownDiffMode, err)
+}
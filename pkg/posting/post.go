@@ -1,7 +1,17 @@
 package posting
 
+import "time"
+
 type PostData struct {
 	Title     string `json:"title"`
 	ShortDesc string `json:"short_desc"`
 	Content   string `json:"content"`
+	AuthorID  int64  `json:"author_id" validate:"required"`
+	// Status is the initial status of the post, empty means draft
+	Status string `json:"status" validate:"omitempty,oneof=draft published"`
+	// PublishAt schedule a draft to be published later
+	PublishAt *time.Time `json:"publish_at"`
+	// Tags that doesn't exist yet is created, Categories must already exist
+	Tags       []string `json:"tags"`
+	Categories []string `json:"categories"`
 }
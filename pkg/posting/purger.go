@@ -0,0 +1,107 @@
+package posting
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	caching "github.com/izzanzahrial/blog-api-echo/pkg/redis"
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+)
+
+// Purger remove for good the posts that stayed in the trash longer than the retention
+type Purger struct {
+	Repository repository.Post
+	DB         DBtx
+	Cache      caching.Cache
+	Interval   time.Duration
+	Retention  time.Duration
+	BatchSize  int
+	// Clock return the current time, replace it in test to control which post is expired
+	Clock func() time.Time
+}
+
+func NewPurger(rp repository.Post, db DBtx, cache caching.Cache, interval time.Duration, retention time.Duration) *Purger {
+	return &Purger{
+		Repository: rp,
+		DB:         db,
+		Cache:      cache,
+		Interval:   interval,
+		Retention:  retention,
+		BatchSize:  defaultBatchSize,
+		Clock:      time.Now,
+	}
+}
+
+// Start run the purger until the context is canceled
+func (p *Purger) Start(ctx context.Context) {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := p.PurgeExpired(ctx)
+			if err != nil {
+				log.Printf("purger failed to purge deleted posts: %v", err)
+			}
+			if len(purged) > 0 {
+				log.Printf("purger purged %d posts", len(purged))
+			}
+		}
+	}
+}
+
+// PurgeExpired purge every expired post, a batch at a time, and return the id of the purged posts
+func (p *Purger) PurgeExpired(ctx context.Context) ([]int64, error) {
+	var purged []int64
+	before := p.Clock().Add(-p.Retention)
+
+	for {
+		ids, err := p.purgeBatch(ctx, before)
+		purged = append(purged, ids...)
+		if err != nil {
+			return purged, err
+		}
+
+		if len(ids) < p.BatchSize {
+			return purged, nil
+		}
+	}
+}
+
+func (p *Purger) purgeBatch(ctx context.Context, before time.Time) ([]int64, error) {
+	tx, err := p.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction for deleted post because: %w", err)
+	}
+	defer tx.Rollback()
+
+	ids, err := p.Repository.Purge(ctx, tx, before, p.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction for deleted post because: %w", err)
+	}
+
+	// the post left the index and the cache when it was deleted,
+	// only the favourite count that went along with it is still cached
+	if len(ids) > 0 {
+		keys := make([]string, 0, len(ids))
+		for _, id := range ids {
+			keys = append(keys, caching.FavouriteKey(id))
+		}
+
+		op1 := p.Cache.Del(ctx, keys...)
+		if err := op1.Err(); err != nil {
+			log.Printf("failed to remove cached favourite count of purged posts: %v because %v", ids, err)
+		}
+	}
+
+	return ids, nil
+}
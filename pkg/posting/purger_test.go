@@ -0,0 +1,45 @@
+package posting
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	redisDB "github.com/izzanzahrial/blog-api-echo/pkg/redis"
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPurgerPurgeExpired(t *testing.T) {
+	mockRepo := new(repository.MockPostingPostgre)
+	mockDB := new(MockDBtx)
+	mockRedis := new(redisDB.MockRedis)
+
+	now := time.Date(2022, time.May, 1, 9, 0, 0, 0, time.UTC)
+	retention := 30 * 24 * time.Hour
+
+	purger := NewPurger(mockRepo, mockDB, mockRedis, time.Hour, retention)
+	purger.Clock = func() time.Time { return now }
+	purger.BatchSize = 2
+
+	ctx := context.Background()
+	first := newTx(t)
+	second := newTx(t)
+
+	// a full batch mean there may be more, the purger keep going until a batch come back short
+	mockDB.On("Begin").Return(first, nil).Once()
+	mockRepo.On("Purge", ctx, first, now.Add(-retention), 2).Return([]int64{1, 2}, nil).Once()
+	mockRedis.On("Del", ctx, []string{"favourites1", "favourites2"}).Return(&redis.IntCmd{}).Once()
+	mockDB.On("Begin").Return(second, nil).Once()
+	mockRepo.On("Purge", ctx, second, now.Add(-retention), 2).Return([]int64{3}, nil).Once()
+	mockRedis.On("Del", ctx, []string{"favourites3"}).Return(&redis.IntCmd{}).Once()
+
+	purged, err := purger.PurgeExpired(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 2, 3}, purged)
+
+	mockDB.AssertExpectations(t)
+	mockRepo.AssertExpectations(t)
+	mockRedis.AssertExpectations(t)
+}
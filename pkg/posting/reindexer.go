@@ -0,0 +1,210 @@
+package posting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/izzanzahrial/blog-api-echo/pkg/elastic"
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+)
+
+// defaultReindexBatchSize is how many post is sent in one bulk request
+const defaultReindexBatchSize = 500
+
+// ReindexCheckpoint is the progress of a reindex, it's saved after every batch
+// so an interrupted reindex carry on where it stopped
+type ReindexCheckpoint struct {
+	Alias   string `json:"alias"`
+	Index   string `json:"index"`
+	LastID  int64  `json:"last_id"`
+	Indexed int64  `json:"indexed"`
+}
+
+// Reindexer rebuild the search index from the repository into a fresh versioned index,
+// then swap the alias to it and delete the old index
+type Reindexer struct {
+	Repository repository.Post
+	DB         DBtx
+	Es         elastic.ElasticDB
+	BatchSize  int
+	// Checkpoint is the file the progress is saved in, empty mean the reindex can't be resumed
+	Checkpoint string
+	// Progress is called after every batch with how many post is indexed out of the total
+	Progress func(indexed int64, total int64)
+	// Clock return the current time, it's used to version the new index
+	Clock func() time.Time
+}
+
+func NewReindexer(rp repository.Post, db DBtx, es elastic.ElasticDB, checkpoint string) *Reindexer {
+	return &Reindexer{
+		Repository: rp,
+		DB:         db,
+		Es:         es,
+		BatchSize:  defaultReindexBatchSize,
+		Checkpoint: checkpoint,
+		Progress:   func(indexed int64, total int64) {},
+		Clock:      time.Now,
+	}
+}
+
+// Run reindex every published post into a new version of the index and return the checkpoint of the finished reindex,
+// changes made while it run are still written to the old index so the relay should be stopped for the duration
+func (r *Reindexer) Run(ctx context.Context, index string) (ReindexCheckpoint, error) {
+	alias := elastic.AliasOf(index)
+
+	cp, err := r.load()
+	if err != nil {
+		return ReindexCheckpoint{}, err
+	}
+
+	if cp.Index == "" {
+		cp = ReindexCheckpoint{Alias: alias, Index: fmt.Sprintf("%s_%d", index, r.Clock().Unix())}
+	} else if cp.Alias != alias {
+		return cp, fmt.Errorf("checkpoint: %s belong to the reindex of alias: %s", r.Checkpoint, cp.Alias)
+	}
+
+	if err := r.Es.EnsureIndex(ctx, cp.Index); err != nil {
+		return cp, err
+	}
+
+	total, err := r.count(ctx)
+	if err != nil {
+		return cp, err
+	}
+
+	for {
+		posts, err := r.findBatch(ctx, cp.LastID)
+		if err != nil {
+			return cp, err
+		}
+
+		if len(posts) == 0 {
+			break
+		}
+
+		if err := r.Es.Bulk(ctx, cp.Index, posts); err != nil {
+			return cp, err
+		}
+
+		cp.LastID = posts[len(posts)-1].ID
+		cp.Indexed += int64(len(posts))
+		if err := r.save(cp); err != nil {
+			return cp, err
+		}
+		r.Progress(cp.Indexed, total)
+
+		if len(posts) < r.BatchSize {
+			break
+		}
+	}
+
+	previous, err := r.Es.SwapAlias(ctx, alias, cp.Index)
+	if err != nil {
+		return cp, err
+	}
+
+	if err := r.Es.DeleteIndices(ctx, previous); err != nil {
+		return cp, err
+	}
+
+	return cp, r.clear()
+}
+
+func (r *Reindexer) count(ctx context.Context) (int64, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction for reindex because: %w", err)
+	}
+	defer tx.Rollback()
+
+	total, err := r.Repository.CountPublished(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction for reindex because: %w", err)
+	}
+
+	return total, nil
+}
+
+func (r *Reindexer) findBatch(ctx context.Context, afterID int64) ([]repository.PostData, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction for reindex because: %w", err)
+	}
+	defer tx.Rollback()
+
+	posts, err := r.Repository.FindPublishedAfter(ctx, tx, afterID, r.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction for reindex because: %w", err)
+	}
+
+	return posts, nil
+}
+
+// load read the checkpoint, a missing checkpoint is a fresh reindex
+func (r *Reindexer) load() (ReindexCheckpoint, error) {
+	var cp ReindexCheckpoint
+	if r.Checkpoint == "" {
+		return cp, nil
+	}
+
+	data, err := os.ReadFile(r.Checkpoint)
+	if errors.Is(err, os.ErrNotExist) {
+		return cp, nil
+	}
+	if err != nil {
+		return cp, fmt.Errorf("failed to read checkpoint: %s because %w", r.Checkpoint, err)
+	}
+
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return cp, fmt.Errorf("failed to decode checkpoint: %s because %w", r.Checkpoint, err)
+	}
+
+	return cp, nil
+}
+
+// save write the checkpoint to a temporary file first, so an interruption never leave half a checkpoint
+func (r *Reindexer) save(cp ReindexCheckpoint) error {
+	if r.Checkpoint == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint because %w", err)
+	}
+
+	tmp := r.Checkpoint + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %s because %w", tmp, err)
+	}
+
+	if err := os.Rename(tmp, r.Checkpoint); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %s because %w", r.Checkpoint, err)
+	}
+
+	return nil
+}
+
+func (r *Reindexer) clear() error {
+	if r.Checkpoint == "" {
+		return nil
+	}
+
+	if err := os.Remove(r.Checkpoint); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove checkpoint: %s because %w", r.Checkpoint, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package posting
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/izzanzahrial/blog-api-echo/pkg/elastic"
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReindexerRun(t *testing.T) {
+	mockRepo := new(repository.MockPostingPostgre)
+	mockDB := new(MockDBtx)
+	mockElastic := new(elastic.MockElastic)
+
+	now := time.Date(2022, time.May, 1, 9, 0, 0, 0, time.UTC)
+	checkpoint := filepath.Join(t.TempDir(), "reindex.json")
+
+	reindexer := NewReindexer(mockRepo, mockDB, mockElastic, checkpoint)
+	reindexer.Clock = func() time.Time { return now }
+	reindexer.BatchSize = 2
+
+	var progress []int64
+	reindexer.Progress = func(indexed int64, total int64) {
+		assert.Equal(t, int64(3), total)
+		progress = append(progress, indexed)
+	}
+
+	ctx := context.Background()
+	countTx, firstTx, secondTx := newTx(t), newTx(t), newTx(t)
+	index := "posts_1651395600"
+	first := []repository.PostData{{ID: 1}, {ID: 4}}
+	second := []repository.PostData{{ID: 7}}
+
+	mockElastic.On("EnsureIndex", ctx, index).Return(nil).Once()
+	mockDB.On("Begin").Return(countTx, nil).Once()
+	mockRepo.On("CountPublished", ctx, countTx).Return(int64(3), nil).Once()
+	mockDB.On("Begin").Return(firstTx, nil).Once()
+	mockRepo.On("FindPublishedAfter", ctx, firstTx, int64(0), 2).Return(first, nil).Once()
+	mockElastic.On("Bulk", ctx, index, first).Return(nil).Once()
+	mockDB.On("Begin").Return(secondTx, nil).Once()
+	mockRepo.On("FindPublishedAfter", ctx, secondTx, int64(4), 2).Return(second, nil).Once()
+	mockElastic.On("Bulk", ctx, index, second).Return(nil).Once()
+	mockElastic.On("SwapAlias", ctx, "posts_alias", index).Return([]string{"posts_1"}, nil).Once()
+	mockElastic.On("DeleteIndices", ctx, []string{"posts_1"}).Return(nil).Once()
+
+	cp, err := reindexer.Run(ctx, "posts")
+	assert.NoError(t, err)
+	assert.Equal(t, ReindexCheckpoint{Alias: "posts_alias", Index: index, LastID: 7, Indexed: 3}, cp)
+	assert.Equal(t, []int64{2, 3}, progress)
+
+	// the checkpoint is removed once the alias is swapped
+	_, err = os.Stat(checkpoint)
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+
+	mockRepo.AssertExpectations(t)
+	mockElastic.AssertExpectations(t)
+}
+
+func TestReindexerResume(t *testing.T) {
+	mockRepo := new(repository.MockPostingPostgre)
+	mockDB := new(MockDBtx)
+	mockElastic := new(elastic.MockElastic)
+
+	checkpoint := filepath.Join(t.TempDir(), "reindex.json")
+	index := "posts_1651395600"
+	err := os.WriteFile(checkpoint, []byte(`{"alias":"posts_alias","index":"posts_1651395600","last_id":4,"indexed":2}`), 0o644)
+	assert.NoError(t, err)
+
+	reindexer := NewReindexer(mockRepo, mockDB, mockElastic, checkpoint)
+	reindexer.BatchSize = 2
+
+	ctx := context.Background()
+	countTx, batchTx := newTx(t), newTx(t)
+	second := []repository.PostData{{ID: 7}}
+
+	// the interrupted index is reused and the posts already indexed are skipped
+	mockElastic.On("EnsureIndex", ctx, index).Return(nil).Once()
+	mockDB.On("Begin").Return(countTx, nil).Once()
+	mockRepo.On("CountPublished", ctx, countTx).Return(int64(3), nil).Once()
+	mockDB.On("Begin").Return(batchTx, nil).Once()
+	mockRepo.On("FindPublishedAfter", ctx, batchTx, int64(4), 2).Return(second, nil).Once()
+	mockElastic.On("Bulk", ctx, index, second).Return(errors.New("elastic is down")).Once()
+
+	cp, err := reindexer.Run(ctx, "posts")
+	assert.Error(t, err)
+	assert.Equal(t, int64(4), cp.LastID)
+
+	// a failed batch doesn't move the checkpoint
+	data, err := os.ReadFile(checkpoint)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"alias":"posts_alias","index":"posts_1651395600","last_id":4,"indexed":2}`, string(data))
+
+	mockRepo.AssertExpectations(t)
+	mockElastic.AssertExpectations(t)
+}
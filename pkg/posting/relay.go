@@ -0,0 +1,227 @@
+package posting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	caching "github.com/izzanzahrial/blog-api-echo/pkg/redis"
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+	"github.com/izzanzahrial/blog-api-echo/pkg/search"
+)
+
+const (
+	// defaultMaxAttempts is how many time an event is tried before it's dead lettered
+	defaultMaxAttempts = 10
+	defaultBaseBackoff = time.Duration(10) * time.Second
+	defaultMaxBackoff  = time.Duration(3600) * time.Second
+)
+
+// Relay deliver the outbox events to the searcher and the cache, a failed event is tried again
+// with exponential backoff until it run out of attempts
+type Relay struct {
+	Repository  repository.Post
+	Outbox      repository.OutboxRepository
+	DB          DBtx
+	Cache       caching.Cache
+	Search      search.Searcher
+	Interval    time.Duration
+	BatchSize   int
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// Clock return the current time, replace it in test to control which event is due
+	Clock func() time.Time
+}
+
+func NewRelay(rp repository.Post, ob repository.OutboxRepository, db DBtx, cache caching.Cache, searcher search.Searcher, interval time.Duration) *Relay {
+	return &Relay{
+		Repository:  rp,
+		Outbox:      ob,
+		DB:          db,
+		Cache:       cache,
+		Search:      searcher,
+		Interval:    interval,
+		BatchSize:   defaultBatchSize,
+		MaxAttempts: defaultMaxAttempts,
+		BaseBackoff: defaultBaseBackoff,
+		MaxBackoff:  defaultMaxBackoff,
+		Clock:       time.Now,
+	}
+}
+
+// Start run the relay until the context is canceled
+func (r *Relay) Start(ctx context.Context) {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			delivered, err := r.Deliver(ctx)
+			if err != nil {
+				log.Printf("relay failed to deliver outbox events: %v", err)
+			}
+			if delivered > 0 {
+				log.Printf("relay delivered %d outbox events", delivered)
+			}
+		}
+	}
+}
+
+// Deliver deliver every due event, a batch at a time, and return how many is delivered
+func (r *Relay) Deliver(ctx context.Context) (int, error) {
+	var delivered int
+
+	for {
+		n, found, err := r.deliverBatch(ctx)
+		delivered += n
+		if err != nil {
+			return delivered, err
+		}
+
+		if found < r.BatchSize {
+			return delivered, nil
+		}
+	}
+}
+
+// deliverBatch return how many event is delivered and how many is found
+func (r *Relay) deliverBatch(ctx context.Context) (int, int, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to begin transaction for outbox events because: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := r.Clock()
+	events, err := r.Outbox.FindPending(ctx, tx, now, r.BatchSize)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// the posts are locked in the order of their id, so two relays never wait on each other in a cycle
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].PostID < events[j].PostID
+	})
+
+	delivered := 0
+	for _, event := range events {
+		// FOR UPDATE SKIP LOCKED only lock the event, another relay can hold another event of the same post.
+		// The post stay locked until the commit, so that relay read the post after this delivery is done
+		// and the older post can't be delivered last
+		if err := r.Repository.LockByID(ctx, tx, event.PostID); err != nil {
+			return delivered, len(events), err
+		}
+
+		post, err := r.Repository.FindByID(ctx, tx, event.PostID)
+		if err != nil && !errors.Is(err, repository.ErrPostNotFound) {
+			return delivered, len(events), err
+		}
+
+		if err == nil {
+			err = r.sync(ctx, event, post)
+		} else {
+			err = r.withdraw(ctx, event, repository.PostData{ID: event.PostID})
+		}
+
+		if err == nil {
+			if err := r.Outbox.Delete(ctx, tx, event.ID); err != nil {
+				return delivered, len(events), err
+			}
+			delivered++
+			continue
+		}
+
+		event.Attempts++
+		event.LastError = err.Error()
+
+		if event.Attempts >= r.MaxAttempts {
+			log.Printf("relay dead lettered outbox event: %d of post: %d because %v", event.ID, event.PostID, err)
+			if err := r.Outbox.DeadLetter(ctx, tx, event, now); err != nil {
+				return delivered, len(events), err
+			}
+			continue
+		}
+
+		event.NextAttemptAt = now.Add(r.backoff(event.Attempts))
+		if err := r.Outbox.Retry(ctx, tx, event); err != nil {
+			return delivered, len(events), err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, len(events), fmt.Errorf("failed to commit transaction for outbox events because: %w", err)
+	}
+
+	return delivered, len(events), nil
+}
+
+// backoff double the wait after every attempt, capped at MaxBackoff
+func (r *Relay) backoff(attempts int) time.Duration {
+	wait := r.BaseBackoff
+	for i := 1; i < attempts; i++ {
+		wait *= 2
+		if wait >= r.MaxBackoff {
+			return r.MaxBackoff
+		}
+	}
+
+	return wait
+}
+
+// sync put the current state of the post in the index and cache, delivering it twice is harmless
+func (r *Relay) sync(ctx context.Context, event repository.OutboxEvent, post repository.PostData) error {
+	if post.Status != repository.StatusPublished {
+		return r.withdraw(ctx, event, post)
+	}
+
+	if err := r.Search.Index(ctx, post); err != nil {
+		return fmt.Errorf("failed to index post: %d because %w", post.ID, err)
+	}
+
+	op1 := r.Cache.Set(ctx, caching.PostKey(post.ID), post, postTTL)
+	if err := op1.Err(); err != nil {
+		return fmt.Errorf("failed to cache post: %d because %w", post.ID, err)
+	}
+
+	return r.forgetSlugs(ctx, event, post)
+}
+
+// withdraw remove a post that is no longer public from the index and cache
+func (r *Relay) withdraw(ctx context.Context, event repository.OutboxEvent, post repository.PostData) error {
+	if err := r.Search.Remove(ctx, post.ID); err != nil {
+		return fmt.Errorf("failed to remove post: %d from the search because %w", post.ID, err)
+	}
+
+	op1 := r.Cache.Del(ctx, caching.PostKey(post.ID))
+	if err := op1.Err(); err != nil {
+		return fmt.Errorf("failed to remove cached post: %d because %w", post.ID, err)
+	}
+
+	return r.forgetSlugs(ctx, event, post)
+}
+
+// forgetSlugs drop the cached slug of the post and the slug it had before the change,
+// the related posts are dropped with them since they're found by what the post say
+func (r *Relay) forgetSlugs(ctx context.Context, event repository.OutboxEvent, post repository.PostData) error {
+	keys := []string{caching.RelatedKey(post.ID)}
+	if post.Slug != "" {
+		keys = append(keys, caching.SlugKey(post.Slug))
+	}
+	if event.Slug != "" && event.Slug != post.Slug {
+		keys = append(keys, caching.SlugKey(event.Slug))
+	}
+
+	op1 := r.Cache.Del(ctx, keys...)
+	if err := op1.Err(); err != nil {
+		return fmt.Errorf("failed to remove cached keys: %v because %w", keys, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,197 @@
+package posting
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	redisDB "github.com/izzanzahrial/blog-api-echo/pkg/redis"
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+	"github.com/izzanzahrial/blog-api-echo/pkg/search"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRelayDeliver(t *testing.T) {
+	mockRepo := new(repository.MockPostingPostgre)
+	mockOutbox := new(repository.MockOutboxPostgre)
+	mockDB := new(MockDBtx)
+	mockRedis := new(redisDB.MockRedis)
+	mockSearch := new(search.MockSearcher)
+
+	now := time.Date(2022, time.May, 1, 9, 0, 0, 0, time.UTC)
+
+	relay := NewRelay(mockRepo, mockOutbox, mockDB, mockRedis, mockSearch, time.Second)
+	relay.Clock = func() time.Time { return now }
+
+	ctx := context.Background()
+	tx := newTx(t)
+	published := repository.PostData{ID: 1, Slug: "new-title", Status: repository.StatusPublished}
+	events := []repository.OutboxEvent{
+		{ID: 10, PostID: 1, Slug: "old-title"},
+		{ID: 11, PostID: 2, Slug: "deleted"},
+	}
+
+	mockDB.On("Begin").Return(tx, nil).Once()
+	mockOutbox.On("FindPending", ctx, tx, now, defaultBatchSize).Return(events, nil).Once()
+
+	// the published post is indexed and cached, the old slug and the related posts are forgotten
+	mockRepo.On("LockByID", ctx, tx, int64(1)).Return(nil).Once()
+	mockRepo.On("FindByID", ctx, tx, int64(1)).Return(published, nil).Once()
+	mockSearch.On("Index", ctx, published).Return(nil).Once()
+	mockRedis.On("Set", ctx, "post1", published, postTTL).Return(&redis.StatusCmd{}).Once()
+	mockRedis.On("Del", ctx, []string{"related1", "slugnew-title", "slugold-title"}).Return(&redis.IntCmd{}).Once()
+	mockOutbox.On("Delete", ctx, tx, int64(10)).Return(nil).Once()
+
+	// the deleted post is taken out of the index and cache
+	mockRepo.On("LockByID", ctx, tx, int64(2)).Return(nil).Once()
+	mockRepo.On("FindByID", ctx, tx, int64(2)).Return(repository.PostData{}, repository.ErrPostNotFound).Once()
+	mockSearch.On("Remove", ctx, int64(2)).Return(nil).Once()
+	mockRedis.On("Del", ctx, []string{"post2"}).Return(&redis.IntCmd{}).Once()
+	mockRedis.On("Del", ctx, []string{"related2", "slugdeleted"}).Return(&redis.IntCmd{}).Once()
+	mockOutbox.On("Delete", ctx, tx, int64(11)).Return(nil).Once()
+
+	delivered, err := relay.Deliver(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, delivered)
+
+	mockDB.AssertExpectations(t)
+	mockRepo.AssertExpectations(t)
+	mockOutbox.AssertExpectations(t)
+	mockSearch.AssertExpectations(t)
+	mockRedis.AssertExpectations(t)
+}
+
+func TestRelayRetryAndDeadLetter(t *testing.T) {
+	mockRepo := new(repository.MockPostingPostgre)
+	mockOutbox := new(repository.MockOutboxPostgre)
+	mockDB := new(MockDBtx)
+	mockSearch := new(search.MockSearcher)
+
+	now := time.Date(2022, time.May, 1, 9, 0, 0, 0, time.UTC)
+
+	relay := NewRelay(mockRepo, mockOutbox, mockDB, new(redisDB.MockRedis), mockSearch, time.Second)
+	relay.Clock = func() time.Time { return now }
+	relay.MaxAttempts = 3
+
+	ctx := context.Background()
+	tx := newTx(t)
+	post := repository.PostData{ID: 1, Slug: "title", Status: repository.StatusPublished}
+	events := []repository.OutboxEvent{
+		{ID: 10, PostID: 1, Attempts: 1},
+		{ID: 11, PostID: 1, Attempts: 2},
+	}
+	down := errors.New("elastic is down")
+
+	mockDB.On("Begin").Return(tx, nil).Once()
+	mockOutbox.On("FindPending", ctx, tx, now, defaultBatchSize).Return(events, nil).Once()
+	mockRepo.On("LockByID", ctx, tx, int64(1)).Return(nil).Twice()
+	mockRepo.On("FindByID", ctx, tx, int64(1)).Return(post, nil).Twice()
+	mockSearch.On("Index", ctx, post).Return(down).Twice()
+
+	// second attempt wait twice the base backoff
+	mockOutbox.On("Retry", ctx, tx, repository.OutboxEvent{
+		ID: 10, PostID: 1, Attempts: 2, NextAttemptAt: now.Add(2 * defaultBaseBackoff),
+		LastError: "failed to index post: 1 because elastic is down",
+	}).Return(nil).Once()
+	mockOutbox.On("DeadLetter", ctx, tx, repository.OutboxEvent{
+		ID: 11, PostID: 1, Attempts: 3,
+		LastError: "failed to index post: 1 because elastic is down",
+	}, now).Return(nil).Once()
+
+	delivered, err := relay.Deliver(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, delivered)
+
+	mockRepo.AssertExpectations(t)
+	mockOutbox.AssertExpectations(t)
+	mockSearch.AssertExpectations(t)
+}
+
+func TestRelayInterleaved(t *testing.T) {
+	mockRepo := new(repository.MockPostingPostgre)
+	mockOutbox := new(repository.MockOutboxPostgre)
+	mockRedis := new(redisDB.MockRedis)
+	mockSearch := new(search.MockSearcher)
+
+	now := time.Date(2022, time.May, 1, 9, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	// two relays each hold an event of the same post, the post is changed between the events
+	dbA, dbB := new(MockDBtx), new(MockDBtx)
+	// the memory store transactions, testify print the arguments it compare and a *sql.Tx is changed by its own goroutine
+	txA, err := repository.NewMemoryStore().Begin()
+	assert.NoError(t, err)
+	txB, err := repository.NewMemoryStore().Begin()
+	assert.NoError(t, err)
+	relayA := NewRelay(mockRepo, mockOutbox, dbA, mockRedis, mockSearch, time.Second)
+	relayB := NewRelay(mockRepo, mockOutbox, dbB, mockRedis, mockSearch, time.Second)
+	relayA.Clock = func() time.Time { return now }
+	relayB.Clock = func() time.Time { return now }
+
+	older := repository.PostData{ID: 1, Slug: "title", Title: "older", Status: repository.StatusPublished}
+	newer := repository.PostData{ID: 1, Slug: "title", Title: "newer", Status: repository.StatusPublished}
+
+	aRead, bLocking, aDone := make(chan struct{}), make(chan struct{}), make(chan struct{})
+	var mu sync.Mutex
+	var indexed []string
+
+	dbA.On("Begin").Return(txA, nil).Once()
+	dbB.On("Begin").Return(txB, nil).Once()
+	mockOutbox.On("FindPending", ctx, txA, now, defaultBatchSize).Return([]repository.OutboxEvent{{ID: 10, PostID: 1}}, nil).Once()
+	mockOutbox.On("FindPending", ctx, txB, now, defaultBatchSize).Return([]repository.OutboxEvent{{ID: 11, PostID: 1}}, nil).Once()
+
+	// A read the older post, then B wait on the lock of the post until A commit
+	mockRepo.On("LockByID", ctx, txA, int64(1)).Return(nil).Once()
+	mockRepo.On("FindByID", ctx, txA, int64(1)).Return(older, nil).Once().Run(func(args mock.Arguments) {
+		close(aRead)
+		<-bLocking
+	})
+	mockRepo.On("LockByID", ctx, txB, int64(1)).Return(nil).Once().Run(func(args mock.Arguments) {
+		close(bLocking)
+		<-aDone
+	})
+	mockRepo.On("FindByID", ctx, txB, int64(1)).Return(newer, nil).Once()
+
+	for _, post := range []repository.PostData{older, newer} {
+		post := post
+		mockSearch.On("Index", ctx, post).Return(nil).Once().Run(func(args mock.Arguments) {
+			mu.Lock()
+			defer mu.Unlock()
+			indexed = append(indexed, post.Title)
+		})
+		mockRedis.On("Set", ctx, "post1", post, postTTL).Return(&redis.StatusCmd{}).Once()
+	}
+	mockRedis.On("Del", ctx, []string{"related1", "slugtitle"}).Return(&redis.IntCmd{}).Twice()
+	mockOutbox.On("Delete", ctx, txA, int64(10)).Return(nil).Once()
+	mockOutbox.On("Delete", ctx, txB, int64(11)).Return(nil).Once()
+
+	go func() {
+		defer close(aDone)
+		_, err := relayA.Deliver(ctx)
+		assert.NoError(t, err)
+	}()
+
+	<-aRead
+	delivered, err := relayB.Deliver(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+
+	// the post delivered last is the newer one
+	assert.Equal(t, []string{"older", "newer"}, indexed)
+
+	mockRepo.AssertExpectations(t)
+	mockOutbox.AssertExpectations(t)
+	mockSearch.AssertExpectations(t)
+}
+
+func TestRelayBackoff(t *testing.T) {
+	relay := &Relay{BaseBackoff: time.Second, MaxBackoff: 10 * time.Second}
+
+	assert.Equal(t, time.Second, relay.backoff(1))
+	assert.Equal(t, 4*time.Second, relay.backoff(3))
+	assert.Equal(t, 10*time.Second, relay.backoff(5))
+}
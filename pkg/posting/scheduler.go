@@ -0,0 +1,104 @@
+package posting
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+)
+
+// defaultBatchSize is how many due post published in one transaction
+const defaultBatchSize = 50
+
+// Scheduler publish scheduled drafts once their publish time is due
+type Scheduler struct {
+	Repository repository.Post
+	Outbox     repository.OutboxRepository
+	DB         DBtx
+	Interval   time.Duration
+	BatchSize  int
+	// Clock return the current time, replace it in test to control which post is due
+	Clock func() time.Time
+}
+
+func NewScheduler(rp repository.Post, ob repository.OutboxRepository, db DBtx, interval time.Duration) *Scheduler {
+	return &Scheduler{
+		Repository: rp,
+		Outbox:     ob,
+		DB:         db,
+		Interval:   interval,
+		BatchSize:  defaultBatchSize,
+		Clock:      time.Now,
+	}
+}
+
+// Start run the scheduler until the context is canceled
+func (s *Scheduler) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			published, err := s.PublishDue(ctx)
+			if err != nil {
+				log.Printf("scheduler failed to publish due posts: %v", err)
+			}
+			if len(published) > 0 {
+				log.Printf("scheduler published %d posts", len(published))
+			}
+		}
+	}
+}
+
+// PublishDue publish every due post, a batch at a time, and return the published posts
+func (s *Scheduler) PublishDue(ctx context.Context) ([]repository.PostData, error) {
+	var published []repository.PostData
+
+	for {
+		posts, err := s.publishBatch(ctx)
+		published = append(published, posts...)
+		if err != nil {
+			return published, err
+		}
+
+		if len(posts) < s.BatchSize {
+			return published, nil
+		}
+	}
+}
+
+func (s *Scheduler) publishBatch(ctx context.Context) ([]repository.PostData, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction for scheduled post because: %w", err)
+	}
+	defer tx.Rollback()
+
+	posts, err := s.Repository.FindDueScheduled(ctx, tx, s.Clock(), s.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range posts {
+		if err := s.Repository.UpdateStatus(ctx, tx, posts[i].ID, repository.StatusPublished); err != nil {
+			return nil, err
+		}
+		posts[i].Status = repository.StatusPublished
+		posts[i].PublishAt = nil
+
+		if err := Sync(ctx, s.Outbox, tx, posts[i].ID, posts[i].Slug); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction for scheduled post because: %w", err)
+	}
+
+	return posts, nil
+}
@@ -0,0 +1,75 @@
+package posting
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSchedulerPublishDue(t *testing.T) {
+	mockRepo := new(repository.MockPostingPostgre)
+	mockOutbox := new(repository.MockOutboxPostgre)
+	mockDB := new(MockDBtx)
+
+	now := time.Date(2022, time.May, 1, 9, 0, 0, 0, time.UTC)
+	publishAt := now.Add(-time.Minute)
+
+	scheduler := NewScheduler(mockRepo, mockOutbox, mockDB, time.Minute)
+	scheduler.Clock = func() time.Time { return now }
+
+	ctx := context.Background()
+	tx := newTx(t)
+	due := []repository.PostData{
+		{ID: 1, Title: "First", Status: repository.StatusDraft, PublishAt: &publishAt},
+		{ID: 2, Title: "Second", Status: repository.StatusDraft, PublishAt: &publishAt},
+	}
+	published := []repository.PostData{
+		{ID: 1, Title: "First", Status: repository.StatusPublished},
+		{ID: 2, Title: "Second", Status: repository.StatusPublished},
+	}
+
+	mockDB.On("Begin").Return(tx, nil).Once()
+	mockRepo.On("FindDueScheduled", ctx, tx, now, defaultBatchSize).Return(due, nil).Once()
+	mockRepo.On("UpdateStatus", ctx, tx, int64(1), repository.StatusPublished).Return(nil).Once()
+	mockRepo.On("UpdateStatus", ctx, tx, int64(2), repository.StatusPublished).Return(nil).Once()
+	for _, id := range []int64{1, 2} {
+		id := id
+		mockOutbox.On("Add", ctx, tx, mock.MatchedBy(func(e repository.OutboxEvent) bool {
+			return e.PostID == id
+		})).Return(nil).Once()
+	}
+
+	posts, err := scheduler.PublishDue(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, published, posts)
+
+	mockDB.AssertExpectations(t)
+	mockRepo.AssertExpectations(t)
+	mockOutbox.AssertExpectations(t)
+}
+
+func TestSchedulerNothingDue(t *testing.T) {
+	mockRepo := new(repository.MockPostingPostgre)
+	mockDB := new(MockDBtx)
+
+	now := time.Date(2022, time.May, 1, 9, 0, 0, 0, time.UTC)
+
+	scheduler := NewScheduler(mockRepo, new(repository.MockOutboxPostgre), mockDB, time.Minute)
+	scheduler.Clock = func() time.Time { return now }
+
+	ctx := context.Background()
+	tx := newTx(t)
+
+	mockDB.On("Begin").Return(tx, nil).Once()
+	mockRepo.On("FindDueScheduled", ctx, tx, now, defaultBatchSize).Return([]repository.PostData{}, nil).Once()
+
+	posts, err := scheduler.PublishDue(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, posts)
+
+	mockRepo.AssertExpectations(t)
+}
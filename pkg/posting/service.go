@@ -2,7 +2,6 @@ package posting
 
 import (
 	"context"
-	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -11,9 +10,10 @@ import (
 	"time"
 
 	"github.com/go-playground/validator/v10"
-	"github.com/izzanzahrial/blog-api-echo/pkg/elastic"
 	caching "github.com/izzanzahrial/blog-api-echo/pkg/redis"
 	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+	"github.com/izzanzahrial/blog-api-echo/pkg/search"
+	"github.com/izzanzahrial/blog-api-echo/pkg/taxonomy"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -22,15 +22,54 @@ var (
 	ErrFailedToBeginTransaction  = errors.New("failed to begin transaction to the repository")
 	ErrFailedToCommitTransaction = errors.New("failed to commit transaction to the repository")
 	ErrFailedToCachePost         = errors.New("failed to cache post")
+	ErrNotPostAuthor             = errors.New("user isn't the author of the post")
+	ErrPostNotFound              = errors.New("post not found")
+	ErrPostAlreadyPublished      = errors.New("post is already published")
+	ErrUnknownDiffMode           = errors.New("diff mode isn't unified or word")
+	ErrPublishAtPast             = errors.New("publish at isn't in the future")
 )
 
+// SlugMovedError is returned when the slug is an old slug of a post
+type SlugMovedError struct {
+	Slug string
+}
+
+func (e *SlugMovedError) Error() string {
+	return "post slug has moved to " + e.Slug
+}
+
+// postTTL is how long a published post live in the cache
+const postTTL = time.Duration(3600) * time.Second
+
+// suggestTTL is short so a new title show up in the suggestions soon after it's published
+const suggestTTL = time.Duration(60) * time.Second
+
+// relatedTTL bound how long a new post take to show up as related to an older one,
+// the related posts of a post are dropped as soon as the post itself change
+const relatedTTL = time.Duration(3600) * time.Second
+
+// MaxRelated is how many related posts is found and cached for a post
+const MaxRelated = 10
+
 type Service interface {
 	Create(ctx context.Context, post PostData) (repository.PostData, error)
-	Update(ctx context.Context, post repository.PostData) error
-	Delete(ctx context.Context, id int64) error
-	FindByID(ctx context.Context, id int64) (repository.PostData, error)
-	FindByTitleContent(ctx context.Context, query string, from int, size int) ([]repository.PostData, error)
-	FindRecent(ctx context.Context, from int, size int) ([]repository.PostData, error)
+	Update(ctx context.Context, actor Actor, post repository.PostData) error
+	Delete(ctx context.Context, actor Actor, id int64) error
+	Undelete(ctx context.Context, actor Actor, id int64) (repository.PostData, error)
+	FindTrash(ctx context.Context, userID int64, from int, size int) ([]repository.PostData, error)
+	Publish(ctx context.Context, actor Actor, id int64) (repository.PostData, error)
+	Unpublish(ctx context.Context, actor Actor, id int64) (repository.PostData, error)
+	Archive(ctx context.Context, actor Actor, id int64) (repository.PostData, error)
+	Schedule(ctx context.Context, actor Actor, id int64, publishAt *time.Time) (repository.PostData, error)
+	FindRevisions(ctx context.Context, actor Actor, id int64) ([]repository.Revision, error)
+	DiffRevisions(ctx context.Context, actor Actor, id int64, from int64, to int64, mode string) (RevisionDiff, error)
+	Restore(ctx context.Context, actor Actor, id int64, revisionID int64) (repository.PostData, error)
+	FindByID(ctx context.Context, viewerID int64, id int64) (repository.PostData, error)
+	FindBySlug(ctx context.Context, viewerID int64, slug string) (repository.PostData, error)
+	FindByTitleContent(ctx context.Context, query repository.SearchQuery, filter repository.PostFilter, from int, size int) (repository.SearchResult, error)
+	FindRecent(ctx context.Context, filter repository.PostFilter, from int, size int) ([]repository.PostData, error)
+	Suggest(ctx context.Context, prefix string, size int) ([]repository.Suggestion, error)
+	Related(ctx context.Context, id int64, size int) ([]repository.PostData, error)
 }
 
 type MockService struct {
@@ -42,31 +81,91 @@ func (m *MockService) Create(ctx context.Context, post PostData) (repository.Pos
 	return args.Get(0).(repository.PostData), args.Error(1)
 }
 
-func (m *MockService) Update(ctx context.Context, post repository.PostData) error {
-	args := m.Called(ctx, post)
+func (m *MockService) Update(ctx context.Context, actor Actor, post repository.PostData) error {
+	args := m.Called(ctx, actor, post)
 	return args.Error(0)
 }
 
-func (m *MockService) Delete(ctx context.Context, id int64) error {
-	args := m.Called(ctx, id)
+func (m *MockService) Delete(ctx context.Context, actor Actor, id int64) error {
+	args := m.Called(ctx, actor, id)
 	return args.Error(0)
 }
 
-func (m *MockService) FindByID(ctx context.Context, id int64) (repository.PostData, error) {
-	args := m.Called(ctx, id)
+func (m *MockService) Undelete(ctx context.Context, actor Actor, id int64) (repository.PostData, error) {
+	args := m.Called(ctx, actor, id)
 	return args.Get(0).(repository.PostData), args.Error(1)
 }
 
-func (m *MockService) FindRecent(ctx context.Context, from int, size int) ([]repository.PostData, error) {
-	args := m.Called(ctx, from, size)
+func (m *MockService) FindTrash(ctx context.Context, userID int64, from int, size int) ([]repository.PostData, error) {
+	args := m.Called(ctx, userID, from, size)
 	return args.Get(0).([]repository.PostData), args.Error(1)
 }
 
-func (m *MockService) FindByTitleContent(ctx context.Context, query string, from int, size int) ([]repository.PostData, error) {
-	args := m.Called(ctx, query, from, size)
+func (m *MockService) Publish(ctx context.Context, actor Actor, id int64) (repository.PostData, error) {
+	args := m.Called(ctx, actor, id)
+	return args.Get(0).(repository.PostData), args.Error(1)
+}
+
+func (m *MockService) Unpublish(ctx context.Context, actor Actor, id int64) (repository.PostData, error) {
+	args := m.Called(ctx, actor, id)
+	return args.Get(0).(repository.PostData), args.Error(1)
+}
+
+func (m *MockService) Archive(ctx context.Context, actor Actor, id int64) (repository.PostData, error) {
+	args := m.Called(ctx, actor, id)
+	return args.Get(0).(repository.PostData), args.Error(1)
+}
+
+func (m *MockService) Schedule(ctx context.Context, actor Actor, id int64, publishAt *time.Time) (repository.PostData, error) {
+	args := m.Called(ctx, actor, id, publishAt)
+	return args.Get(0).(repository.PostData), args.Error(1)
+}
+
+func (m *MockService) FindRevisions(ctx context.Context, actor Actor, id int64) ([]repository.Revision, error) {
+	args := m.Called(ctx, actor, id)
+	return args.Get(0).([]repository.Revision), args.Error(1)
+}
+
+func (m *MockService) DiffRevisions(ctx context.Context, actor Actor, id int64, from int64, to int64, mode string) (RevisionDiff, error) {
+	args := m.Called(ctx, actor, id, from, to, mode)
+	return args.Get(0).(RevisionDiff), args.Error(1)
+}
+
+func (m *MockService) Restore(ctx context.Context, actor Actor, id int64, revisionID int64) (repository.PostData, error) {
+	args := m.Called(ctx, actor, id, revisionID)
+	return args.Get(0).(repository.PostData), args.Error(1)
+}
+
+func (m *MockService) FindByID(ctx context.Context, viewerID int64, id int64) (repository.PostData, error) {
+	args := m.Called(ctx, viewerID, id)
+	return args.Get(0).(repository.PostData), args.Error(1)
+}
+
+func (m *MockService) FindBySlug(ctx context.Context, viewerID int64, slug string) (repository.PostData, error) {
+	args := m.Called(ctx, viewerID, slug)
+	return args.Get(0).(repository.PostData), args.Error(1)
+}
+
+func (m *MockService) Suggest(ctx context.Context, prefix string, size int) ([]repository.Suggestion, error) {
+	args := m.Called(ctx, prefix, size)
+	return args.Get(0).([]repository.Suggestion), args.Error(1)
+}
+
+func (m *MockService) Related(ctx context.Context, id int64, size int) ([]repository.PostData, error) {
+	args := m.Called(ctx, id, size)
+	return args.Get(0).([]repository.PostData), args.Error(1)
+}
+
+func (m *MockService) FindRecent(ctx context.Context, filter repository.PostFilter, from int, size int) ([]repository.PostData, error) {
+	args := m.Called(ctx, filter, from, size)
 	return args.Get(0).([]repository.PostData), args.Error(1)
 }
 
+func (m *MockService) FindByTitleContent(ctx context.Context, query repository.SearchQuery, filter repository.PostFilter, from int, size int) (repository.SearchResult, error) {
+	args := m.Called(ctx, query, filter, from, size)
+	return args.Get(0).(repository.SearchResult), args.Error(1)
+}
+
 // type transaction interface {
 // 	Rollback() error
 // 	Commit() error
@@ -74,34 +173,68 @@ func (m *MockService) FindByTitleContent(ctx context.Context, query string, from
 
 // naming things is hard
 type DBtx interface {
-	Begin() (*sql.Tx, error)
+	Begin() (repository.Tx, error)
 }
 
 type MockDBtx struct {
 	mock.Mock
 }
 
-func (m *MockDBtx) Begin() (*sql.Tx, error) {
+func (m *MockDBtx) Begin() (repository.Tx, error) {
 	args := m.Called()
-	return args.Get(0).(*sql.Tx), args.Error(1)
+	return args.Get(0).(repository.Tx), args.Error(1)
 }
 
 type service struct {
 	Repository repository.Post
-	DB         DBtx
+	Outbox     repository.OutboxRepository
+	Transactor repository.Transactor
 	Validate   *validator.Validate
 	Cache      caching.Cache
-	Es         elastic.ElasticDB
+	Search     search.Searcher
 }
 
-func NewService(rp repository.Post, db DBtx, val *validator.Validate, cache caching.Cache, es elastic.ElasticDB) Service {
+func NewService(rp repository.Post, ob repository.OutboxRepository, tr repository.Transactor, val *validator.Validate, cache caching.Cache, searcher search.Searcher) Service {
 	return &service{
 		Repository: rp,
-		DB:         db,
+		Outbox:     ob,
+		Transactor: tr,
 		Validate:   val,
 		Cache:      cache,
-		Es:         es,
+		Search:     searcher,
+	}
+}
+
+// Sync add an outbox event inside tx, so the relay bring the indexed and cached copy of the post in line
+// once the change is committed, slug is the slug the post had before the change
+func Sync(ctx context.Context, ob repository.OutboxRepository, tx repository.Tx, postID int64, slug string) error {
+	now := time.Now()
+	event := repository.OutboxEvent{
+		PostID:        postID,
+		Slug:          slug,
+		CreatedAt:     now,
+		NextAttemptAt: now,
+	}
+
+	return ob.Add(ctx, tx, event)
+}
+
+// uniqueNames trim the category names and drop the empty and duplicate one,
+// unlike tag the category name keep its case
+func uniqueNames(names []string) []string {
+	seen := make(map[string]bool)
+	result := []string{}
+
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		result = append(result, name)
 	}
+
+	return result
 }
 
 func (ps *service) Create(ctx context.Context, post PostData) (repository.PostData, error) {
@@ -110,238 +243,640 @@ func (ps *service) Create(ctx context.Context, post PostData) (repository.PostDa
 		return repository.PostData{}, fmt.Errorf("failed to validate: %v because %w", post, err)
 	}
 
-	tx, err := ps.DB.Begin()
-	if err != nil {
-		return repository.PostData{}, fmt.Errorf("failed to begin transaction for: %v because %w", post, err)
+	status := post.Status
+	if status == "" {
+		status = repository.StatusDraft
 	}
-	defer tx.Rollback()
 
-	postData := repository.PostData{
-		Title:     post.Title,
-		ShortDesc: post.ShortDesc,
-		Content:   post.Content,
-		CreatedAt: time.Now(),
+	if status == repository.StatusPublished && post.PublishAt != nil {
+		return repository.PostData{}, ErrPostAlreadyPublished
 	}
 
-	createdPost, err := ps.Repository.Create(ctx, tx, postData)
-	if err != nil {
-		return repository.PostData{}, err
+	if post.PublishAt != nil && !post.PublishAt.After(time.Now()) {
+		return repository.PostData{}, ErrPublishAtPast
 	}
 
-	if err := tx.Commit(); err != nil {
-		return repository.PostData{}, fmt.Errorf("failed to commit transaction: %v because %w", createdPost, err)
-	}
+	var createdPost repository.PostData
+	err = ps.Transactor.Run(ctx, func(ctx context.Context, tx repository.Tx) error {
+		slug, err := ps.Repository.UniqueSlug(ctx, tx, Slugify(post.Title), 0)
+		if err != nil {
+			return err
+		}
 
-	if err = ps.Es.Insert(ctx, createdPost); err != nil {
-		return repository.PostData{}, fmt.Errorf("failed to insert data: %v to elasticsearch because %w", createdPost, err)
-	}
+		postData := repository.PostData{
+			Title:     post.Title,
+			Slug:      slug,
+			ShortDesc: post.ShortDesc,
+			Content:   post.Content,
+			CreatedAt: time.Now(),
+			Status:    status,
+			PublishAt: post.PublishAt,
+			Author:    repository.Author{ID: post.AuthorID},
+		}
 
-	ttl := time.Duration(3600) * time.Second
-	strID := strconv.Itoa(int(createdPost.ID))
+		createdPost, err = ps.Repository.Create(ctx, tx, postData)
+		if err != nil {
+			return err
+		}
 
-	str := strings.Builder{}
-	str.WriteString("post")
-	str.WriteString(strID)
+		createdPost.Tags = taxonomy.NormalizeNames(post.Tags)
+		createdPost.Categories = uniqueNames(post.Categories)
 
-	op1 := ps.Cache.Set(context.Background(), str.String(), createdPost, ttl)
-	if err := op1.Err(); err != nil {
-		return createdPost, fmt.Errorf("failed to cache post: %v because %w", createdPost, err)
+		if err := ps.Repository.SetTags(ctx, tx, createdPost.ID, createdPost.Tags); err != nil {
+			return err
+		}
+
+		if err := ps.Repository.SetCategories(ctx, tx, createdPost.ID, createdPost.Categories); err != nil {
+			return err
+		}
+
+		if _, err := ps.Repository.CreateRevision(ctx, tx, snapshot(createdPost, post.AuthorID)); err != nil {
+			return err
+		}
+
+		// draft stay out of the index and cache until it's published
+		if createdPost.Status == repository.StatusPublished {
+			return Sync(ctx, ps.Outbox, tx, createdPost.ID, createdPost.Slug)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return repository.PostData{}, err
 	}
 
 	return createdPost, nil
 }
 
-func (ps *service) Update(ctx context.Context, post repository.PostData) error {
+func (ps *service) Update(ctx context.Context, actor Actor, post repository.PostData) error {
+	_, err := ps.update(ctx, actor, post)
+	return err
+}
+
+// update store the post with a new revision and refresh the indexed and cached copy of a published post
+func (ps *service) update(ctx context.Context, actor Actor, post repository.PostData) (repository.PostData, error) {
 	err := ps.Validate.Struct(post)
 	if err != nil {
-		return fmt.Errorf("failed to validate: %v because %w", post, err)
+		return post, fmt.Errorf("failed to validate: %v because %w", post, err)
 	}
 
-	tx, err := ps.DB.Begin()
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction for: %v because %w", post, err)
-	}
-	defer tx.Rollback()
+	err = ps.Transactor.Run(ctx, func(ctx context.Context, tx repository.Tx) error {
+		foundPost, err := ps.Repository.FindByID(ctx, tx, post.ID)
+		if err != nil {
+			return err
+		}
+
+		if !actor.CanEdit(foundPost) {
+			return ErrNotPostAuthor
+		}
+
+		// ownership, status, schedule and creation time can't be changed through an update
+		post.Author = foundPost.Author
+		post.CommentCount = foundPost.CommentCount
+		post.CreatedAt = foundPost.CreatedAt
+		post.Status = foundPost.Status
+		post.PublishAt = foundPost.PublishAt
+		post.Slug = foundPost.Slug
+
+		if post.Title != foundPost.Title {
+			post.Slug, err = ps.Repository.UniqueSlug(ctx, tx, Slugify(post.Title), post.ID)
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := ps.Repository.Update(ctx, tx, post); err != nil {
+			return err
+		}
+
+		// tags and categories are replaced like the rest of the post
+		post.Tags = taxonomy.NormalizeNames(post.Tags)
+		post.Categories = uniqueNames(post.Categories)
+
+		if err := ps.Repository.SetTags(ctx, tx, post.ID, post.Tags); err != nil {
+			return err
+		}
+
+		if err := ps.Repository.SetCategories(ctx, tx, post.ID, post.Categories); err != nil {
+			return err
+		}
+
+		if _, err := ps.Repository.CreateRevision(ctx, tx, snapshot(post, actor.ID)); err != nil {
+			return err
+		}
+
+		if post.Slug != foundPost.Slug {
+			if err := ps.Repository.ChangeSlug(ctx, tx, post.ID, foundPost.Slug, post.Slug); err != nil {
+				return err
+			}
+		}
+
+		if post.Status == repository.StatusPublished {
+			return Sync(ctx, ps.Outbox, tx, post.ID, foundPost.Slug)
+		}
+
+		return nil
+	})
+
+	return post, err
+}
 
-	foundPost, err := ps.Repository.FindByID(ctx, tx, post.ID)
+// Delete move the post to the trash, it's taken out of the index and cache until it's undeleted
+func (ps *service) Delete(ctx context.Context, actor Actor, id int64) error {
+	return ps.Transactor.Run(ctx, func(ctx context.Context, tx repository.Tx) error {
+		foundPost, err := ps.Repository.FindByID(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		if !actor.CanDelete(foundPost) {
+			return ErrNotPostAuthor
+		}
+
+		now := time.Now()
+		foundPost.DeletedAt = &now
+
+		if err := ps.Repository.Delete(ctx, tx, foundPost); err != nil {
+			return err
+		}
+
+		if foundPost.Status == repository.StatusPublished {
+			return Sync(ctx, ps.Outbox, tx, id, foundPost.Slug)
+		}
+
+		return nil
+	})
+}
+
+// Undelete take the post out of the trash for whoever could delete it, a published post is indexed and cached again
+func (ps *service) Undelete(ctx context.Context, actor Actor, id int64) (repository.PostData, error) {
+	var post repository.PostData
+	err := ps.Transactor.Run(ctx, func(ctx context.Context, tx repository.Tx) error {
+		deletedPost, err := ps.Repository.FindDeletedByID(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		if !actor.CanDelete(deletedPost) {
+			return ErrNotPostAuthor
+		}
+
+		if err := ps.Repository.Undelete(ctx, tx, id); err != nil {
+			return err
+		}
+
+		post, err = ps.Repository.FindByID(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		if post.Status == repository.StatusPublished {
+			return Sync(ctx, ps.Outbox, tx, id, post.Slug)
+		}
+
+		return nil
+	})
 	if err != nil {
-		return err
+		return repository.PostData{}, err
 	}
 
-	if err := ps.Repository.Update(ctx, tx, post); err != nil {
+	return post, nil
+}
+
+// FindTrash return a page of the deleted posts of the user, the latest deleted first
+func (ps *service) FindTrash(ctx context.Context, userID int64, from int, size int) ([]repository.PostData, error) {
+	var posts []repository.PostData
+	err := ps.Transactor.ReadOnly(ctx, func(ctx context.Context, tx repository.Tx) error {
+		var err error
+		posts, err = ps.Repository.FindTrash(ctx, tx, userID, from, size)
 		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transcation: %v because %w", post, err)
-	}
+	return posts, nil
+}
+
+func (ps *service) Publish(ctx context.Context, actor Actor, id int64) (repository.PostData, error) {
+	return ps.changeStatus(ctx, actor, id, repository.StatusPublished)
+}
 
-	if err = ps.Es.Update(ctx, post); err != nil {
-		return fmt.Errorf("failed to update data: %v from elasticsearch because %w", post, err)
+func (ps *service) Unpublish(ctx context.Context, actor Actor, id int64) (repository.PostData, error) {
+	return ps.changeStatus(ctx, actor, id, repository.StatusDraft)
+}
+
+func (ps *service) Archive(ctx context.Context, actor Actor, id int64) (repository.PostData, error) {
+	return ps.changeStatus(ctx, actor, id, repository.StatusArchived)
+}
+
+// Schedule set when a draft will be published by the scheduler, nil publishAt cancel the schedule
+func (ps *service) Schedule(ctx context.Context, actor Actor, id int64, publishAt *time.Time) (repository.PostData, error) {
+	if publishAt != nil && !publishAt.After(time.Now()) {
+		return repository.PostData{}, ErrPublishAtPast
 	}
 
-	ttl := time.Duration(3600) * time.Second
-	strID := strconv.Itoa(int(foundPost.ID))
+	var foundPost repository.PostData
+	err := ps.Transactor.Run(ctx, func(ctx context.Context, tx repository.Tx) error {
+		var err error
+		foundPost, err = ps.Repository.FindByID(ctx, tx, id)
+		if err != nil {
+			return err
+		}
 
-	str := strings.Builder{}
-	str.WriteString("post")
-	str.WriteString(strID)
+		if !actor.CanEdit(foundPost) {
+			return ErrNotPostAuthor
+		}
 
-	op1 := ps.Cache.Set(context.Background(), str.String(), post, ttl)
-	if err := op1.Err(); err != nil {
-		return fmt.Errorf("failed to cache post: %v because %w", post, err)
+		if foundPost.Status == repository.StatusPublished {
+			return ErrPostAlreadyPublished
+		}
+
+		if err := ps.Repository.UpdatePublishAt(ctx, tx, id, publishAt); err != nil {
+			return err
+		}
+		foundPost.PublishAt = publishAt
+
+		return nil
+	})
+	if err != nil {
+		return repository.PostData{}, err
 	}
 
-	return nil
+	return foundPost, nil
 }
 
-func (ps *service) Delete(ctx context.Context, id int64) error {
-	tx, err := ps.DB.Begin()
+// changeStatus move the post to the given status, the index and cache are synced
+// when the post is published or stop being published
+func (ps *service) changeStatus(ctx context.Context, actor Actor, id int64, status string) (repository.PostData, error) {
+	var foundPost repository.PostData
+	err := ps.Transactor.Run(ctx, func(ctx context.Context, tx repository.Tx) error {
+		var err error
+		foundPost, err = ps.Repository.FindByID(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		if !actor.CanEdit(foundPost) {
+			return ErrNotPostAuthor
+		}
+
+		previous := foundPost.Status
+		if previous == status {
+			return nil
+		}
+
+		if err := ps.Repository.UpdateStatus(ctx, tx, id, status); err != nil {
+			return err
+		}
+		foundPost.Status = status
+		foundPost.PublishAt = nil
+
+		if previous == repository.StatusPublished || status == repository.StatusPublished {
+			return Sync(ctx, ps.Outbox, tx, id, foundPost.Slug)
+		}
+
+		return nil
+	})
 	if err != nil {
-		return ErrFailedToBeginTransaction
+		return repository.PostData{}, err
 	}
-	defer tx.Rollback()
 
-	foundPost, err := ps.Repository.FindByID(ctx, tx, id)
-	if err != nil {
+	return foundPost, nil
+}
+
+// FindRevisions return every revision of the post to whoever can edit it, the latest first
+func (ps *service) FindRevisions(ctx context.Context, actor Actor, id int64) ([]repository.Revision, error) {
+	var revisions []repository.Revision
+	err := ps.Transactor.ReadOnly(ctx, func(ctx context.Context, tx repository.Tx) error {
+		if err := ps.checkAuthor(ctx, tx, actor, id); err != nil {
+			return err
+		}
+
+		var err error
+		revisions, err = ps.Repository.FindRevisions(ctx, tx, id)
 		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	if err := ps.Repository.Delete(ctx, tx, foundPost); err != nil {
+	return revisions, nil
+}
+
+// DiffRevisions compare two revisions of the post, mode is either DiffModeUnified or DiffModeWord
+func (ps *service) DiffRevisions(ctx context.Context, actor Actor, id int64, from int64, to int64, mode string) (RevisionDiff, error) {
+	var fromRevision, toRevision repository.Revision
+	err := ps.Transactor.ReadOnly(ctx, func(ctx context.Context, tx repository.Tx) error {
+		if err := ps.checkAuthor(ctx, tx, actor, id); err != nil {
+			return err
+		}
+
+		var err error
+		fromRevision, err = ps.Repository.FindRevision(ctx, tx, id, from)
+		if err != nil {
+			return err
+		}
+
+		toRevision, err = ps.Repository.FindRevision(ctx, tx, id, to)
 		return err
+	})
+	if err != nil {
+		return RevisionDiff{}, err
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %d because %w", id, err)
-	}
+	return diffRevisions(fromRevision, toRevision, mode)
+}
 
-	strID := strconv.Itoa(int(id))
+// Restore update the post back to the revision, the restore is stored as a new revision
+// so it can be undone like any other update. The update run in the transaction the revision is read in
+func (ps *service) Restore(ctx context.Context, actor Actor, id int64, revisionID int64) (repository.PostData, error) {
+	var post repository.PostData
+	err := ps.Transactor.Run(ctx, func(ctx context.Context, tx repository.Tx) error {
+		if err := ps.checkAuthor(ctx, tx, actor, id); err != nil {
+			return err
+		}
+
+		revision, err := ps.Repository.FindRevision(ctx, tx, id, revisionID)
+		if err != nil {
+			return err
+		}
 
-	if err = ps.Es.Delete(ctx, strID); err != nil {
-		return fmt.Errorf("failed to delete data: %d from elasticsearch because %w", id, err)
+		post, err = ps.update(ctx, actor, repository.PostData{
+			ID:         id,
+			Title:      revision.Title,
+			ShortDesc:  revision.ShortDesc,
+			Content:    revision.Content,
+			Tags:       revision.Tags,
+			Categories: revision.Categories,
+		})
+		return err
+	})
+	if err != nil {
+		return repository.PostData{}, err
 	}
 
-	str := strings.Builder{}
-	str.WriteString("post")
-	str.WriteString(strID)
+	return post, nil
+}
 
-	op1 := ps.Cache.Del(context.Background(), str.String())
-	if err := op1.Err(); err != nil {
+// checkAuthor return ErrNotPostAuthor when the actor can't edit the post
+func (ps *service) checkAuthor(ctx context.Context, tx repository.Tx, actor Actor, id int64) error {
+	post, err := ps.Repository.FindByID(ctx, tx, id)
+	if err != nil {
 		return err
 	}
 
+	if !actor.CanEdit(post) {
+		return ErrNotPostAuthor
+	}
+
 	return nil
 }
-func (ps *service) FindByID(ctx context.Context, id int64) (repository.PostData, error) {
-	strID := strconv.Itoa(int(id))
 
-	str := strings.Builder{}
-	str.WriteString("post")
-	str.WriteString(strID)
+// snapshot is the revision of the post as it's stored by the editor
+func snapshot(post repository.PostData, editorID int64) repository.Revision {
+	return repository.Revision{
+		PostID:     post.ID,
+		EditorID:   &editorID,
+		CreatedAt:  time.Now(),
+		Title:      post.Title,
+		ShortDesc:  post.ShortDesc,
+		Content:    post.Content,
+		Tags:       post.Tags,
+		Categories: post.Categories,
+	}
+}
 
-	val, err := ps.Cache.Get(context.Background(), str.String()).Result()
+// FindByID only return draft and archived post to its author, viewerID is 0 for anonymous user
+func (ps *service) FindByID(ctx context.Context, viewerID int64, id int64) (repository.PostData, error) {
+	val, err := ps.Cache.Get(context.Background(), caching.PostKey(id)).Result()
 	if err == nil {
 		var post repository.PostData
 		json.Unmarshal([]byte(val), &post)
 		return post, nil
 	}
 
-	foundPost, err := ps.Es.FindByID(ctx, strID)
+	foundPost, err := ps.Search.FindByID(ctx, id)
 	if err == nil {
 		return foundPost, nil
 	}
 
-	tx, err := ps.DB.Begin()
-	if err != nil {
-		return repository.PostData{}, fmt.Errorf("failed to begin transaction: %d because %w", id, err)
-	}
-	defer tx.Rollback()
-
-	foundPost, err = ps.Repository.FindByID(ctx, tx, id)
+	err = ps.Transactor.ReadOnly(ctx, func(ctx context.Context, tx repository.Tx) error {
+		foundPost, err = ps.Repository.FindByID(ctx, tx, id)
+		return err
+	})
 	if err != nil {
 		return repository.PostData{}, err
 	}
 
-	if err := tx.Commit(); err != nil {
-		return repository.PostData{}, fmt.Errorf("failed to commit transaction: %d because %w", id, err)
+	if foundPost.Status != repository.StatusPublished && foundPost.Author.ID != viewerID {
+		return repository.PostData{}, ErrPostNotFound
 	}
 
 	return foundPost, nil
 }
 
-func (ps *service) FindByTitleContent(ctx context.Context, query string, from int, size int) ([]repository.PostData, error) {
-	var posts []repository.PostData
-
-	val, err := ps.Cache.Get(ctx, query).Result()
+// FindBySlug follow the same cache, searcher then repository path as FindByID,
+// an old slug return SlugMovedError with the current slug
+func (ps *service) FindBySlug(ctx context.Context, viewerID int64, slug string) (repository.PostData, error) {
+	val, err := ps.Cache.Get(context.Background(), caching.SlugKey(slug)).Result()
 	if err == nil {
-		json.Unmarshal([]byte(val), &posts)
-		return posts, nil
+		var post repository.PostData
+		json.Unmarshal([]byte(val), &post)
+		return post, nil
 	}
 
-	foundPosts, err := ps.Es.FindByTitleContent(ctx, query, from, size)
+	foundPost, err := ps.Search.FindBySlug(ctx, slug)
 	if err == nil {
-		for _, doc := range foundPosts.Hits {
-			var post repository.PostData
-			post.ID = int64(doc.ID)
-			post.Title = doc.Title
-			post.Content = doc.Content
+		ps.Cache.Set(context.Background(), caching.SlugKey(slug), foundPost, postTTL)
+		return foundPost, nil
+	}
 
-			posts = append(posts, post)
+	err = ps.Transactor.ReadOnly(ctx, func(ctx context.Context, tx repository.Tx) error {
+		foundPost, err = ps.Repository.FindBySlug(ctx, tx, slug)
+		if errors.Is(err, repository.ErrPostNotFound) {
+			current, err := ps.Repository.FindSlugRedirect(ctx, tx, slug)
+			if err != nil {
+				return err
+			}
+			return &SlugMovedError{Slug: current}
 		}
+		return err
+	})
+	if err != nil {
+		return repository.PostData{}, err
+	}
 
-		ttl := time.Duration(3600) * time.Second
-		op1 := ps.Cache.Set(ctx, query, posts, ttl)
-		if err := op1.Err(); err != nil {
-			return posts, fmt.Errorf("failed to cache: %v because %w", posts, err)
+	if foundPost.Status != repository.StatusPublished {
+		if foundPost.Author.ID != viewerID {
+			return repository.PostData{}, ErrPostNotFound
 		}
+		return foundPost, nil
+	}
+
+	ps.Cache.Set(context.Background(), caching.SlugKey(slug), foundPost, postTTL)
+
+	return foundPost, nil
+}
 
-		return posts, nil
+// searchKey is the cache key of a search result page
+func searchKey(query repository.SearchQuery, filter repository.PostFilter, from int, size int) string {
+	str := strings.Builder{}
+	str.WriteString("search")
+	str.WriteString(strconv.Itoa(from))
+	str.WriteString(":")
+	str.WriteString(strconv.Itoa(size))
+	str.WriteString(":")
+	str.WriteString(filter.Tag)
+	str.WriteString(":")
+	str.WriteString(filter.Category)
+	str.WriteString(":")
+	str.WriteString(filter.Author)
+	str.WriteString(":")
+	if filter.From != nil {
+		str.WriteString(filter.From.Format(time.RFC3339))
+	}
+	str.WriteString(":")
+	if filter.To != nil {
+		str.WriteString(filter.To.Format(time.RFC3339))
+	}
+	str.WriteString(":")
+	str.WriteString(query.Mode)
+	str.WriteString(":")
+	str.WriteString(query.Text)
+
+	return str.String()
+}
+
+// FindByTitleContent search the posts, every hit carry its score and the highlighted fragments that matched
+// and the result is counted per tag, author and month. Only published post is searchable and cached,
+// so a search for another status always go to the repository
+func (ps *service) FindByTitleContent(ctx context.Context, query repository.SearchQuery, filter repository.PostFilter, from int, size int) (repository.SearchResult, error) {
+	var result repository.SearchResult
+	if !filter.PublishedOnly() {
+		return ps.findByTitleContent(ctx, query, filter, from, size)
+	}
+
+	key := searchKey(query, filter, from, size)
+
+	val, err := ps.Cache.Get(ctx, key).Result()
+	if err == nil {
+		json.Unmarshal([]byte(val), &result)
+		return result, nil
+	}
+
+	result, err = ps.Search.Search(ctx, query, filter, from, size)
+	if err != nil {
+		return repository.SearchResult{}, err
 	}
 
-	tx, err := ps.DB.Begin()
+	value, err := json.Marshal(result)
 	if err != nil {
-		return posts, fmt.Errorf("failed to begin transaction for query: %v because %w", query, err)
+		return result, fmt.Errorf("failed to marshal: %v because %w", result, err)
+	}
+
+	ttl := time.Duration(3600) * time.Second
+	op1 := ps.Cache.Set(ctx, key, value, ttl)
+	if err := op1.Err(); err != nil {
+		return result, fmt.Errorf("failed to cache: %v because %w", result, err)
 	}
-	defer tx.Rollback()
 
-	posts, err = ps.Repository.FindByTitleContent(ctx, tx, query, from, size)
+	return result, nil
+}
+
+func (ps *service) findByTitleContent(ctx context.Context, query repository.SearchQuery, filter repository.PostFilter, from int, size int) (repository.SearchResult, error) {
+	var result repository.SearchResult
+	err := ps.Transactor.ReadOnly(ctx, func(ctx context.Context, tx repository.Tx) error {
+		var err error
+		result, err = ps.Repository.FindByTitleContent(ctx, tx, query, filter, from, size)
+		return err
+	})
 	if err != nil {
-		return posts, err
+		return repository.SearchResult{}, err
+	}
+
+	return result, nil
+}
+
+func (ps *service) FindRecent(ctx context.Context, filter repository.PostFilter, from int, size int) ([]repository.PostData, error) {
+	if filter.PublishedOnly() {
+		return ps.Search.Recent(ctx, filter, from, size)
 	}
 
-	if err := tx.Commit(); err != nil {
-		return []repository.PostData{}, fmt.Errorf("failed to commit transaction for query: %v because %w", query, err)
+	var posts []repository.PostData
+	err := ps.Transactor.ReadOnly(ctx, func(ctx context.Context, tx repository.Tx) error {
+		var err error
+		posts, err = ps.Repository.FindRecent(ctx, tx, filter, from, size)
+		return err
+	})
+	if err != nil {
+		return []repository.PostData{}, err
 	}
 
 	return posts, nil
 }
 
-func (ps *service) FindRecent(ctx context.Context, from int, size int) ([]repository.PostData, error) {
-	val, err := ps.Cache.Keys(context.Background(), "post*").Result()
-	if err == nil {
-		var posts []repository.PostData
-		var post repository.PostData
-		for _, n := range val {
-			json.Unmarshal([]byte(n), &post)
-			posts = append(posts, post)
-		}
-		return posts, nil
+// Suggest return the titles that complete the prefix, the prefix is case insensitive
+// so "Go" and "go" share the cached suggestions
+func (ps *service) Suggest(ctx context.Context, prefix string, size int) ([]repository.Suggestion, error) {
+	prefix = strings.ToLower(strings.Join(strings.Fields(prefix), " "))
+	if prefix == "" {
+		return []repository.Suggestion{}, nil
 	}
 
-	tx, err := ps.DB.Begin()
+	var suggestions []repository.Suggestion
+	key := caching.SuggestKey(prefix, size)
+
+	val, err := ps.Cache.Get(ctx, key).Result()
+	if err == nil && json.Unmarshal([]byte(val), &suggestions) == nil {
+		return suggestions, nil
+	}
+
+	suggestions, err = ps.Search.Suggest(ctx, prefix, size)
 	if err != nil {
-		return []repository.PostData{}, fmt.Errorf("failed to begin transaction for finding recent post because: %w", err)
+		return nil, err
 	}
-	defer tx.Rollback()
 
-	posts, err := ps.Repository.FindRecent(ctx, tx, from, size)
+	value, err := json.Marshal(suggestions)
 	if err != nil {
-		return []repository.PostData{}, err
+		return suggestions, fmt.Errorf("failed to marshal: %v because %w", suggestions, err)
 	}
 
-	if err := tx.Commit(); err != nil {
-		return []repository.PostData{}, fmt.Errorf("failed to commit transaction for finding recent post because: %w", err)
+	// a suggestion that isn't cached is only a bit slower next time
+	ps.Cache.Set(ctx, key, value, suggestTTL)
+
+	return suggestions, nil
+}
+
+// Related return the published posts most similar to the post, MaxRelated of them is cached
+// so any size up to it share the cache
+func (ps *service) Related(ctx context.Context, id int64, size int) ([]repository.PostData, error) {
+	var posts []repository.PostData
+	key := caching.RelatedKey(id)
+
+	val, err := ps.Cache.Get(ctx, key).Result()
+	if err == nil && json.Unmarshal([]byte(val), &posts) == nil {
+		return firstPosts(posts, size), nil
 	}
 
-	return posts, nil
+	posts, err = ps.Search.Related(ctx, id, MaxRelated)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := json.Marshal(posts)
+	if err != nil {
+		return posts, fmt.Errorf("failed to marshal: %v because %w", posts, err)
+	}
+
+	ps.Cache.Set(ctx, key, value, relatedTTL)
+
+	return firstPosts(posts, size), nil
+}
+
+func firstPosts(posts []repository.PostData, size int) []repository.PostData {
+	if size < len(posts) {
+		return posts[:size]
+	}
+	return posts
 }
@@ -3,31 +3,73 @@ package posting
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/go-redis/redis/v8"
-	"github.com/izzanzahrial/blog-api-echo/pkg/elastic"
 	redisDB "github.com/izzanzahrial/blog-api-echo/pkg/redis"
 	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+	"github.com/izzanzahrial/blog-api-echo/pkg/search"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
+// noopDriver give the test a real *sql.Tx that can be committed and rolled back
+// without a database, the repository itself is mocked
+type noopDriver struct{}
+
+func (noopDriver) Open(name string) (driver.Conn, error) { return noopConn{}, nil }
+
+type noopConn struct{}
+
+func (noopConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("noop driver doesn't run query")
+}
+func (noopConn) Close() error              { return nil }
+func (noopConn) Begin() (driver.Tx, error) { return noopTx{}, nil }
+
+type noopTx struct{}
+
+func (noopTx) Commit() error   { return nil }
+func (noopTx) Rollback() error { return nil }
+
+func init() {
+	sql.Register("noop", noopDriver{})
+}
+
+func newTx(t *testing.T) *sql.Tx {
+	db, err := sql.Open("noop", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tx
+}
+
 func TestServiceCreate(t *testing.T) {
 	mockRepo := new(repository.MockPostingPostgre)
-	mockDB := new(MockDBtx)
+	mockOutbox := new(repository.MockOutboxPostgre)
+	mockDB := new(repository.MockDB)
 	mockRedis := new(redisDB.MockRedis)
-	mockElastic := new(elastic.MockElastic)
+	mockSearch := new(search.MockSearcher)
 	validator := validator.New()
 
-	service := NewService(mockRepo, mockDB, validator, mockRedis, mockElastic)
+	service := NewService(mockRepo, mockOutbox, repository.NewTransactor(mockDB), validator, mockRedis, mockSearch)
 
 	subtests := []struct {
 		status       bool
 		name         string
 		ctx          context.Context
 		post         PostData
-		expectedData PostData
+		expectedData repository.PostData
 		expectedErr  error
 	}{
 		{
@@ -38,11 +80,19 @@ func TestServiceCreate(t *testing.T) {
 				Title:     "Test title",
 				ShortDesc: "Test description",
 				Content:   "Test content",
+				AuthorID:  1,
+				Status:    repository.StatusPublished,
+				Tags:      []string{" Go ", "go", "Echo"},
 			},
-			expectedData: PostData{
-				Title:     "Test title",
-				ShortDesc: "Test description",
-				Content:   "Test content",
+			expectedData: repository.PostData{
+				ID:         1,
+				Title:      "Test title",
+				ShortDesc:  "Test description",
+				Content:    "Test content",
+				Status:     repository.StatusPublished,
+				Author:     repository.Author{ID: 1},
+				Tags:       []string{"go", "echo"},
+				Categories: []string{},
 			},
 			expectedErr: nil,
 		},
@@ -54,40 +104,476 @@ func TestServiceCreate(t *testing.T) {
 				Title:     "Test title Error",
 				ShortDesc: "Test description Error",
 				Content:   "Test content Error",
+				AuthorID:  1,
 			},
-			expectedData: PostData{},
+			expectedData: repository.PostData{},
 			expectedErr:  repository.ErrFailedToCreatePost,
 		},
 	}
 
 	for _, test := range subtests {
 		t.Run(test.name, func(t *testing.T) {
-			mockDB.On("Begin").Return(&sql.Tx{}, nil).Once()
+			tx := newTx(t)
+			mockDB.On("BeginTx", mock.Anything, &sql.TxOptions{Isolation: repository.TxIsolation}).Return(tx, nil).Once()
+			mockRepo.On("UniqueSlug", mock.Anything, tx, Slugify(test.post.Title), int64(0)).Return(Slugify(test.post.Title), nil).Once()
+
+			// created time is set by the service so only match the rest of the post
+			matchPost := mock.MatchedBy(func(pd repository.PostData) bool {
+				return pd.Title == test.post.Title && pd.Slug == Slugify(test.post.Title) && pd.Author.ID == test.post.AuthorID && !pd.CreatedAt.IsZero()
+			})
+
 			switch test.status {
 			case true:
-				mockRepo.On("Create", test.ctx, &sql.Tx{}, test.post).Return(test.post, nil).Once()
+				mockRepo.On("Create", mock.Anything, tx, matchPost).Return(test.expectedData, nil).Once()
+				mockRepo.On("SetTags", mock.Anything, tx, int64(1), []string{"go", "echo"}).Return(nil).Once()
+				mockRepo.On("SetCategories", mock.Anything, tx, int64(1), []string{}).Return(nil).Once()
+				mockRepo.On("CreateRevision", mock.Anything, tx, mock.AnythingOfType("repository.Revision")).Return(repository.Revision{ID: 1}, nil).Once()
+				mockOutbox.On("Add", mock.Anything, tx, mock.MatchedBy(func(e repository.OutboxEvent) bool {
+					return e.PostID == 1 && !e.NextAttemptAt.IsZero()
+				})).Return(nil).Once()
 			case false:
-				mockRepo.On("Create", test.ctx, &sql.Tx{}, test.post).Return(
+				mockRepo.On("Create", mock.Anything, tx, matchPost).Return(
 					repository.PostData{}, repository.ErrFailedToCreatePost).Once()
 			}
 
-			// return repo still no created time
-			// mock for rollback and commit
-
-			if test.status == true {
-				mockRedis.On("Set").Return(&redis.StatusCmd{}).Once()
-			}
-
 			data, err := service.Create(test.ctx, test.post)
 			assert.Equal(t, test.expectedData, data)
 			assert.Equal(t, test.expectedErr, err)
 
 			mockDB.AssertExpectations(t)
 			mockRepo.AssertExpectations(t)
+			mockOutbox.AssertExpectations(t)
+			// the index and cache are left to the relay
+			mockSearch.AssertNotCalled(t, "Insert", mock.Anything, mock.Anything)
+			mockRedis.AssertNotCalled(t, "Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestServiceCreateDraftIsNotIndexed(t *testing.T) {
+	mockRepo := new(repository.MockPostingPostgre)
+	mockOutbox := new(repository.MockOutboxPostgre)
+	mockDB := new(repository.MockDB)
+
+	service := NewService(mockRepo, mockOutbox, repository.NewTransactor(mockDB), validator.New(), new(redisDB.MockRedis), new(search.MockSearcher))
+
+	tx := newTx(t)
+	ctx := context.Background()
+	draft := repository.PostData{ID: 1, Title: "Test title", Status: repository.StatusDraft, Author: repository.Author{ID: 1}}
+
+	mockDB.On("BeginTx", mock.Anything, &sql.TxOptions{Isolation: repository.TxIsolation}).Return(tx, nil).Once()
+	mockRepo.On("UniqueSlug", mock.Anything, tx, "test-title", int64(0)).Return("test-title-2", nil).Once()
+	mockRepo.On("Create", mock.Anything, tx, mock.AnythingOfType("repository.PostData")).Return(draft, nil).Once()
+	mockRepo.On("SetTags", mock.Anything, tx, int64(1), []string{}).Return(nil).Once()
+	mockRepo.On("SetCategories", mock.Anything, tx, int64(1), []string{}).Return(nil).Once()
+	mockRepo.On("CreateRevision", mock.Anything, tx, mock.AnythingOfType("repository.Revision")).Return(repository.Revision{ID: 1}, nil).Once()
+
+	data, err := service.Create(ctx, PostData{Title: "Test title", AuthorID: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, draft.ID, data.ID)
+	assert.Equal(t, repository.StatusDraft, data.Status)
+
+	mockOutbox.AssertNotCalled(t, "Add", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestServiceRestoreRunInOneTransaction(t *testing.T) {
+	mockRepo := new(repository.MockPostingPostgre)
+	mockDB := new(repository.MockDB)
+
+	service := NewService(mockRepo, new(repository.MockOutboxPostgre), repository.NewTransactor(mockDB), validator.New(), new(redisDB.MockRedis), new(search.MockSearcher))
+
+	tx := newTx(t)
+	post := repository.PostData{ID: 1, Title: "Test title", Slug: "test-title", Status: repository.StatusDraft, Author: repository.Author{ID: 1}}
+	revision := repository.Revision{ID: 2, PostID: 1, Title: "Test title", Content: "old content"}
+
+	// the update of the restore run in a savepoint of the transaction the revision is read in
+	mockDB.On("BeginTx", mock.Anything, &sql.TxOptions{Isolation: repository.TxIsolation}).Return(tx, nil).Once()
+	mockDB.On("Savepoint", mock.Anything, tx, "sp_1").Return(nil).Once()
+	mockDB.On("ReleaseSavepoint", mock.Anything, tx, "sp_1").Return(nil).Once()
+	mockRepo.On("FindByID", mock.Anything, tx, int64(1)).Return(post, nil).Twice()
+	mockRepo.On("FindRevision", mock.Anything, tx, int64(1), int64(2)).Return(revision, nil).Once()
+	mockRepo.On("Update", mock.Anything, tx, mock.MatchedBy(func(pd repository.PostData) bool {
+		return pd.Content == "old content"
+	})).Return(nil).Once()
+	mockRepo.On("SetTags", mock.Anything, tx, int64(1), []string{}).Return(nil).Once()
+	mockRepo.On("SetCategories", mock.Anything, tx, int64(1), []string{}).Return(nil).Once()
+	mockRepo.On("CreateRevision", mock.Anything, tx, mock.AnythingOfType("repository.Revision")).Return(repository.Revision{ID: 3}, nil).Once()
+
+	restored, err := service.Restore(context.Background(), Actor{ID: 1}, 1, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, "old content", restored.Content)
+
+	mockDB.AssertExpectations(t)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestServiceUpdateOtherAuthor(t *testing.T) {
+	subtest := []struct {
+		name     string
+		actor    Actor
+		expected error
+	}{
+		{
+			name:     "Author",
+			actor:    Actor{ID: 2},
+			expected: ErrNotPostAuthor,
+		},
+		{
+			name:  "Editor",
+			actor: Actor{ID: 2, EditAny: true},
+		},
+	}
+
+	for _, test := range subtest {
+		t.Run(test.name, func(t *testing.T) {
+			mockRepo := new(repository.MockPostingPostgre)
+			mockDB := new(repository.MockDB)
+
+			service := NewService(mockRepo, new(repository.MockOutboxPostgre), repository.NewTransactor(mockDB), validator.New(), new(redisDB.MockRedis), new(search.MockSearcher))
+
+			tx := newTx(t)
+			post := repository.PostData{ID: 1, Title: "Test title", Slug: "test-title", Status: repository.StatusDraft, Author: repository.Author{ID: 1}}
+
+			mockDB.On("BeginTx", mock.Anything, &sql.TxOptions{Isolation: repository.TxIsolation}).Return(tx, nil).Once()
+			mockRepo.On("FindByID", mock.Anything, tx, int64(1)).Return(post, nil).Once()
+			if test.expected == nil {
+				mockRepo.On("Update", mock.Anything, tx, mock.MatchedBy(func(pd repository.PostData) bool {
+					return pd.Author.ID == 1 && pd.Content == "edited"
+				})).Return(nil).Once()
+				mockRepo.On("SetTags", mock.Anything, tx, int64(1), []string{}).Return(nil).Once()
+				mockRepo.On("SetCategories", mock.Anything, tx, int64(1), []string{}).Return(nil).Once()
+				// the editor is recorded in the revision while the post keep its author
+				mockRepo.On("CreateRevision", mock.Anything, tx, mock.MatchedBy(func(r repository.Revision) bool {
+					return r.EditorID != nil && *r.EditorID == 2
+				})).Return(repository.Revision{ID: 2}, nil).Once()
+			}
+
+			err := service.Update(context.Background(), test.actor, repository.PostData{ID: 1, Title: "Test title", Content: "edited"})
+			if test.expected != nil {
+				assert.ErrorIs(t, err, test.expected)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestServicePublishOtherAuthor(t *testing.T) {
+	subtest := []struct {
+		name     string
+		actor    Actor
+		expected error
+	}{
+		{
+			name:     "Author",
+			actor:    Actor{ID: 2},
+			expected: ErrNotPostAuthor,
+		},
+		{
+			name:  "Editor",
+			actor: Actor{ID: 2, EditAny: true},
+		},
+	}
+
+	for _, test := range subtest {
+		t.Run(test.name, func(t *testing.T) {
+			mockRepo := new(repository.MockPostingPostgre)
+			mockOutbox := new(repository.MockOutboxPostgre)
+			mockDB := new(repository.MockDB)
+
+			service := NewService(mockRepo, mockOutbox, repository.NewTransactor(mockDB), validator.New(), new(redisDB.MockRedis), new(search.MockSearcher))
+
+			tx := newTx(t)
+			post := repository.PostData{ID: 1, Slug: "test-title", Status: repository.StatusDraft, Author: repository.Author{ID: 1}}
+
+			mockDB.On("BeginTx", mock.Anything, &sql.TxOptions{Isolation: repository.TxIsolation}).Return(tx, nil).Once()
+			mockRepo.On("FindByID", mock.Anything, tx, int64(1)).Return(post, nil).Once()
+			if test.expected == nil {
+				mockRepo.On("UpdateStatus", mock.Anything, tx, int64(1), repository.StatusPublished).Return(nil).Once()
+				mockOutbox.On("Add", mock.Anything, tx, mock.Anything).Return(nil).Once()
+			}
+
+			published, err := service.Publish(context.Background(), test.actor, 1)
+			if test.expected != nil {
+				assert.ErrorIs(t, err, test.expected)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, repository.StatusPublished, published.Status)
+			}
+
+			mockRepo.AssertExpectations(t)
+			mockOutbox.AssertExpectations(t)
+		})
+	}
+}
+
+func TestServicePublishAtPast(t *testing.T) {
+	mockRepo := new(repository.MockPostingPostgre)
+	mockDB := new(repository.MockDB)
+	service := NewService(mockRepo, new(repository.MockOutboxPostgre), repository.NewTransactor(mockDB), validator.New(), new(redisDB.MockRedis), new(search.MockSearcher))
+
+	// nothing is written, the transaction isn't even begun
+	past := time.Now().Add(-time.Minute)
+	_, err := service.Create(context.Background(), PostData{Title: "Test title", AuthorID: 1, PublishAt: &past})
+	assert.ErrorIs(t, err, ErrPublishAtPast)
+
+	_, err = service.Schedule(context.Background(), Actor{ID: 1}, 1, &past)
+	assert.ErrorIs(t, err, ErrPublishAtPast)
 
-			if test.status == true {
-				mockRedis.AssertExpectations(t)
+	mockDB.AssertNotCalled(t, "BeginTx", mock.Anything, mock.Anything)
+}
+
+func TestServiceUndeleteOtherAuthor(t *testing.T) {
+	subtest := []struct {
+		name     string
+		actor    Actor
+		expected error
+	}{
+		{
+			name:     "Editor",
+			actor:    Actor{ID: 2, EditAny: true},
+			expected: ErrNotPostAuthor,
+		},
+		{
+			name:  "Admin",
+			actor: Actor{ID: 2, DeleteAny: true},
+		},
+	}
+
+	for _, test := range subtest {
+		t.Run(test.name, func(t *testing.T) {
+			mockRepo := new(repository.MockPostingPostgre)
+			mockDB := new(repository.MockDB)
+
+			service := NewService(mockRepo, new(repository.MockOutboxPostgre), repository.NewTransactor(mockDB), validator.New(), new(redisDB.MockRedis), new(search.MockSearcher))
+
+			tx := newTx(t)
+			post := repository.PostData{ID: 1, Slug: "test-title", Status: repository.StatusDraft, Author: repository.Author{ID: 1}}
+
+			mockDB.On("BeginTx", mock.Anything, &sql.TxOptions{Isolation: repository.TxIsolation}).Return(tx, nil).Once()
+			mockRepo.On("FindDeletedByID", mock.Anything, tx, int64(1)).Return(post, nil).Once()
+			if test.expected == nil {
+				mockRepo.On("Undelete", mock.Anything, tx, int64(1)).Return(nil).Once()
+				mockRepo.On("FindByID", mock.Anything, tx, int64(1)).Return(post, nil).Once()
+			}
+
+			_, err := service.Undelete(context.Background(), test.actor, 1)
+			if test.expected != nil {
+				assert.ErrorIs(t, err, test.expected)
+			} else {
+				assert.NoError(t, err)
 			}
+
+			mockRepo.AssertExpectations(t)
 		})
 	}
 }
+
+func TestServiceFindBySlugMoved(t *testing.T) {
+	mockRepo := new(repository.MockPostingPostgre)
+	mockDB := new(repository.MockDB)
+	mockRedis := new(redisDB.MockRedis)
+	mockSearch := new(search.MockSearcher)
+
+	service := NewService(mockRepo, new(repository.MockOutboxPostgre), repository.NewTransactor(mockDB), validator.New(), mockRedis, mockSearch)
+
+	tx := newTx(t)
+	ctx := context.Background()
+
+	mockRedis.On("Get", mock.Anything, "slugold-title").Return(redis.NewStringResult("", redis.Nil)).Once()
+	mockSearch.On("FindBySlug", ctx, "old-title").Return(repository.PostData{}, repository.ErrPostNotFound).Once()
+	mockDB.On("BeginTx", mock.Anything, &sql.TxOptions{Isolation: repository.TxIsolation, ReadOnly: true}).Return(tx, nil).Once()
+	mockRepo.On("FindBySlug", mock.Anything, tx, "old-title").Return(repository.PostData{}, repository.ErrPostNotFound).Once()
+	mockRepo.On("FindSlugRedirect", mock.Anything, tx, "old-title").Return("new-title", nil).Once()
+
+	_, err := service.FindBySlug(ctx, 0, "old-title")
+
+	var moved *SlugMovedError
+	assert.ErrorAs(t, err, &moved)
+	assert.Equal(t, "new-title", moved.Slug)
+
+	mockRepo.AssertExpectations(t)
+	mockSearch.AssertExpectations(t)
+}
+
+func TestServiceSuggest(t *testing.T) {
+	mockRedis := new(redisDB.MockRedis)
+	mockSearch := new(search.MockSearcher)
+
+	service := NewService(new(repository.MockPostingPostgre), new(repository.MockOutboxPostgre), repository.NewTransactor(new(repository.MockDB)), validator.New(), mockRedis, mockSearch)
+
+	ctx := context.Background()
+	suggestions := []repository.Suggestion{{ID: 1, Title: "Go Concurrency", Slug: "go-concurrency"}}
+
+	// the prefix is normalized before it's looked up
+	mockRedis.On("Get", ctx, "suggest5:go con").Return(redis.NewStringResult("", redis.Nil)).Once()
+	mockSearch.On("Suggest", ctx, "go con", 5).Return(suggestions, nil).Once()
+	mockRedis.On("Set", ctx, "suggest5:go con", mock.Anything, suggestTTL).Return(&redis.StatusCmd{}).Once()
+
+	result, err := service.Suggest(ctx, "  Go   Con", 5)
+	assert.NoError(t, err)
+	assert.Equal(t, suggestions, result)
+
+	mockSearch.AssertExpectations(t)
+	mockRedis.AssertExpectations(t)
+}
+
+func TestServiceSuggestCached(t *testing.T) {
+	mockRedis := new(redisDB.MockRedis)
+	mockSearch := new(search.MockSearcher)
+
+	service := NewService(new(repository.MockPostingPostgre), new(repository.MockOutboxPostgre), repository.NewTransactor(new(repository.MockDB)), validator.New(), mockRedis, mockSearch)
+
+	ctx := context.Background()
+	cached := `[{"id":1,"title":"Go Concurrency","slug":"go-concurrency"}]`
+
+	mockRedis.On("Get", ctx, "suggest5:go").Return(redis.NewStringResult(cached, nil)).Once()
+
+	result, err := service.Suggest(ctx, "go", 5)
+	assert.NoError(t, err)
+	assert.Equal(t, []repository.Suggestion{{ID: 1, Title: "Go Concurrency", Slug: "go-concurrency"}}, result)
+
+	mockSearch.AssertNotCalled(t, "Suggest", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestServiceFindByTitleContentHighlights(t *testing.T) {
+	mockRedis := new(redisDB.MockRedis)
+	mockSearch := new(search.MockSearcher)
+
+	service := NewService(new(repository.MockPostingPostgre), new(repository.MockOutboxPostgre), repository.NewTransactor(new(repository.MockDB)), validator.New(), mockRedis, mockSearch)
+
+	ctx := context.Background()
+	query, _ := repository.ParseQuery("echo", "")
+	filter := repository.PostFilter{}
+	key := searchKey(query, filter, 0, 10)
+	highlights := map[string][]string{"content": {"routing with <mark>echo</mark>"}}
+	facets := &repository.Facets{
+		Tags:    []repository.FacetBucket{{Key: "go", Count: 30}, {Key: "web", Count: 12}},
+		Authors: []repository.FacetBucket{{Key: "izzan", Count: 42}},
+		Months:  []repository.FacetBucket{{Key: "2022-04", Count: 40}, {Key: "2022-05", Count: 2}},
+	}
+
+	mockRedis.On("Get", ctx, key).Return(redis.NewStringResult("", redis.Nil)).Once()
+	mockSearch.On("Search", ctx, query, filter, 0, 10).Return(repository.SearchResult{
+		Total: 42,
+		Hits: []repository.SearchHit{{
+			PostData:   repository.PostData{ID: 1, Title: "Routing", ShortDesc: "Routing in go"},
+			Score:      1.5,
+			Highlights: highlights,
+		}},
+		Facets: facets,
+	}, nil).Once()
+	mockRedis.On("Set", ctx, key, mock.Anything, mock.Anything).Return(&redis.StatusCmd{}).Once()
+
+	result, err := service.FindByTitleContent(ctx, query, filter, 0, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), result.Total)
+	assert.Equal(t, []repository.SearchHit{{
+		PostData:   repository.PostData{ID: 1, Title: "Routing", ShortDesc: "Routing in go"},
+		Score:      1.5,
+		Highlights: highlights,
+	}}, result.Hits)
+	assert.Equal(t, facets, result.Facets)
+
+	mockSearch.AssertExpectations(t)
+	mockRedis.AssertExpectations(t)
+}
+
+func TestServiceFindByTitleContentDraft(t *testing.T) {
+	mockRepo := new(repository.MockPostingPostgre)
+	mockDB := new(repository.MockDB)
+
+	// draft isn't indexed nor cached, any call to them would fail the test
+	service := NewService(mockRepo, new(repository.MockOutboxPostgre), repository.NewTransactor(mockDB), validator.New(), new(redisDB.MockRedis), new(search.MockSearcher))
+
+	ctx := context.Background()
+	tx := newTx(t)
+	query, _ := repository.ParseQuery("echo", "")
+	filter := repository.PostFilter{Author: "izzan", Status: repository.StatusDraft}
+	expected := repository.SearchResult{
+		Total: 1,
+		Hits:  []repository.SearchHit{{PostData: repository.PostData{ID: 3, Status: repository.StatusDraft}}},
+		Facets: &repository.Facets{
+			Tags:    []repository.FacetBucket{},
+			Authors: []repository.FacetBucket{{Key: "izzan", Count: 1}},
+			Months:  []repository.FacetBucket{{Key: "2022-05", Count: 1}},
+		},
+	}
+
+	mockDB.On("BeginTx", mock.Anything, &sql.TxOptions{Isolation: repository.TxIsolation, ReadOnly: true}).Return(tx, nil).Once()
+	mockRepo.On("FindByTitleContent", mock.Anything, tx, query, filter, 0, 10).Return(expected, nil).Once()
+
+	result, err := service.FindByTitleContent(ctx, query, filter, 0, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, result)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestServiceFindByTitleContentDidYouMean(t *testing.T) {
+	mockRedis := new(redisDB.MockRedis)
+	mockSearch := new(search.MockSearcher)
+
+	service := NewService(new(repository.MockPostingPostgre), new(repository.MockOutboxPostgre), repository.NewTransactor(new(repository.MockDB)), validator.New(), mockRedis, mockSearch)
+
+	ctx := context.Background()
+	query, _ := repository.ParseQuery("gorutine", repository.QueryModeExact)
+	filter := repository.PostFilter{}
+	key := searchKey(query, filter, 0, 10)
+
+	mockRedis.On("Get", ctx, key).Return(redis.NewStringResult("", redis.Nil)).Once()
+	mockSearch.On("Search", ctx, query, filter, 0, 10).Return(repository.SearchResult{
+		Hits:       []repository.SearchHit{},
+		DidYouMean: "goroutine",
+	}, nil).Once()
+	mockRedis.On("Set", ctx, key, mock.Anything, mock.Anything).Return(&redis.StatusCmd{}).Once()
+
+	result, err := service.FindByTitleContent(ctx, query, filter, 0, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), result.Total)
+	assert.Equal(t, "goroutine", result.DidYouMean)
+
+	mockSearch.AssertExpectations(t)
+}
+
+func TestServiceRelated(t *testing.T) {
+	mockRedis := new(redisDB.MockRedis)
+	mockSearch := new(search.MockSearcher)
+
+	service := NewService(new(repository.MockPostingPostgre), new(repository.MockOutboxPostgre), repository.NewTransactor(new(repository.MockDB)), validator.New(), mockRedis, mockSearch)
+
+	ctx := context.Background()
+	related := []repository.PostData{{ID: 2}, {ID: 3}, {ID: 4}}
+
+	// every related post is cached even though fewer is asked for
+	mockRedis.On("Get", ctx, "related1").Return(redis.NewStringResult("", redis.Nil)).Once()
+	mockSearch.On("Related", ctx, int64(1), MaxRelated).Return(related, nil).Once()
+	mockRedis.On("Set", ctx, "related1", mock.Anything, relatedTTL).Return(&redis.StatusCmd{}).Once()
+
+	result, err := service.Related(ctx, 1, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []repository.PostData{{ID: 2}, {ID: 3}}, result)
+
+	mockSearch.AssertExpectations(t)
+	mockRedis.AssertExpectations(t)
+}
+
+func TestServiceRelatedCached(t *testing.T) {
+	mockRedis := new(redisDB.MockRedis)
+	mockSearch := new(search.MockSearcher)
+
+	service := NewService(new(repository.MockPostingPostgre), new(repository.MockOutboxPostgre), repository.NewTransactor(new(repository.MockDB)), validator.New(), mockRedis, mockSearch)
+
+	ctx := context.Background()
+	cached := `[{"id":2,"title":"Go Channels"}]`
+
+	mockRedis.On("Get", ctx, "related1").Return(redis.NewStringResult(cached, nil)).Once()
+
+	result, err := service.Related(ctx, 1, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, []repository.PostData{{ID: 2, Title: "Go Channels"}}, result)
+
+	mockSearch.AssertNotCalled(t, "Related", mock.Anything, mock.Anything, mock.Anything)
+}
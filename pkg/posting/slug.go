@@ -0,0 +1,60 @@
+package posting
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// maxSlugLength keep the url readable, the collision suffix is added after it
+const maxSlugLength = 80
+
+// transliteration for letters that don't decompose into an ascii letter and a mark
+var transliteration = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'Æ': "ae", 'œ': "oe", 'Œ': "oe",
+	'ø': "o", 'Ø': "o", 'ł': "l", 'Ł': "l", 'đ': "d", 'Đ': "d",
+	'ð': "d", 'Ð': "d", 'þ': "th", 'Þ': "th", 'ı': "i",
+}
+
+// Slugify turn the title into lowercase ascii words joined by dash,
+// e.g. "Crème Brûlée, für alle!" become "creme-brulee-fur-alle"
+func Slugify(title string) string {
+	var str strings.Builder
+	dash := false
+
+	for _, r := range norm.NFD.String(title) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+
+		if t, ok := transliteration[r]; ok {
+			str.WriteString(t)
+			dash = false
+			continue
+		}
+
+		r = unicode.ToLower(r)
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			str.WriteRune(r)
+			dash = false
+			continue
+		}
+
+		if !dash && str.Len() > 0 {
+			str.WriteRune('-')
+			dash = true
+		}
+	}
+
+	slug := strings.TrimSuffix(str.String(), "-")
+	if len(slug) > maxSlugLength {
+		slug = strings.TrimSuffix(slug[:maxSlugLength], "-")
+	}
+
+	if slug == "" {
+		return "post"
+	}
+
+	return slug
+}
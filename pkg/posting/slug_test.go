@@ -0,0 +1,34 @@
+package posting
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlugify(t *testing.T) {
+	subtests := []struct {
+		name     string
+		title    string
+		expected string
+	}{
+		{name: "Simple title", title: "Hello World", expected: "hello-world"},
+		{name: "Punctuation and spaces", title: "  Go, Echo & Postgres!  ", expected: "go-echo-postgres"},
+		{name: "Accented letters", title: "Crème Brûlée, für alle", expected: "creme-brulee-fur-alle"},
+		{name: "Special letters", title: "Straße in Łódź", expected: "strasse-in-lodz"},
+		{name: "Non latin only", title: "日本語", expected: "post"},
+	}
+
+	for _, test := range subtests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, Slugify(test.title))
+		})
+	}
+}
+
+func TestSlugifyLength(t *testing.T) {
+	slug := Slugify(strings.Repeat("word ", 40))
+	assert.LessOrEqual(t, len(slug), maxSlugLength)
+	assert.False(t, strings.HasSuffix(slug, "-"))
+}
@@ -0,0 +1,89 @@
+package caching
+
+import (
+	"strconv"
+	"strings"
+)
+
+// PostKey is the cache key of a published post
+func PostKey(id int64) string {
+	str := strings.Builder{}
+	str.WriteString("post")
+	str.WriteString(strconv.Itoa(int(id)))
+
+	return str.String()
+}
+
+// SlugKey is the cache key of a published post looked up by slug
+func SlugKey(slug string) string {
+	str := strings.Builder{}
+	str.WriteString("slug")
+	str.WriteString(slug)
+
+	return str.String()
+}
+
+// FavouriteKey is the cache key of the favourite count of a post
+func FavouriteKey(id int64) string {
+	str := strings.Builder{}
+	str.WriteString("favourites")
+	str.WriteString(strconv.Itoa(int(id)))
+
+	return str.String()
+}
+
+// SuggestKey is the cache key of the title suggestions for a prefix
+func SuggestKey(prefix string, size int) string {
+	str := strings.Builder{}
+	str.WriteString("suggest")
+	str.WriteString(strconv.Itoa(size))
+	str.WriteString(":")
+	str.WriteString(prefix)
+
+	return str.String()
+}
+
+// RelatedKey is the cache key of the posts related to a post
+func RelatedKey(id int64) string {
+	str := strings.Builder{}
+	str.WriteString("related")
+	str.WriteString(strconv.Itoa(int(id)))
+
+	return str.String()
+}
+
+// RevokedTokenKey is the cache key that deny the access token with the jti until it expire
+func RevokedTokenKey(jti string) string {
+	str := strings.Builder{}
+	str.WriteString("revokedtoken")
+	str.WriteString(jti)
+
+	return str.String()
+}
+
+// RevokedSessionKey is the cache key that deny every access token of the session until the last of them expire
+func RevokedSessionKey(sid string) string {
+	str := strings.Builder{}
+	str.WriteString("revokedsession")
+	str.WriteString(sid)
+
+	return str.String()
+}
+
+// RevokedUserKey is the cache key of when every access token of the user was revoked
+func RevokedUserKey(id int64) string {
+	str := strings.Builder{}
+	str.WriteString("revokeduser")
+	str.WriteString(strconv.Itoa(int(id)))
+
+	return str.String()
+}
+
+// VerificationResendKey is the cache key that hold back another verification mail to the user until it expire
+func VerificationResendKey(id int64) string {
+	str := strings.Builder{}
+	str.WriteString("verificationresend")
+	str.WriteString(strconv.Itoa(int(id)))
+
+	return str.String()
+}
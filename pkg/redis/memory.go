@@ -0,0 +1,168 @@
+package caching
+
+import (
+	"context"
+	"encoding"
+	"fmt"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Memory is a Cache that keep the values in memory instead of redis, it's meant for test and local development
+type Memory struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	now     func() time.Time
+}
+
+type memoryEntry struct {
+	value string
+	// expireAt is zero for a value that doesn't expire
+	expireAt time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		entries: map[string]memoryEntry{},
+		now:     time.Now,
+	}
+}
+
+// Set store the value the way the redis client write it, any other type than a string, bytes, number, bool,
+// time or binary marshaler is refused, an expiration of 0 keep the value until it's deleted
+func (m *Memory) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+	str, err := memoryValue(value)
+	if err != nil {
+		return redis.NewStatusResult("", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.put(key, str, expiration)
+
+	return redis.NewStatusResult("OK", nil)
+}
+
+// SetNX store the value like Set unless the key is already there, it return whether the value was stored
+func (m *Memory) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd {
+	str, err := memoryValue(value)
+	if err != nil {
+		return redis.NewBoolResult(false, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.entry(key); ok {
+		return redis.NewBoolResult(false, nil)
+	}
+	m.put(key, str, expiration)
+
+	return redis.NewBoolResult(true, nil)
+}
+
+// memoryValue write the value the way the redis client does
+func memoryValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case nil:
+		return "", nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, bool:
+		return fmt.Sprint(v), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case encoding.BinaryMarshaler:
+		b, err := v.MarshalBinary()
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	default:
+		return "", fmt.Errorf("redis: can't marshal %T (implement encoding.BinaryMarshaler)", v)
+	}
+}
+
+// put store the entry, mu must be held
+func (m *Memory) put(key string, value string, expiration time.Duration) {
+	entry := memoryEntry{value: value}
+	if expiration > 0 {
+		entry.expireAt = m.now().Add(expiration)
+	}
+	m.entries[key] = entry
+}
+
+// Del return how many of the keys were deleted
+func (m *Memory) Del(ctx context.Context, keys ...string) *redis.IntCmd {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var deleted int64
+	for _, key := range keys {
+		if _, ok := m.entry(key); ok {
+			delete(m.entries, key)
+			deleted++
+		}
+	}
+
+	return redis.NewIntResult(deleted, nil)
+}
+
+// Get return redis.Nil when the key is missing or expired
+func (m *Memory) Get(ctx context.Context, key string) *redis.StringCmd {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entry(key)
+	if !ok {
+		return redis.NewStringResult("", redis.Nil)
+	}
+
+	return redis.NewStringResult(entry.value, nil)
+}
+
+// Keys return the keys matching the glob pattern, sorted
+func (m *Memory) Keys(ctx context.Context, pattern string) *redis.StringSliceCmd {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := []string{}
+	for key := range m.entries {
+		if _, ok := m.entry(key); !ok {
+			continue
+		}
+
+		matched, err := path.Match(pattern, key)
+		if err != nil {
+			return redis.NewStringSliceResult(nil, err)
+		}
+		if matched {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	return redis.NewStringSliceResult(keys, nil)
+}
+
+// entry return the entry unless it's expired, an expired entry is dropped, mu must be held
+func (m *Memory) entry(key string) (memoryEntry, bool) {
+	entry, ok := m.entries[key]
+	if !ok {
+		return entry, false
+	}
+
+	if !entry.expireAt.IsZero() && !m.now().Before(entry.expireAt) {
+		delete(m.entries, key)
+		return entry, false
+	}
+
+	return entry, true
+}
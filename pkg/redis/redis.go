@@ -27,6 +27,11 @@ func (mr *MockRedis) Set(ctx context.Context, key string, value interface{}, exp
 	return args.Get(0).(*redis.StatusCmd)
 }
 
+func (mr *MockRedis) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd {
+	args := mr.Called(ctx, key, value, expiration)
+	return args.Get(0).(*redis.BoolCmd)
+}
+
 func (mr *MockRedis) Del(ctx context.Context, keys ...string) *redis.IntCmd {
 	args := mr.Called(ctx, keys)
 	return args.Get(0).(*redis.IntCmd)
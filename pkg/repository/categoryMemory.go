@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+type categoryMemory struct {
+	Store *MemoryStore
+}
+
+func NewCategoryMemory(store *MemoryStore) CategoryRepository {
+	return &categoryMemory{
+		Store: store,
+	}
+}
+
+func (m *categoryMemory) Create(ctx context.Context, tx Tx, c Category) (Category, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if err := m.check(c); err != nil {
+		return c, fmt.Errorf("failed to create category: %v because %w", c, err)
+	}
+
+	c.ID = m.Store.categories.nextID()
+	m.Store.categories.put(tx, c.ID, c)
+
+	return c, nil
+}
+
+func (m *categoryMemory) Update(ctx context.Context, tx Tx, c Category) (Category, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if _, ok := m.Store.categories.rows[c.ID]; !ok {
+		return c, fmt.Errorf("failed to update category: %v because %w", c, ErrCategoryNotFound)
+	}
+
+	if err := m.check(c); err != nil {
+		return c, fmt.Errorf("failed to update category: %v because %w", c, err)
+	}
+
+	m.Store.categories.put(tx, c.ID, c)
+
+	return c, nil
+}
+
+// Delete remove the category, its children move up to its parent
+func (m *categoryMemory) Delete(ctx context.Context, tx Tx, id int64) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	category, ok := m.Store.categories.rows[id].(Category)
+	if !ok {
+		return fmt.Errorf("failed to delete category with id: %d because %w", id, ErrCategoryNotFound)
+	}
+
+	for key, row := range m.Store.categories.rows {
+		if child := row.(Category); child.ParentID != nil && *child.ParentID == id {
+			child.ParentID = category.ParentID
+			m.Store.categories.put(tx, key, child)
+		}
+	}
+
+	m.Store.categories.remove(tx, id)
+	m.Store.unlink(tx, &m.Store.postCategories, id)
+
+	return nil
+}
+
+func (m *categoryMemory) FindByID(ctx context.Context, tx Tx, id int64) (Category, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	category, ok := m.Store.categories.rows[id].(Category)
+	if !ok {
+		return category, fmt.Errorf("failed to find category with id: %d because %w", id, ErrCategoryNotFound)
+	}
+
+	return category, nil
+}
+
+func (m *categoryMemory) FindAll(ctx context.Context, tx Tx) ([]Category, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	var categories []Category
+	for _, row := range m.Store.categories.rows {
+		categories = append(categories, row.(Category))
+	}
+
+	sort.Slice(categories, func(i, j int) bool {
+		return categories[i].Name < categories[j].Name
+	})
+
+	return categories, nil
+}
+
+// IsDescendant walk up from id to the root and report whether ancestorID is on the way
+func (m *categoryMemory) IsDescendant(ctx context.Context, tx Tx, id int64, ancestorID int64) (bool, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	visited := map[int64]bool{}
+	for !visited[id] {
+		category, ok := m.Store.categories.rows[id].(Category)
+		if !ok {
+			return false, nil
+		}
+		if category.ID == ancestorID {
+			return true, nil
+		}
+		if category.ParentID == nil {
+			return false, nil
+		}
+
+		visited[id] = true
+		id = *category.ParentID
+	}
+
+	return false, nil
+}
+
+// check the category against the constraints of the table, the store must be locked
+func (m *categoryMemory) check(c Category) error {
+	for _, row := range m.Store.categories.rows {
+		if category := row.(Category); category.ID != c.ID && category.Name == c.Name {
+			return fmt.Errorf("the name is already used")
+		}
+	}
+
+	if c.ParentID != nil {
+		if _, ok := m.Store.categories.rows[*c.ParentID]; !ok {
+			return ErrCategoryNotFound
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockCategoryPostgre struct {
+	mock.Mock
+}
+
+func (m *MockCategoryPostgre) Create(ctx context.Context, tx Tx, c Category) (Category, error) {
+	args := m.Called(ctx, tx, c)
+	return args.Get(0).(Category), args.Error(1)
+}
+
+func (m *MockCategoryPostgre) Update(ctx context.Context, tx Tx, c Category) (Category, error) {
+	args := m.Called(ctx, tx, c)
+	return args.Get(0).(Category), args.Error(1)
+}
+
+func (m *MockCategoryPostgre) Delete(ctx context.Context, tx Tx, id int64) error {
+	args := m.Called(ctx, tx, id)
+	return args.Error(0)
+}
+
+func (m *MockCategoryPostgre) FindByID(ctx context.Context, tx Tx, id int64) (Category, error) {
+	args := m.Called(ctx, tx, id)
+	return args.Get(0).(Category), args.Error(1)
+}
+
+func (m *MockCategoryPostgre) FindAll(ctx context.Context, tx Tx) ([]Category, error) {
+	args := m.Called(ctx, tx)
+	return args.Get(0).([]Category), args.Error(1)
+}
+
+func (m *MockCategoryPostgre) IsDescendant(ctx context.Context, tx Tx, id int64, ancestorID int64) (bool, error) {
+	args := m.Called(ctx, tx, id, ancestorID)
+	return args.Bool(0), args.Error(1)
+}
+
+type categoryPostgre struct {
+}
+
+func NewCategoryPostgre() CategoryRepository {
+	return &categoryPostgre{}
+}
+
+func (p *categoryPostgre) Create(ctx context.Context, tx Tx, c Category) (Category, error) {
+	SQL := "INSERT INTO categories(name, parent_id) VALUES ($1, $2) RETURNING category_id"
+	if err := sqlTx(tx).QueryRowContext(ctx, SQL, c.Name, c.ParentID).Scan(&c.ID); err != nil {
+		return c, fmt.Errorf("failed to create category: %v because %w", c, err)
+	}
+
+	return c, nil
+}
+
+func (p *categoryPostgre) Update(ctx context.Context, tx Tx, c Category) (Category, error) {
+	SQL := "UPDATE categories SET name = $1, parent_id = $2 WHERE category_id = $3"
+	result, err := sqlTx(tx).ExecContext(ctx, SQL, c.Name, c.ParentID, c.ID)
+	if err != nil {
+		return c, fmt.Errorf("failed to update category: %v because %w", c, err)
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return c, fmt.Errorf("failed to update category: %v because %w", c, ErrCategoryNotFound)
+	}
+
+	return c, nil
+}
+
+// Delete remove the category, its children move up to its parent
+func (p *categoryPostgre) Delete(ctx context.Context, tx Tx, id int64) error {
+	SQL := "UPDATE categories SET parent_id = (SELECT parent_id FROM categories WHERE category_id = $1) WHERE parent_id = $1"
+	if _, err := sqlTx(tx).ExecContext(ctx, SQL, id); err != nil {
+		return fmt.Errorf("failed to move children of category with id: %d because %w", id, err)
+	}
+
+	SQL = "DELETE FROM categories WHERE category_id = $1"
+	result, err := sqlTx(tx).ExecContext(ctx, SQL, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete category with id: %d because %w", id, err)
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("failed to delete category with id: %d because %w", id, ErrCategoryNotFound)
+	}
+
+	return nil
+}
+
+func (p *categoryPostgre) FindByID(ctx context.Context, tx Tx, id int64) (Category, error) {
+	SQL := "SELECT category_id, name, parent_id FROM categories WHERE category_id = $1"
+
+	var (
+		category Category
+		parentID sql.NullInt64
+	)
+	if err := sqlTx(tx).QueryRowContext(ctx, SQL, id).Scan(&category.ID, &category.Name, &parentID); err != nil {
+		if err == sql.ErrNoRows {
+			return category, fmt.Errorf("failed to find category with id: %d because %w", id, ErrCategoryNotFound)
+		}
+		return category, fmt.Errorf("failed to find category with id: %d because %w", id, err)
+	}
+
+	if parentID.Valid {
+		category.ParentID = &parentID.Int64
+	}
+
+	return category, nil
+}
+
+func (p *categoryPostgre) FindAll(ctx context.Context, tx Tx) ([]Category, error) {
+	SQL := "SELECT category_id, name, parent_id FROM categories ORDER BY name"
+	rows, err := sqlTx(tx).QueryContext(ctx, SQL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find categories because %w", err)
+	}
+	defer rows.Close()
+
+	var categories []Category
+	for rows.Next() {
+		var (
+			category Category
+			parentID sql.NullInt64
+		)
+		if err := rows.Scan(&category.ID, &category.Name, &parentID); err != nil {
+			return nil, fmt.Errorf("failed to scan category because %w", err)
+		}
+		if parentID.Valid {
+			category.ParentID = &parentID.Int64
+		}
+		categories = append(categories, category)
+	}
+
+	return categories, nil
+}
+
+// IsDescendant walk up from id to the root and report whether ancestorID is on the way
+func (p *categoryPostgre) IsDescendant(ctx context.Context, tx Tx, id int64, ancestorID int64) (bool, error) {
+	SQL := `WITH RECURSIVE ancestors AS (
+			SELECT category_id, parent_id FROM categories WHERE category_id = $1
+			UNION SELECT c.category_id, c.parent_id FROM categories c JOIN ancestors a ON c.category_id = a.parent_id
+		)
+		SELECT EXISTS (SELECT 1 FROM ancestors WHERE category_id = $2)`
+
+	var found bool
+	if err := sqlTx(tx).QueryRowContext(ctx, SQL, id, ancestorID).Scan(&found); err != nil {
+		return false, fmt.Errorf("failed to check ancestor of category with id: %d because %w", id, err)
+	}
+
+	return found, nil
+}
@@ -0,0 +1,14 @@
+package repository
+
+import "time"
+
+// Comment belongs to a post, a reply has the comment it replies to as its parent
+type Comment struct {
+	ID        int64     `json:"id"`
+	PostID    int64     `json:"post_id"`
+	ParentID  *int64    `json:"parent_id"`
+	Author    Author    `json:"author"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+type commentMemory struct {
+	Store *MemoryStore
+}
+
+func NewCommentMemory(store *MemoryStore) CommentRepository {
+	return &commentMemory{
+		Store: store,
+	}
+}
+
+func (m *commentMemory) Create(ctx context.Context, tx Tx, c Comment) (Comment, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if _, ok := m.Store.posts.rows[c.PostID]; !ok {
+		return c, fmt.Errorf("failed to create comment: %v because %w", c, ErrPostNotFound)
+	}
+	if c.ParentID != nil {
+		if _, ok := m.Store.comments.rows[*c.ParentID]; !ok {
+			return c, fmt.Errorf("failed to create comment: %v because %w", c, ErrCommentNotFound)
+		}
+	}
+	if _, ok := m.Store.users.rows[c.Author.ID]; !ok {
+		return c, fmt.Errorf("failed to create comment: %v because %w", c, ErrUserNotFound)
+	}
+
+	c.ID = m.Store.comments.nextID()
+	c.UpdatedAt = c.CreatedAt
+	m.Store.comments.put(tx, c.ID, Comment{
+		ID:        c.ID,
+		PostID:    c.PostID,
+		ParentID:  c.ParentID,
+		Author:    Author{ID: c.Author.ID},
+		Content:   c.Content,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+	})
+
+	return m.Store.comment(c), nil
+}
+
+// Update only change the content of the comment
+func (m *commentMemory) Update(ctx context.Context, tx Tx, c Comment) (Comment, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	comment, ok := m.Store.comments.rows[c.ID].(Comment)
+	if !ok {
+		return c, fmt.Errorf("failed to update comment: %v because %w", c, ErrCommentNotFound)
+	}
+
+	comment.Content = c.Content
+	comment.UpdatedAt = c.UpdatedAt
+	m.Store.comments.put(tx, c.ID, comment)
+
+	return c, nil
+}
+
+// Delete remove the comment along with its replies
+func (m *commentMemory) Delete(ctx context.Context, tx Tx, id int64) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if _, ok := m.Store.comments.rows[id]; !ok {
+		return fmt.Errorf("failed to delete comment with id: %d because %w", id, ErrCommentNotFound)
+	}
+
+	m.Store.deleteComment(tx, id)
+
+	return nil
+}
+
+func (m *commentMemory) FindByID(ctx context.Context, tx Tx, id int64) (Comment, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	comment, ok := m.Store.comments.rows[id].(Comment)
+	if !ok {
+		return Comment{}, fmt.Errorf("failed to find comment with id: %d because %w", id, ErrCommentNotFound)
+	}
+
+	return m.Store.comment(comment), nil
+}
+
+// FindThreads return the top level comments of the post, oldest first
+func (m *commentMemory) FindThreads(ctx context.Context, tx Tx, postID int64, from int, size int) ([]Comment, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	threads := m.find(func(c Comment) bool {
+		return c.PostID == postID && c.ParentID == nil
+	})
+
+	start, end := page(len(threads), from, size)
+	return append([]Comment{}, threads[start:end]...), nil
+}
+
+// FindReplies return every reply under the root comments, however deep, oldest first
+func (m *commentMemory) FindReplies(ctx context.Context, tx Tx, rootIDs []int64) ([]Comment, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	return m.find(func(c Comment) bool {
+		for parentID := c.ParentID; parentID != nil; {
+			if containsID(rootIDs, *parentID) {
+				return true
+			}
+
+			parent, ok := m.Store.comments.rows[*parentID].(Comment)
+			if !ok {
+				return false
+			}
+			parentID = parent.ParentID
+		}
+		return false
+	}), nil
+}
+
+// find return the comments matching, oldest first, the store must be locked
+func (m *commentMemory) find(match func(Comment) bool) []Comment {
+	comments := []Comment{}
+	for _, row := range m.Store.comments.rows {
+		if comment := row.(Comment); match(comment) {
+			comments = append(comments, m.Store.comment(comment))
+		}
+	}
+
+	sort.Slice(comments, func(i, j int) bool {
+		if !comments[i].CreatedAt.Equal(comments[j].CreatedAt) {
+			return comments[i].CreatedAt.Before(comments[j].CreatedAt)
+		}
+		return comments[i].ID < comments[j].ID
+	})
+
+	return comments
+}
@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockCommentPostgre struct {
+	mock.Mock
+}
+
+func (m *MockCommentPostgre) Create(ctx context.Context, tx Tx, c Comment) (Comment, error) {
+	args := m.Called(ctx, tx, c)
+	return args.Get(0).(Comment), args.Error(1)
+}
+
+func (m *MockCommentPostgre) Update(ctx context.Context, tx Tx, c Comment) (Comment, error) {
+	args := m.Called(ctx, tx, c)
+	return args.Get(0).(Comment), args.Error(1)
+}
+
+func (m *MockCommentPostgre) Delete(ctx context.Context, tx Tx, id int64) error {
+	args := m.Called(ctx, tx, id)
+	return args.Error(0)
+}
+
+func (m *MockCommentPostgre) FindByID(ctx context.Context, tx Tx, id int64) (Comment, error) {
+	args := m.Called(ctx, tx, id)
+	return args.Get(0).(Comment), args.Error(1)
+}
+
+func (m *MockCommentPostgre) FindThreads(ctx context.Context, tx Tx, postID int64, from int, size int) ([]Comment, error) {
+	args := m.Called(ctx, tx, postID, from, size)
+	return args.Get(0).([]Comment), args.Error(1)
+}
+
+func (m *MockCommentPostgre) FindReplies(ctx context.Context, tx Tx, rootIDs []int64) ([]Comment, error) {
+	args := m.Called(ctx, tx, rootIDs)
+	return args.Get(0).([]Comment), args.Error(1)
+}
+
+type commentPostgre struct {
+}
+
+func NewCommentPostgre() CommentRepository {
+	return &commentPostgre{}
+}
+
+func (p *commentPostgre) Create(ctx context.Context, tx Tx, c Comment) (Comment, error) {
+	SQL := `WITH inserted AS (
+			INSERT INTO comments(post_id, parent_id, author_id, content, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $5)
+			RETURNING comment_id, author_id
+		)
+		SELECT i.comment_id, u.username, u.name FROM inserted i JOIN users u ON u.user_id = i.author_id`
+	err := sqlTx(tx).QueryRowContext(ctx, SQL, c.PostID, c.ParentID, c.Author.ID, c.Content, c.CreatedAt).Scan(&c.ID, &c.Author.Username, &c.Author.Name)
+	if err != nil {
+		return c, fmt.Errorf("failed to create comment: %v because %w", c, err)
+	}
+	c.UpdatedAt = c.CreatedAt
+
+	return c, nil
+}
+
+// Update only change the content of the comment
+func (p *commentPostgre) Update(ctx context.Context, tx Tx, c Comment) (Comment, error) {
+	SQL := "UPDATE comments SET content = $1, updated_at = $2 WHERE comment_id = $3"
+	result, err := sqlTx(tx).ExecContext(ctx, SQL, c.Content, c.UpdatedAt, c.ID)
+	if err != nil {
+		return c, fmt.Errorf("failed to update comment: %v because %w", c, err)
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return c, fmt.Errorf("failed to update comment: %v because %w", c, ErrCommentNotFound)
+	}
+
+	return c, nil
+}
+
+// Delete remove the comment, its replies are removed by the foreign key
+func (p *commentPostgre) Delete(ctx context.Context, tx Tx, id int64) error {
+	SQL := "DELETE FROM comments WHERE comment_id = $1"
+	result, err := sqlTx(tx).ExecContext(ctx, SQL, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete comment with id: %d because %w", id, err)
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("failed to delete comment with id: %d because %w", id, ErrCommentNotFound)
+	}
+
+	return nil
+}
+
+func (p *commentPostgre) FindByID(ctx context.Context, tx Tx, id int64) (Comment, error) {
+	SQL := selectComment + " WHERE c.comment_id = $1"
+	rows, err := sqlTx(tx).QueryContext(ctx, SQL, id)
+	if err != nil {
+		return Comment{}, fmt.Errorf("failed to find comment with id: %d because %w", id, err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return Comment{}, fmt.Errorf("failed to find comment with id: %d because %w", id, ErrCommentNotFound)
+	}
+
+	comment, err := scanComment(rows)
+	if err != nil {
+		return comment, fmt.Errorf("failed to scan comment with id: %d because %w", id, err)
+	}
+
+	return comment, nil
+}
+
+// FindThreads return the top level comments of the post, oldest first
+func (p *commentPostgre) FindThreads(ctx context.Context, tx Tx, postID int64, from int, size int) ([]Comment, error) {
+	SQL := selectComment + " WHERE c.post_id = $1 AND c.parent_id IS NULL ORDER BY c.created_at, c.comment_id LIMIT $2 OFFSET $3"
+	rows, err := sqlTx(tx).QueryContext(ctx, SQL, postID, size, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find comments of post with id: %d because %w", postID, err)
+	}
+	defer rows.Close()
+
+	return scanComments(rows)
+}
+
+// FindReplies return every reply under the root comments, however deep, oldest first
+func (p *commentPostgre) FindReplies(ctx context.Context, tx Tx, rootIDs []int64) ([]Comment, error) {
+	SQL := `WITH RECURSIVE thread AS (
+			SELECT comment_id FROM comments WHERE parent_id = ANY($1)
+			UNION ALL SELECT c.comment_id FROM comments c JOIN thread t ON c.parent_id = t.comment_id
+		)
+		` + selectComment + " WHERE c.comment_id IN (SELECT comment_id FROM thread) ORDER BY c.created_at, c.comment_id"
+	rows, err := sqlTx(tx).QueryContext(ctx, SQL, pq.Array(rootIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find replies of comments: %v because %w", rootIDs, err)
+	}
+	defer rows.Close()
+
+	return scanComments(rows)
+}
+
+// selectComment joins the author like selectPost
+const selectComment = `SELECT c.comment_id, c.post_id, c.parent_id, c.content, c.created_at, c.updated_at, u.user_id, u.username, u.name
+	FROM comments c JOIN users u ON u.user_id = c.author_id`
+
+func scanComment(rows *sql.Rows) (Comment, error) {
+	var (
+		comment  Comment
+		parentID sql.NullInt64
+	)
+	err := rows.Scan(&comment.ID, &comment.PostID, &parentID, &comment.Content, &comment.CreatedAt, &comment.UpdatedAt,
+		&comment.Author.ID, &comment.Author.Username, &comment.Author.Name)
+	if parentID.Valid {
+		comment.ParentID = &parentID.Int64
+	}
+	return comment, err
+}
+
+func scanComments(rows *sql.Rows) ([]Comment, error) {
+	comments := []Comment{}
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan comment because %w", err)
+		}
+		comments = append(comments, comment)
+	}
+
+	return comments, nil
+}
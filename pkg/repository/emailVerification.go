@@ -0,0 +1,13 @@
+package repository
+
+import "time"
+
+// EmailVerification is the one time token mailed to confirm the email of the user, only the hash of the token is stored
+type EmailVerification struct {
+	ID        int64      `json:"id"`
+	UserID    int64      `json:"user_id"`
+	TokenHash string     `json:"-"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+}
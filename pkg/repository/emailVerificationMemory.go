@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+type emailVerificationMemory struct {
+	Store *MemoryStore
+}
+
+func NewEmailVerificationMemory(store *MemoryStore) EmailVerificationRepository {
+	return &emailVerificationMemory{
+		Store: store,
+	}
+}
+
+func (m *emailVerificationMemory) Create(ctx context.Context, tx Tx, v EmailVerification) (EmailVerification, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	v.ID = m.Store.emailVerifications.nextID()
+	m.Store.emailVerifications.put(tx, v.ID, v)
+
+	return v, nil
+}
+
+// FindByHash doesn't lock the token, the store is meant for a single writer
+func (m *emailVerificationMemory) FindByHash(ctx context.Context, tx Tx, hash string) (EmailVerification, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	for _, row := range m.Store.emailVerifications.rows {
+		if v := row.(EmailVerification); v.TokenHash == hash {
+			return v, nil
+		}
+	}
+
+	return EmailVerification{}, ErrEmailVerificationNotFound
+}
+
+// MarkUsed use up every token of the user once their email is verified
+func (m *emailVerificationMemory) MarkUsed(ctx context.Context, tx Tx, userID int64, usedAt time.Time) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	for key, row := range m.Store.emailVerifications.rows {
+		if v := row.(EmailVerification); v.UserID == userID && v.UsedAt == nil {
+			v.UsedAt = &usedAt
+			m.Store.emailVerifications.put(tx, key, v)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockEmailVerificationPostgre struct {
+	mock.Mock
+}
+
+func (m *MockEmailVerificationPostgre) Create(ctx context.Context, tx Tx, v EmailVerification) (EmailVerification, error) {
+	args := m.Called(ctx, tx, v)
+	return args.Get(0).(EmailVerification), args.Error(1)
+}
+
+func (m *MockEmailVerificationPostgre) FindByHash(ctx context.Context, tx Tx, hash string) (EmailVerification, error) {
+	args := m.Called(ctx, tx, hash)
+	return args.Get(0).(EmailVerification), args.Error(1)
+}
+
+func (m *MockEmailVerificationPostgre) MarkUsed(ctx context.Context, tx Tx, userID int64, usedAt time.Time) error {
+	args := m.Called(ctx, tx, userID, usedAt)
+	return args.Error(0)
+}
+
+type emailVerificationPostgre struct {
+}
+
+func NewEmailVerificationPostgre() EmailVerificationRepository {
+	return &emailVerificationPostgre{}
+}
+
+func (p *emailVerificationPostgre) Create(ctx context.Context, tx Tx, v EmailVerification) (EmailVerification, error) {
+	SQL := `INSERT INTO email_verifications(user_id, token_hash, created_at, expires_at)
+		VALUES ($1, $2, $3, $4) RETURNING email_verification_id`
+	if err := sqlTx(tx).QueryRowContext(ctx, SQL, v.UserID, v.TokenHash, v.CreatedAt, v.ExpiresAt).Scan(&v.ID); err != nil {
+		return EmailVerification{}, fmt.Errorf("failed to create email verification of user with id: %d because %w", v.UserID, err)
+	}
+
+	return v, nil
+}
+
+// FindByHash lock the token, so the same token consumed twice at once is only used once
+func (p *emailVerificationPostgre) FindByHash(ctx context.Context, tx Tx, hash string) (EmailVerification, error) {
+	SQL := `SELECT email_verification_id, user_id, token_hash, created_at, expires_at, used_at
+		FROM email_verifications WHERE token_hash = $1 FOR UPDATE`
+
+	var v EmailVerification
+	err := sqlTx(tx).QueryRowContext(ctx, SQL, hash).Scan(&v.ID, &v.UserID, &v.TokenHash, &v.CreatedAt, &v.ExpiresAt, &v.UsedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return EmailVerification{}, ErrEmailVerificationNotFound
+	}
+	if err != nil {
+		return EmailVerification{}, fmt.Errorf("failed to find email verification because %w", err)
+	}
+
+	return v, nil
+}
+
+// MarkUsed use up every token of the user once their email is verified
+func (p *emailVerificationPostgre) MarkUsed(ctx context.Context, tx Tx, userID int64, usedAt time.Time) error {
+	SQL := "UPDATE email_verifications SET used_at = $1 WHERE user_id = $2 AND used_at IS NULL"
+	if _, err := sqlTx(tx).ExecContext(ctx, SQL, usedAt, userID); err != nil {
+		return fmt.Errorf("failed to mark email verifications of user with id: %d as used because %w", userID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+type favouriteMemory struct {
+	Store *MemoryStore
+}
+
+func NewFavouriteMemory(store *MemoryStore) FavouriteRepository {
+	return &favouriteMemory{
+		Store: store,
+	}
+}
+
+// Add favourite the post, favouriting it again is a no-op
+func (m *favouriteMemory) Add(ctx context.Context, tx Tx, userID int64, postID int64) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if _, ok := m.Store.users.rows[userID]; !ok {
+		return fmt.Errorf("failed to favourite post with id: %d for user: %d because %w", postID, userID, ErrUserNotFound)
+	}
+	if _, ok := m.Store.posts.rows[postID]; !ok {
+		return fmt.Errorf("failed to favourite post with id: %d for user: %d because %w", postID, userID, ErrPostNotFound)
+	}
+
+	key := favouriteKey{UserID: userID, PostID: postID}
+	if _, ok := m.Store.favourites.rows[key]; !ok {
+		m.Store.favourites.put(tx, key, memoryFavourite{CreatedAt: time.Now(), Seq: m.Store.favourites.nextID()})
+	}
+
+	return nil
+}
+
+// Remove unfavourite the post, removing a post that isn't favourited is a no-op
+func (m *favouriteMemory) Remove(ctx context.Context, tx Tx, userID int64, postID int64) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	m.Store.favourites.remove(tx, favouriteKey{UserID: userID, PostID: postID})
+
+	return nil
+}
+
+// Count return the favourite count of every post, post without favourite is counted as 0
+func (m *favouriteMemory) Count(ctx context.Context, tx Tx, postIDs []int64) (map[int64]int64, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	counts := make(map[int64]int64, len(postIDs))
+	for _, id := range postIDs {
+		counts[id] = 0
+	}
+
+	for key := range m.Store.favourites.rows {
+		if id := key.(favouriteKey).PostID; containsID(postIDs, id) {
+			counts[id]++
+		}
+	}
+
+	return counts, nil
+}
+
+// FavouritedBy report which of the posts is favourited by the user
+func (m *favouriteMemory) FavouritedBy(ctx context.Context, tx Tx, userID int64, postIDs []int64) (map[int64]bool, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	favourited := make(map[int64]bool, len(postIDs))
+	for _, id := range postIDs {
+		if _, ok := m.Store.favourites.rows[favouriteKey{UserID: userID, PostID: id}]; ok {
+			favourited[id] = true
+		}
+	}
+
+	return favourited, nil
+}
+
+// FindByUser return the published post favourited by the user, the latest favourite first
+func (m *favouriteMemory) FindByUser(ctx context.Context, tx Tx, userID int64, from int, size int) ([]PostData, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	type favourite struct {
+		post PostData
+		memoryFavourite
+	}
+	var favourites []favourite
+	for key, row := range m.Store.favourites.rows {
+		if key.(favouriteKey).UserID != userID {
+			continue
+		}
+
+		post, ok := m.Store.posts.rows[key.(favouriteKey).PostID].(PostData)
+		if !ok || post.Status != StatusPublished || post.DeletedAt != nil {
+			continue
+		}
+		favourites = append(favourites, favourite{post: post, memoryFavourite: row.(memoryFavourite)})
+	}
+
+	sort.Slice(favourites, func(i, j int) bool {
+		if !favourites[i].CreatedAt.Equal(favourites[j].CreatedAt) {
+			return favourites[i].CreatedAt.After(favourites[j].CreatedAt)
+		}
+		return favourites[i].Seq > favourites[j].Seq
+	})
+
+	posts := []PostData{}
+	start, end := page(len(favourites), from, size)
+	for _, f := range favourites[start:end] {
+		posts = append(posts, m.Store.post(f.post))
+	}
+
+	return posts, nil
+}
+
+// FindPostIDsByUser return every post favourited by the user, whatever its status
+func (m *favouriteMemory) FindPostIDsByUser(ctx context.Context, tx Tx, userID int64) ([]int64, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	var ids []int64
+	for key := range m.Store.favourites.rows {
+		if key.(favouriteKey).UserID == userID {
+			ids = append(ids, key.(favouriteKey).PostID)
+		}
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return ids, nil
+}
@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockFavouritePostgre struct {
+	mock.Mock
+}
+
+func (m *MockFavouritePostgre) Add(ctx context.Context, tx Tx, userID int64, postID int64) error {
+	args := m.Called(ctx, tx, userID, postID)
+	return args.Error(0)
+}
+
+func (m *MockFavouritePostgre) Remove(ctx context.Context, tx Tx, userID int64, postID int64) error {
+	args := m.Called(ctx, tx, userID, postID)
+	return args.Error(0)
+}
+
+func (m *MockFavouritePostgre) Count(ctx context.Context, tx Tx, postIDs []int64) (map[int64]int64, error) {
+	args := m.Called(ctx, tx, postIDs)
+	return args.Get(0).(map[int64]int64), args.Error(1)
+}
+
+func (m *MockFavouritePostgre) FavouritedBy(ctx context.Context, tx Tx, userID int64, postIDs []int64) (map[int64]bool, error) {
+	args := m.Called(ctx, tx, userID, postIDs)
+	return args.Get(0).(map[int64]bool), args.Error(1)
+}
+
+func (m *MockFavouritePostgre) FindByUser(ctx context.Context, tx Tx, userID int64, from int, size int) ([]PostData, error) {
+	args := m.Called(ctx, tx, userID, from, size)
+	return args.Get(0).([]PostData), args.Error(1)
+}
+
+func (m *MockFavouritePostgre) FindPostIDsByUser(ctx context.Context, tx Tx, userID int64) ([]int64, error) {
+	args := m.Called(ctx, tx, userID)
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+type favouritePostgre struct {
+}
+
+func NewFavouritePostgre() FavouriteRepository {
+	return &favouritePostgre{}
+}
+
+// Add favourite the post, favouriting it again is a no-op
+func (p *favouritePostgre) Add(ctx context.Context, tx Tx, userID int64, postID int64) error {
+	SQL := "INSERT INTO favourites(user_id, post_id) VALUES ($1, $2) ON CONFLICT DO NOTHING"
+	if _, err := sqlTx(tx).ExecContext(ctx, SQL, userID, postID); err != nil {
+		return fmt.Errorf("failed to favourite post with id: %d for user: %d because %w", postID, userID, err)
+	}
+
+	return nil
+}
+
+// Remove unfavourite the post, removing a post that isn't favourited is a no-op
+func (p *favouritePostgre) Remove(ctx context.Context, tx Tx, userID int64, postID int64) error {
+	SQL := "DELETE FROM favourites WHERE user_id = $1 AND post_id = $2"
+	if _, err := sqlTx(tx).ExecContext(ctx, SQL, userID, postID); err != nil {
+		return fmt.Errorf("failed to unfavourite post with id: %d for user: %d because %w", postID, userID, err)
+	}
+
+	return nil
+}
+
+// Count return the favourite count of every post, post without favourite is counted as 0
+func (p *favouritePostgre) Count(ctx context.Context, tx Tx, postIDs []int64) (map[int64]int64, error) {
+	SQL := "SELECT post_id, COUNT(*) FROM favourites WHERE post_id = ANY($1) GROUP BY post_id"
+	rows, err := sqlTx(tx).QueryContext(ctx, SQL, pq.Array(postIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to count favourites of posts: %v because %w", postIDs, err)
+	}
+	defer rows.Close()
+
+	counts := make(map[int64]int64, len(postIDs))
+	for _, id := range postIDs {
+		counts[id] = 0
+	}
+
+	for rows.Next() {
+		var id, count int64
+		if err := rows.Scan(&id, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan favourite count because %w", err)
+		}
+		counts[id] = count
+	}
+
+	return counts, nil
+}
+
+// FavouritedBy report which of the posts is favourited by the user
+func (p *favouritePostgre) FavouritedBy(ctx context.Context, tx Tx, userID int64, postIDs []int64) (map[int64]bool, error) {
+	SQL := "SELECT post_id FROM favourites WHERE user_id = $1 AND post_id = ANY($2)"
+	rows, err := sqlTx(tx).QueryContext(ctx, SQL, userID, pq.Array(postIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find favourites of user: %d because %w", userID, err)
+	}
+	defer rows.Close()
+
+	favourited := make(map[int64]bool, len(postIDs))
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan favourite because %w", err)
+		}
+		favourited[id] = true
+	}
+
+	return favourited, nil
+}
+
+// FindByUser return the published post favourited by the user, the latest favourite first
+func (p *favouritePostgre) FindByUser(ctx context.Context, tx Tx, userID int64, from int, size int) ([]PostData, error) {
+	SQL := selectPost + ` JOIN favourites f ON f.post_id = p.post_id
+		WHERE f.user_id = $1 AND p.status = 'published' AND p.deleted_at IS NULL ORDER BY f.created_at DESC LIMIT $2 OFFSET $3`
+	rows, err := sqlTx(tx).QueryContext(ctx, SQL, userID, size, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find favourites of user: %d because %w", userID, err)
+	}
+	defer rows.Close()
+
+	posts := []PostData{}
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan favourite post because %w", err)
+		}
+		posts = append(posts, post)
+	}
+
+	return posts, nil
+}
+
+// FindPostIDsByUser return every post favourited by the user, whatever its status
+func (p *favouritePostgre) FindPostIDsByUser(ctx context.Context, tx Tx, userID int64) ([]int64, error) {
+	SQL := "SELECT post_id FROM favourites WHERE user_id = $1"
+	rows, err := sqlTx(tx).QueryContext(ctx, SQL, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find favourites of user: %d because %w", userID, err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan favourite because %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,436 @@
+package repository
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// the functions below search a list of posts without any index, the way the search backends do,
+// they're shared by the memory repositories and the memory search index. Every post of the list is scanned
+// and the words aren't stemmed, so it's only meant for test and deployment with a handful of posts
+
+// SearchPosts score the posts matching the filter by how many time the words of the query show up in them,
+// a word in the title count twice, and return a page of them with the facets of every matched post
+func SearchPosts(posts []PostData, query SearchQuery, filter PostFilter, from int, size int) SearchResult {
+	var hits []SearchHit
+	for _, post := range posts {
+		if !filter.Match(post) {
+			continue
+		}
+
+		if score, ok := query.Match(post); ok {
+			hits = append(hits, SearchHit{PostData: post, Score: score})
+		}
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return newer(hits[i].PostData, hits[j].PostData)
+	})
+
+	matched := make([]PostData, len(hits))
+	for i, hit := range hits {
+		matched[i] = hit.PostData
+	}
+
+	result := SearchResult{
+		Total:  int64(len(hits)),
+		Hits:   []SearchHit{},
+		Facets: countFacets(matched),
+	}
+	start, end := page(len(hits), from, size)
+	result.Hits = append(result.Hits, hits[start:end]...)
+
+	if result.Total == 0 && !query.Empty() {
+		result.DidYouMean = query.Corrected(spellCorrections(posts, query.Words()))
+	}
+
+	return result
+}
+
+// RecentPosts return a page of the posts matching the filter, the most recent first
+func RecentPosts(posts []PostData, filter PostFilter, from int, size int) []PostData {
+	var matched []PostData
+	for _, post := range posts {
+		if filter.Match(post) {
+			matched = append(matched, post)
+		}
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		return newer(matched[i], matched[j])
+	})
+
+	start, end := page(len(matched), from, size)
+	return matched[start:end]
+}
+
+// SuggestPosts complete the prefix with the title of the published posts, the title starting with the prefix
+// come first then the one with a word starting with it
+func SuggestPosts(posts []PostData, prefix string, size int) []Suggestion {
+	prefix = strings.ToLower(prefix)
+
+	type suggestion struct {
+		post  PostData
+		start bool
+	}
+	var found []suggestion
+	for _, post := range posts {
+		if post.Status != StatusPublished {
+			continue
+		}
+
+		title := strings.ToLower(post.Title)
+		if strings.HasPrefix(title, prefix) {
+			found = append(found, suggestion{post: post, start: true})
+			continue
+		}
+
+		for _, word := range strings.Fields(title) {
+			if strings.HasPrefix(word, prefix) {
+				found = append(found, suggestion{post: post})
+				break
+			}
+		}
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].start != found[j].start {
+			return found[i].start
+		}
+		return newer(found[i].post, found[j].post)
+	})
+
+	suggestions := []Suggestion{}
+	_, end := page(len(found), 0, size)
+	for _, s := range found[:end] {
+		suggestions = append(suggestions, Suggestion{ID: s.post.ID, Title: s.post.Title, Slug: s.post.Slug})
+	}
+
+	return suggestions
+}
+
+// RelatedPosts score the other published posts by the words and tags they share with the source post,
+// a word in the title and a tag count twice
+func RelatedPosts(source PostData, posts []PostData, size int) []PostData {
+	words := map[string]bool{}
+	for _, word := range tokenize(source.Title + " " + source.ShortDesc + " " + source.Content) {
+		words[word] = true
+	}
+
+	scores := map[int64]float64{}
+	var related []PostData
+	for _, post := range posts {
+		if post.ID == source.ID || post.Status != StatusPublished {
+			continue
+		}
+
+		var score float64
+		for _, word := range tokenize(post.Title) {
+			if words[word] {
+				score += 2
+			}
+		}
+		for _, word := range tokenize(post.ShortDesc + " " + post.Content) {
+			if words[word] {
+				score++
+			}
+		}
+		for _, tag := range post.Tags {
+			if contains(source.Tags, tag) {
+				score += 2
+			}
+		}
+
+		if score > 0 {
+			scores[post.ID] = score
+			related = append(related, post)
+		}
+	}
+
+	sort.Slice(related, func(i, j int) bool {
+		if scores[related[i].ID] != scores[related[j].ID] {
+			return scores[related[i].ID] > scores[related[j].ID]
+		}
+		return newer(related[i], related[j])
+	})
+
+	_, end := page(len(related), 0, size)
+	return append([]PostData{}, related[:end]...)
+}
+
+// Match report whether the post pass the filter
+func (f PostFilter) Match(post PostData) bool {
+	status := f.Status
+	if status == "" {
+		status = StatusPublished
+	}
+
+	switch {
+	case post.Status != status:
+		return false
+	case f.Tag != "" && !contains(post.Tags, f.Tag):
+		return false
+	case f.Category != "" && !contains(post.Categories, f.Category):
+		return false
+	case f.Author != "" && post.Author.Username != f.Author:
+		return false
+	case f.From != nil && post.CreatedAt.Before(*f.From):
+		return false
+	case f.To != nil && !post.CreatedAt.Before(*f.To):
+		return false
+	}
+
+	return true
+}
+
+// Match return the score of the post and whether it match every group of the query
+func (q SearchQuery) Match(post PostData) (float64, bool) {
+	fields := map[string][]string{
+		"title":      tokenize(post.Title),
+		"short_desc": tokenize(post.ShortDesc),
+		"content":    tokenize(post.Content),
+	}
+
+	var score float64
+	for _, group := range q.Groups {
+		matched := false
+		for _, clause := range group.Clauses {
+			if s := matchClause(fields, clause, q.Mode); s > 0 {
+				score += s
+				matched = true
+			}
+		}
+
+		if matched == group.Exclude {
+			return 0, false
+		}
+	}
+
+	return score, true
+}
+
+// clauseFields is the field a clause is matched against with its weight, by the field it's limited to
+var clauseFields = map[string]map[string]float64{
+	"":        {"title": 2, "short_desc": 1, "content": 1},
+	"title":   {"title": 1},
+	"content": {"content": 1},
+}
+
+// matchClause return the weighted number of time the clause show up in the fields, every word of a clause
+// must be found and a phrase must be found in order
+func matchClause(fields map[string][]string, clause QueryClause, mode string) float64 {
+	words := tokenize(clause.Text)
+	if len(words) == 0 {
+		return 0
+	}
+
+	var score float64
+	for field, weight := range clauseFields[clause.Field] {
+		tokens := fields[field]
+
+		if clause.Phrase {
+			for i := 0; i+len(words) <= len(tokens); i++ {
+				if equalWords(tokens[i:i+len(words)], words) {
+					score += weight
+				}
+			}
+			continue
+		}
+
+		var count float64
+		for _, word := range words {
+			found := 0.0
+			for _, token := range tokens {
+				if token == word || (mode == QueryModeFuzzy && editDistance(token, word) <= maxEdits(word)) {
+					found++
+				}
+			}
+			if found == 0 {
+				count = 0
+				break
+			}
+			count += found
+		}
+		score += count * weight
+	}
+
+	return score
+}
+
+// spellCorrections find the closest word of the published posts to every word that none of them use
+func spellCorrections(posts []PostData, words []string) map[string]string {
+	vocabulary := map[string]bool{}
+	for _, post := range posts {
+		if post.Status != StatusPublished || post.DeletedAt != nil {
+			continue
+		}
+		for _, word := range tokenize(post.Title + " " + post.ShortDesc + " " + post.Content) {
+			vocabulary[word] = true
+		}
+	}
+
+	corrections := map[string]string{}
+	for _, word := range words {
+		if vocabulary[word] {
+			continue
+		}
+
+		best, bestDistance := "", maxEdits(word)+1
+		for candidate := range vocabulary {
+			distance := editDistance(word, candidate)
+			if distance < bestDistance || (distance == bestDistance && candidate < best) {
+				best, bestDistance = candidate, distance
+			}
+		}
+
+		if best != "" {
+			corrections[word] = best
+		}
+	}
+
+	return corrections
+}
+
+// countFacets count the posts the same way as the elasticsearch aggregations
+func countFacets(posts []PostData) *Facets {
+	tags := map[string]int64{}
+	authors := map[string]int64{}
+	months := map[string]int64{}
+	for _, post := range posts {
+		for _, tag := range post.Tags {
+			tags[tag]++
+		}
+		authors[post.Author.Username]++
+		months[post.CreatedAt.Format("2006-01")]++
+	}
+
+	monthBuckets := buckets(months)
+	sort.Slice(monthBuckets, func(i, j int) bool {
+		return monthBuckets[i].Key < monthBuckets[j].Key
+	})
+
+	return &Facets{
+		Tags:    topBuckets(tags),
+		Authors: topBuckets(authors),
+		Months:  monthBuckets,
+	}
+}
+
+func buckets(counts map[string]int64) []FacetBucket {
+	buckets := []FacetBucket{}
+	for key, count := range counts {
+		buckets = append(buckets, FacetBucket{Key: key, Count: count})
+	}
+	return buckets
+}
+
+// topBuckets return the most common FacetSize keys
+func topBuckets(counts map[string]int64) []FacetBucket {
+	buckets := buckets(counts)
+	sort.Slice(buckets, func(i, j int) bool {
+		if buckets[i].Count != buckets[j].Count {
+			return buckets[i].Count > buckets[j].Count
+		}
+		return buckets[i].Key < buckets[j].Key
+	})
+
+	if len(buckets) > FacetSize {
+		buckets = buckets[:FacetSize]
+	}
+	return buckets
+}
+
+// page return the range of a page in a list of n item
+func page(n int, from int, size int) (start, end int) {
+	start, end = from, from+size
+	if start > n {
+		start = n
+	}
+	if end > n {
+		end = n
+	}
+	return start, end
+}
+
+// newer order the posts from the most recent one
+func newer(a PostData, b PostData) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.After(b.CreatedAt)
+	}
+	return a.ID > b.ID
+}
+
+// tokenize split the text into lowercased words
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+func equalWords(a []string, b []string) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// maxEdits is how many typo a word can have, like the AUTO fuzziness of elasticsearch
+func maxEdits(word string) int {
+	switch n := len([]rune(word)); {
+	case n <= 2:
+		return 0
+	case n <= 5:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// editDistance is the levenshtein distance between the two words
+func editDistance(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(rb)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
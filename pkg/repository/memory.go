@@ -0,0 +1,411 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore keep the tables of the memory repositories, every repository built on the same store see
+// the changes of the others like the postgres repositories share the database. It's also the DB they run in,
+// a transaction see the changes of the other transactions right away and only undo its own on Rollback,
+// so it's meant for test and local development rather than for concurrent writers
+type MemoryStore struct {
+	mu sync.RWMutex
+
+	users memoryTable
+	// posts hold the post columns, the author is only the id and the tags and categories are kept
+	// in postTags and postCategories like the join tables
+	posts          memoryTable
+	slugs          memoryTable
+	revisions      memoryTable
+	tags           memoryTable
+	postTags       memoryTable
+	categories     memoryTable
+	postCategories memoryTable
+	// comments hold the comment columns, the author is only the id
+	comments           memoryTable
+	favourites         memoryTable
+	outbox             memoryTable
+	refreshTokens      memoryTable
+	passwordResets     memoryTable
+	emailVerifications memoryTable
+	// roles is keyed by the name, it's seeded like the migration does
+	roles memoryTable
+}
+
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{
+		users:              newMemoryTable(),
+		posts:              newMemoryTable(),
+		slugs:              newMemoryTable(),
+		revisions:          newMemoryTable(),
+		tags:               newMemoryTable(),
+		postTags:           newMemoryTable(),
+		categories:         newMemoryTable(),
+		postCategories:     newMemoryTable(),
+		comments:           newMemoryTable(),
+		favourites:         newMemoryTable(),
+		outbox:             newMemoryTable(),
+		refreshTokens:      newMemoryTable(),
+		passwordResets:     newMemoryTable(),
+		emailVerifications: newMemoryTable(),
+		roles:              newMemoryTable(),
+	}
+
+	for _, role := range memoryRoles {
+		s.roles.rows[role.Name] = memoryRole{ID: s.roles.nextID(), Role: role}
+	}
+
+	return s
+}
+
+// ErrReadOnlyTx is returned when a read only transaction that changed something is committed
+var ErrReadOnlyTx = errors.New("cannot change anything in a read only transaction")
+
+func (s *MemoryStore) Begin() (Tx, error) {
+	return &memoryTx{store: s}, nil
+}
+
+// BeginTx ignore the isolation level, every change is seen by the other transactions right away
+func (s *MemoryStore) BeginTx(ctx context.Context, opts *sql.TxOptions) (Tx, error) {
+	return &memoryTx{store: s, readOnly: opts != nil && opts.ReadOnly}, nil
+}
+
+func (s *MemoryStore) Savepoint(ctx context.Context, tx Tx, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mtx := memTx(tx)
+	if mtx.savepoints == nil {
+		mtx.savepoints = map[string]int{}
+	}
+	mtx.savepoints[name] = len(mtx.undo)
+
+	return nil
+}
+
+// RollbackToSavepoint undo the changes made since the savepoint, the savepoint is kept like in postgres
+func (s *MemoryStore) RollbackToSavepoint(ctx context.Context, tx Tx, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mtx := memTx(tx)
+	mark, ok := mtx.savepoints[name]
+	if !ok {
+		return fmt.Errorf("savepoint %s does not exist", name)
+	}
+	mtx.undoTo(mark)
+
+	return nil
+}
+
+func (s *MemoryStore) ReleaseSavepoint(ctx context.Context, tx Tx, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mtx := memTx(tx)
+	if _, ok := mtx.savepoints[name]; !ok {
+		return fmt.Errorf("savepoint %s does not exist", name)
+	}
+	delete(mtx.savepoints, name)
+
+	return nil
+}
+
+// memoryTx undo its changes in the reverse order they were made on Rollback, savepoints are the number
+// of changes made when they were set
+type memoryTx struct {
+	store      *MemoryStore
+	undo       []func()
+	savepoints map[string]int
+	readOnly   bool
+	done       bool
+}
+
+func (tx *memoryTx) Commit() error {
+	tx.store.mu.Lock()
+	defer tx.store.mu.Unlock()
+
+	if tx.done {
+		return sql.ErrTxDone
+	}
+
+	// nothing stop the repositories from writing, the changes are only refused here
+	if tx.readOnly && len(tx.undo) > 0 {
+		tx.undoTo(0)
+		tx.done = true
+		return ErrReadOnlyTx
+	}
+
+	tx.done = true
+	tx.undo = nil
+	return nil
+}
+
+func (tx *memoryTx) Rollback() error {
+	tx.store.mu.Lock()
+	defer tx.store.mu.Unlock()
+
+	if tx.done {
+		return sql.ErrTxDone
+	}
+
+	tx.undoTo(0)
+	tx.done = true
+	return nil
+}
+
+// undoTo undo the changes made after the first n, the store must be locked
+func (tx *memoryTx) undoTo(n int) {
+	for i := len(tx.undo) - 1; i >= n; i-- {
+		tx.undo[i]()
+	}
+	tx.undo = tx.undo[:n]
+}
+
+// memTx return the transaction the memory repositories record their changes in,
+// mixing a memory repository with a transaction begun by another storage is a programming error
+func memTx(tx Tx) *memoryTx {
+	return tx.(*memoryTx)
+}
+
+// memoryTable is a table keyed by its primary key, the rows are stored by value so a row is changed
+// by putting it back
+type memoryTable struct {
+	rows   map[interface{}]interface{}
+	lastID int64
+}
+
+func newMemoryTable() memoryTable {
+	return memoryTable{rows: map[interface{}]interface{}{}}
+}
+
+// nextID is the serial of the table, like a sequence it isn't given back on Rollback
+func (t *memoryTable) nextID() int64 {
+	t.lastID++
+	return t.lastID
+}
+
+// put insert or replace the row and record how to undo it, the store must be locked
+func (t *memoryTable) put(tx Tx, key interface{}, row interface{}) {
+	previous, ok := t.rows[key]
+	t.rows[key] = row
+
+	rows := t.rows
+	mtx := memTx(tx)
+	mtx.undo = append(mtx.undo, func() {
+		if ok {
+			rows[key] = previous
+		} else {
+			delete(rows, key)
+		}
+	})
+}
+
+// remove delete the row and record how to undo it, the store must be locked
+func (t *memoryTable) remove(tx Tx, key interface{}) {
+	previous, ok := t.rows[key]
+	if !ok {
+		return
+	}
+	delete(t.rows, key)
+
+	rows := t.rows
+	mtx := memTx(tx)
+	mtx.undo = append(mtx.undo, func() {
+		rows[key] = previous
+	})
+}
+
+// favouriteKey is the primary key of the favourites
+type favouriteKey struct {
+	UserID int64
+	PostID int64
+}
+
+// memoryEvent is an outbox event with when it was dead lettered
+type memoryEvent struct {
+	OutboxEvent
+	DeadAt *time.Time
+}
+
+// memoryRole is a role with its id, the id order the roles
+type memoryRole struct {
+	ID int64
+	Role
+}
+
+// memoryFavourite is when the post was favourited, Seq order the favourites added at the same time
+type memoryFavourite struct {
+	CreatedAt time.Time
+	Seq       int64
+}
+
+// post join the author, tags, categories and comment count of the post row like selectPost, the store must be locked
+func (s *MemoryStore) post(row PostData) PostData {
+	post := row
+	if user, ok := s.users.rows[row.Author.ID].(User); ok {
+		post.Author = Author{ID: user.ID, Username: user.Username, Name: user.Name}
+	}
+
+	post.Tags = []string{}
+	tagIDs, _ := s.postTags.rows[row.ID].([]int64)
+	for _, id := range tagIDs {
+		post.Tags = append(post.Tags, s.tags.rows[id].(Tag).Name)
+	}
+	sort.Strings(post.Tags)
+
+	post.Categories = []string{}
+	categoryIDs, _ := s.postCategories.rows[row.ID].([]int64)
+	for _, id := range categoryIDs {
+		post.Categories = append(post.Categories, s.categories.rows[id].(Category).Name)
+	}
+	sort.Strings(post.Categories)
+
+	post.CommentCount = 0
+	for _, row := range s.comments.rows {
+		if row.(Comment).PostID == post.ID {
+			post.CommentCount++
+		}
+	}
+
+	return post
+}
+
+// livePosts return every post that isn't in the trash, the store must be locked
+func (s *MemoryStore) livePosts() []PostData {
+	var posts []PostData
+	for _, row := range s.posts.rows {
+		post := row.(PostData)
+		if post.DeletedAt == nil {
+			posts = append(posts, s.post(post))
+		}
+	}
+
+	return posts
+}
+
+// comment join the author of the comment row like selectComment, the store must be locked
+func (s *MemoryStore) comment(row Comment) Comment {
+	comment := row
+	if user, ok := s.users.rows[row.Author.ID].(User); ok {
+		comment.Author = Author{ID: user.ID, Username: user.Username, Name: user.Name}
+	}
+
+	return comment
+}
+
+// deletePost remove the post and everything that belong to it like the foreign keys do, the store must be locked
+func (s *MemoryStore) deletePost(tx Tx, id int64) {
+	s.posts.remove(tx, id)
+	s.postTags.remove(tx, id)
+	s.postCategories.remove(tx, id)
+
+	for key, row := range s.slugs.rows {
+		if row.(int64) == id {
+			s.slugs.remove(tx, key)
+		}
+	}
+
+	for key, row := range s.revisions.rows {
+		if row.(Revision).PostID == id {
+			s.revisions.remove(tx, key)
+		}
+	}
+
+	for key, row := range s.comments.rows {
+		if row.(Comment).PostID == id {
+			s.deleteComment(tx, key.(int64))
+		}
+	}
+
+	for key := range s.favourites.rows {
+		if key.(favouriteKey).PostID == id {
+			s.favourites.remove(tx, key)
+		}
+	}
+}
+
+// deleteComment remove the comment and its replies, however deep, the store must be locked
+func (s *MemoryStore) deleteComment(tx Tx, id int64) {
+	s.comments.remove(tx, id)
+
+	for key, row := range s.comments.rows {
+		if parentID := row.(Comment).ParentID; parentID != nil && *parentID == id {
+			s.deleteComment(tx, key.(int64))
+		}
+	}
+}
+
+// deleteUser remove the user with their posts, comments, favourites, refresh tokens, password resets and email verifications, the revisions they edited
+// are kept without an editor, the store must be locked
+func (s *MemoryStore) deleteUser(tx Tx, id int64) {
+	s.users.remove(tx, id)
+
+	for key, row := range s.posts.rows {
+		if row.(PostData).Author.ID == id {
+			s.deletePost(tx, key.(int64))
+		}
+	}
+
+	for key, row := range s.comments.rows {
+		if row.(Comment).Author.ID == id {
+			s.deleteComment(tx, key.(int64))
+		}
+	}
+
+	for key := range s.favourites.rows {
+		if key.(favouriteKey).UserID == id {
+			s.favourites.remove(tx, key)
+		}
+	}
+
+	for key, row := range s.refreshTokens.rows {
+		if row.(RefreshToken).UserID == id {
+			s.refreshTokens.remove(tx, key)
+		}
+	}
+
+	for key, row := range s.passwordResets.rows {
+		if row.(PasswordReset).UserID == id {
+			s.passwordResets.remove(tx, key)
+		}
+	}
+
+	for key, row := range s.emailVerifications.rows {
+		if row.(EmailVerification).UserID == id {
+			s.emailVerifications.remove(tx, key)
+		}
+	}
+
+	for key, row := range s.revisions.rows {
+		if revision := row.(Revision); revision.EditorID != nil && *revision.EditorID == id {
+			revision.EditorID = nil
+			s.revisions.put(tx, key, revision)
+		}
+	}
+}
+
+// unlink remove the id from the join table of every post like the foreign keys do when a tag or a category
+// is deleted, the store must be locked
+func (s *MemoryStore) unlink(tx Tx, join *memoryTable, id int64) {
+	for key, row := range join.rows {
+		ids := row.([]int64)
+		if !containsID(ids, id) {
+			continue
+		}
+
+		kept := []int64{}
+		for _, i := range ids {
+			if i != id {
+				kept = append(kept, i)
+			}
+		}
+		join.put(tx, key, kept)
+	}
+}
@@ -0,0 +1,16 @@
+package repository
+
+import "time"
+
+// OutboxEvent ask the relay to bring the indexed and cached copy of the post in line with the repository,
+// it's written in the same transaction as the change so the change can't be lost
+type OutboxEvent struct {
+	ID     int64 `json:"id"`
+	PostID int64 `json:"post_id"`
+	// Slug is the slug the post had before the change, its cached copy is dropped
+	Slug          string    `json:"slug"`
+	CreatedAt     time.Time `json:"created_at"`
+	Attempts      int       `json:"attempts"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	LastError     string    `json:"last_error"`
+}
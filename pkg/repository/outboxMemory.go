@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+type outboxMemory struct {
+	Store *MemoryStore
+}
+
+func NewOutboxMemory(store *MemoryStore) OutboxRepository {
+	return &outboxMemory{
+		Store: store,
+	}
+}
+
+func (m *outboxMemory) Add(ctx context.Context, tx Tx, e OutboxEvent) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	e.ID = m.Store.outbox.nextID()
+	e.Attempts = 0
+	e.LastError = ""
+	m.Store.outbox.put(tx, e.ID, memoryEvent{OutboxEvent: e})
+
+	return nil
+}
+
+// FindPending return the due events in the order they were added, dead lettered event is never picked again.
+// Nothing is locked, so unlike the postgres repository only a single relay should run on the store
+func (m *outboxMemory) FindPending(ctx context.Context, tx Tx, now time.Time, limit int) ([]OutboxEvent, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	var events []OutboxEvent
+	for _, row := range m.Store.outbox.rows {
+		e := row.(memoryEvent)
+		if e.DeadAt == nil && !e.NextAttemptAt.After(now) {
+			events = append(events, e.OutboxEvent)
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].ID < events[j].ID
+	})
+	if len(events) > limit {
+		events = events[:limit]
+	}
+
+	return events, nil
+}
+
+// Delete remove the delivered event
+func (m *outboxMemory) Delete(ctx context.Context, tx Tx, id int64) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	m.Store.outbox.remove(tx, id)
+
+	return nil
+}
+
+// Retry store the failed attempt and when the event is tried again
+func (m *outboxMemory) Retry(ctx context.Context, tx Tx, e OutboxEvent) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if event, ok := m.Store.outbox.rows[e.ID].(memoryEvent); ok {
+		event.Attempts = e.Attempts
+		event.NextAttemptAt = e.NextAttemptAt
+		event.LastError = e.LastError
+		m.Store.outbox.put(tx, e.ID, event)
+	}
+
+	return nil
+}
+
+// DeadLetter park the event that ran out of attempts
+func (m *outboxMemory) DeadLetter(ctx context.Context, tx Tx, e OutboxEvent, deadAt time.Time) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if event, ok := m.Store.outbox.rows[e.ID].(memoryEvent); ok {
+		event.Attempts = e.Attempts
+		event.LastError = e.LastError
+		event.DeadAt = &deadAt
+		m.Store.outbox.put(tx, e.ID, event)
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockOutboxPostgre struct {
+	mock.Mock
+}
+
+func (m *MockOutboxPostgre) Add(ctx context.Context, tx Tx, e OutboxEvent) error {
+	args := m.Called(ctx, tx, e)
+	return args.Error(0)
+}
+
+func (m *MockOutboxPostgre) FindPending(ctx context.Context, tx Tx, now time.Time, limit int) ([]OutboxEvent, error) {
+	args := m.Called(ctx, tx, now, limit)
+	return args.Get(0).([]OutboxEvent), args.Error(1)
+}
+
+func (m *MockOutboxPostgre) Delete(ctx context.Context, tx Tx, id int64) error {
+	args := m.Called(ctx, tx, id)
+	return args.Error(0)
+}
+
+func (m *MockOutboxPostgre) Retry(ctx context.Context, tx Tx, e OutboxEvent) error {
+	args := m.Called(ctx, tx, e)
+	return args.Error(0)
+}
+
+func (m *MockOutboxPostgre) DeadLetter(ctx context.Context, tx Tx, e OutboxEvent, deadAt time.Time) error {
+	args := m.Called(ctx, tx, e, deadAt)
+	return args.Error(0)
+}
+
+type outboxPostgre struct {
+}
+
+func NewOutboxPostgre() OutboxRepository {
+	return &outboxPostgre{}
+}
+
+func (p *outboxPostgre) Add(ctx context.Context, tx Tx, e OutboxEvent) error {
+	SQL := "INSERT INTO outbox(post_id, slug, created_at, next_attempt_at) VALUES ($1, $2, $3, $4)"
+	if _, err := sqlTx(tx).ExecContext(ctx, SQL, e.PostID, e.Slug, e.CreatedAt, e.NextAttemptAt); err != nil {
+		return fmt.Errorf("failed to add outbox event of post with id: %d because %w", e.PostID, err)
+	}
+
+	return nil
+}
+
+// FindPending lock the due events with SKIP LOCKED, so several relays can run side by side,
+// dead lettered event is never picked again
+func (p *outboxPostgre) FindPending(ctx context.Context, tx Tx, now time.Time, limit int) ([]OutboxEvent, error) {
+	SQL := `SELECT event_id, post_id, slug, created_at, attempts, next_attempt_at, last_error FROM outbox
+		WHERE dead_at IS NULL AND next_attempt_at <= $1 ORDER BY event_id LIMIT $2 FOR UPDATE SKIP LOCKED`
+	rows, err := sqlTx(tx).QueryContext(ctx, SQL, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find pending outbox events because %w", err)
+	}
+	defer rows.Close()
+
+	var events []OutboxEvent
+	for rows.Next() {
+		var e OutboxEvent
+		if err := rows.Scan(&e.ID, &e.PostID, &e.Slug, &e.CreatedAt, &e.Attempts, &e.NextAttemptAt, &e.LastError); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox event because %w", err)
+		}
+		events = append(events, e)
+	}
+
+	return events, nil
+}
+
+// Delete remove the delivered event
+func (p *outboxPostgre) Delete(ctx context.Context, tx Tx, id int64) error {
+	SQL := "DELETE FROM outbox WHERE event_id = $1"
+	if _, err := sqlTx(tx).ExecContext(ctx, SQL, id); err != nil {
+		return fmt.Errorf("failed to delete outbox event with id: %d because %w", id, err)
+	}
+
+	return nil
+}
+
+// Retry store the failed attempt and when the event is tried again
+func (p *outboxPostgre) Retry(ctx context.Context, tx Tx, e OutboxEvent) error {
+	SQL := "UPDATE outbox SET attempts = $1, next_attempt_at = $2, last_error = $3 WHERE event_id = $4"
+	if _, err := sqlTx(tx).ExecContext(ctx, SQL, e.Attempts, e.NextAttemptAt, e.LastError, e.ID); err != nil {
+		return fmt.Errorf("failed to retry outbox event with id: %d because %w", e.ID, err)
+	}
+
+	return nil
+}
+
+// DeadLetter park the event that ran out of attempts, clearing dead_at put it back in the queue
+func (p *outboxPostgre) DeadLetter(ctx context.Context, tx Tx, e OutboxEvent, deadAt time.Time) error {
+	SQL := "UPDATE outbox SET attempts = $1, last_error = $2, dead_at = $3 WHERE event_id = $4"
+	if _, err := sqlTx(tx).ExecContext(ctx, SQL, e.Attempts, e.LastError, deadAt, e.ID); err != nil {
+		return fmt.Errorf("failed to dead letter outbox event with id: %d because %w", e.ID, err)
+	}
+
+	return nil
+}
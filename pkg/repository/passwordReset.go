@@ -0,0 +1,13 @@
+package repository
+
+import "time"
+
+// PasswordReset is the one time token of a forgotten password, only the hash of the token is stored
+type PasswordReset struct {
+	ID        int64      `json:"id"`
+	UserID    int64      `json:"user_id"`
+	TokenHash string     `json:"-"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+type passwordResetMemory struct {
+	Store *MemoryStore
+}
+
+func NewPasswordResetMemory(store *MemoryStore) PasswordResetRepository {
+	return &passwordResetMemory{
+		Store: store,
+	}
+}
+
+func (m *passwordResetMemory) Create(ctx context.Context, tx Tx, r PasswordReset) (PasswordReset, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	r.ID = m.Store.passwordResets.nextID()
+	m.Store.passwordResets.put(tx, r.ID, r)
+
+	return r, nil
+}
+
+// FindByHash doesn't lock the token, the store is meant for a single writer
+func (m *passwordResetMemory) FindByHash(ctx context.Context, tx Tx, hash string) (PasswordReset, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	for _, row := range m.Store.passwordResets.rows {
+		if r := row.(PasswordReset); r.TokenHash == hash {
+			return r, nil
+		}
+	}
+
+	return PasswordReset{}, ErrPasswordResetNotFound
+}
+
+// MarkUsed use up every token of the user, the other links that were sent can't be used once the password is reset
+func (m *passwordResetMemory) MarkUsed(ctx context.Context, tx Tx, userID int64, usedAt time.Time) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	for key, row := range m.Store.passwordResets.rows {
+		if r := row.(PasswordReset); r.UserID == userID && r.UsedAt == nil {
+			r.UsedAt = &usedAt
+			m.Store.passwordResets.put(tx, key, r)
+		}
+	}
+
+	return nil
+}
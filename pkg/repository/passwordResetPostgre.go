@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockPasswordResetPostgre struct {
+	mock.Mock
+}
+
+func (m *MockPasswordResetPostgre) Create(ctx context.Context, tx Tx, r PasswordReset) (PasswordReset, error) {
+	args := m.Called(ctx, tx, r)
+	return args.Get(0).(PasswordReset), args.Error(1)
+}
+
+func (m *MockPasswordResetPostgre) FindByHash(ctx context.Context, tx Tx, hash string) (PasswordReset, error) {
+	args := m.Called(ctx, tx, hash)
+	return args.Get(0).(PasswordReset), args.Error(1)
+}
+
+func (m *MockPasswordResetPostgre) MarkUsed(ctx context.Context, tx Tx, userID int64, usedAt time.Time) error {
+	args := m.Called(ctx, tx, userID, usedAt)
+	return args.Error(0)
+}
+
+type passwordResetPostgre struct {
+}
+
+func NewPasswordResetPostgre() PasswordResetRepository {
+	return &passwordResetPostgre{}
+}
+
+func (p *passwordResetPostgre) Create(ctx context.Context, tx Tx, r PasswordReset) (PasswordReset, error) {
+	SQL := `INSERT INTO password_resets(user_id, token_hash, created_at, expires_at)
+		VALUES ($1, $2, $3, $4) RETURNING password_reset_id`
+	if err := sqlTx(tx).QueryRowContext(ctx, SQL, r.UserID, r.TokenHash, r.CreatedAt, r.ExpiresAt).Scan(&r.ID); err != nil {
+		return PasswordReset{}, fmt.Errorf("failed to create password reset of user with id: %d because %w", r.UserID, err)
+	}
+
+	return r, nil
+}
+
+// FindByHash lock the token, so the same token consumed twice at once is only used once
+func (p *passwordResetPostgre) FindByHash(ctx context.Context, tx Tx, hash string) (PasswordReset, error) {
+	SQL := `SELECT password_reset_id, user_id, token_hash, created_at, expires_at, used_at
+		FROM password_resets WHERE token_hash = $1 FOR UPDATE`
+
+	var r PasswordReset
+	err := sqlTx(tx).QueryRowContext(ctx, SQL, hash).Scan(&r.ID, &r.UserID, &r.TokenHash, &r.CreatedAt, &r.ExpiresAt, &r.UsedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return PasswordReset{}, ErrPasswordResetNotFound
+	}
+	if err != nil {
+		return PasswordReset{}, fmt.Errorf("failed to find password reset because %w", err)
+	}
+
+	return r, nil
+}
+
+// MarkUsed use up every token of the user, the other links that were sent can't be used once the password is reset
+func (p *passwordResetPostgre) MarkUsed(ctx context.Context, tx Tx, userID int64, usedAt time.Time) error {
+	SQL := "UPDATE password_resets SET used_at = $1 WHERE user_id = $2 AND used_at IS NULL"
+	if _, err := sqlTx(tx).ExecContext(ctx, SQL, usedAt, userID); err != nil {
+		return fmt.Errorf("failed to mark password resets of user with id: %d as used because %w", userID, err)
+	}
+
+	return nil
+}
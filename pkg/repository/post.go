@@ -1,11 +1,107 @@
 package repository
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
+
+// Post status, only published post is indexed, cached and visible to everyone
+const (
+	StatusDraft     = "draft"
+	StatusPublished = "published"
+	StatusArchived  = "archived"
+)
 
 type PostData struct {
 	ID        int64     `json:"id"`
 	Title     string    `json:"title"`
+	Slug      string    `json:"slug"`
 	ShortDesc string    `json:"short_desc"`
 	Content   string    `json:"content"`
 	CreatedAt time.Time `json:"created_at"`
+	Status    string    `json:"status"`
+	// PublishAt is when a scheduled draft will be published by the scheduler
+	PublishAt *time.Time `json:"publish_at,omitempty"`
+	// DeletedAt is set while the post is in the trash, see Purge
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
+	Author     Author     `json:"author"`
+	Tags       []string   `json:"tags"`
+	Categories []string   `json:"categories"`
+	// CommentCount is the number of comments including the replies
+	CommentCount int64 `json:"comment_count"`
+	// FavouriteCount and FavouritedByMe depend on the viewer, they are filled before the post is returned
+	FavouriteCount int64 `json:"favourite_count"`
+	FavouritedByMe bool  `json:"favourited_by_me"`
+}
+
+// Suggestion is a published post title that complete what the user is typing
+type Suggestion struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+	Slug  string `json:"slug"`
+}
+
+// SearchHit is a post found by the search with its score and the highlighted fragments of the field that matched,
+// the matched words are wrapped in <mark></mark>
+type SearchHit struct {
+	PostData
+	Score      float64             `json:"score"`
+	Highlights map[string][]string `json:"highlights,omitempty"`
+}
+
+// SearchResult is a page of the search, Total count every post that matched
+// and Facets break them down so the result can be narrowed further.
+// DidYouMean is the query with its misspelled words corrected, it's only suggested when nothing matched
+type SearchResult struct {
+	Total      int64       `json:"total"`
+	Hits       []SearchHit `json:"hits"`
+	Facets     *Facets     `json:"facets,omitempty"`
+	DidYouMean string      `json:"did_you_mean,omitempty"`
+}
+
+// FacetBucket is how many of the matched posts share the key
+type FacetBucket struct {
+	Key   string `json:"key"`
+	Count int64  `json:"count"`
+}
+
+// Facets count the matched posts per tag and author, the most common first,
+// and per month they were created in, keyed by YYYY-MM from the oldest
+type Facets struct {
+	Tags    []FacetBucket `json:"tags"`
+	Authors []FacetBucket `json:"authors"`
+	Months  []FacetBucket `json:"months"`
+}
+
+// FacetSize is how many tag and author bucket is counted
+const FacetSize = 10
+
+// PostFilter narrow down the list and search of post, empty field isn't filtered
+// except Status which default to published
+type PostFilter struct {
+	Tag      string `json:"tag,omitempty"`
+	Category string `json:"category,omitempty"`
+	// Author is the username of the author
+	Author string `json:"author,omitempty"`
+	// From and To limit when the post was created, From is inclusive and To is exclusive
+	From   *time.Time `json:"from,omitempty"`
+	To     *time.Time `json:"to,omitempty"`
+	Status string     `json:"status,omitempty"`
+}
+
+// PublishedOnly report whether the filter only match published post
+func (f PostFilter) PublishedOnly() bool {
+	return f.Status == "" || f.Status == StatusPublished
+}
+
+// Author is the public part of the user who wrote the post
+type Author struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	Name     string `json:"name"`
+}
+
+// MarshalBinary let the post be stored in redis as json
+func (pd PostData) MarshalBinary() ([]byte, error) {
+	return json.Marshal(pd)
 }
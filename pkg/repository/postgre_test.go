@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakePostgres is a database/sql driver that record the statements of the postgres repositories
+// and answer them with its results in order, there's no postgres to run the repositories against
+type fakePostgres struct {
+	results    []fakeResult
+	statements []fakeStatement
+}
+
+type fakeResult struct {
+	columns      []string
+	rows         [][]driver.Value
+	rowsAffected int64
+}
+
+type fakeStatement struct {
+	query string
+	args  []driver.Value
+}
+
+// begin return a transaction of the fake driver that answer with the results
+func (f *fakePostgres) begin(t *testing.T, results ...fakeResult) Tx {
+	f.results = results
+
+	tx, err := sql.OpenDB(f).Begin()
+	assert.NoError(t, err)
+
+	return tx
+}
+
+func (f *fakePostgres) Connect(ctx context.Context) (driver.Conn, error) { return f, nil }
+func (f *fakePostgres) Driver() driver.Driver                            { return nil }
+func (f *fakePostgres) Close() error                                     { return nil }
+func (f *fakePostgres) Begin() (driver.Tx, error)                        { return f, nil }
+func (f *fakePostgres) Commit() error                                    { return nil }
+func (f *fakePostgres) Rollback() error                                  { return nil }
+
+func (f *fakePostgres) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("the fake postgres doesn't prepare statements")
+}
+
+func (f *fakePostgres) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	result, err := f.next(query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fakeRows{result: result}, nil
+}
+
+func (f *fakePostgres) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	result, err := f.next(query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return driver.RowsAffected(result.rowsAffected), nil
+}
+
+func (f *fakePostgres) next(query string, args []driver.NamedValue) (fakeResult, error) {
+	values := make([]driver.Value, 0, len(args))
+	for _, arg := range args {
+		values = append(values, arg.Value)
+	}
+	f.statements = append(f.statements, fakeStatement{query: query, args: values})
+
+	if len(f.results) == 0 {
+		return fakeResult{}, errors.New("the fake postgres has no result left")
+	}
+	result := f.results[0]
+	f.results = f.results[1:]
+
+	return result, nil
+}
+
+type fakeRows struct {
+	result fakeResult
+	next   int
+}
+
+func (r *fakeRows) Columns() []string { return r.result.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.result.rows) {
+		return io.EOF
+	}
+	copy(dest, r.result.rows[r.next])
+	r.next++
+
+	return nil
+}
+
+var (
+	placeholder  = regexp.MustCompile(`\$(\d+)`)
+	mysqlUserRef = regexp.MustCompile(`(?i)\b(FROM|INTO|UPDATE)\s+user\b`)
+)
+
+// assertPostgres check the statement is written for postgres, it query the users table
+// and number its placeholders from $1 to one for each argument
+func assertPostgres(t *testing.T, s fakeStatement) {
+	assert.NotContains(t, s.query, "?", s.query)
+	assert.False(t, mysqlUserRef.MatchString(s.query), s.query)
+
+	highest := 0
+	for _, match := range placeholder.FindAllStringSubmatch(s.query, -1) {
+		n, err := strconv.Atoi(match[1])
+		assert.NoError(t, err)
+		if n > highest {
+			highest = n
+		}
+	}
+	assert.Equal(t, len(s.args), highest, s.query)
+}
+
+var userColumns = []string{"user_id", "email", "username", "name", "password", "role", "suspended_at", "verified_at"}
+
+func TestUserPostgre(t *testing.T) {
+	ctx := context.Background()
+	repo := NewUserPostgreRepository()
+	user := User{Email: "izzan@example.com", Username: "izzan", Name: "Izzan", Password: "hash"}
+
+	t.Run("Create", func(t *testing.T) {
+		f := &fakePostgres{}
+		tx := f.begin(t, fakeResult{columns: []string{"user_id", "role"}, rows: [][]driver.Value{{int64(1), RoleAuthor}}})
+
+		created, err := repo.Create(ctx, tx, user)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), created.ID)
+		assert.Equal(t, RoleAuthor, created.Role)
+		assertPostgres(t, f.statements[0])
+		assert.Contains(t, f.statements[0].query, "RETURNING user_id")
+	})
+
+	t.Run("Login", func(t *testing.T) {
+		for _, login := range []func(ctx context.Context, tx Tx, s string) (User, error){repo.LoginByEmail, repo.LoginByUsername} {
+			f := &fakePostgres{}
+			tx := f.begin(t, fakeResult{columns: userColumns, rows: [][]driver.Value{{int64(1), user.Email, user.Username, user.Name, "hash", RoleAuthor, nil, nil}}})
+
+			found, err := login(ctx, tx, "izzan")
+			assert.NoError(t, err)
+			assert.Equal(t, int64(1), found.ID)
+			assert.Equal(t, "hash", found.Password)
+			assertPostgres(t, f.statements[0])
+		}
+	})
+
+	t.Run("Find By Username", func(t *testing.T) {
+		f := &fakePostgres{}
+		tx := f.begin(t, fakeResult{columns: userColumns[:4]})
+
+		_, err := repo.FindByUsername(ctx, tx, "unknown")
+		assert.ErrorIs(t, err, ErrUserNotFound)
+		assertPostgres(t, f.statements[0])
+	})
+
+	t.Run("Find By Email", func(t *testing.T) {
+		f := &fakePostgres{}
+		columns := []string{"user_id", "email", "username", "name", "role", "suspended_at", "verified_at"}
+		found := []driver.Value{int64(1), user.Email, user.Username, user.Name, RoleAuthor, nil, nil}
+		tx := f.begin(t, fakeResult{columns: columns, rows: [][]driver.Value{found}}, fakeResult{columns: columns})
+
+		got, err := repo.FindByEmail(ctx, tx, "Izzan@Example.com")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), got.ID)
+		assert.Empty(t, got.Password)
+
+		_, err = repo.FindByEmail(ctx, tx, "unknown@example.com")
+		assert.ErrorIs(t, err, ErrUserNotFound)
+
+		for _, s := range f.statements {
+			assertPostgres(t, s)
+		}
+	})
+
+	t.Run("Update Password", func(t *testing.T) {
+		f := &fakePostgres{}
+		tx := f.begin(t, fakeResult{rowsAffected: 1}, fakeResult{rowsAffected: 0})
+
+		_, err := repo.UpdatePassword(ctx, tx, User{Email: user.Email, Password: "hash"})
+		assert.NoError(t, err)
+		assert.Equal(t, []driver.Value{"hash", user.Email}, f.statements[0].args)
+
+		_, err = repo.UpdatePassword(ctx, tx, User{Email: "unknown@example.com", Password: "hash"})
+		assert.ErrorIs(t, err, ErrUserNotFound)
+
+		for _, s := range f.statements {
+			assertPostgres(t, s)
+		}
+	})
+
+	t.Run("Update And Delete", func(t *testing.T) {
+		f := &fakePostgres{}
+		tx := f.begin(t, fakeResult{rowsAffected: 1}, fakeResult{rowsAffected: 1})
+
+		_, err := repo.UpdateUser(ctx, tx, User{ID: 1, Email: user.Email, Username: user.Username, Name: user.Name})
+		assert.NoError(t, err)
+		assert.NoError(t, repo.Delete(ctx, tx, user))
+
+		for _, s := range f.statements {
+			assertPostgres(t, s)
+		}
+		assert.Equal(t, []driver.Value{user.Email, user.Username, user.Name, int64(1)}, f.statements[0].args)
+		assert.Contains(t, f.statements[0].query, "WHERE user_id = $4")
+		assert.True(t, strings.HasPrefix(f.statements[1].query, "DELETE FROM users"))
+	})
+
+	t.Run("Update Unknown User", func(t *testing.T) {
+		f := &fakePostgres{}
+		tx := f.begin(t, fakeResult{rowsAffected: 0})
+
+		_, err := repo.UpdateUser(ctx, tx, User{ID: 2, Email: user.Email, Username: user.Username, Name: user.Name})
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+}
@@ -0,0 +1,500 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type postingMemory struct {
+	Store *MemoryStore
+}
+
+func NewPostMemory(store *MemoryStore) Post {
+	return &postingMemory{
+		Store: store,
+	}
+}
+
+func (m *postingMemory) Create(ctx context.Context, tx Tx, pd PostData) (PostData, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if _, ok := m.Store.users.rows[pd.Author.ID]; !ok {
+		return pd, fmt.Errorf("failed to find author with id: %d because %w", pd.Author.ID, ErrUserNotFound)
+	}
+
+	for _, row := range m.Store.posts.rows {
+		if row.(PostData).Slug == pd.Slug {
+			return pd, fmt.Errorf("failed to created post: %v, because the slug is already used", pd)
+		}
+	}
+
+	pd.ID = m.Store.posts.nextID()
+	row := pd
+	row.Author = Author{ID: pd.Author.ID}
+	row.Tags, row.Categories = nil, nil
+	m.Store.posts.put(tx, pd.ID, row)
+
+	pd.Author = m.Store.post(row).Author
+
+	return pd, nil
+}
+
+func (m *postingMemory) Update(ctx context.Context, tx Tx, pd PostData) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	post, ok := m.livePost(pd.ID)
+	if !ok {
+		return nil
+	}
+
+	post.Title = pd.Title
+	post.ShortDesc = pd.ShortDesc
+	post.Content = pd.Content
+	m.Store.posts.put(tx, post.ID, post)
+
+	return nil
+}
+
+// Delete move the post to the trash at pd.DeletedAt, it's only removed for good by Purge
+func (m *postingMemory) Delete(ctx context.Context, tx Tx, pd PostData) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	post, ok := m.livePost(pd.ID)
+	if !ok {
+		return fmt.Errorf("failed to delete post: %v because %w", pd, ErrPostNotFound)
+	}
+
+	post.DeletedAt = pd.DeletedAt
+	m.Store.posts.put(tx, post.ID, post)
+
+	return nil
+}
+
+// Undelete take the post out of the trash
+func (m *postingMemory) Undelete(ctx context.Context, tx Tx, id int64) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	post, ok := m.Store.posts.rows[id].(PostData)
+	if !ok || post.DeletedAt == nil {
+		return fmt.Errorf("failed to restore post with id: %d because %w", id, ErrPostNotFound)
+	}
+
+	post.DeletedAt = nil
+	m.Store.posts.put(tx, id, post)
+
+	return nil
+}
+
+// Purge remove for good up to limit posts that were deleted before the given time,
+// the comments, favourites, tags and revisions go along
+func (m *postingMemory) Purge(ctx context.Context, tx Tx, before time.Time, limit int) ([]int64, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	var ids []int64
+	for _, row := range m.Store.posts.rows {
+		post := row.(PostData)
+		if post.DeletedAt != nil && post.DeletedAt.Before(before) {
+			ids = append(ids, post.ID)
+		}
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+
+	for _, id := range ids {
+		m.Store.deletePost(tx, id)
+	}
+
+	return ids, nil
+}
+
+// UpdateStatus also clear the publishing schedule, a manual status change override the schedule
+// and a post published by the scheduler shouldn't be picked up again
+func (m *postingMemory) UpdateStatus(ctx context.Context, tx Tx, id int64, status string) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if post, ok := m.livePost(id); ok {
+		post.Status = status
+		post.PublishAt = nil
+		m.Store.posts.put(tx, id, post)
+	}
+
+	return nil
+}
+
+func (m *postingMemory) UpdatePublishAt(ctx context.Context, tx Tx, id int64, publishAt *time.Time) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if post, ok := m.livePost(id); ok {
+		post.PublishAt = publishAt
+		m.Store.posts.put(tx, id, post)
+	}
+
+	return nil
+}
+
+// FindDueScheduled return the drafts whose publishing time has come, the earliest first
+func (m *postingMemory) FindDueScheduled(ctx context.Context, tx Tx, now time.Time, limit int) ([]PostData, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	var posts []PostData
+	for _, post := range m.Store.livePosts() {
+		if post.Status == StatusDraft && post.PublishAt != nil && !post.PublishAt.After(now) {
+			posts = append(posts, post)
+		}
+	}
+
+	sort.Slice(posts, func(i, j int) bool {
+		return posts[i].PublishAt.Before(*posts[j].PublishAt)
+	})
+	if len(posts) > limit {
+		posts = posts[:limit]
+	}
+
+	return posts, nil
+}
+
+// UniqueSlug return the slug, or the slug with the first free "-n" suffix, that isn't used
+// as the current or an old slug of another post, id is 0 for a new post
+func (m *postingMemory) UniqueSlug(ctx context.Context, tx Tx, slug string, id int64) (string, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	taken := make(map[string]bool)
+	for _, row := range m.Store.posts.rows {
+		if post := row.(PostData); post.ID != id {
+			taken[post.Slug] = true
+		}
+	}
+	for key, row := range m.Store.slugs.rows {
+		if row.(int64) != id {
+			taken[key.(string)] = true
+		}
+	}
+
+	if !taken[slug] {
+		return slug, nil
+	}
+
+	for n := 2; ; n++ {
+		str := strings.Builder{}
+		str.WriteString(slug)
+		str.WriteString("-")
+		str.WriteString(strconv.Itoa(n))
+
+		if !taken[str.String()] {
+			return str.String(), nil
+		}
+	}
+}
+
+// ChangeSlug keep the old slug in the history so old link can be redirected,
+// the post can also take back one of its own old slug
+func (m *postingMemory) ChangeSlug(ctx context.Context, tx Tx, id int64, oldSlug string, newSlug string) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if postID, ok := m.Store.slugs.rows[newSlug].(int64); ok && postID == id {
+		m.Store.slugs.remove(tx, newSlug)
+	}
+
+	post, ok := m.Store.posts.rows[id].(PostData)
+	if !ok {
+		return nil
+	}
+	post.Slug = newSlug
+	m.Store.posts.put(tx, id, post)
+
+	if _, ok := m.Store.slugs.rows[oldSlug]; !ok {
+		m.Store.slugs.put(tx, oldSlug, id)
+	}
+
+	return nil
+}
+
+func (m *postingMemory) FindBySlug(ctx context.Context, tx Tx, slug string) (PostData, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	for _, post := range m.Store.livePosts() {
+		if post.Slug == slug {
+			return post, nil
+		}
+	}
+
+	return PostData{}, fmt.Errorf("failed to find post with slug: %s because %w", slug, ErrPostNotFound)
+}
+
+// FindSlugRedirect return the current slug of the post that used to have the old slug
+func (m *postingMemory) FindSlugRedirect(ctx context.Context, tx Tx, oldSlug string) (string, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	if id, ok := m.Store.slugs.rows[oldSlug].(int64); ok {
+		if post, ok := m.livePost(id); ok {
+			return post.Slug, nil
+		}
+	}
+
+	return "", fmt.Errorf("failed to find old slug: %s because %w", oldSlug, ErrPostNotFound)
+}
+
+// SetTags replace the tags of the post, unknown tag is created
+func (m *postingMemory) SetTags(ctx context.Context, tx Tx, id int64, tags []string) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	ids := []int64{}
+	for _, name := range tags {
+		tagID, ok := m.tagID(name)
+		if !ok {
+			tagID = m.Store.tags.nextID()
+			m.Store.tags.put(tx, tagID, Tag{ID: tagID, Name: name})
+		}
+		if !containsID(ids, tagID) {
+			ids = append(ids, tagID)
+		}
+	}
+	m.Store.postTags.put(tx, id, ids)
+
+	return nil
+}
+
+// SetCategories replace the categories of the post, every category must already exist
+func (m *postingMemory) SetCategories(ctx context.Context, tx Tx, id int64, categories []string) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	ids := []int64{}
+	for _, name := range categories {
+		categoryID, ok := m.categoryID(name)
+		if !ok {
+			return fmt.Errorf("failed to set categories: %v of post with id: %d because %w", categories, id, ErrCategoryNotFound)
+		}
+		if !containsID(ids, categoryID) {
+			ids = append(ids, categoryID)
+		}
+	}
+	m.Store.postCategories.put(tx, id, ids)
+
+	return nil
+}
+
+func (m *postingMemory) CreateRevision(ctx context.Context, tx Tx, r Revision) (Revision, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if _, ok := m.Store.posts.rows[r.PostID]; !ok {
+		return r, fmt.Errorf("failed to create revision of post with id: %d because %w", r.PostID, ErrPostNotFound)
+	}
+
+	r.ID = m.Store.revisions.nextID()
+	m.Store.revisions.put(tx, r.ID, r)
+
+	return r, nil
+}
+
+// FindRevisions return every revision of the post, the latest first
+func (m *postingMemory) FindRevisions(ctx context.Context, tx Tx, postID int64) ([]Revision, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	revisions := []Revision{}
+	for _, row := range m.Store.revisions.rows {
+		if revision := row.(Revision); revision.PostID == postID {
+			revisions = append(revisions, revision)
+		}
+	}
+
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].ID > revisions[j].ID
+	})
+
+	return revisions, nil
+}
+
+func (m *postingMemory) FindRevision(ctx context.Context, tx Tx, postID int64, id int64) (Revision, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	revision, ok := m.Store.revisions.rows[id].(Revision)
+	if !ok || revision.PostID != postID {
+		return Revision{}, fmt.Errorf("failed to find revision with id: %d because %w", id, ErrRevisionNotFound)
+	}
+
+	return revision, nil
+}
+
+// LockByID lock nothing, the memory store is meant for test and local development where the relay
+// and the favourites of a post don't run side by side
+func (m *postingMemory) LockByID(ctx context.Context, tx Tx, id int64) error {
+	return nil
+}
+
+func (m *postingMemory) FindByID(ctx context.Context, tx Tx, id int64) (PostData, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	post, ok := m.livePost(id)
+	if !ok {
+		return PostData{}, fmt.Errorf("failed to find post with id: %d because %w", id, ErrPostNotFound)
+	}
+
+	return m.Store.post(post), nil
+}
+
+// FindDeletedByID find the post in the trash
+func (m *postingMemory) FindDeletedByID(ctx context.Context, tx Tx, id int64) (PostData, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	post, ok := m.Store.posts.rows[id].(PostData)
+	if !ok || post.DeletedAt == nil {
+		return PostData{}, fmt.Errorf("failed to find deleted post with id: %d because %w", id, ErrPostNotFound)
+	}
+
+	return m.Store.post(post), nil
+}
+
+// FindTrash return the deleted posts of the author, the latest deleted first
+func (m *postingMemory) FindTrash(ctx context.Context, tx Tx, authorID int64, from int, size int) ([]PostData, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	var trash []PostData
+	for _, row := range m.Store.posts.rows {
+		if post := row.(PostData); post.Author.ID == authorID && post.DeletedAt != nil {
+			trash = append(trash, m.Store.post(post))
+		}
+	}
+
+	sort.Slice(trash, func(i, j int) bool {
+		return trash[i].DeletedAt.After(*trash[j].DeletedAt)
+	})
+
+	start, end := page(len(trash), from, size)
+	return append([]PostData{}, trash[start:end]...), nil
+}
+
+// FindByTitleContent search the posts with SearchPosts, the hits aren't highlighted
+func (m *postingMemory) FindByTitleContent(ctx context.Context, tx Tx, query SearchQuery, filter PostFilter, from int, size int) (SearchResult, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	return SearchPosts(m.Store.livePosts(), query, filter, from, size), nil
+}
+
+func (m *postingMemory) FindRecent(ctx context.Context, tx Tx, filter PostFilter, from int, size int) ([]PostData, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	return RecentPosts(m.Store.livePosts(), filter, from, size), nil
+}
+
+func (m *postingMemory) SuggestTitles(ctx context.Context, tx Tx, prefix string, size int) ([]Suggestion, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	return SuggestPosts(m.Store.livePosts(), prefix, size), nil
+}
+
+func (m *postingMemory) FindRelated(ctx context.Context, tx Tx, id int64, size int) ([]PostData, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	source, ok := m.Store.posts.rows[id].(PostData)
+	if !ok {
+		return []PostData{}, nil
+	}
+
+	return RelatedPosts(m.Store.post(source), m.Store.livePosts(), size), nil
+}
+
+// FindPublishedAfter page through every published post by id
+func (m *postingMemory) FindPublishedAfter(ctx context.Context, tx Tx, afterID int64, limit int) ([]PostData, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	var posts []PostData
+	for _, post := range m.Store.livePosts() {
+		if post.Status == StatusPublished && post.ID > afterID {
+			posts = append(posts, post)
+		}
+	}
+
+	sort.Slice(posts, func(i, j int) bool {
+		return posts[i].ID < posts[j].ID
+	})
+	if len(posts) > limit {
+		posts = posts[:limit]
+	}
+
+	return posts, nil
+}
+
+func (m *postingMemory) CountPublished(ctx context.Context, tx Tx) (int64, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	var count int64
+	for _, post := range m.Store.livePosts() {
+		if post.Status == StatusPublished {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// livePost return the row of the post unless it's in the trash, the store must be locked
+func (m *postingMemory) livePost(id int64) (PostData, bool) {
+	post, ok := m.Store.posts.rows[id].(PostData)
+	if !ok || post.DeletedAt != nil {
+		return PostData{}, false
+	}
+
+	return post, true
+}
+
+func (m *postingMemory) tagID(name string) (int64, bool) {
+	for _, row := range m.Store.tags.rows {
+		if tag := row.(Tag); tag.Name == name {
+			return tag.ID, true
+		}
+	}
+
+	return 0, false
+}
+
+func (m *postingMemory) categoryID(name string) (int64, bool) {
+	for _, row := range m.Store.categories.rows {
+		if category := row.(Category); category.Name == name {
+			return category.ID, true
+		}
+	}
+
+	return 0, false
+}
+
+func containsID(ids []int64, id int64) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
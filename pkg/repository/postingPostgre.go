@@ -4,7 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -12,33 +16,138 @@ type MockPostingPostgre struct {
 	mock.Mock
 }
 
-func (m *MockPostingPostgre) Create(ctx context.Context, tx *sql.Tx, pd PostData) (PostData, error) {
+func (m *MockPostingPostgre) Create(ctx context.Context, tx Tx, pd PostData) (PostData, error) {
 	args := m.Called(ctx, tx, pd)
 	return args.Get(0).(PostData), args.Error(1)
 }
 
-func (m *MockPostingPostgre) Update(ctx context.Context, tx *sql.Tx, pd PostData) error {
+func (m *MockPostingPostgre) Update(ctx context.Context, tx Tx, pd PostData) error {
 	args := m.Called(ctx, tx, pd)
 	return args.Error(0)
 }
 
-func (m *MockPostingPostgre) Delete(ctx context.Context, tx *sql.Tx, pd PostData) error {
+func (m *MockPostingPostgre) Delete(ctx context.Context, tx Tx, pd PostData) error {
 	args := m.Called(ctx, tx, pd)
 	return args.Error(0)
 }
 
-func (m *MockPostingPostgre) FindByID(ctx context.Context, tx *sql.Tx, id int64) (PostData, error) {
+func (m *MockPostingPostgre) Undelete(ctx context.Context, tx Tx, id int64) error {
+	args := m.Called(ctx, tx, id)
+	return args.Error(0)
+}
+
+func (m *MockPostingPostgre) Purge(ctx context.Context, tx Tx, before time.Time, limit int) ([]int64, error) {
+	args := m.Called(ctx, tx, before, limit)
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+func (m *MockPostingPostgre) FindDeletedByID(ctx context.Context, tx Tx, id int64) (PostData, error) {
 	args := m.Called(ctx, tx, id)
 	return args.Get(0).(PostData), args.Error(1)
 }
 
-func (m *MockPostingPostgre) FindByTitleContent(ctx context.Context, tx *sql.Tx, query string, from int, size int) ([]PostData, error) {
-	args := m.Called(ctx, tx, query, from, size)
+func (m *MockPostingPostgre) FindTrash(ctx context.Context, tx Tx, authorID int64, from int, size int) ([]PostData, error) {
+	args := m.Called(ctx, tx, authorID, from, size)
 	return args.Get(0).([]PostData), args.Error(1)
 }
 
-func (m *MockPostingPostgre) FindRecent(ctx context.Context, tx *sql.Tx, from int, size int) ([]PostData, error) {
-	args := m.Called(ctx, tx, from, size)
+func (m *MockPostingPostgre) UpdateStatus(ctx context.Context, tx Tx, id int64, status string) error {
+	args := m.Called(ctx, tx, id, status)
+	return args.Error(0)
+}
+
+func (m *MockPostingPostgre) UpdatePublishAt(ctx context.Context, tx Tx, id int64, publishAt *time.Time) error {
+	args := m.Called(ctx, tx, id, publishAt)
+	return args.Error(0)
+}
+
+func (m *MockPostingPostgre) FindDueScheduled(ctx context.Context, tx Tx, now time.Time, limit int) ([]PostData, error) {
+	args := m.Called(ctx, tx, now, limit)
+	return args.Get(0).([]PostData), args.Error(1)
+}
+
+func (m *MockPostingPostgre) SuggestTitles(ctx context.Context, tx Tx, prefix string, size int) ([]Suggestion, error) {
+	args := m.Called(ctx, tx, prefix, size)
+	return args.Get(0).([]Suggestion), args.Error(1)
+}
+
+func (m *MockPostingPostgre) FindRelated(ctx context.Context, tx Tx, id int64, size int) ([]PostData, error) {
+	args := m.Called(ctx, tx, id, size)
+	return args.Get(0).([]PostData), args.Error(1)
+}
+
+func (m *MockPostingPostgre) FindPublishedAfter(ctx context.Context, tx Tx, afterID int64, limit int) ([]PostData, error) {
+	args := m.Called(ctx, tx, afterID, limit)
+	return args.Get(0).([]PostData), args.Error(1)
+}
+
+func (m *MockPostingPostgre) CountPublished(ctx context.Context, tx Tx) (int64, error) {
+	args := m.Called(ctx, tx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockPostingPostgre) UniqueSlug(ctx context.Context, tx Tx, slug string, id int64) (string, error) {
+	args := m.Called(ctx, tx, slug, id)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockPostingPostgre) ChangeSlug(ctx context.Context, tx Tx, id int64, oldSlug string, newSlug string) error {
+	args := m.Called(ctx, tx, id, oldSlug, newSlug)
+	return args.Error(0)
+}
+
+func (m *MockPostingPostgre) FindBySlug(ctx context.Context, tx Tx, slug string) (PostData, error) {
+	args := m.Called(ctx, tx, slug)
+	return args.Get(0).(PostData), args.Error(1)
+}
+
+func (m *MockPostingPostgre) FindSlugRedirect(ctx context.Context, tx Tx, oldSlug string) (string, error) {
+	args := m.Called(ctx, tx, oldSlug)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockPostingPostgre) LockByID(ctx context.Context, tx Tx, id int64) error {
+	args := m.Called(ctx, tx, id)
+	return args.Error(0)
+}
+
+func (m *MockPostingPostgre) FindByID(ctx context.Context, tx Tx, id int64) (PostData, error) {
+	args := m.Called(ctx, tx, id)
+	return args.Get(0).(PostData), args.Error(1)
+}
+
+func (m *MockPostingPostgre) SetTags(ctx context.Context, tx Tx, id int64, tags []string) error {
+	args := m.Called(ctx, tx, id, tags)
+	return args.Error(0)
+}
+
+func (m *MockPostingPostgre) SetCategories(ctx context.Context, tx Tx, id int64, categories []string) error {
+	args := m.Called(ctx, tx, id, categories)
+	return args.Error(0)
+}
+
+func (m *MockPostingPostgre) CreateRevision(ctx context.Context, tx Tx, r Revision) (Revision, error) {
+	args := m.Called(ctx, tx, r)
+	return args.Get(0).(Revision), args.Error(1)
+}
+
+func (m *MockPostingPostgre) FindRevisions(ctx context.Context, tx Tx, postID int64) ([]Revision, error) {
+	args := m.Called(ctx, tx, postID)
+	return args.Get(0).([]Revision), args.Error(1)
+}
+
+func (m *MockPostingPostgre) FindRevision(ctx context.Context, tx Tx, postID int64, id int64) (Revision, error) {
+	args := m.Called(ctx, tx, postID, id)
+	return args.Get(0).(Revision), args.Error(1)
+}
+
+func (m *MockPostingPostgre) FindByTitleContent(ctx context.Context, tx Tx, query SearchQuery, filter PostFilter, from int, size int) (SearchResult, error) {
+	args := m.Called(ctx, tx, query, filter, from, size)
+	return args.Get(0).(SearchResult), args.Error(1)
+}
+
+func (m *MockPostingPostgre) FindRecent(ctx context.Context, tx Tx, filter PostFilter, from int, size int) ([]PostData, error) {
+	args := m.Called(ctx, tx, filter, from, size)
 	return args.Get(0).([]PostData), args.Error(1)
 }
 
@@ -49,26 +158,25 @@ func NewPostgre() Post {
 	return &postingPostgre{}
 }
 
-func (p *postingPostgre) Create(ctx context.Context, tx *sql.Tx, pd PostData) (PostData, error) {
-	SQL := "INSERT INTO post(title, short_desc, content) VALUES (?, ?, ?)"
-	result, err := tx.ExecContext(ctx, SQL, pd.Title, pd.ShortDesc, pd.Content, pd.CreatedAt)
-	if err != nil {
+func (p *postingPostgre) Create(ctx context.Context, tx Tx, pd PostData) (PostData, error) {
+	SQL := "INSERT INTO posts(title, slug, short_desc, content, created_at, status, publish_at, author_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING post_id"
+	row := sqlTx(tx).QueryRowContext(ctx, SQL, pd.Title, pd.Slug, pd.ShortDesc, pd.Content, pd.CreatedAt, pd.Status, pd.PublishAt, pd.Author.ID)
+	if err := row.Scan(&pd.ID); err != nil {
 		return pd, fmt.Errorf("failed to created post: %v, because %w", pd, err)
 	}
 
-	id, err := result.LastInsertId()
+	author, err := p.findAuthor(ctx, tx, pd.Author.ID)
 	if err != nil {
-		return pd, fmt.Errorf("failed post cannot be found: %v, because %w", pd, err)
+		return pd, err
 	}
-
-	pd.ID = id
+	pd.Author = author
 
 	return pd, nil
 }
 
-func (p *postingPostgre) Update(ctx context.Context, tx *sql.Tx, pd PostData) error {
-	SQL := "UPDATE post SET title = ?, short_desc = ?, content = ? WHERE id = ?"
-	_, err := tx.ExecContext(ctx, SQL, pd.Title, pd.ShortDesc, pd.Content, pd.ID)
+func (p *postingPostgre) Update(ctx context.Context, tx Tx, pd PostData) error {
+	SQL := "UPDATE posts SET title = $1, short_desc = $2, content = $3 WHERE post_id = $4 AND deleted_at IS NULL"
+	_, err := sqlTx(tx).ExecContext(ctx, SQL, pd.Title, pd.ShortDesc, pd.Content, pd.ID)
 	if err != nil {
 		return fmt.Errorf("failed to update post: %v, because %w", pd, err)
 	}
@@ -76,62 +184,723 @@ func (p *postingPostgre) Update(ctx context.Context, tx *sql.Tx, pd PostData) er
 	return nil
 }
 
-func (p *postingPostgre) Delete(ctx context.Context, tx *sql.Tx, pd PostData) error {
-	SQL := "DELETE FROM post WHERE id = ?"
-	_, err := tx.ExecContext(ctx, SQL, pd.ID)
+// Delete move the post to the trash at pd.DeletedAt, it's only removed for good by Purge
+func (p *postingPostgre) Delete(ctx context.Context, tx Tx, pd PostData) error {
+	SQL := "UPDATE posts SET deleted_at = $1 WHERE post_id = $2 AND deleted_at IS NULL"
+	result, err := sqlTx(tx).ExecContext(ctx, SQL, pd.DeletedAt, pd.ID)
 	if err != nil {
 		return fmt.Errorf("failed to delete post: %v because %w", pd, err)
 	}
 
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("failed to delete post: %v because %w", pd, ErrPostNotFound)
+	}
+
+	return nil
+}
+
+// Undelete take the post out of the trash
+func (p *postingPostgre) Undelete(ctx context.Context, tx Tx, id int64) error {
+	SQL := "UPDATE posts SET deleted_at = NULL WHERE post_id = $1 AND deleted_at IS NOT NULL"
+	result, err := sqlTx(tx).ExecContext(ctx, SQL, id)
+	if err != nil {
+		return fmt.Errorf("failed to restore post with id: %d because %w", id, err)
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("failed to restore post with id: %d because %w", id, ErrPostNotFound)
+	}
+
+	return nil
+}
+
+// Purge remove for good up to limit posts that were deleted before the given time,
+// the comments, favourites, tags and revisions go along through the foreign keys
+func (p *postingPostgre) Purge(ctx context.Context, tx Tx, before time.Time, limit int) ([]int64, error) {
+	SQL := `DELETE FROM posts WHERE post_id IN (
+			SELECT post_id FROM posts WHERE deleted_at IS NOT NULL AND deleted_at < $1 LIMIT $2 FOR UPDATE SKIP LOCKED
+		) RETURNING post_id`
+	rows, err := sqlTx(tx).QueryContext(ctx, SQL, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to purge posts deleted before: %v because %w", before, err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan purged post because %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// UpdateStatus also clear the publishing schedule, a manual status change override the schedule
+// and a post published by the scheduler shouldn't be picked up again
+func (p *postingPostgre) UpdateStatus(ctx context.Context, tx Tx, id int64, status string) error {
+	SQL := "UPDATE posts SET status = $1, publish_at = NULL WHERE post_id = $2 AND deleted_at IS NULL"
+	_, err := sqlTx(tx).ExecContext(ctx, SQL, status, id)
+	if err != nil {
+		return fmt.Errorf("failed to update status of post with id: %d to %s because %w", id, status, err)
+	}
+
 	return nil
 }
 
-func (p *postingPostgre) FindByID(ctx context.Context, tx *sql.Tx, id int64) (PostData, error) {
-	SQL := "SELECT title, short_desc, content, created_at FROM post WHERE id = ?"
-	rows, err := tx.QueryContext(ctx, SQL, id)
+func (p *postingPostgre) UpdatePublishAt(ctx context.Context, tx Tx, id int64, publishAt *time.Time) error {
+	SQL := "UPDATE posts SET publish_at = $1 WHERE post_id = $2 AND deleted_at IS NULL"
+	_, err := sqlTx(tx).ExecContext(ctx, SQL, publishAt, id)
+	if err != nil {
+		return fmt.Errorf("failed to update publish time of post with id: %d because %w", id, err)
+	}
+
+	return nil
+}
+
+// FindDueScheduled lock the due drafts with SKIP LOCKED, so when several replicas run the scheduler
+// each post is only picked by one of them until the transaction end
+func (p *postingPostgre) FindDueScheduled(ctx context.Context, tx Tx, now time.Time, limit int) ([]PostData, error) {
+	condition := "WHERE p.deleted_at IS NULL AND p.status = 'draft' AND p.publish_at IS NOT NULL AND p.publish_at <= $1"
+	SQL := selectPost + " " + condition + " ORDER BY p.publish_at LIMIT $2 FOR UPDATE OF p SKIP LOCKED"
+	rows, err := sqlTx(tx).QueryContext(ctx, SQL, now, limit)
+	if err != nil {
+		return []PostData{}, fmt.Errorf("failed to find scheduled posts because %w", err)
+	}
+	defer rows.Close()
+
+	var posts []PostData
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan scheduled post because %w", err)
+		}
+		posts = append(posts, post)
+	}
+
+	return posts, nil
+}
+
+// UniqueSlug return the slug, or the slug with the first free "-n" suffix, that isn't used
+// as the current or an old slug of another post, id is 0 for a new post
+func (p *postingPostgre) UniqueSlug(ctx context.Context, tx Tx, slug string, id int64) (string, error) {
+	SQL := `SELECT slug FROM posts WHERE post_id <> $1 AND (slug = $2 OR slug LIKE $2 || '-%')
+		UNION SELECT slug FROM post_slugs WHERE post_id <> $1 AND (slug = $2 OR slug LIKE $2 || '-%')`
+	rows, err := sqlTx(tx).QueryContext(ctx, SQL, id, slug)
+	if err != nil {
+		return "", fmt.Errorf("failed to find slug: %s because %w", slug, err)
+	}
+	defer rows.Close()
+
+	taken := make(map[string]bool)
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return "", fmt.Errorf("failed to scan slug: %s because %w", slug, err)
+		}
+		taken[s] = true
+	}
+
+	if !taken[slug] {
+		return slug, nil
+	}
+
+	for n := 2; ; n++ {
+		str := strings.Builder{}
+		str.WriteString(slug)
+		str.WriteString("-")
+		str.WriteString(strconv.Itoa(n))
+
+		if !taken[str.String()] {
+			return str.String(), nil
+		}
+	}
+}
+
+// ChangeSlug keep the old slug in the history so old link can be redirected,
+// the post can also take back one of its own old slug
+func (p *postingPostgre) ChangeSlug(ctx context.Context, tx Tx, id int64, oldSlug string, newSlug string) error {
+	SQL := "DELETE FROM post_slugs WHERE slug = $1 AND post_id = $2"
+	if _, err := sqlTx(tx).ExecContext(ctx, SQL, newSlug, id); err != nil {
+		return fmt.Errorf("failed to reclaim slug: %s because %w", newSlug, err)
+	}
+
+	SQL = "UPDATE posts SET slug = $1 WHERE post_id = $2"
+	if _, err := sqlTx(tx).ExecContext(ctx, SQL, newSlug, id); err != nil {
+		return fmt.Errorf("failed to change slug of post with id: %d because %w", id, err)
+	}
+
+	SQL = "INSERT INTO post_slugs(slug, post_id) VALUES ($1, $2) ON CONFLICT (slug) DO NOTHING"
+	if _, err := sqlTx(tx).ExecContext(ctx, SQL, oldSlug, id); err != nil {
+		return fmt.Errorf("failed to keep old slug: %s because %w", oldSlug, err)
+	}
+
+	return nil
+}
+
+func (p *postingPostgre) FindBySlug(ctx context.Context, tx Tx, slug string) (PostData, error) {
+	SQL := selectPost + " WHERE p.slug = $1 AND p.deleted_at IS NULL"
+	rows, err := sqlTx(tx).QueryContext(ctx, SQL, slug)
+	if err != nil {
+		return PostData{}, fmt.Errorf("failed to find post with slug: %s because %w", slug, err)
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return post, fmt.Errorf("failed to scan post with slug: %s because %w", slug, err)
+		}
+		return post, nil
+	} else {
+		return PostData{}, fmt.Errorf("failed to find post with slug: %s because %w", slug, ErrPostNotFound)
+	}
+}
+
+// FindSlugRedirect return the current slug of the post that used to have the old slug
+func (p *postingPostgre) FindSlugRedirect(ctx context.Context, tx Tx, oldSlug string) (string, error) {
+	SQL := "SELECT p.slug FROM post_slugs s JOIN posts p ON p.post_id = s.post_id WHERE s.slug = $1 AND p.deleted_at IS NULL"
+	var slug string
+	if err := sqlTx(tx).QueryRowContext(ctx, SQL, oldSlug).Scan(&slug); err != nil {
+		if err == sql.ErrNoRows {
+			return "", fmt.Errorf("failed to find old slug: %s because %w", oldSlug, ErrPostNotFound)
+		}
+		return "", fmt.Errorf("failed to find old slug: %s because %w", oldSlug, err)
+	}
+
+	return slug, nil
+}
+
+// SetTags replace the tags of the post, unknown tag is created
+func (p *postingPostgre) SetTags(ctx context.Context, tx Tx, id int64, tags []string) error {
+	SQL := "DELETE FROM post_tags WHERE post_id = $1"
+	if _, err := sqlTx(tx).ExecContext(ctx, SQL, id); err != nil {
+		return fmt.Errorf("failed to clear tags of post with id: %d because %w", id, err)
+	}
+
+	if len(tags) == 0 {
+		return nil
+	}
+
+	SQL = "INSERT INTO tags(name) SELECT unnest($1::text[]) ON CONFLICT (name) DO NOTHING"
+	if _, err := sqlTx(tx).ExecContext(ctx, SQL, pq.Array(tags)); err != nil {
+		return fmt.Errorf("failed to create tags: %v because %w", tags, err)
+	}
+
+	SQL = "INSERT INTO post_tags(post_id, tag_id) SELECT $1, tag_id FROM tags WHERE name = ANY($2)"
+	if _, err := sqlTx(tx).ExecContext(ctx, SQL, id, pq.Array(tags)); err != nil {
+		return fmt.Errorf("failed to set tags: %v of post with id: %d because %w", tags, id, err)
+	}
+
+	return nil
+}
+
+// SetCategories replace the categories of the post, every category must already exist
+func (p *postingPostgre) SetCategories(ctx context.Context, tx Tx, id int64, categories []string) error {
+	SQL := "DELETE FROM post_categories WHERE post_id = $1"
+	if _, err := sqlTx(tx).ExecContext(ctx, SQL, id); err != nil {
+		return fmt.Errorf("failed to clear categories of post with id: %d because %w", id, err)
+	}
+
+	if len(categories) == 0 {
+		return nil
+	}
+
+	SQL = "INSERT INTO post_categories(post_id, category_id) SELECT $1, category_id FROM categories WHERE name = ANY($2)"
+	result, err := sqlTx(tx).ExecContext(ctx, SQL, id, pq.Array(categories))
+	if err != nil {
+		return fmt.Errorf("failed to set categories: %v of post with id: %d because %w", categories, id, err)
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n != int64(len(categories)) {
+		return fmt.Errorf("failed to set categories: %v of post with id: %d because %w", categories, id, ErrCategoryNotFound)
+	}
+
+	return nil
+}
+
+func (p *postingPostgre) CreateRevision(ctx context.Context, tx Tx, r Revision) (Revision, error) {
+	SQL := `INSERT INTO post_revisions(post_id, editor_id, created_at, title, short_desc, content, tags, categories)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING revision_id`
+	err := sqlTx(tx).QueryRowContext(ctx, SQL, r.PostID, r.EditorID, r.CreatedAt, r.Title, r.ShortDesc, r.Content,
+		pq.Array(r.Tags), pq.Array(r.Categories)).Scan(&r.ID)
+	if err != nil {
+		return r, fmt.Errorf("failed to create revision of post with id: %d because %w", r.PostID, err)
+	}
+
+	return r, nil
+}
+
+// FindRevisions return every revision of the post, the latest first
+func (p *postingPostgre) FindRevisions(ctx context.Context, tx Tx, postID int64) ([]Revision, error) {
+	SQL := selectRevision + " WHERE post_id = $1 ORDER BY revision_id DESC"
+	rows, err := sqlTx(tx).QueryContext(ctx, SQL, postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find revisions of post with id: %d because %w", postID, err)
+	}
+	defer rows.Close()
+
+	revisions := []Revision{}
+	for rows.Next() {
+		revision, err := scanRevision(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan revision because %w", err)
+		}
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, nil
+}
+
+func (p *postingPostgre) FindRevision(ctx context.Context, tx Tx, postID int64, id int64) (Revision, error) {
+	SQL := selectRevision + " WHERE post_id = $1 AND revision_id = $2"
+	rows, err := sqlTx(tx).QueryContext(ctx, SQL, postID, id)
+	if err != nil {
+		return Revision{}, fmt.Errorf("failed to find revision with id: %d because %w", id, err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return Revision{}, fmt.Errorf("failed to find revision with id: %d because %w", id, ErrRevisionNotFound)
+	}
+
+	revision, err := scanRevision(rows)
+	if err != nil {
+		return revision, fmt.Errorf("failed to scan revision with id: %d because %w", id, err)
+	}
+
+	return revision, nil
+}
+
+const selectRevision = `SELECT revision_id, post_id, editor_id, created_at, title, short_desc, content, tags, categories FROM post_revisions`
+
+func scanRevision(rows *sql.Rows) (Revision, error) {
+	var (
+		revision Revision
+		editorID sql.NullInt64
+	)
+	err := rows.Scan(&revision.ID, &revision.PostID, &editorID, &revision.CreatedAt, &revision.Title, &revision.ShortDesc, &revision.Content,
+		pq.Array(&revision.Tags), pq.Array(&revision.Categories))
+	if editorID.Valid {
+		revision.EditorID = &editorID.Int64
+	}
+	return revision, err
+}
+
+// selectPost joins the author so every post that leaves the repository carries its owner,
+// tags and categories are aggregated into arrays and the comments are counted
+const postColumns = `SELECT p.post_id, p.title, p.slug, p.short_desc, p.content, p.created_at, p.status, p.publish_at, p.deleted_at, u.user_id, u.username, u.name,
+		ARRAY(SELECT t.name FROM post_tags pt JOIN tags t ON t.tag_id = pt.tag_id WHERE pt.post_id = p.post_id ORDER BY t.name),
+		ARRAY(SELECT c.name FROM post_categories pc JOIN categories c ON c.category_id = pc.category_id WHERE pc.post_id = p.post_id ORDER BY c.name),
+		(SELECT COUNT(*) FROM comments cm WHERE cm.post_id = p.post_id)`
+
+const fromPost = `FROM posts p JOIN users u ON u.user_id = p.author_id`
+
+// selectPost select every column scanned by scanPost
+const selectPost = postColumns + "\n\t" + fromPost
+
+// scanPost scan the columns of selectPost, extra is scanned from the column selected after them
+func scanPost(rows *sql.Rows, extra ...interface{}) (PostData, error) {
+	var (
+		post      PostData
+		publishAt sql.NullTime
+		deletedAt sql.NullTime
+	)
+	dest := []interface{}{&post.ID, &post.Title, &post.Slug, &post.ShortDesc, &post.Content, &post.CreatedAt, &post.Status, &publishAt, &deletedAt,
+		&post.Author.ID, &post.Author.Username, &post.Author.Name, pq.Array(&post.Tags), pq.Array(&post.Categories), &post.CommentCount}
+	err := rows.Scan(append(dest, extra...)...)
+	if publishAt.Valid {
+		post.PublishAt = &publishAt.Time
+	}
+	if deletedAt.Valid {
+		post.DeletedAt = &deletedAt.Time
+	}
+	return post, err
+}
+
+func (p *postingPostgre) findAuthor(ctx context.Context, tx Tx, id int64) (Author, error) {
+	SQL := "SELECT user_id, username, name FROM users WHERE user_id = $1"
+	var author Author
+	if err := sqlTx(tx).QueryRowContext(ctx, SQL, id).Scan(&author.ID, &author.Username, &author.Name); err != nil {
+		return author, fmt.Errorf("failed to find author with id: %d because %w", id, err)
+	}
+
+	return author, nil
+}
+
+// LockByID lock the row of the post FOR UPDATE, the statements after it in a read committed transaction
+// see the changes committed while it waited. A purged post has no row and nothing to lock
+func (p *postingPostgre) LockByID(ctx context.Context, tx Tx, id int64) error {
+	SQL := "SELECT post_id FROM posts WHERE post_id = $1 FOR UPDATE"
+	var locked int64
+	err := sqlTx(tx).QueryRowContext(ctx, SQL, id).Scan(&locked)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to lock post with id: %d because %w", id, err)
+	}
+
+	return nil
+}
+
+func (p *postingPostgre) FindByID(ctx context.Context, tx Tx, id int64) (PostData, error) {
+	SQL := selectPost + " WHERE p.post_id = $1 AND p.deleted_at IS NULL"
+	rows, err := sqlTx(tx).QueryContext(ctx, SQL, id)
 	if err != nil {
 		return PostData{}, fmt.Errorf("failed to find post with id: %d because %w", id, err)
 	}
 	defer rows.Close()
 
-	var post PostData
 	if rows.Next() {
-		if err := rows.Scan(&post.ID, &post.Title, &post.ShortDesc, &post.Content, &post.CreatedAt); err != nil {
+		post, err := scanPost(rows)
+		if err != nil {
 			return post, fmt.Errorf("failed to scan post with id: %d because %w", id, err)
 		}
 		return post, nil
 	} else {
-		return post, fmt.Errorf("failed to find post with id: %d because %w", id, err)
+		return PostData{}, fmt.Errorf("failed to find post with id: %d because %w", id, ErrPostNotFound)
+	}
+}
+
+// FindDeletedByID find the post in the trash
+func (p *postingPostgre) FindDeletedByID(ctx context.Context, tx Tx, id int64) (PostData, error) {
+	SQL := selectPost + " WHERE p.post_id = $1 AND p.deleted_at IS NOT NULL"
+	rows, err := sqlTx(tx).QueryContext(ctx, SQL, id)
+	if err != nil {
+		return PostData{}, fmt.Errorf("failed to find deleted post with id: %d because %w", id, err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return PostData{}, fmt.Errorf("failed to find deleted post with id: %d because %w", id, ErrPostNotFound)
+	}
+
+	post, err := scanPost(rows)
+	if err != nil {
+		return post, fmt.Errorf("failed to scan deleted post with id: %d because %w", id, err)
+	}
+
+	return post, nil
+}
+
+// FindTrash return the deleted posts of the author, the latest deleted first
+func (p *postingPostgre) FindTrash(ctx context.Context, tx Tx, authorID int64, from int, size int) ([]PostData, error) {
+	SQL := selectPost + " WHERE p.author_id = $1 AND p.deleted_at IS NOT NULL ORDER BY p.deleted_at DESC LIMIT $2 OFFSET $3"
+	rows, err := sqlTx(tx).QueryContext(ctx, SQL, authorID, size, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find deleted posts of author: %d because %w", authorID, err)
+	}
+	defer rows.Close()
+
+	posts := []PostData{}
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan deleted post because %w", err)
+		}
+		posts = append(posts, post)
+	}
+
+	return posts, nil
+}
+
+// filterCondition return the sql condition for the filter, the placeholder continue from the given args,
+// only published post is matched unless the filter ask for another status
+func filterCondition(filter PostFilter, args []interface{}) (string, []interface{}) {
+	var condition strings.Builder
+
+	if filter.Tag != "" {
+		args = append(args, filter.Tag)
+		condition.WriteString(" AND EXISTS (SELECT 1 FROM post_tags pt JOIN tags t ON t.tag_id = pt.tag_id")
+		condition.WriteString(" WHERE pt.post_id = p.post_id AND t.name = $" + strconv.Itoa(len(args)) + ")")
+	}
+
+	if filter.Category != "" {
+		args = append(args, filter.Category)
+		condition.WriteString(" AND EXISTS (SELECT 1 FROM post_categories pc JOIN categories c ON c.category_id = pc.category_id")
+		condition.WriteString(" WHERE pc.post_id = p.post_id AND c.name = $" + strconv.Itoa(len(args)) + ")")
+	}
+
+	if filter.Author != "" {
+		args = append(args, filter.Author)
+		condition.WriteString(" AND u.username = $" + strconv.Itoa(len(args)))
+	}
+
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		condition.WriteString(" AND p.created_at >= $" + strconv.Itoa(len(args)))
 	}
+
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		condition.WriteString(" AND p.created_at < $" + strconv.Itoa(len(args)))
+	}
+
+	status := filter.Status
+	if status == "" {
+		status = StatusPublished
+	}
+	args = append(args, status)
+	condition.WriteString(" AND p.status = $" + strconv.Itoa(len(args)))
+
+	return condition.String(), args
 }
 
-func (p *postingPostgre) FindByTitleContent(ctx context.Context, tx *sql.Tx, query string, from int, size int) ([]PostData, error) {
+// fragmentDelimiter split the content headline into its fragments, it's a control character
+// so it doesn't show up in the content itself
+const fragmentDelimiter = "\x1f"
+
+// headlineOptions mark the matched words like the elasticsearch highlight, the title is highlighted whole
+// and the content is cut into a few fragments around the matches
+const (
+	titleHeadline   = "StartSel=<mark>, StopSel=</mark>, HighlightAll=true"
+	contentHeadline = "StartSel=<mark>, StopSel=</mark>, MaxFragments=3, MaxWords=25, MinWords=10, FragmentDelimiter=" + fragmentDelimiter
+)
+
+func (p *postingPostgre) FindByTitleContent(ctx context.Context, tx Tx, query SearchQuery, filter PostFilter, from int, size int) (SearchResult, error) {
 	// full text search postgres https://blog.crunchydata.com/blog/postgres-full-text-search-a-search-engine-in-a-database
-	selectFrom := "SELECT id, title, short_desc, content, created_at FROM posts"
-	condition := "WHERE ts_title_content @@ to_tsquery('english', '?')"
-	orderBy := "ORDER BY ts_rank(ts_title_content, to_tsquery('english', '?')) LIMIT ? OFFSET ? DESC"
-	SQL := selectFrom + " " + condition + " " + orderBy
-	rows, err := tx.QueryContext(ctx, SQL, query, query, size, from)
+	// $1 is the whole query, it rank and highlight the hits while each group of the query is matched on its own
+	matches, args := queryCondition(query, []interface{}{query.Websearch()})
+	filters, args := filterCondition(filter, args)
+	condition := "WHERE p.deleted_at IS NULL" + matches + filters
+
+	// the total is counted by the window before the limit is applied
+	n := len(args)
+	hitArgs := append(args[:n:n], titleHeadline, contentHeadline, size, from)
+	columns := postColumns + `,
+		ts_rank(p.ts_title_content, websearch_to_tsquery('english', $1)),
+		ts_headline('english', p.title, websearch_to_tsquery('english', $1), $` + strconv.Itoa(n+1) + `),
+		ts_headline('english', p.content, websearch_to_tsquery('english', $1), $` + strconv.Itoa(n+2) + `),
+		COUNT(*) OVER()`
+	orderBy := "ORDER BY ts_rank(p.ts_title_content, websearch_to_tsquery('english', $1)) DESC, p.created_at DESC"
+	limit := "LIMIT $" + strconv.Itoa(n+3) + " OFFSET $" + strconv.Itoa(n+4)
+	SQL := columns + " " + fromPost + " " + condition + " " + orderBy + " " + limit
+	rows, err := sqlTx(tx).QueryContext(ctx, SQL, hitArgs...)
 	if err != nil {
-		return []PostData{}, fmt.Errorf("failed to find post with keywords: %s because %w", query, err)
+		return SearchResult{}, fmt.Errorf("failed to find post with keywords: %s because %w", query.Text, err)
 	}
 	defer rows.Close()
 
-	var result []PostData
+	result := SearchResult{Hits: []SearchHit{}}
 	for rows.Next() {
-		var post PostData
-		if err := rows.Scan(&post.ID, &post.Title, &post.ShortDesc, &post.Content, &post.CreatedAt); err != nil {
-			return []PostData{}, fmt.Errorf("failed to find scan post with keywords: %s because %w", query, err)
+		var (
+			hit            SearchHit
+			title, content string
+		)
+		hit.PostData, err = scanPost(rows, &hit.Score, &title, &content, &result.Total)
+		if err != nil {
+			return SearchResult{}, fmt.Errorf("failed to find scan post with keywords: %s because %w", query.Text, err)
+		}
+
+		hit.Highlights = map[string][]string{}
+		// ts_headline return the text as it is when nothing in it matched
+		if strings.Contains(title, "<mark>") {
+			hit.Highlights["title"] = []string{title}
+		}
+		if strings.Contains(content, "<mark>") {
+			hit.Highlights["content"] = strings.Split(content, fragmentDelimiter)
+		}
+
+		result.Hits = append(result.Hits, hit)
+	}
+
+	result.Facets, err = p.facets(ctx, tx, condition, args)
+	if err != nil {
+		return SearchResult{}, fmt.Errorf("failed to count facets with keywords: %s because %w", query.Text, err)
+	}
+
+	if result.Total == 0 && !query.Empty() {
+		corrections, err := p.correctSpelling(ctx, tx, query.Words())
+		if err != nil {
+			return SearchResult{}, fmt.Errorf("failed to correct spelling of keywords: %s because %w", query.Text, err)
 		}
-		result = append(result, post)
+		result.DidYouMean = query.Corrected(corrections)
 	}
 
 	return result, nil
 }
 
-func (p *postingPostgre) FindRecent(ctx context.Context, tx *sql.Tx, from int, size int) ([]PostData, error) {
-	SQL := "SELECT id, title, short_desc, created_at content FROM post LIMIT ? OFFSET ?"
-	rows, err := tx.QueryContext(ctx, SQL, size, from)
+// queryVector is the tsvector a clause is matched against, by the field it's limited to
+var queryVector = map[string]string{
+	"":        "p.ts_title_content",
+	"title":   "to_tsvector('english', p.title)",
+	"content": "to_tsvector('english', p.content)",
+}
+
+// queryCondition return the sql condition matching every group of the query, the placeholder continue from the given args
+func queryCondition(query SearchQuery, args []interface{}) (string, []interface{}) {
+	var condition strings.Builder
+
+	for _, group := range query.Groups {
+		matches := make([]string, 0, len(group.Clauses))
+		for _, clause := range group.Clauses {
+			args = append(args, clause.Websearch(nil))
+			matches = append(matches, queryVector[clause.Field]+" @@ websearch_to_tsquery('english', $"+strconv.Itoa(len(args))+")")
+		}
+
+		if group.Exclude {
+			condition.WriteString(" AND NOT (")
+		} else {
+			condition.WriteString(" AND (")
+		}
+		condition.WriteString(strings.Join(matches, " OR ") + ")")
+	}
+
+	return condition.String(), args
+}
+
+// spellingCorrection select the word of the published posts most similar to each of the given words, the words are taken
+// from the title and short description since the content is too long to be split on every search that found nothing.
+// A word that is already used by a post isn't corrected
+const spellingCorrection = `WITH words AS (
+		SELECT DISTINCT unnest(tsvector_to_array(to_tsvector('simple', title || ' ' || short_desc))) AS word
+		FROM posts WHERE deleted_at IS NULL AND status = 'published'
+	)
+	SELECT DISTINCT ON (q.word) q.word, w.word FROM unnest($1::text[]) AS q(word) JOIN words w ON w.word % q.word
+	ORDER BY q.word, w.word = q.word DESC, similarity(w.word, q.word) DESC, w.word`
+
+// correctSpelling return the correction of the misspelled words, keyed by the word
+func (p *postingPostgre) correctSpelling(ctx context.Context, tx Tx, words []string) (map[string]string, error) {
+	rows, err := sqlTx(tx).QueryContext(ctx, spellingCorrection, pq.Array(words))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	corrections := map[string]string{}
+	for rows.Next() {
+		var word, correction string
+		if err := rows.Scan(&word, &correction); err != nil {
+			return nil, err
+		}
+		if word != correction {
+			corrections[word] = correction
+		}
+	}
+
+	return corrections, rows.Err()
+}
+
+// the facet queries group the posts matched by the condition the same way as the elasticsearch aggregations,
+// terms for the tags and authors and a monthly histogram of the creation date
+const (
+	tagFacet = `SELECT t.name, COUNT(*) ` + fromPost + `
+		JOIN post_tags pt ON pt.post_id = p.post_id JOIN tags t ON t.tag_id = pt.tag_id
+		%s GROUP BY t.name ORDER BY COUNT(*) DESC, t.name LIMIT %d`
+	authorFacet = `SELECT u.username, COUNT(*) ` + fromPost + `
+		%s GROUP BY u.username ORDER BY COUNT(*) DESC, u.username LIMIT %d`
+	monthFacet = `SELECT to_char(date_trunc('month', p.created_at), 'YYYY-MM') AS month, COUNT(*) ` + fromPost + `
+		%s GROUP BY month ORDER BY month`
+)
+
+// facets count the posts matched by the condition per tag, author and month
+func (p *postingPostgre) facets(ctx context.Context, tx Tx, condition string, args []interface{}) (*Facets, error) {
+	tags, err := p.facetBuckets(ctx, tx, fmt.Sprintf(tagFacet, condition, FacetSize), args)
+	if err != nil {
+		return nil, err
+	}
+
+	authors, err := p.facetBuckets(ctx, tx, fmt.Sprintf(authorFacet, condition, FacetSize), args)
+	if err != nil {
+		return nil, err
+	}
+
+	months, err := p.facetBuckets(ctx, tx, fmt.Sprintf(monthFacet, condition), args)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Facets{Tags: tags, Authors: authors, Months: months}, nil
+}
+
+func (p *postingPostgre) facetBuckets(ctx context.Context, tx Tx, SQL string, args []interface{}) ([]FacetBucket, error) {
+	rows, err := sqlTx(tx).QueryContext(ctx, SQL, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	buckets := []FacetBucket{}
+	for rows.Next() {
+		var bucket FacetBucket
+		if err := rows.Scan(&bucket.Key, &bucket.Count); err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, bucket)
+	}
+
+	return buckets, rows.Err()
+}
+
+// SuggestTitles complete the prefix with the title of published posts, title starting with the prefix come first
+// then the one with a word similar to it, so a typo still find something
+func (p *postingPostgre) SuggestTitles(ctx context.Context, tx Tx, prefix string, size int) ([]Suggestion, error) {
+	SQL := `SELECT post_id, title, slug FROM posts
+		WHERE deleted_at IS NULL AND status = 'published' AND (title ILIKE $2 OR $1 <% title)
+		ORDER BY title ILIKE $2 DESC, word_similarity($1, title) DESC, created_at DESC LIMIT $3`
+	rows, err := sqlTx(tx).QueryContext(ctx, SQL, prefix, likePrefix(prefix), size)
+	if err != nil {
+		return []Suggestion{}, fmt.Errorf("failed to suggest title for prefix: %s because %w", prefix, err)
+	}
+	defer rows.Close()
+
+	suggestions := []Suggestion{}
+	for rows.Next() {
+		var suggestion Suggestion
+		if err := rows.Scan(&suggestion.ID, &suggestion.Title, &suggestion.Slug); err != nil {
+			return []Suggestion{}, fmt.Errorf("failed to scan title suggestion because %w", err)
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+
+	return suggestions, nil
+}
+
+// relatedSource turn every lexeme of the source post into a query matching any of them, the lexemes are already
+// stemmed so they're parsed with the simple configuration which leave them as they are
+const relatedSource = `WITH source AS (
+		SELECT websearch_to_tsquery('simple', array_to_string(tsvector_to_array(ts_title_content), ' or ')) AS query
+		FROM posts WHERE post_id = $1
+	)`
+
+// FindRelated find the published posts sharing the most words with the post, the title count more than the content
+// like it does in the search
+func (p *postingPostgre) FindRelated(ctx context.Context, tx Tx, id int64, size int) ([]PostData, error) {
+	condition := "WHERE p.deleted_at IS NULL AND p.status = 'published' AND p.post_id <> $1 AND p.ts_title_content @@ s.query"
+	orderBy := "ORDER BY ts_rank(p.ts_title_content, s.query) DESC, p.created_at DESC LIMIT $2"
+	SQL := relatedSource + " " + postColumns + " " + fromPost + " CROSS JOIN source s " + condition + " " + orderBy
+	rows, err := sqlTx(tx).QueryContext(ctx, SQL, id, size)
+	if err != nil {
+		return []PostData{}, fmt.Errorf("failed to find post related to post: %d because %w", id, err)
+	}
+	defer rows.Close()
+
+	posts := []PostData{}
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return []PostData{}, fmt.Errorf("failed to scan post related to post: %d because %w", id, err)
+		}
+		posts = append(posts, post)
+	}
+
+	return posts, nil
+}
+
+// likePrefix escape the LIKE wildcard in the prefix so it's matched as it is
+func likePrefix(prefix string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+	return replacer.Replace(prefix) + "%"
+}
+
+func (p *postingPostgre) FindRecent(ctx context.Context, tx Tx, filter PostFilter, from int, size int) ([]PostData, error) {
+	filters, args := filterCondition(filter, nil)
+	args = append(args, size, from)
+
+	condition := "WHERE p.deleted_at IS NULL" + filters
+	limit := "LIMIT $" + strconv.Itoa(len(args)-1) + " OFFSET $" + strconv.Itoa(len(args))
+	SQL := selectPost + " " + condition + " ORDER BY p.created_at DESC " + limit
+	rows, err := sqlTx(tx).QueryContext(ctx, SQL, args...)
 	if err != nil {
 		return []PostData{}, fmt.Errorf("failed to find posts because %w", err)
 	}
@@ -139,8 +908,8 @@ func (p *postingPostgre) FindRecent(ctx context.Context, tx *sql.Tx, from int, s
 
 	var posts []PostData
 	for rows.Next() {
-		var post PostData
-		if err := rows.Scan(&post.ID, &post.Title, &post.ShortDesc, &post.Content, &post.CreatedAt); err != nil {
+		post, err := scanPost(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan post becasue %w", err)
 		}
 		posts = append(posts, post)
@@ -148,3 +917,36 @@ func (p *postingPostgre) FindRecent(ctx context.Context, tx *sql.Tx, from int, s
 
 	return posts, nil
 }
+
+// FindPublishedAfter page through every published post by id, so the page stay stable
+// while posts are created or deleted in between
+func (p *postingPostgre) FindPublishedAfter(ctx context.Context, tx Tx, afterID int64, limit int) ([]PostData, error) {
+	condition := "WHERE p.deleted_at IS NULL AND p.status = 'published' AND p.post_id > $1"
+	SQL := selectPost + " " + condition + " ORDER BY p.post_id LIMIT $2"
+	rows, err := sqlTx(tx).QueryContext(ctx, SQL, afterID, limit)
+	if err != nil {
+		return []PostData{}, fmt.Errorf("failed to find published posts after id: %d because %w", afterID, err)
+	}
+	defer rows.Close()
+
+	var posts []PostData
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan published post because %w", err)
+		}
+		posts = append(posts, post)
+	}
+
+	return posts, nil
+}
+
+func (p *postingPostgre) CountPublished(ctx context.Context, tx Tx) (int64, error) {
+	SQL := "SELECT COUNT(*) FROM posts WHERE deleted_at IS NULL AND status = 'published'"
+	var count int64
+	if err := sqlTx(tx).QueryRowContext(ctx, SQL).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count published posts because %w", err)
+	}
+
+	return count, nil
+}
@@ -0,0 +1,242 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Query mode, fuzzy and exact understand the query syntax, phrase search the whole text as it is
+const (
+	// QueryModeFuzzy match every word while tolerating a typo or two
+	QueryModeFuzzy = "fuzzy"
+	// QueryModeExact match every word as it is written, after stemming
+	QueryModeExact = "exact"
+	// QueryModePhrase match the text as one phrase, word for word and in order
+	QueryModePhrase = "phrase"
+)
+
+// maxQueryClauses keep a query from turning into a huge bool query
+const maxQueryClauses = 32
+
+var ErrInvalidQuery = errors.New("invalid search query")
+
+// queryFields is the field that can be searched on its own with a prefix like title:
+var queryFields = map[string]bool{
+	"title":   true,
+	"content": true,
+}
+
+// QueryClause is a word or a quoted phrase, Field is empty when every searchable field is matched
+type QueryClause struct {
+	Field  string
+	Text   string
+	Phrase bool
+}
+
+// QueryGroup is matched when any of its clauses match, or when none of them match if it's excluded
+type QueryGroup struct {
+	Clauses []QueryClause
+	Exclude bool
+}
+
+// SearchQuery is the parsed search text, a post is matched by every group of the query
+type SearchQuery struct {
+	Text   string
+	Mode   string
+	Groups []QueryGroup
+}
+
+// ParseQuery parse the search text with the query syntax, word is matched on its own
+// and "quoted phrase" as a whole, every word or phrase must match unless they're joined by OR,
+// -word exclude the post containing it and title: or content: only match the word in that field.
+// Nothing in the text is passed as syntax to the search backend, so it's safe to parse user input
+func ParseQuery(text string, mode string) (SearchQuery, error) {
+	query := SearchQuery{Text: text, Mode: mode}
+	if query.Mode == "" {
+		query.Mode = QueryModeFuzzy
+	}
+
+	switch query.Mode {
+	case QueryModeFuzzy, QueryModeExact:
+	case QueryModePhrase:
+		if phrase := strings.Join(strings.Fields(text), " "); phrase != "" {
+			query.Groups = []QueryGroup{{Clauses: []QueryClause{{Text: phrase, Phrase: true}}}}
+		}
+		return query, nil
+	default:
+		return query, fmt.Errorf("%w: unknown mode %s", ErrInvalidQuery, mode)
+	}
+
+	var (
+		clauses int
+		or      bool
+	)
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		var (
+			clause  QueryClause
+			exclude bool
+		)
+		if runes[i] == '-' {
+			exclude = true
+			i++
+		}
+
+		if field, next := fieldPrefix(runes, i); field != "" {
+			clause.Field = field
+			i = next
+		}
+
+		if i < len(runes) && runes[i] == '"' {
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			clause.Text = strings.Join(strings.Fields(string(runes[i+1:end])), " ")
+			clause.Phrase = true
+			i = end + 1
+		} else {
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && runes[end] != '"' {
+				end++
+			}
+			clause.Text = strings.Trim(string(runes[i:end]), "-")
+			i = end
+		}
+
+		if clause.Text == "" {
+			continue
+		}
+
+		// AND is the default so it's only skipped, the case is ignored like websearch_to_tsquery does
+		if !exclude && !clause.Phrase && clause.Field == "" {
+			switch strings.ToUpper(clause.Text) {
+			case "AND":
+				continue
+			case "OR":
+				or = len(query.Groups) > 0 && !query.Groups[len(query.Groups)-1].Exclude
+				continue
+			}
+		}
+
+		clauses++
+		if clauses > maxQueryClauses {
+			return query, fmt.Errorf("%w: more than %d words or phrases", ErrInvalidQuery, maxQueryClauses)
+		}
+
+		if or && !exclude {
+			last := &query.Groups[len(query.Groups)-1]
+			last.Clauses = append(last.Clauses, clause)
+		} else {
+			query.Groups = append(query.Groups, QueryGroup{Clauses: []QueryClause{clause}, Exclude: exclude})
+		}
+		or = false
+	}
+
+	return query, nil
+}
+
+// fieldPrefix return the field of a prefix like title: starting at i and where the text after it start,
+// an unknown prefix is part of the word
+func fieldPrefix(runes []rune, i int) (string, int) {
+	end := i
+	for end < len(runes) && unicode.IsLetter(runes[end]) {
+		end++
+	}
+
+	if end >= len(runes) || runes[end] != ':' {
+		return "", i
+	}
+
+	field := strings.ToLower(string(runes[i:end]))
+	if !queryFields[field] {
+		return "", i
+	}
+
+	return field, end + 1
+}
+
+// Empty report whether the query has nothing to match
+func (q SearchQuery) Empty() bool {
+	return len(q.Groups) == 0
+}
+
+// Words return every word of the query once, lowercased and with the phrases split into words
+func (q SearchQuery) Words() []string {
+	var words []string
+	seen := map[string]bool{}
+	for _, group := range q.Groups {
+		for _, clause := range group.Clauses {
+			for _, word := range strings.Fields(strings.ToLower(clause.Text)) {
+				if !seen[word] {
+					seen[word] = true
+					words = append(words, word)
+				}
+			}
+		}
+	}
+
+	return words
+}
+
+// Websearch write the query in the websearch_to_tsquery syntax, the field prefixes are left out
+func (q SearchQuery) Websearch() string {
+	return q.render(false, nil)
+}
+
+// Corrected write the query back with the corrected words, keyed by the lowercased word,
+// it's empty when none of the words is corrected
+func (q SearchQuery) Corrected(corrections map[string]string) string {
+	corrected := q.render(true, corrections)
+	if strings.EqualFold(corrected, q.render(true, nil)) {
+		return ""
+	}
+
+	return corrected
+}
+
+func (q SearchQuery) render(fields bool, corrections map[string]string) string {
+	groups := make([]string, 0, len(q.Groups))
+	for _, group := range q.Groups {
+		clauses := make([]string, 0, len(group.Clauses))
+		for _, clause := range group.Clauses {
+			text := clause.Websearch(corrections)
+			if fields && clause.Field != "" {
+				text = clause.Field + ":" + text
+			}
+			clauses = append(clauses, text)
+		}
+
+		rendered := strings.Join(clauses, " OR ")
+		if group.Exclude {
+			rendered = "-" + rendered
+		}
+		groups = append(groups, rendered)
+	}
+
+	return strings.Join(groups, " ")
+}
+
+// Websearch write the clause in the websearch_to_tsquery syntax, a phrase is quoted
+func (c QueryClause) Websearch(corrections map[string]string) string {
+	words := strings.Fields(c.Text)
+	for i, word := range words {
+		if correction, ok := corrections[strings.ToLower(word)]; ok {
+			words[i] = correction
+		}
+	}
+
+	text := strings.Join(words, " ")
+	if c.Phrase {
+		return `"` + text + `"`
+	}
+
+	return text
+}
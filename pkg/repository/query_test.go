@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseQuery(t *testing.T) {
+	subtest := []struct {
+		name     string
+		text     string
+		mode     string
+		expected []QueryGroup
+	}{
+		{
+			name: "Words",
+			text: "echo  routing",
+			expected: []QueryGroup{
+				{Clauses: []QueryClause{{Text: "echo"}}},
+				{Clauses: []QueryClause{{Text: "routing"}}},
+			},
+		},
+		{
+			name: "Operators",
+			text: `go AND echo OR gin -"web framework"`,
+			expected: []QueryGroup{
+				{Clauses: []QueryClause{{Text: "go"}}},
+				{Clauses: []QueryClause{{Text: "echo"}, {Text: "gin"}}},
+				{Clauses: []QueryClause{{Text: "web framework", Phrase: true}}, Exclude: true},
+			},
+		},
+		{
+			name: "Field Prefix",
+			text: `title:"hello  world" content:echo http:server`,
+			expected: []QueryGroup{
+				{Clauses: []QueryClause{{Field: "title", Text: "hello world", Phrase: true}}},
+				{Clauses: []QueryClause{{Field: "content", Text: "echo"}}},
+				{Clauses: []QueryClause{{Text: "http:server"}}},
+			},
+		},
+		{
+			name: "Dangling Syntax",
+			text: `OR - "" "unclosed`,
+			expected: []QueryGroup{
+				{Clauses: []QueryClause{{Text: "unclosed", Phrase: true}}},
+			},
+		},
+		{
+			name: "Phrase Mode",
+			text: `go -echo OR "gin"`,
+			mode: QueryModePhrase,
+			expected: []QueryGroup{
+				{Clauses: []QueryClause{{Text: `go -echo OR "gin"`, Phrase: true}}},
+			},
+		},
+	}
+
+	for _, test := range subtest {
+		t.Run(test.name, func(t *testing.T) {
+			query, err := ParseQuery(test.text, test.mode)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, query.Groups)
+		})
+	}
+}
+
+func TestParseQueryInvalid(t *testing.T) {
+	_, err := ParseQuery("echo", "regex")
+	assert.True(t, errors.Is(err, ErrInvalidQuery))
+
+	long := ""
+	for i := 0; i <= maxQueryClauses; i++ {
+		long += "word "
+	}
+	_, err = ParseQuery(long, "")
+	assert.True(t, errors.Is(err, ErrInvalidQuery))
+}
+
+func TestSearchQueryRender(t *testing.T) {
+	query, err := ParseQuery(`title:gorutine OR "concurency patterns" -java`, "")
+	assert.NoError(t, err)
+
+	assert.Equal(t, `gorutine OR "concurency patterns" -java`, query.Websearch())
+	assert.Equal(t, []string{"gorutine", "concurency", "patterns", "java"}, query.Words())
+	assert.Equal(t, `title:goroutine OR "concurrency patterns" -java`, query.Corrected(map[string]string{
+		"gorutine":   "goroutine",
+		"concurency": "concurrency",
+	}))
+	assert.Empty(t, query.Corrected(map[string]string{}))
+}
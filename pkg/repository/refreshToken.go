@@ -0,0 +1,17 @@
+package repository
+
+import "time"
+
+// RefreshToken is the refresh token of a login, only the hash of the token is stored. Every refresh
+// replace the token with a new one of the same family, so a used token that's presented again was stolen
+// and the whole family is revoked
+type RefreshToken struct {
+	ID        int64      `json:"id"`
+	UserID    int64      `json:"user_id"`
+	FamilyID  string     `json:"family_id"`
+	TokenHash string     `json:"-"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+type refreshTokenMemory struct {
+	Store *MemoryStore
+}
+
+func NewRefreshTokenMemory(store *MemoryStore) RefreshTokenRepository {
+	return &refreshTokenMemory{
+		Store: store,
+	}
+}
+
+func (m *refreshTokenMemory) Create(ctx context.Context, tx Tx, t RefreshToken) (RefreshToken, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	t.ID = m.Store.refreshTokens.nextID()
+	m.Store.refreshTokens.put(tx, t.ID, t)
+
+	return t, nil
+}
+
+// FindByHash doesn't lock the token, the store is meant for a single writer
+func (m *refreshTokenMemory) FindByHash(ctx context.Context, tx Tx, hash string) (RefreshToken, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	for _, row := range m.Store.refreshTokens.rows {
+		if t := row.(RefreshToken); t.TokenHash == hash {
+			return t, nil
+		}
+	}
+
+	return RefreshToken{}, ErrRefreshTokenNotFound
+}
+
+// MarkUsed record that the token was rotated
+func (m *refreshTokenMemory) MarkUsed(ctx context.Context, tx Tx, id int64, usedAt time.Time) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if t, ok := m.Store.refreshTokens.rows[id].(RefreshToken); ok {
+		t.UsedAt = &usedAt
+		m.Store.refreshTokens.put(tx, id, t)
+	}
+
+	return nil
+}
+
+// RevokeFamily revoke every token of the family that isn't revoked yet
+func (m *refreshTokenMemory) RevokeFamily(ctx context.Context, tx Tx, familyID string, revokedAt time.Time) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	for key, row := range m.Store.refreshTokens.rows {
+		if t := row.(RefreshToken); t.FamilyID == familyID && t.RevokedAt == nil {
+			t.RevokedAt = &revokedAt
+			m.Store.refreshTokens.put(tx, key, t)
+		}
+	}
+
+	return nil
+}
+
+// RevokeUser revoke every token of the user that isn't revoked yet, whatever their family
+func (m *refreshTokenMemory) RevokeUser(ctx context.Context, tx Tx, userID int64, revokedAt time.Time) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	for key, row := range m.Store.refreshTokens.rows {
+		if t := row.(RefreshToken); t.UserID == userID && t.RevokedAt == nil {
+			t.RevokedAt = &revokedAt
+			m.Store.refreshTokens.put(tx, key, t)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRefreshTokenPostgre struct {
+	mock.Mock
+}
+
+func (m *MockRefreshTokenPostgre) Create(ctx context.Context, tx Tx, t RefreshToken) (RefreshToken, error) {
+	args := m.Called(ctx, tx, t)
+	return args.Get(0).(RefreshToken), args.Error(1)
+}
+
+func (m *MockRefreshTokenPostgre) FindByHash(ctx context.Context, tx Tx, hash string) (RefreshToken, error) {
+	args := m.Called(ctx, tx, hash)
+	return args.Get(0).(RefreshToken), args.Error(1)
+}
+
+func (m *MockRefreshTokenPostgre) MarkUsed(ctx context.Context, tx Tx, id int64, usedAt time.Time) error {
+	args := m.Called(ctx, tx, id, usedAt)
+	return args.Error(0)
+}
+
+func (m *MockRefreshTokenPostgre) RevokeFamily(ctx context.Context, tx Tx, familyID string, revokedAt time.Time) error {
+	args := m.Called(ctx, tx, familyID, revokedAt)
+	return args.Error(0)
+}
+
+func (m *MockRefreshTokenPostgre) RevokeUser(ctx context.Context, tx Tx, userID int64, revokedAt time.Time) error {
+	args := m.Called(ctx, tx, userID, revokedAt)
+	return args.Error(0)
+}
+
+type refreshTokenPostgre struct {
+}
+
+func NewRefreshTokenPostgre() RefreshTokenRepository {
+	return &refreshTokenPostgre{}
+}
+
+func (p *refreshTokenPostgre) Create(ctx context.Context, tx Tx, t RefreshToken) (RefreshToken, error) {
+	SQL := `INSERT INTO refresh_tokens(user_id, family_id, token_hash, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5) RETURNING refresh_token_id`
+	if err := sqlTx(tx).QueryRowContext(ctx, SQL, t.UserID, t.FamilyID, t.TokenHash, t.CreatedAt, t.ExpiresAt).Scan(&t.ID); err != nil {
+		return RefreshToken{}, fmt.Errorf("failed to create refresh token of user with id: %d because %w", t.UserID, err)
+	}
+
+	return t, nil
+}
+
+// FindByHash lock the token, so the same token refreshed twice at once is only rotated once
+// and the other refresh see it as used
+func (p *refreshTokenPostgre) FindByHash(ctx context.Context, tx Tx, hash string) (RefreshToken, error) {
+	SQL := `SELECT refresh_token_id, user_id, family_id, token_hash, created_at, expires_at, used_at, revoked_at
+		FROM refresh_tokens WHERE token_hash = $1 FOR UPDATE`
+
+	var t RefreshToken
+	err := sqlTx(tx).QueryRowContext(ctx, SQL, hash).Scan(&t.ID, &t.UserID, &t.FamilyID, &t.TokenHash, &t.CreatedAt, &t.ExpiresAt, &t.UsedAt, &t.RevokedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return RefreshToken{}, ErrRefreshTokenNotFound
+	}
+	if err != nil {
+		return RefreshToken{}, fmt.Errorf("failed to find refresh token because %w", err)
+	}
+
+	return t, nil
+}
+
+// MarkUsed record that the token was rotated
+func (p *refreshTokenPostgre) MarkUsed(ctx context.Context, tx Tx, id int64, usedAt time.Time) error {
+	SQL := "UPDATE refresh_tokens SET used_at = $1 WHERE refresh_token_id = $2"
+	if _, err := sqlTx(tx).ExecContext(ctx, SQL, usedAt, id); err != nil {
+		return fmt.Errorf("failed to mark refresh token with id: %d as used because %w", id, err)
+	}
+
+	return nil
+}
+
+// RevokeFamily revoke every token of the family that isn't revoked yet
+func (p *refreshTokenPostgre) RevokeFamily(ctx context.Context, tx Tx, familyID string, revokedAt time.Time) error {
+	SQL := "UPDATE refresh_tokens SET revoked_at = $1 WHERE family_id = $2 AND revoked_at IS NULL"
+	if _, err := sqlTx(tx).ExecContext(ctx, SQL, revokedAt, familyID); err != nil {
+		return fmt.Errorf("failed to revoke refresh token family: %s because %w", familyID, err)
+	}
+
+	return nil
+}
+
+// RevokeUser revoke every token of the user that isn't revoked yet, whatever their family
+func (p *refreshTokenPostgre) RevokeUser(ctx context.Context, tx Tx, userID int64, revokedAt time.Time) error {
+	SQL := "UPDATE refresh_tokens SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL"
+	if _, err := sqlTx(tx).ExecContext(ctx, SQL, revokedAt, userID); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens of user with id: %d because %w", userID, err)
+	}
+
+	return nil
+}
@@ -2,8 +2,8 @@ package repository
 
 import (
 	"context"
-	"database/sql"
 	"errors"
+	"time"
 )
 
 var (
@@ -17,24 +17,132 @@ var (
 	ErrFailedUpdateUser   = errors.New("failed to update the user in the repository")
 	ErrFailedToDeleteUser = errors.New("failed to delete the user in the repository")
 	ErrFailedToAssertUser = errors.New("failed to assert the user")
+	ErrTagNotFound        = errors.New("the tag was not found in the repository")
+	ErrCategoryNotFound   = errors.New("the category was not found in the repository")
+	ErrCategoryCycle      = errors.New("the category can't be its own ancestor")
+	ErrCommentNotFound    = errors.New("the comment was not found in the repository")
+	ErrRevisionNotFound   = errors.New("the revision was not found in the repository")
+
+	ErrRefreshTokenNotFound = errors.New("the refresh token was not found in the repository")
+	ErrRoleNotFound         = errors.New("the role was not found in the repository")
+
+	ErrPasswordResetNotFound     = errors.New("the password reset was not found in the repository")
+	ErrEmailVerificationNotFound = errors.New("the email verification was not found in the repository")
 )
 
 type Post interface {
-	Create(ctx context.Context, tx *sql.Tx, pd PostData) (PostData, error)
-	Update(ctx context.Context, tx *sql.Tx, pd PostData) error
-	Delete(ctx context.Context, tx *sql.Tx, pd PostData) error
-	FindByID(ctx context.Context, tx *sql.Tx, id int64) (PostData, error)
-	FindByTitleContent(ctx context.Context, tx *sql.Tx, query string, from int, size int) ([]PostData, error)
-	FindRecent(ctx context.Context, tx *sql.Tx, from int, size int) ([]PostData, error)
+	Create(ctx context.Context, tx Tx, pd PostData) (PostData, error)
+	Update(ctx context.Context, tx Tx, pd PostData) error
+	Delete(ctx context.Context, tx Tx, pd PostData) error
+	Undelete(ctx context.Context, tx Tx, id int64) error
+	Purge(ctx context.Context, tx Tx, before time.Time, limit int) ([]int64, error)
+	UpdateStatus(ctx context.Context, tx Tx, id int64, status string) error
+	UpdatePublishAt(ctx context.Context, tx Tx, id int64, publishAt *time.Time) error
+	FindDueScheduled(ctx context.Context, tx Tx, now time.Time, limit int) ([]PostData, error)
+	UniqueSlug(ctx context.Context, tx Tx, slug string, id int64) (string, error)
+	ChangeSlug(ctx context.Context, tx Tx, id int64, oldSlug string, newSlug string) error
+	// LockByID lock the post until the transaction end, whether it's deleted or not
+	LockByID(ctx context.Context, tx Tx, id int64) error
+	FindByID(ctx context.Context, tx Tx, id int64) (PostData, error)
+	FindDeletedByID(ctx context.Context, tx Tx, id int64) (PostData, error)
+	FindTrash(ctx context.Context, tx Tx, authorID int64, from int, size int) ([]PostData, error)
+	FindBySlug(ctx context.Context, tx Tx, slug string) (PostData, error)
+	FindSlugRedirect(ctx context.Context, tx Tx, oldSlug string) (string, error)
+	SetTags(ctx context.Context, tx Tx, id int64, tags []string) error
+	SetCategories(ctx context.Context, tx Tx, id int64, categories []string) error
+	CreateRevision(ctx context.Context, tx Tx, r Revision) (Revision, error)
+	FindRevisions(ctx context.Context, tx Tx, postID int64) ([]Revision, error)
+	FindRevision(ctx context.Context, tx Tx, postID int64, id int64) (Revision, error)
+	FindByTitleContent(ctx context.Context, tx Tx, query SearchQuery, filter PostFilter, from int, size int) (SearchResult, error)
+	FindRecent(ctx context.Context, tx Tx, filter PostFilter, from int, size int) ([]PostData, error)
+	SuggestTitles(ctx context.Context, tx Tx, prefix string, size int) ([]Suggestion, error)
+	FindRelated(ctx context.Context, tx Tx, id int64, size int) ([]PostData, error)
+	FindPublishedAfter(ctx context.Context, tx Tx, afterID int64, limit int) ([]PostData, error)
+	CountPublished(ctx context.Context, tx Tx) (int64, error)
+}
+
+type OutboxRepository interface {
+	Add(ctx context.Context, tx Tx, e OutboxEvent) error
+	FindPending(ctx context.Context, tx Tx, now time.Time, limit int) ([]OutboxEvent, error)
+	Delete(ctx context.Context, tx Tx, id int64) error
+	Retry(ctx context.Context, tx Tx, e OutboxEvent) error
+	DeadLetter(ctx context.Context, tx Tx, e OutboxEvent, deadAt time.Time) error
+}
+
+type TagRepository interface {
+	Create(ctx context.Context, tx Tx, t Tag) (Tag, error)
+	Update(ctx context.Context, tx Tx, t Tag) (Tag, error)
+	Delete(ctx context.Context, tx Tx, id int64) error
+	FindAll(ctx context.Context, tx Tx) ([]Tag, error)
+}
+
+type CategoryRepository interface {
+	Create(ctx context.Context, tx Tx, c Category) (Category, error)
+	Update(ctx context.Context, tx Tx, c Category) (Category, error)
+	Delete(ctx context.Context, tx Tx, id int64) error
+	FindByID(ctx context.Context, tx Tx, id int64) (Category, error)
+	FindAll(ctx context.Context, tx Tx) ([]Category, error)
+	IsDescendant(ctx context.Context, tx Tx, id int64, ancestorID int64) (bool, error)
+}
+
+type CommentRepository interface {
+	Create(ctx context.Context, tx Tx, c Comment) (Comment, error)
+	Update(ctx context.Context, tx Tx, c Comment) (Comment, error)
+	Delete(ctx context.Context, tx Tx, id int64) error
+	FindByID(ctx context.Context, tx Tx, id int64) (Comment, error)
+	FindThreads(ctx context.Context, tx Tx, postID int64, from int, size int) ([]Comment, error)
+	FindReplies(ctx context.Context, tx Tx, rootIDs []int64) ([]Comment, error)
+}
+
+type FavouriteRepository interface {
+	Add(ctx context.Context, tx Tx, userID int64, postID int64) error
+	Remove(ctx context.Context, tx Tx, userID int64, postID int64) error
+	Count(ctx context.Context, tx Tx, postIDs []int64) (map[int64]int64, error)
+	FavouritedBy(ctx context.Context, tx Tx, userID int64, postIDs []int64) (map[int64]bool, error)
+	FindByUser(ctx context.Context, tx Tx, userID int64, from int, size int) ([]PostData, error)
+	FindPostIDsByUser(ctx context.Context, tx Tx, userID int64) ([]int64, error)
 }
 
 type UserRepository interface {
-	Create(ctx context.Context, tx *sql.Tx, u User) (User, error)
-	UpdateUser(ctx context.Context, tx *sql.Tx, u User) (User, error)
-	UpdatePassword(ctx context.Context, tx *sql.Tx, u User) (User, error)
-	Delete(ctx context.Context, tx *sql.Tx, u User) error
-	LoginByEmail(ctx context.Context, tx *sql.Tx, email string, pass string) (User, error)
-	LoginByUsername(ctx context.Context, tx *sql.Tx, username string, pass string) (User, error)
-	FindByEmail(ctx context.Context, tx *sql.Tx, email string) (User, error)
-	FindByUsername(ctx context.Context, tx *sql.Tx, username string) (User, error)
+	Create(ctx context.Context, tx Tx, u User) (User, error)
+	// UpdateUser change the email, username and name of the user with the id, a new email isn't verified yet
+	UpdateUser(ctx context.Context, tx Tx, u User) (User, error)
+	UpdatePassword(ctx context.Context, tx Tx, u User) (User, error)
+	Delete(ctx context.Context, tx Tx, u User) error
+	// LoginByEmail and LoginByUsername return the user with the hash of their password, the other
+	// finds leave the password out
+	LoginByEmail(ctx context.Context, tx Tx, email string) (User, error)
+	LoginByUsername(ctx context.Context, tx Tx, username string) (User, error)
+	FindByEmail(ctx context.Context, tx Tx, email string) (User, error)
+	FindByUsername(ctx context.Context, tx Tx, username string) (User, error)
+	FindByID(ctx context.Context, tx Tx, id int64) (User, error)
+	FindAll(ctx context.Context, tx Tx, from int, size int) ([]User, error)
+	UpdateRole(ctx context.Context, tx Tx, id int64, role string) error
+	Suspend(ctx context.Context, tx Tx, id int64, suspendedAt *time.Time) error
+	Verify(ctx context.Context, tx Tx, id int64, verifiedAt time.Time) error
+}
+
+type PasswordResetRepository interface {
+	Create(ctx context.Context, tx Tx, r PasswordReset) (PasswordReset, error)
+	FindByHash(ctx context.Context, tx Tx, hash string) (PasswordReset, error)
+	MarkUsed(ctx context.Context, tx Tx, userID int64, usedAt time.Time) error
+}
+
+type EmailVerificationRepository interface {
+	Create(ctx context.Context, tx Tx, v EmailVerification) (EmailVerification, error)
+	FindByHash(ctx context.Context, tx Tx, hash string) (EmailVerification, error)
+	MarkUsed(ctx context.Context, tx Tx, userID int64, usedAt time.Time) error
+}
+
+type RoleRepository interface {
+	FindAll(ctx context.Context, tx Tx) ([]Role, error)
+	FindPermissions(ctx context.Context, tx Tx, role string) ([]string, error)
+}
+
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, tx Tx, t RefreshToken) (RefreshToken, error)
+	FindByHash(ctx context.Context, tx Tx, hash string) (RefreshToken, error)
+	MarkUsed(ctx context.Context, tx Tx, id int64, usedAt time.Time) error
+	RevokeFamily(ctx context.Context, tx Tx, familyID string, revokedAt time.Time) error
+	RevokeUser(ctx context.Context, tx Tx, userID int64, revokedAt time.Time) error
 }
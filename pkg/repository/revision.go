@@ -0,0 +1,17 @@
+package repository
+
+import "time"
+
+// Revision is a full snapshot of a post taken on every create, update and restore
+type Revision struct {
+	ID     int64 `json:"id"`
+	PostID int64 `json:"post_id"`
+	// EditorID is nil once the editor is deleted
+	EditorID   *int64    `json:"editor_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	Title      string    `json:"title"`
+	ShortDesc  string    `json:"short_desc"`
+	Content    string    `json:"content"`
+	Tags       []string  `json:"tags"`
+	Categories []string  `json:"categories"`
+}
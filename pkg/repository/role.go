@@ -0,0 +1,24 @@
+package repository
+
+// User role, a new user is an author
+const (
+	RoleReader = "reader"
+	RoleAuthor = "author"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+// Permission granted to a role, the user can always act on their own post, the "any" permissions
+// let them act on the post of others
+const (
+	PermissionPostCreate     = "post:create"
+	PermissionPostEditAny    = "post:edit:any"
+	PermissionPostDeleteAny  = "post:delete:any"
+	PermissionUserManage     = "user:manage"
+	PermissionTaxonomyManage = "taxonomy:manage"
+)
+
+type Role struct {
+	Name        string   `json:"name"`
+	Permissions []string `json:"permissions"`
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"sort"
+)
+
+// memoryRoles is what the migration seed the roles with
+var memoryRoles = []Role{
+	{Name: RoleReader, Permissions: []string{}},
+	{Name: RoleAuthor, Permissions: []string{PermissionPostCreate}},
+	{Name: RoleEditor, Permissions: []string{PermissionPostCreate, PermissionPostEditAny, PermissionTaxonomyManage}},
+	{Name: RoleAdmin, Permissions: []string{PermissionPostCreate, PermissionPostDeleteAny, PermissionPostEditAny, PermissionTaxonomyManage, PermissionUserManage}},
+}
+
+type roleMemory struct {
+	Store *MemoryStore
+}
+
+func NewRoleMemory(store *MemoryStore) RoleRepository {
+	return &roleMemory{
+		Store: store,
+	}
+}
+
+// FindAll return every role with its permissions, in the order they were created
+func (m *roleMemory) FindAll(ctx context.Context, tx Tx) ([]Role, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	roles := []Role{}
+	for _, row := range m.Store.roles.rows {
+		roles = append(roles, m.role(row.(memoryRole)))
+	}
+
+	sort.Slice(roles, func(i, j int) bool {
+		return m.Store.roles.rows[roles[i].Name].(memoryRole).ID < m.Store.roles.rows[roles[j].Name].(memoryRole).ID
+	})
+
+	return roles, nil
+}
+
+// FindPermissions return the permissions of the role, sorted, or ErrRoleNotFound when there's no such role
+func (m *roleMemory) FindPermissions(ctx context.Context, tx Tx, role string) ([]string, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	row, ok := m.Store.roles.rows[role].(memoryRole)
+	if !ok {
+		return nil, ErrRoleNotFound
+	}
+
+	return m.role(row).Permissions, nil
+}
+
+// role copy the permissions so the caller can't change the stored role, the store must be locked
+func (m *roleMemory) role(row memoryRole) Role {
+	permissions := append([]string{}, row.Permissions...)
+	sort.Strings(permissions)
+
+	return Role{Name: row.Name, Permissions: permissions}
+}
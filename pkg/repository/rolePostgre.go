@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRolePostgre struct {
+	mock.Mock
+}
+
+func (m *MockRolePostgre) FindAll(ctx context.Context, tx Tx) ([]Role, error) {
+	args := m.Called(ctx, tx)
+	return args.Get(0).([]Role), args.Error(1)
+}
+
+func (m *MockRolePostgre) FindPermissions(ctx context.Context, tx Tx, role string) ([]string, error) {
+	args := m.Called(ctx, tx, role)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+type rolePostgre struct {
+}
+
+func NewRolePostgre() RoleRepository {
+	return &rolePostgre{}
+}
+
+// FindAll return every role with its permissions, in the order they were created
+func (p *rolePostgre) FindAll(ctx context.Context, tx Tx) ([]Role, error) {
+	SQL := `SELECT r.name, p.name FROM roles r
+		LEFT JOIN role_permissions rp ON rp.role_id = r.role_id
+		LEFT JOIN permissions p ON p.permission_id = rp.permission_id
+		ORDER BY r.role_id, p.name`
+	rows, err := sqlTx(tx).QueryContext(ctx, SQL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find roles because %w", err)
+	}
+	defer rows.Close()
+
+	roles := []Role{}
+	for rows.Next() {
+		var name string
+		var permission sql.NullString
+		if err := rows.Scan(&name, &permission); err != nil {
+			return nil, fmt.Errorf("failed to scan role because %w", err)
+		}
+
+		if len(roles) == 0 || roles[len(roles)-1].Name != name {
+			roles = append(roles, Role{Name: name, Permissions: []string{}})
+		}
+		if permission.Valid {
+			last := &roles[len(roles)-1]
+			last.Permissions = append(last.Permissions, permission.String)
+		}
+	}
+
+	return roles, nil
+}
+
+// FindPermissions return the permissions of the role, sorted, or ErrRoleNotFound when there's no such role
+func (p *rolePostgre) FindPermissions(ctx context.Context, tx Tx, role string) ([]string, error) {
+	SQL := `SELECT p.name FROM roles r
+		LEFT JOIN role_permissions rp ON rp.role_id = r.role_id
+		LEFT JOIN permissions p ON p.permission_id = rp.permission_id
+		WHERE r.name = $1 ORDER BY p.name`
+	rows, err := sqlTx(tx).QueryContext(ctx, SQL, role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find permissions of role: %s because %w", role, err)
+	}
+	defer rows.Close()
+
+	found := false
+	permissions := []string{}
+	for rows.Next() {
+		found = true
+
+		var permission sql.NullString
+		if err := rows.Scan(&permission); err != nil {
+			return nil, fmt.Errorf("failed to scan permission because %w", err)
+		}
+		if permission.Valid {
+			permissions = append(permissions, permission.String)
+		}
+	}
+
+	if !found {
+		return nil, ErrRoleNotFound
+	}
+
+	return permissions, nil
+}
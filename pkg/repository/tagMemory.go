@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+type tagMemory struct {
+	Store *MemoryStore
+}
+
+func NewTagMemory(store *MemoryStore) TagRepository {
+	return &tagMemory{
+		Store: store,
+	}
+}
+
+func (m *tagMemory) Create(ctx context.Context, tx Tx, t Tag) (Tag, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if m.nameTaken(t.Name, 0) {
+		return t, fmt.Errorf("failed to create tag: %v because the name is already used", t)
+	}
+
+	t.ID = m.Store.tags.nextID()
+	m.Store.tags.put(tx, t.ID, Tag{ID: t.ID, Name: t.Name})
+
+	return t, nil
+}
+
+func (m *tagMemory) Update(ctx context.Context, tx Tx, t Tag) (Tag, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if _, ok := m.Store.tags.rows[t.ID]; !ok {
+		return t, fmt.Errorf("failed to update tag: %v because %w", t, ErrTagNotFound)
+	}
+
+	if m.nameTaken(t.Name, t.ID) {
+		return t, fmt.Errorf("failed to update tag: %v because the name is already used", t)
+	}
+
+	m.Store.tags.put(tx, t.ID, Tag{ID: t.ID, Name: t.Name})
+
+	return t, nil
+}
+
+func (m *tagMemory) Delete(ctx context.Context, tx Tx, id int64) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if _, ok := m.Store.tags.rows[id]; !ok {
+		return fmt.Errorf("failed to delete tag with id: %d because %w", id, ErrTagNotFound)
+	}
+
+	m.Store.tags.remove(tx, id)
+	m.Store.unlink(tx, &m.Store.postTags, id)
+
+	return nil
+}
+
+// FindAll return every tag with the number of published post, most used first, deleted post isn't counted
+func (m *tagMemory) FindAll(ctx context.Context, tx Tx) ([]Tag, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	counts := map[int64]int64{}
+	for key, row := range m.Store.postTags.rows {
+		post, ok := m.Store.posts.rows[key].(PostData)
+		if !ok || post.Status != StatusPublished || post.DeletedAt != nil {
+			continue
+		}
+		for _, id := range row.([]int64) {
+			counts[id]++
+		}
+	}
+
+	var tags []Tag
+	for _, row := range m.Store.tags.rows {
+		tag := row.(Tag)
+		tag.PostCount = counts[tag.ID]
+		tags = append(tags, tag)
+	}
+
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].PostCount != tags[j].PostCount {
+			return tags[i].PostCount > tags[j].PostCount
+		}
+		return tags[i].Name < tags[j].Name
+	})
+
+	return tags, nil
+}
+
+// nameTaken report whether another tag than id already use the name, the store must be locked
+func (m *tagMemory) nameTaken(name string, id int64) bool {
+	for _, row := range m.Store.tags.rows {
+		if tag := row.(Tag); tag.ID != id && tag.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockTagPostgre struct {
+	mock.Mock
+}
+
+func (m *MockTagPostgre) Create(ctx context.Context, tx Tx, t Tag) (Tag, error) {
+	args := m.Called(ctx, tx, t)
+	return args.Get(0).(Tag), args.Error(1)
+}
+
+func (m *MockTagPostgre) Update(ctx context.Context, tx Tx, t Tag) (Tag, error) {
+	args := m.Called(ctx, tx, t)
+	return args.Get(0).(Tag), args.Error(1)
+}
+
+func (m *MockTagPostgre) Delete(ctx context.Context, tx Tx, id int64) error {
+	args := m.Called(ctx, tx, id)
+	return args.Error(0)
+}
+
+func (m *MockTagPostgre) FindAll(ctx context.Context, tx Tx) ([]Tag, error) {
+	args := m.Called(ctx, tx)
+	return args.Get(0).([]Tag), args.Error(1)
+}
+
+type tagPostgre struct {
+}
+
+func NewTagPostgre() TagRepository {
+	return &tagPostgre{}
+}
+
+func (p *tagPostgre) Create(ctx context.Context, tx Tx, t Tag) (Tag, error) {
+	SQL := "INSERT INTO tags(name) VALUES ($1) RETURNING tag_id"
+	if err := sqlTx(tx).QueryRowContext(ctx, SQL, t.Name).Scan(&t.ID); err != nil {
+		return t, fmt.Errorf("failed to create tag: %v because %w", t, err)
+	}
+
+	return t, nil
+}
+
+func (p *tagPostgre) Update(ctx context.Context, tx Tx, t Tag) (Tag, error) {
+	SQL := "UPDATE tags SET name = $1 WHERE tag_id = $2"
+	result, err := sqlTx(tx).ExecContext(ctx, SQL, t.Name, t.ID)
+	if err != nil {
+		return t, fmt.Errorf("failed to update tag: %v because %w", t, err)
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return t, fmt.Errorf("failed to update tag: %v because %w", t, ErrTagNotFound)
+	}
+
+	return t, nil
+}
+
+func (p *tagPostgre) Delete(ctx context.Context, tx Tx, id int64) error {
+	SQL := "DELETE FROM tags WHERE tag_id = $1"
+	result, err := sqlTx(tx).ExecContext(ctx, SQL, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete tag with id: %d because %w", id, err)
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("failed to delete tag with id: %d because %w", id, ErrTagNotFound)
+	}
+
+	return nil
+}
+
+// FindAll return every tag with the number of published post, most used first, deleted post isn't counted
+func (p *tagPostgre) FindAll(ctx context.Context, tx Tx) ([]Tag, error) {
+	SQL := `SELECT t.tag_id, t.name, COUNT(p.post_id) FROM tags t
+		LEFT JOIN post_tags pt ON pt.tag_id = t.tag_id
+		LEFT JOIN posts p ON p.post_id = pt.post_id AND p.status = 'published' AND p.deleted_at IS NULL
+		GROUP BY t.tag_id, t.name ORDER BY COUNT(p.post_id) DESC, t.name`
+	rows, err := sqlTx(tx).QueryContext(ctx, SQL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find tags because %w", err)
+	}
+	defer rows.Close()
+
+	var tags []Tag
+	for rows.Next() {
+		var tag Tag
+		if err := rows.Scan(&tag.ID, &tag.Name, &tag.PostCount); err != nil {
+			return nil, fmt.Errorf("failed to scan tag because %w", err)
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, nil
+}
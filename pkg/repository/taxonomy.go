@@ -0,0 +1,15 @@
+package repository
+
+type Tag struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	// PostCount is the number of published post with the tag, used for tag cloud
+	PostCount int64 `json:"post_count"`
+}
+
+// Category is hierarchical, ParentID is nil for top level category
+type Category struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	ParentID *int64 `json:"parent_id"`
+}
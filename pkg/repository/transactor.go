@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Transactor run a unit of work in a transaction, the services call the repositories from inside fn
+// with the tx and ctx it's given instead of beginning and committing the transaction themselves
+type Transactor interface {
+	// Run commit the transaction when fn return nil and roll it back otherwise, when fn is run again
+	// after a serialization failure every change it made outside of tx must be safe to repeat.
+	// A Run called with the ctx given to fn doesn't begin another transaction, fn run in a savepoint
+	// of the same transaction and only its own changes are rolled back when it fail
+	Run(ctx context.Context, fn func(ctx context.Context, tx Tx) error) error
+	// ReadOnly is Run in a read only transaction, nested in another transaction it only set a savepoint
+	// so it can change what the outer transaction can
+	ReadOnly(ctx context.Context, fn func(ctx context.Context, tx Tx) error) error
+}
+
+// TxIsolation is the isolation level of the transaction Run begin, at repeatable read postgres fail the transaction
+// with a serialization failure when it change a row another transaction committed a change to since it began.
+// At the default read committed it never does and the retry of Run would only ever see a deadlock
+const TxIsolation = sql.LevelRepeatableRead
+
+// MaxTxAttempts is how many time Run try the unit of work when it keep failing on a serialization failure
+const MaxTxAttempts = 3
+
+// txRetryDelay is how long Run wait before the next attempt, it grow with the attempts
+const txRetryDelay = 20 * time.Millisecond
+
+// txKey is the context key of the transaction a unit of work run in
+type txKey struct{}
+
+// txState is the transaction of a unit of work, savepoints count the savepoints set in it so far
+// to name the next one
+type txState struct {
+	db         DB
+	tx         Tx
+	savepoints int
+}
+
+type transactor struct {
+	DB DB
+}
+
+func NewTransactor(db DB) Transactor {
+	return &transactor{
+		DB: db,
+	}
+}
+
+func (t *transactor) Run(ctx context.Context, fn func(ctx context.Context, tx Tx) error) error {
+	return t.run(ctx, false, fn)
+}
+
+func (t *transactor) ReadOnly(ctx context.Context, fn func(ctx context.Context, tx Tx) error) error {
+	return t.run(ctx, true, fn)
+}
+
+func (t *transactor) run(ctx context.Context, readOnly bool, fn func(ctx context.Context, tx Tx) error) error {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok && state.db == t.DB {
+		return t.nested(ctx, state, fn)
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := t.attempt(ctx, readOnly, fn)
+		if err == nil || !IsSerializationFailure(err) || attempt >= MaxTxAttempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(attempt) * txRetryDelay):
+		}
+	}
+}
+
+// attempt run fn in a new transaction
+func (t *transactor) attempt(ctx context.Context, readOnly bool, fn func(ctx context.Context, tx Tx) error) error {
+	tx, err := t.DB.BeginTx(ctx, &sql.TxOptions{Isolation: TxIsolation, ReadOnly: readOnly})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction because %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{}, &txState{db: t.DB, tx: tx}), tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction because %w", err)
+	}
+
+	return nil
+}
+
+// nested run fn in a savepoint of the transaction that is already running
+func (t *transactor) nested(ctx context.Context, state *txState, fn func(ctx context.Context, tx Tx) error) error {
+	state.savepoints++
+	name := "sp_" + strconv.Itoa(state.savepoints)
+
+	if err := t.DB.Savepoint(ctx, state.tx, name); err != nil {
+		return fmt.Errorf("failed to set savepoint: %s because %w", name, err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			t.DB.RollbackToSavepoint(ctx, state.tx, name)
+			panic(p)
+		}
+	}()
+
+	if err := fn(ctx, state.tx); err != nil {
+		if rollbackErr := t.DB.RollbackToSavepoint(ctx, state.tx, name); rollbackErr != nil {
+			return fmt.Errorf("failed to roll back to savepoint: %s because %v after %w", name, rollbackErr, err)
+		}
+		return err
+	}
+
+	if err := t.DB.ReleaseSavepoint(ctx, state.tx, name); err != nil {
+		return fmt.Errorf("failed to release savepoint: %s because %w", name, err)
+	}
+
+	return nil
+}
+
+// IsSerializationFailure report whether the transaction failed because it conflicted with another one,
+// running it again may succeed
+func IsSerializationFailure(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+
+	// serialization_failure and deadlock_detected
+	return pqErr.Code == "40001" || pqErr.Code == "40P01"
+}
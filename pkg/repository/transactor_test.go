@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+var errUnitOfWork = errors.New("unit of work failed")
+
+// tagNames return the name of every committed tag of the store
+func tagNames(t *testing.T, store *MemoryStore) []string {
+	tx, err := store.Begin()
+	assert.NoError(t, err)
+	defer tx.Rollback()
+
+	tags, err := NewTagMemory(store).FindAll(context.Background(), tx)
+	assert.NoError(t, err)
+
+	names := []string{}
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+
+	return names
+}
+
+func TestTransactorRun(t *testing.T) {
+	subtest := []struct {
+		name     string
+		err      error
+		expected []string
+	}{
+		{
+			name:     "Commit",
+			expected: []string{"go"},
+		},
+		{
+			name:     "Rollback",
+			err:      errUnitOfWork,
+			expected: []string{},
+		},
+	}
+
+	for _, test := range subtest {
+		t.Run(test.name, func(t *testing.T) {
+			store := NewMemoryStore()
+			tags := NewTagMemory(store)
+
+			err := NewTransactor(store).Run(context.Background(), func(ctx context.Context, tx Tx) error {
+				if _, err := tags.Create(ctx, tx, Tag{Name: "go"}); err != nil {
+					return err
+				}
+				return test.err
+			})
+			assert.ErrorIs(t, err, test.err)
+			assert.Equal(t, test.expected, tagNames(t, store))
+		})
+	}
+}
+
+func TestTransactorNested(t *testing.T) {
+	store := NewMemoryStore()
+	tags := NewTagMemory(store)
+	tr := NewTransactor(store)
+
+	err := tr.Run(context.Background(), func(ctx context.Context, tx Tx) error {
+		if _, err := tags.Create(ctx, tx, Tag{Name: "go"}); err != nil {
+			return err
+		}
+
+		// only the change of the failed unit of work is rolled back
+		err := tr.Run(ctx, func(ctx context.Context, nestedTx Tx) error {
+			assert.Equal(t, tx, nestedTx)
+			if _, err := tags.Create(ctx, nestedTx, Tag{Name: "echo"}); err != nil {
+				return err
+			}
+			return errUnitOfWork
+		})
+		assert.ErrorIs(t, err, errUnitOfWork)
+
+		return tr.Run(ctx, func(ctx context.Context, tx Tx) error {
+			_, err := tags.Create(ctx, tx, Tag{Name: "redis"})
+			return err
+		})
+	})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"go", "redis"}, tagNames(t, store))
+}
+
+func TestTransactorRetry(t *testing.T) {
+	subtest := []struct {
+		name     string
+		err      error
+		attempts int
+	}{
+		{
+			name:     "Serialization Failure",
+			err:      &pq.Error{Code: "40001"},
+			attempts: MaxTxAttempts,
+		},
+		{
+			name:     "Deadlock",
+			err:      &pq.Error{Code: "40P01"},
+			attempts: MaxTxAttempts,
+		},
+		{
+			name:     "Other Error",
+			err:      errUnitOfWork,
+			attempts: 1,
+		},
+	}
+
+	for _, test := range subtest {
+		t.Run(test.name, func(t *testing.T) {
+			attempts := 0
+			err := NewTransactor(NewMemoryStore()).Run(context.Background(), func(ctx context.Context, tx Tx) error {
+				attempts++
+				return test.err
+			})
+			assert.ErrorIs(t, err, test.err)
+			assert.Equal(t, test.attempts, attempts)
+		})
+	}
+}
+
+func TestTransactorRetrySucceed(t *testing.T) {
+	store := NewMemoryStore()
+	tags := NewTagMemory(store)
+
+	attempts := 0
+	err := NewTransactor(store).Run(context.Background(), func(ctx context.Context, tx Tx) error {
+		attempts++
+		if _, err := tags.Create(ctx, tx, Tag{Name: "go"}); err != nil {
+			return err
+		}
+		if attempts == 1 {
+			return &pq.Error{Code: "40001"}
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, []string{"go"}, tagNames(t, store))
+}
+
+func TestTransactorReadOnly(t *testing.T) {
+	store := NewMemoryStore()
+	tags := NewTagMemory(store)
+
+	err := NewTransactor(store).ReadOnly(context.Background(), func(ctx context.Context, tx Tx) error {
+		_, err := tags.Create(ctx, tx, Tag{Name: "go"})
+		return err
+	})
+	assert.ErrorIs(t, err, ErrReadOnlyTx)
+	assert.Equal(t, []string{}, tagNames(t, store))
+}
+
+func TestTransactorIsolation(t *testing.T) {
+	subtest := []struct {
+		name     string
+		run      func(tr Transactor, ctx context.Context, fn func(ctx context.Context, tx Tx) error) error
+		readOnly bool
+	}{
+		{
+			name: "Run",
+			run:  Transactor.Run,
+		},
+		{
+			name:     "Read Only",
+			run:      Transactor.ReadOnly,
+			readOnly: true,
+		},
+	}
+
+	for _, test := range subtest {
+		t.Run(test.name, func(t *testing.T) {
+			tx, err := NewMemoryStore().Begin()
+			assert.NoError(t, err)
+
+			// every attempt begin its transaction at the isolation level that report the serialization failure
+			db := &MockDB{}
+			db.On("BeginTx", context.Background(), &sql.TxOptions{Isolation: TxIsolation, ReadOnly: test.readOnly}).
+				Return(tx, nil).Times(MaxTxAttempts)
+
+			err = test.run(NewTransactor(db), context.Background(), func(ctx context.Context, tx Tx) error {
+				return &pq.Error{Code: "40001"}
+			})
+			assert.True(t, IsSerializationFailure(err))
+			db.AssertExpectations(t)
+		})
+	}
+}
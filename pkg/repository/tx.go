@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/mock"
+)
+
+// Tx is the transaction every repository method run in, it's begun by the DB of the same storage,
+// the postgres repositories query through the *sql.Tx begun by NewSQLDB and the memory repositories
+// keep what they changed so it can be undone on Rollback
+type Tx interface {
+	Commit() error
+	Rollback() error
+}
+
+// DB begin the transaction the repositories run in, the savepoints let a Transactor nest a unit of work
+// in the transaction of another
+type DB interface {
+	Begin() (Tx, error)
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (Tx, error)
+	Savepoint(ctx context.Context, tx Tx, name string) error
+	RollbackToSavepoint(ctx context.Context, tx Tx, name string) error
+	ReleaseSavepoint(ctx context.Context, tx Tx, name string) error
+}
+
+type MockDB struct {
+	mock.Mock
+}
+
+func (m *MockDB) Begin() (Tx, error) {
+	args := m.Called()
+	return args.Get(0).(Tx), args.Error(1)
+}
+
+func (m *MockDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (Tx, error) {
+	args := m.Called(ctx, opts)
+	return args.Get(0).(Tx), args.Error(1)
+}
+
+func (m *MockDB) Savepoint(ctx context.Context, tx Tx, name string) error {
+	args := m.Called(ctx, tx, name)
+	return args.Error(0)
+}
+
+func (m *MockDB) RollbackToSavepoint(ctx context.Context, tx Tx, name string) error {
+	args := m.Called(ctx, tx, name)
+	return args.Error(0)
+}
+
+func (m *MockDB) ReleaseSavepoint(ctx context.Context, tx Tx, name string) error {
+	args := m.Called(ctx, tx, name)
+	return args.Error(0)
+}
+
+type sqlDB struct {
+	DB *sql.DB
+}
+
+// NewSQLDB begin the transaction of the postgres repositories
+func NewSQLDB(db *sql.DB) DB {
+	return &sqlDB{
+		DB: db,
+	}
+}
+
+func (s *sqlDB) Begin() (Tx, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+func (s *sqlDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (Tx, error) {
+	tx, err := s.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+func (s *sqlDB) Savepoint(ctx context.Context, tx Tx, name string) error {
+	_, err := sqlTx(tx).ExecContext(ctx, "SAVEPOINT "+pq.QuoteIdentifier(name))
+	return err
+}
+
+func (s *sqlDB) RollbackToSavepoint(ctx context.Context, tx Tx, name string) error {
+	_, err := sqlTx(tx).ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+pq.QuoteIdentifier(name))
+	return err
+}
+
+func (s *sqlDB) ReleaseSavepoint(ctx context.Context, tx Tx, name string) error {
+	_, err := sqlTx(tx).ExecContext(ctx, "RELEASE SAVEPOINT "+pq.QuoteIdentifier(name))
+	return err
+}
+
+// sqlTx return the *sql.Tx the postgres repositories query through,
+// mixing a postgres repository with a transaction begun by another storage is a programming error
+func sqlTx(tx Tx) *sql.Tx {
+	return tx.(*sql.Tx)
+}
@@ -1,9 +1,15 @@
 package repository
 
+import "time"
+
 type User struct {
-	ID       int64  `json:"id"`
-	Email    string `json:"email"`
-	Username string `json:"username"`
-	Name     string `json:"name"`
-	Password string `json:"password"`
+	ID          int64      `json:"id"`
+	Email       string     `json:"email"`
+	Username    string     `json:"username"`
+	Name        string     `json:"name"`
+	Password    string     `json:"password"`
+	Role        string     `json:"role"`
+	SuspendedAt *time.Time `json:"suspended_at"`
+	// VerifiedAt is when the user confirmed their email, nil until then
+	VerifiedAt *time.Time `json:"verified_at"`
 }
@@ -0,0 +1,239 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+type userMemory struct {
+	Store *MemoryStore
+}
+
+func NewUserMemory(store *MemoryStore) UserRepository {
+	return &userMemory{
+		Store: store,
+	}
+}
+
+func (m *userMemory) Create(ctx context.Context, tx Tx, u User) (User, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	for _, row := range m.Store.users.rows {
+		user := row.(User)
+		if strings.EqualFold(user.Email, u.Email) || strings.EqualFold(user.Username, u.Username) {
+			return User{}, ErrFailedToCreateUser
+		}
+	}
+
+	// like the default of the role column
+	if u.Role == "" {
+		u.Role = RoleAuthor
+	}
+
+	u.ID = m.Store.users.nextID()
+	m.Store.users.put(tx, u.ID, u)
+
+	return u, nil
+}
+
+func (m *userMemory) UpdateUser(ctx context.Context, tx Tx, u User) (User, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	user, ok := m.findBy(func(user User) bool { return user.ID == u.ID })
+	if !ok {
+		return User{}, fmt.Errorf("failed to update user with id: %d because %w", u.ID, ErrUserNotFound)
+	}
+
+	for _, row := range m.Store.users.rows {
+		if other := row.(User); other.ID != user.ID && (strings.EqualFold(other.Email, u.Email) || strings.EqualFold(other.Username, u.Username)) {
+			return User{}, ErrFailedUpdateUser
+		}
+	}
+
+	// a new email isn't verified yet
+	if !strings.EqualFold(user.Email, u.Email) {
+		user.VerifiedAt = nil
+	}
+	user.Email = u.Email
+	user.Username = u.Username
+	user.Name = u.Name
+	m.Store.users.put(tx, user.ID, user)
+
+	return u, nil
+}
+
+func (m *userMemory) UpdatePassword(ctx context.Context, tx Tx, u User) (User, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if user, ok := m.findBy(func(user User) bool { return strings.EqualFold(user.Email, u.Email) }); ok {
+		user.Password = u.Password
+		m.Store.users.put(tx, user.ID, user)
+		return u, nil
+	}
+
+	return u, fmt.Errorf("failed to update password of user with email: %s because %w", u.Email, ErrUserNotFound)
+}
+
+// Delete remove the user along with their posts, comments and favourites like the foreign keys do
+func (m *userMemory) Delete(ctx context.Context, tx Tx, u User) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if user, ok := m.findBy(func(user User) bool { return strings.EqualFold(user.Email, u.Email) }); ok {
+		m.Store.deleteUser(tx, user.ID)
+	}
+
+	return nil
+}
+
+// LoginByEmail return the user with the hash of their password so the login can check it
+func (m *userMemory) LoginByEmail(ctx context.Context, tx Tx, email string) (User, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	user, ok := m.findBy(func(user User) bool { return strings.EqualFold(user.Email, email) })
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+func (m *userMemory) LoginByUsername(ctx context.Context, tx Tx, username string) (User, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	user, ok := m.findBy(func(user User) bool { return strings.EqualFold(user.Username, username) })
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+func (m *userMemory) FindByEmail(ctx context.Context, tx Tx, email string) (User, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	return m.find(func(user User) bool { return strings.EqualFold(user.Email, email) })
+}
+
+func (m *userMemory) FindByUsername(ctx context.Context, tx Tx, username string) (User, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	return m.find(func(user User) bool { return strings.EqualFold(user.Username, username) })
+}
+
+func (m *userMemory) FindByID(ctx context.Context, tx Tx, id int64) (User, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	return m.find(func(user User) bool { return user.ID == id })
+}
+
+// FindAll paginate the users in the order they signed up
+func (m *userMemory) FindAll(ctx context.Context, tx Tx, from int, size int) ([]User, error) {
+	m.Store.mu.RLock()
+	defer m.Store.mu.RUnlock()
+
+	users := []User{}
+	for _, row := range m.Store.users.rows {
+		user := row.(User)
+		user.Password = ""
+		users = append(users, user)
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].ID < users[j].ID
+	})
+
+	if from >= len(users) {
+		return []User{}, nil
+	}
+	users = users[from:]
+	if len(users) > size {
+		users = users[:size]
+	}
+
+	return users, nil
+}
+
+func (m *userMemory) UpdateRole(ctx context.Context, tx Tx, id int64, role string) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	user, ok := m.Store.users.rows[id].(User)
+	if !ok {
+		return ErrUserNotFound
+	}
+
+	// like the foreign key of the role column
+	if _, ok := m.Store.roles.rows[role]; !ok {
+		return ErrRoleNotFound
+	}
+
+	user.Role = role
+	m.Store.users.put(tx, id, user)
+
+	return nil
+}
+
+// Suspend suspend the user since suspendedAt, nil lift the suspension
+func (m *userMemory) Suspend(ctx context.Context, tx Tx, id int64, suspendedAt *time.Time) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	user, ok := m.Store.users.rows[id].(User)
+	if !ok {
+		return ErrUserNotFound
+	}
+
+	user.SuspendedAt = suspendedAt
+	m.Store.users.put(tx, id, user)
+
+	return nil
+}
+
+// Verify record that the user confirmed their email at verifiedAt
+func (m *userMemory) Verify(ctx context.Context, tx Tx, id int64, verifiedAt time.Time) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	user, ok := m.Store.users.rows[id].(User)
+	if !ok {
+		return ErrUserNotFound
+	}
+
+	user.VerifiedAt = &verifiedAt
+	m.Store.users.put(tx, id, user)
+
+	return nil
+}
+
+// find return the user without the password like the postgres repository, the store must be locked
+func (m *userMemory) find(match func(User) bool) (User, error) {
+	user, ok := m.findBy(match)
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+
+	user.Password = ""
+	return user, nil
+}
+
+func (m *userMemory) findBy(match func(User) bool) (User, bool) {
+	for _, row := range m.Store.users.rows {
+		if user := row.(User); match(user) {
+			return user, true
+		}
+	}
+
+	return User{}, false
+}
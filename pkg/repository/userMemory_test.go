@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserMemoryUpdateUser(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	repo := NewUserMemory(store)
+
+	tx, err := store.Begin()
+	assert.NoError(t, err)
+	a, err := repo.Create(ctx, tx, User{Email: "a@example.com", Username: "a", Name: "A", Password: "hash"})
+	assert.NoError(t, err)
+	b, err := repo.Create(ctx, tx, User{Email: "b@example.com", Username: "b", Name: "B", Password: "hash"})
+	assert.NoError(t, err)
+	verifiedAt := time.Now()
+	assert.NoError(t, repo.Verify(ctx, tx, a.ID, verifiedAt))
+
+	t.Run("Email Of Another User", func(t *testing.T) {
+		// the user A send the email of the user B, B is never found by it
+		_, err := repo.UpdateUser(ctx, tx, User{ID: a.ID, Email: b.Email, Username: "a2", Name: "A2"})
+		assert.ErrorIs(t, err, ErrFailedUpdateUser)
+
+		found, err := repo.FindByID(ctx, tx, b.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "b", found.Username)
+		assert.Equal(t, "B", found.Name)
+	})
+
+	t.Run("Change Email", func(t *testing.T) {
+		_, err := repo.UpdateUser(ctx, tx, User{ID: a.ID, Email: "new@example.com", Username: "a2", Name: "A2"})
+		assert.NoError(t, err)
+
+		found, err := repo.FindByID(ctx, tx, a.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "new@example.com", found.Email)
+		assert.Equal(t, "a2", found.Username)
+		assert.Nil(t, found.VerifiedAt)
+
+		found, err = repo.FindByID(ctx, tx, b.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "b", found.Username)
+	})
+
+	t.Run("Unknown User", func(t *testing.T) {
+		_, err := repo.UpdateUser(ctx, tx, User{ID: 42, Email: "c@example.com", Username: "c", Name: "C"})
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+
+	assert.NoError(t, tx.Commit())
+}
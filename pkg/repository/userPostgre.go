@@ -3,6 +3,9 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"time"
 )
 
 type userPostgre struct {
@@ -12,46 +15,48 @@ func NewUserPostgreRepository() UserRepository {
 	return &userPostgre{}
 }
 
-func (p *userPostgre) Create(ctx context.Context, tx *sql.Tx, u User) (User, error) {
-	SQL := "INSERT INTO user(email, username, name, password) VALUES(?, ?, ?, ?)"
-	result, err := tx.ExecContext(ctx, SQL, u.Email, u.Username, u.Name, u.Password)
-	if err != nil {
+func (p *userPostgre) Create(ctx context.Context, tx Tx, u User) (User, error) {
+	SQL := "INSERT INTO users(email, username, name, password) VALUES($1, $2, $3, $4) RETURNING user_id, role"
+	if err := sqlTx(tx).QueryRowContext(ctx, SQL, u.Email, u.Username, u.Name, u.Password).Scan(&u.ID, &u.Role); err != nil {
 		return User{}, ErrFailedToCreateUser
 	}
 
-	id, err := result.LastInsertId()
-	if err != nil {
-		return User{}, ErrUserNotFound
-	}
-
-	u.ID = id
-
 	return u, nil
 }
 
-func (p *userPostgre) UpdateUser(ctx context.Context, tx *sql.Tx, u User) (User, error) {
-	SQL := "UPDATE user SET username = ?, name = ? WHERE LOWER(email) = LOWER(?)"
-	_, err := tx.ExecContext(ctx, SQL, u.Name, u.ID)
+func (p *userPostgre) UpdateUser(ctx context.Context, tx Tx, u User) (User, error) {
+	SQL := `UPDATE users SET email = $1, username = $2, name = $3,
+		verified_at = CASE WHEN LOWER(email) = LOWER($1) THEN verified_at END
+		WHERE user_id = $4`
+	result, err := sqlTx(tx).ExecContext(ctx, SQL, u.Email, u.Username, u.Name, u.ID)
 	if err != nil {
 		return User{}, ErrFailedUpdateUser
 	}
 
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return User{}, fmt.Errorf("failed to update user with id: %d because %w", u.ID, ErrUserNotFound)
+	}
+
 	return u, nil
 }
 
-func (p *userPostgre) UpdatePassword(ctx context.Context, tx *sql.Tx, u User) (User, error) {
-	SQL := "UPDATE user SET password = ? WHERE LOWER(email) = LOWER(?)"
-	_, err := tx.ExecContext(ctx, SQL, u.Password, u.Email)
+func (p *userPostgre) UpdatePassword(ctx context.Context, tx Tx, u User) (User, error) {
+	SQL := "UPDATE users SET password = $1 WHERE LOWER(email) = LOWER($2)"
+	result, err := sqlTx(tx).ExecContext(ctx, SQL, u.Password, u.Email)
 	if err != nil {
 		return u, ErrFailedUpdateUser
 	}
 
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return u, fmt.Errorf("failed to update password of user with email: %s because %w", u.Email, ErrUserNotFound)
+	}
+
 	return u, nil
 }
 
-func (p *userPostgre) Delete(ctx context.Context, tx *sql.Tx, u User) error {
-	SQL := "DELETE FROM user WHERE LOWER(email) = LOWER(?)"
-	_, err := tx.ExecContext(ctx, SQL, u.Email)
+func (p *userPostgre) Delete(ctx context.Context, tx Tx, u User) error {
+	SQL := "DELETE FROM users WHERE LOWER(email) = LOWER($1)"
+	_, err := sqlTx(tx).ExecContext(ctx, SQL, u.Email)
 	if err != nil {
 		return ErrFailedToDeleteUser
 	}
@@ -59,78 +64,144 @@ func (p *userPostgre) Delete(ctx context.Context, tx *sql.Tx, u User) error {
 	return nil
 }
 
-func (p *userPostgre) LoginByEmail(ctx context.Context, tx *sql.Tx, email string, pass string) (User, error) {
-	SQL := "SELECT id, email, username, name FROM user WHERE LOWER(email) = LOWER(?) AND password = ?"
-	rows, err := tx.QueryContext(ctx, SQL, email, pass)
-	if err != nil {
-		return User{}, ErrUserNotFound
-	}
-	defer rows.Close()
+func (p *userPostgre) LoginByEmail(ctx context.Context, tx Tx, email string) (User, error) {
+	SQL := `SELECT user_id, email, username, name, password, role, suspended_at, verified_at
+		FROM users WHERE LOWER(email) = LOWER($1)`
 
-	var user User
-	if rows.Next() {
-		if err := rows.Scan(&user.ID, &user.Email, &user.Username, &user.Name); err != nil {
-			return user, ErrFailedToAssertUser
-		}
-		return user, nil
-	} else {
+	user := User{}
+	err := sqlTx(tx).QueryRowContext(ctx, SQL, email).Scan(&user.ID, &user.Email, &user.Username, &user.Name, &user.Password, &user.Role, &user.SuspendedAt, &user.VerifiedAt)
+	if errors.Is(err, sql.ErrNoRows) {
 		return user, ErrUserNotFound
 	}
+	if err != nil {
+		return user, fmt.Errorf("failed to find user with email: %s because %w", email, err)
+	}
+
+	return user, nil
 }
 
-func (p *userPostgre) LoginByUsername(ctx context.Context, tx *sql.Tx, username string, pass string) (User, error) {
-	SQL := "SELECT id, email, username, name FROM user WHERE LOWER(username) = LOWER(?) AND password = ?"
-	rows, err := tx.QueryContext(ctx, SQL, username, pass)
+func (p *userPostgre) LoginByUsername(ctx context.Context, tx Tx, username string) (User, error) {
+	SQL := `SELECT user_id, email, username, name, password, role, suspended_at, verified_at
+		FROM users WHERE LOWER(username) = LOWER($1)`
+
+	user := User{}
+	err := sqlTx(tx).QueryRowContext(ctx, SQL, username).Scan(&user.ID, &user.Email, &user.Username, &user.Name, &user.Password, &user.Role, &user.SuspendedAt, &user.VerifiedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return user, ErrUserNotFound
+	}
 	if err != nil {
-		return User{}, ErrUserNotFound
+		return user, fmt.Errorf("failed to find user with username: %s because %w", username, err)
 	}
-	defer rows.Close()
+
+	return user, nil
+}
+
+func (p *userPostgre) FindByEmail(ctx context.Context, tx Tx, email string) (User, error) {
+	SQL := "SELECT user_id, email, username, name, role, suspended_at, verified_at FROM users WHERE LOWER(email) = LOWER($1)"
 
 	user := User{}
-	if rows.Next() {
-		if err := rows.Scan(&user.ID, &user.Email, &user.Username, &user.Name); err != nil {
-			return user, ErrFailedToAssertUser
-		}
-		return user, nil
-	} else {
+	err := sqlTx(tx).QueryRowContext(ctx, SQL, email).Scan(&user.ID, &user.Email, &user.Username, &user.Name, &user.Role, &user.SuspendedAt, &user.VerifiedAt)
+	if errors.Is(err, sql.ErrNoRows) {
 		return user, ErrUserNotFound
 	}
+	if err != nil {
+		return user, fmt.Errorf("failed to find user with email: %s because %w", email, err)
+	}
+
+	return user, nil
 }
 
-func (p *userPostgre) FindByEmail(ctx context.Context, tx *sql.Tx, email string) (User, error) {
-	SQL := "SELECT id, email, username, name FROM user WHERE LOWER(email) = LOWER(?)"
-	rows, err := tx.QueryContext(ctx, SQL, email)
+func (p *userPostgre) FindByUsername(ctx context.Context, tx Tx, username string) (User, error) {
+	SQL := "SELECT user_id, email, username, name, role, suspended_at, verified_at FROM users WHERE LOWER(username) = LOWER($1)"
+
+	user := User{}
+	err := sqlTx(tx).QueryRowContext(ctx, SQL, username).Scan(&user.ID, &user.Email, &user.Username, &user.Name, &user.Role, &user.SuspendedAt, &user.VerifiedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return user, ErrUserNotFound
+	}
 	if err != nil {
-		return User{}, ErrUserNotFound
+		return user, fmt.Errorf("failed to find user with username: %s because %w", username, err)
 	}
-	defer rows.Close()
+
+	return user, nil
+}
+
+func (p *userPostgre) FindByID(ctx context.Context, tx Tx, id int64) (User, error) {
+	SQL := "SELECT user_id, email, username, name, role, suspended_at, verified_at FROM users WHERE user_id = $1"
 
 	user := User{}
-	if rows.Next() {
-		if err := rows.Scan(&user.ID, &user.Email, &user.Username, &user.Name); err != nil {
-			return user, ErrFailedToAssertUser
-		}
-		return user, nil
-	} else {
+	err := sqlTx(tx).QueryRowContext(ctx, SQL, id).Scan(&user.ID, &user.Email, &user.Username, &user.Name, &user.Role, &user.SuspendedAt, &user.VerifiedAt)
+	if errors.Is(err, sql.ErrNoRows) {
 		return user, ErrUserNotFound
 	}
+	if err != nil {
+		return user, fmt.Errorf("failed to find user with id: %d because %w", id, err)
+	}
+
+	return user, nil
 }
 
-func (p *userPostgre) FindByUsername(ctx context.Context, tx *sql.Tx, username string) (User, error) {
-	SQL := "SELECT id, email, username, name FROM user WHERE LOWER(username) = LOWER(?)"
-	rows, err := tx.QueryContext(ctx, SQL, username)
+// FindAll paginate the users in the order they signed up
+func (p *userPostgre) FindAll(ctx context.Context, tx Tx, from int, size int) ([]User, error) {
+	SQL := "SELECT user_id, email, username, name, role, suspended_at, verified_at FROM users ORDER BY user_id LIMIT $1 OFFSET $2"
+	rows, err := sqlTx(tx).QueryContext(ctx, SQL, size, from)
 	if err != nil {
-		return User{}, ErrUserNotFound
+		return nil, fmt.Errorf("failed to find users because %w", err)
 	}
 	defer rows.Close()
 
-	user := User{}
-	if rows.Next() {
-		if err := rows.Scan(&user.ID, &user.Email, &user.Username, &user.Name); err != nil {
-			return user, ErrFailedToAssertUser
+	users := []User{}
+	for rows.Next() {
+		var user User
+		if err := rows.Scan(&user.ID, &user.Email, &user.Username, &user.Name, &user.Role, &user.SuspendedAt, &user.VerifiedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan user because %w", err)
 		}
-		return user, nil
-	} else {
-		return user, ErrUserNotFound
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
+func (p *userPostgre) UpdateRole(ctx context.Context, tx Tx, id int64, role string) error {
+	SQL := "UPDATE users SET role = $1 WHERE user_id = $2"
+	result, err := sqlTx(tx).ExecContext(ctx, SQL, role, id)
+	if err != nil {
+		return fmt.Errorf("failed to update role of user with id: %d because %w", id, err)
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("failed to update role of user with id: %d because %w", id, ErrUserNotFound)
+	}
+
+	return nil
+}
+
+// Suspend suspend the user since suspendedAt, nil lift the suspension
+func (p *userPostgre) Suspend(ctx context.Context, tx Tx, id int64, suspendedAt *time.Time) error {
+	SQL := "UPDATE users SET suspended_at = $1 WHERE user_id = $2"
+	result, err := sqlTx(tx).ExecContext(ctx, SQL, suspendedAt, id)
+	if err != nil {
+		return fmt.Errorf("failed to suspend user with id: %d because %w", id, err)
 	}
+
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("failed to suspend user with id: %d because %w", id, ErrUserNotFound)
+	}
+
+	return nil
+}
+
+// Verify record that the user confirmed their email at verifiedAt
+func (p *userPostgre) Verify(ctx context.Context, tx Tx, id int64, verifiedAt time.Time) error {
+	SQL := "UPDATE users SET verified_at = $1 WHERE user_id = $2"
+	result, err := sqlTx(tx).ExecContext(ctx, SQL, verifiedAt, id)
+	if err != nil {
+		return fmt.Errorf("failed to verify user with id: %d because %w", id, err)
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("failed to verify user with id: %d because %w", id, ErrUserNotFound)
+	}
+
+	return nil
 }
@@ -0,0 +1,99 @@
+package search
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/izzanzahrial/blog-api-echo/pkg/elastic"
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+)
+
+type elasticSearcher struct {
+	Es elastic.ElasticDB
+}
+
+func NewElastic(es elastic.ElasticDB) Searcher {
+	return &elasticSearcher{
+		Es: es,
+	}
+}
+
+func (e *elasticSearcher) FindByID(ctx context.Context, id int64) (repository.PostData, error) {
+	return e.Es.FindByID(ctx, strconv.Itoa(int(id)))
+}
+
+func (e *elasticSearcher) FindBySlug(ctx context.Context, slug string) (repository.PostData, error) {
+	return e.Es.FindBySlug(ctx, slug)
+}
+
+// Search return every hit with its score and highlighted fragments
+func (e *elasticSearcher) Search(ctx context.Context, query repository.SearchQuery, filter repository.PostFilter, from int, size int) (repository.SearchResult, error) {
+	foundPosts, err := e.Es.FindByTitleContent(ctx, query, filter, from, size)
+	if err != nil {
+		return repository.SearchResult{}, err
+	}
+
+	result := repository.SearchResult{
+		Total:      int64(foundPosts.Total),
+		Hits:       []repository.SearchHit{},
+		Facets:     foundPosts.Facets,
+		DidYouMean: foundPosts.DidYouMean,
+	}
+	for _, doc := range foundPosts.Hits {
+		result.Hits = append(result.Hits, repository.SearchHit{
+			PostData:   documentToPost(doc),
+			Score:      doc.Score,
+			Highlights: doc.Highlights,
+		})
+	}
+
+	return result, nil
+}
+
+func (e *elasticSearcher) Recent(ctx context.Context, filter repository.PostFilter, from int, size int) ([]repository.PostData, error) {
+	foundPosts, err := e.Es.FindByRecent(ctx, filter, from, size)
+	if err != nil {
+		return nil, err
+	}
+
+	var posts []repository.PostData
+	for _, doc := range foundPosts.Hits {
+		posts = append(posts, documentToPost(doc))
+	}
+
+	return posts, nil
+}
+
+func (e *elasticSearcher) Suggest(ctx context.Context, prefix string, size int) ([]repository.Suggestion, error) {
+	return e.Es.Suggest(ctx, prefix, size)
+}
+
+func (e *elasticSearcher) Related(ctx context.Context, id int64, size int) ([]repository.PostData, error) {
+	return e.Es.Related(ctx, id, size)
+}
+
+func (e *elasticSearcher) Index(ctx context.Context, post repository.PostData) error {
+	return e.Es.Upsert(ctx, post)
+}
+
+func (e *elasticSearcher) Remove(ctx context.Context, id int64) error {
+	return e.Es.Delete(ctx, strconv.Itoa(int(id)))
+}
+
+// documentToPost turn an elasticsearch hit into a post
+func documentToPost(doc *elastic.Document) repository.PostData {
+	var post repository.PostData
+	post.ID = int64(doc.ID)
+	post.Title = doc.Title
+	post.Slug = doc.Slug
+	post.ShortDesc = doc.ShortDesc
+	post.Content = doc.Content
+	post.CreatedAt = doc.CreatedAt
+	post.Status = doc.Status
+	post.Author = doc.Author
+	post.Tags = doc.Tags
+	post.Categories = doc.Categories
+	post.CommentCount = doc.CommentCount
+
+	return post
+}
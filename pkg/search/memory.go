@@ -0,0 +1,66 @@
+package search
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/izzanzahrial/blog-api-echo/pkg/elastic"
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+)
+
+// loadBatchSize is how many post is read at once when the memory searcher is loaded
+const loadBatchSize = 500
+
+// Memory search the published posts kept in an elastic.Memory instead of elasticsearch,
+// the relay keep them in line the same way. It's single instance and for development only,
+// another instance neither see the posts it index nor the changes delivered by another relay
+type Memory struct {
+	Searcher
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		Searcher: NewElastic(elastic.NewMemory()),
+	}
+}
+
+// Load index every published post of the repository, the relay keep them in line afterward
+func (m *Memory) Load(ctx context.Context, rp repository.Post, db DBtx) error {
+	var afterID int64
+	for {
+		posts, err := loadBatch(ctx, rp, db, afterID)
+		if err != nil {
+			return err
+		}
+
+		for _, post := range posts {
+			if err := m.Index(ctx, post); err != nil {
+				return err
+			}
+			afterID = post.ID
+		}
+
+		if len(posts) < loadBatchSize {
+			return nil
+		}
+	}
+}
+
+func loadBatch(ctx context.Context, rp repository.Post, db DBtx, afterID int64) ([]repository.PostData, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction for loading posts because: %w", err)
+	}
+	defer tx.Rollback()
+
+	posts, err := rp.FindPublishedAfter(ctx, tx, afterID, loadBatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction for loading posts because: %w", err)
+	}
+
+	return posts, nil
+}
@@ -0,0 +1,136 @@
+package search
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestMemory(t *testing.T) *Memory {
+	m := NewMemory()
+	ctx := context.Background()
+	day := time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	posts := []repository.PostData{
+		{ID: 1, Title: "Goroutine basics", Content: "start a goroutine with the go keyword", Status: repository.StatusPublished,
+			CreatedAt: day, Author: repository.Author{Username: "izzan"}, Tags: []string{"go"}},
+		{ID: 2, Title: "Channels", Slug: "channels", Content: "a channel connect goroutine together", Status: repository.StatusPublished,
+			CreatedAt: day.AddDate(0, 0, 1), Author: repository.Author{Username: "izzan"}, Tags: []string{"go"}},
+		{ID: 3, Title: "Routing with echo", Slug: "routing-with-echo", Content: "echo route the request to a handler", Status: repository.StatusPublished,
+			CreatedAt: day.AddDate(0, -1, 0), Author: repository.Author{Username: "zahrial"}, Tags: []string{"web"}},
+		{ID: 4, Title: "Goroutine leaks", Content: "draft about leaking goroutine", Status: repository.StatusDraft,
+			CreatedAt: day, Author: repository.Author{Username: "izzan"}},
+	}
+	for _, post := range posts {
+		if err := m.Index(ctx, post); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return m
+}
+
+func searchIDs(result repository.SearchResult) []int64 {
+	ids := []int64{}
+	for _, hit := range result.Hits {
+		ids = append(ids, hit.ID)
+	}
+	return ids
+}
+
+func TestMemorySearch(t *testing.T) {
+	m := newTestMemory(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		text   string
+		mode   string
+		filter repository.PostFilter
+		ids    []int64
+	}{
+		{name: "title count twice", text: "goroutine", ids: []int64{1, 2}},
+		{name: "fuzzy", text: "gorutine", ids: []int64{1, 2}},
+		{name: "exact doesn't tolerate typo", text: "gorutine", mode: repository.QueryModeExact, ids: []int64{}},
+		{name: "exclude", text: "goroutine -channel", ids: []int64{1}},
+		{name: "or", text: "echo OR channels", mode: repository.QueryModeExact, ids: []int64{3, 2}},
+		{name: "field", text: "title:echo", ids: []int64{3}},
+		{name: "phrase", text: `"connect goroutine"`, ids: []int64{2}},
+		{name: "filter", text: "goroutine", filter: repository.PostFilter{Tag: "web"}, ids: []int64{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			query, err := repository.ParseQuery(test.text, test.mode)
+			assert.NoError(t, err)
+
+			result, err := m.Search(ctx, query, test.filter, 0, 10)
+			assert.NoError(t, err)
+			assert.Equal(t, test.ids, searchIDs(result))
+			assert.Equal(t, int64(len(test.ids)), result.Total)
+		})
+	}
+}
+
+func TestMemorySearchFacets(t *testing.T) {
+	m := newTestMemory(t)
+
+	query, _ := repository.ParseQuery("", "")
+	result, err := m.Search(context.Background(), query, repository.PostFilter{}, 0, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), result.Total)
+	assert.Equal(t, []int64{2}, searchIDs(result))
+	assert.Equal(t, &repository.Facets{
+		Tags:    []repository.FacetBucket{{Key: "go", Count: 2}, {Key: "web", Count: 1}},
+		Authors: []repository.FacetBucket{{Key: "izzan", Count: 2}, {Key: "zahrial", Count: 1}},
+		Months:  []repository.FacetBucket{{Key: "2022-04", Count: 1}, {Key: "2022-05", Count: 2}},
+	}, result.Facets)
+}
+
+func TestMemorySearchDidYouMean(t *testing.T) {
+	m := newTestMemory(t)
+
+	query, _ := repository.ParseQuery("chanels", repository.QueryModeExact)
+	result, err := m.Search(context.Background(), query, repository.PostFilter{}, 0, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), result.Total)
+	assert.Equal(t, "channels", result.DidYouMean)
+}
+
+func TestMemoryIndex(t *testing.T) {
+	m := newTestMemory(t)
+	ctx := context.Background()
+
+	post, err := m.FindBySlug(ctx, "channels")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), post.ID)
+
+	// the relay remove the post that is no longer published
+	assert.NoError(t, m.Remove(ctx, 2))
+	_, err = m.FindByID(ctx, 2)
+	assert.ErrorIs(t, err, repository.ErrPostNotFound)
+	_, err = m.FindBySlug(ctx, "channels")
+	assert.ErrorIs(t, err, repository.ErrPostNotFound)
+}
+
+func TestMemoryRelated(t *testing.T) {
+	m := newTestMemory(t)
+
+	related, err := m.Related(context.Background(), 1, 5)
+	assert.NoError(t, err)
+	// the shared tag rank the channels above the post that only share a few words
+	assert.Len(t, related, 2)
+	assert.Equal(t, int64(2), related[0].ID)
+	assert.Equal(t, int64(3), related[1].ID)
+}
+
+func TestMemorySuggest(t *testing.T) {
+	m := newTestMemory(t)
+
+	suggestions, err := m.Suggest(context.Background(), "ro", 5)
+	assert.NoError(t, err)
+	assert.Equal(t, []repository.Suggestion{{ID: 3, Title: "Routing with echo", Slug: "routing-with-echo"}}, suggestions)
+}
@@ -0,0 +1,152 @@
+package search
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+)
+
+// postgresSearcher search with the full text and trigram index of the repository,
+// the repository is where the post is stored so there's nothing to index
+type postgresSearcher struct {
+	Repository repository.Post
+	DB         DBtx
+}
+
+func NewPostgres(rp repository.Post, db DBtx) Searcher {
+	return &postgresSearcher{
+		Repository: rp,
+		DB:         db,
+	}
+}
+
+func (p *postgresSearcher) FindByID(ctx context.Context, id int64) (repository.PostData, error) {
+	tx, err := p.DB.Begin()
+	if err != nil {
+		return repository.PostData{}, fmt.Errorf("failed to begin transaction: %d because %w", id, err)
+	}
+	defer tx.Rollback()
+
+	post, err := p.Repository.FindByID(ctx, tx, id)
+	if err != nil {
+		return repository.PostData{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return repository.PostData{}, fmt.Errorf("failed to commit transaction: %d because %w", id, err)
+	}
+
+	if post.Status != repository.StatusPublished {
+		return repository.PostData{}, fmt.Errorf("failed to find the post by id: %d because %w", id, repository.ErrPostNotFound)
+	}
+
+	return post, nil
+}
+
+func (p *postgresSearcher) FindBySlug(ctx context.Context, slug string) (repository.PostData, error) {
+	tx, err := p.DB.Begin()
+	if err != nil {
+		return repository.PostData{}, fmt.Errorf("failed to begin transaction: %s because %w", slug, err)
+	}
+	defer tx.Rollback()
+
+	post, err := p.Repository.FindBySlug(ctx, tx, slug)
+	if err != nil {
+		return repository.PostData{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return repository.PostData{}, fmt.Errorf("failed to commit transaction: %s because %w", slug, err)
+	}
+
+	if post.Status != repository.StatusPublished {
+		return repository.PostData{}, fmt.Errorf("failed to find the post by slug: %s because %w", slug, repository.ErrPostNotFound)
+	}
+
+	return post, nil
+}
+
+func (p *postgresSearcher) Search(ctx context.Context, query repository.SearchQuery, filter repository.PostFilter, from int, size int) (repository.SearchResult, error) {
+	tx, err := p.DB.Begin()
+	if err != nil {
+		return repository.SearchResult{}, fmt.Errorf("failed to begin transaction for query: %v because %w", query.Text, err)
+	}
+	defer tx.Rollback()
+
+	result, err := p.Repository.FindByTitleContent(ctx, tx, query, filter, from, size)
+	if err != nil {
+		return repository.SearchResult{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return repository.SearchResult{}, fmt.Errorf("failed to commit transaction for query: %v because %w", query.Text, err)
+	}
+
+	return result, nil
+}
+
+func (p *postgresSearcher) Recent(ctx context.Context, filter repository.PostFilter, from int, size int) ([]repository.PostData, error) {
+	tx, err := p.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction for finding recent post because: %w", err)
+	}
+	defer tx.Rollback()
+
+	posts, err := p.Repository.FindRecent(ctx, tx, filter, from, size)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction for finding recent post because: %w", err)
+	}
+
+	return posts, nil
+}
+
+func (p *postgresSearcher) Suggest(ctx context.Context, prefix string, size int) ([]repository.Suggestion, error) {
+	tx, err := p.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction for suggestion: %s because %w", prefix, err)
+	}
+	defer tx.Rollback()
+
+	suggestions, err := p.Repository.SuggestTitles(ctx, tx, prefix, size)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction for suggestion: %s because %w", prefix, err)
+	}
+
+	return suggestions, nil
+}
+
+func (p *postgresSearcher) Related(ctx context.Context, id int64, size int) ([]repository.PostData, error) {
+	tx, err := p.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction for post related to post: %d because %w", id, err)
+	}
+	defer tx.Rollback()
+
+	posts, err := p.Repository.FindRelated(ctx, tx, id, size)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction for post related to post: %d because %w", id, err)
+	}
+
+	return posts, nil
+}
+
+func (p *postgresSearcher) Index(ctx context.Context, post repository.PostData) error {
+	return nil
+}
+
+func (p *postgresSearcher) Remove(ctx context.Context, id int64) error {
+	return nil
+}
@@ -0,0 +1,153 @@
+package search
+
+import (
+	"context"
+
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+	"github.com/stretchr/testify/mock"
+)
+
+// Searcher find the published posts, it's kept in line with the repository by the relay
+// through Index and Remove
+type Searcher interface {
+	FindByID(ctx context.Context, id int64) (repository.PostData, error)
+	FindBySlug(ctx context.Context, slug string) (repository.PostData, error)
+	Search(ctx context.Context, query repository.SearchQuery, filter repository.PostFilter, from int, size int) (repository.SearchResult, error)
+	Recent(ctx context.Context, filter repository.PostFilter, from int, size int) ([]repository.PostData, error)
+	Suggest(ctx context.Context, prefix string, size int) ([]repository.Suggestion, error)
+	Related(ctx context.Context, id int64, size int) ([]repository.PostData, error)
+	// Index add or replace a published post, Remove take out a post that is no longer published
+	Index(ctx context.Context, post repository.PostData) error
+	Remove(ctx context.Context, id int64) error
+}
+
+// Searcher backend, elasticsearch fall back to postgres when it's unavailable
+const (
+	BackendElastic  = "elastic"
+	BackendPostgres = "postgres"
+	BackendMemory   = "memory"
+)
+
+type DBtx interface {
+	Begin() (repository.Tx, error)
+}
+
+type MockSearcher struct {
+	mock.Mock
+}
+
+func (m *MockSearcher) FindByID(ctx context.Context, id int64) (repository.PostData, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(repository.PostData), args.Error(1)
+}
+
+func (m *MockSearcher) FindBySlug(ctx context.Context, slug string) (repository.PostData, error) {
+	args := m.Called(ctx, slug)
+	return args.Get(0).(repository.PostData), args.Error(1)
+}
+
+func (m *MockSearcher) Search(ctx context.Context, query repository.SearchQuery, filter repository.PostFilter, from int, size int) (repository.SearchResult, error) {
+	args := m.Called(ctx, query, filter, from, size)
+	return args.Get(0).(repository.SearchResult), args.Error(1)
+}
+
+func (m *MockSearcher) Recent(ctx context.Context, filter repository.PostFilter, from int, size int) ([]repository.PostData, error) {
+	args := m.Called(ctx, filter, from, size)
+	return args.Get(0).([]repository.PostData), args.Error(1)
+}
+
+func (m *MockSearcher) Suggest(ctx context.Context, prefix string, size int) ([]repository.Suggestion, error) {
+	args := m.Called(ctx, prefix, size)
+	return args.Get(0).([]repository.Suggestion), args.Error(1)
+}
+
+func (m *MockSearcher) Related(ctx context.Context, id int64, size int) ([]repository.PostData, error) {
+	args := m.Called(ctx, id, size)
+	return args.Get(0).([]repository.PostData), args.Error(1)
+}
+
+func (m *MockSearcher) Index(ctx context.Context, post repository.PostData) error {
+	args := m.Called(ctx, post)
+	return args.Error(0)
+}
+
+func (m *MockSearcher) Remove(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+// fallback read from the secondary searcher whenever the primary one fail
+type fallback struct {
+	Primary   Searcher
+	Secondary Searcher
+}
+
+func NewFallback(primary Searcher, secondary Searcher) Searcher {
+	return &fallback{
+		Primary:   primary,
+		Secondary: secondary,
+	}
+}
+
+func (f *fallback) FindByID(ctx context.Context, id int64) (repository.PostData, error) {
+	post, err := f.Primary.FindByID(ctx, id)
+	if err != nil {
+		return f.Secondary.FindByID(ctx, id)
+	}
+	return post, nil
+}
+
+func (f *fallback) FindBySlug(ctx context.Context, slug string) (repository.PostData, error) {
+	post, err := f.Primary.FindBySlug(ctx, slug)
+	if err != nil {
+		return f.Secondary.FindBySlug(ctx, slug)
+	}
+	return post, nil
+}
+
+func (f *fallback) Search(ctx context.Context, query repository.SearchQuery, filter repository.PostFilter, from int, size int) (repository.SearchResult, error) {
+	result, err := f.Primary.Search(ctx, query, filter, from, size)
+	if err != nil {
+		return f.Secondary.Search(ctx, query, filter, from, size)
+	}
+	return result, nil
+}
+
+func (f *fallback) Recent(ctx context.Context, filter repository.PostFilter, from int, size int) ([]repository.PostData, error) {
+	posts, err := f.Primary.Recent(ctx, filter, from, size)
+	if err != nil {
+		return f.Secondary.Recent(ctx, filter, from, size)
+	}
+	return posts, nil
+}
+
+func (f *fallback) Suggest(ctx context.Context, prefix string, size int) ([]repository.Suggestion, error) {
+	suggestions, err := f.Primary.Suggest(ctx, prefix, size)
+	if err != nil {
+		return f.Secondary.Suggest(ctx, prefix, size)
+	}
+	return suggestions, nil
+}
+
+func (f *fallback) Related(ctx context.Context, id int64, size int) ([]repository.PostData, error) {
+	posts, err := f.Primary.Related(ctx, id, size)
+	if err != nil {
+		return f.Secondary.Related(ctx, id, size)
+	}
+	return posts, nil
+}
+
+// Index write to both searcher, so the secondary one is ready when it's needed
+func (f *fallback) Index(ctx context.Context, post repository.PostData) error {
+	if err := f.Primary.Index(ctx, post); err != nil {
+		return err
+	}
+	return f.Secondary.Index(ctx, post)
+}
+
+func (f *fallback) Remove(ctx context.Context, id int64) error {
+	if err := f.Primary.Remove(ctx, id); err != nil {
+		return err
+	}
+	return f.Secondary.Remove(ctx, id)
+}
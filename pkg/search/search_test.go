@@ -0,0 +1,67 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFallbackRead(t *testing.T) {
+	primary := new(MockSearcher)
+	secondary := new(MockSearcher)
+	searcher := NewFallback(primary, secondary)
+
+	ctx := context.Background()
+	suggestions := []repository.Suggestion{{ID: 1, Title: "Go Concurrency", Slug: "go-concurrency"}}
+
+	primary.On("Suggest", ctx, "go con", 5).Return([]repository.Suggestion(nil), errors.New("elastic is down")).Once()
+	secondary.On("Suggest", ctx, "go con", 5).Return(suggestions, nil).Once()
+
+	result, err := searcher.Suggest(ctx, "go con", 5)
+	assert.NoError(t, err)
+	assert.Equal(t, suggestions, result)
+
+	primary.AssertExpectations(t)
+	secondary.AssertExpectations(t)
+}
+
+func TestFallbackPrimary(t *testing.T) {
+	primary := new(MockSearcher)
+	secondary := new(MockSearcher)
+	searcher := NewFallback(primary, secondary)
+
+	ctx := context.Background()
+	related := []repository.PostData{{ID: 2}, {ID: 3}}
+
+	primary.On("Related", ctx, int64(1), 10).Return(related, nil).Once()
+
+	result, err := searcher.Related(ctx, 1, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, related, result)
+
+	secondary.AssertNotCalled(t, "Related")
+}
+
+func TestFallbackIndex(t *testing.T) {
+	primary := new(MockSearcher)
+	secondary := new(MockSearcher)
+	searcher := NewFallback(primary, secondary)
+
+	ctx := context.Background()
+	post := repository.PostData{ID: 1, Status: repository.StatusPublished}
+
+	primary.On("Index", ctx, post).Return(nil).Once()
+	secondary.On("Index", ctx, post).Return(nil).Once()
+	primary.On("Remove", ctx, int64(2)).Return(errors.New("elastic is down")).Once()
+
+	assert.NoError(t, searcher.Index(ctx, post))
+	// the relay retry a failed event, so the secondary one is written on the next attempt
+	assert.Error(t, searcher.Remove(ctx, 2))
+
+	primary.AssertExpectations(t)
+	secondary.AssertExpectations(t)
+	secondary.AssertNotCalled(t, "Remove")
+}
@@ -0,0 +1,300 @@
+package taxonomy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+	"github.com/stretchr/testify/mock"
+)
+
+var (
+	ErrTaxonomyIsntValidate = errors.New("tag or category data from handler isn't validate")
+)
+
+type Service interface {
+	CreateTag(ctx context.Context, t Tag) (repository.Tag, error)
+	UpdateTag(ctx context.Context, id int64, t Tag) (repository.Tag, error)
+	DeleteTag(ctx context.Context, id int64) error
+	FindTags(ctx context.Context) ([]repository.Tag, error)
+	CreateCategory(ctx context.Context, c Category) (repository.Category, error)
+	UpdateCategory(ctx context.Context, id int64, c Category) (repository.Category, error)
+	DeleteCategory(ctx context.Context, id int64) error
+	FindCategories(ctx context.Context) ([]*CategoryNode, error)
+}
+
+type MockService struct {
+	mock.Mock
+}
+
+func (m *MockService) CreateTag(ctx context.Context, t Tag) (repository.Tag, error) {
+	args := m.Called(ctx, t)
+	return args.Get(0).(repository.Tag), args.Error(1)
+}
+
+func (m *MockService) UpdateTag(ctx context.Context, id int64, t Tag) (repository.Tag, error) {
+	args := m.Called(ctx, id, t)
+	return args.Get(0).(repository.Tag), args.Error(1)
+}
+
+func (m *MockService) DeleteTag(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockService) FindTags(ctx context.Context) ([]repository.Tag, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]repository.Tag), args.Error(1)
+}
+
+func (m *MockService) CreateCategory(ctx context.Context, c Category) (repository.Category, error) {
+	args := m.Called(ctx, c)
+	return args.Get(0).(repository.Category), args.Error(1)
+}
+
+func (m *MockService) UpdateCategory(ctx context.Context, id int64, c Category) (repository.Category, error) {
+	args := m.Called(ctx, id, c)
+	return args.Get(0).(repository.Category), args.Error(1)
+}
+
+func (m *MockService) DeleteCategory(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockService) FindCategories(ctx context.Context) ([]*CategoryNode, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*CategoryNode), args.Error(1)
+}
+
+type DBtx interface {
+	Begin() (repository.Tx, error)
+}
+
+type service struct {
+	TagRepository      repository.TagRepository
+	CategoryRepository repository.CategoryRepository
+	DB                 DBtx
+	Validate           *validator.Validate
+}
+
+func NewService(tr repository.TagRepository, cr repository.CategoryRepository, db DBtx, val *validator.Validate) Service {
+	return &service{
+		TagRepository:      tr,
+		CategoryRepository: cr,
+		DB:                 db,
+		Validate:           val,
+	}
+}
+
+func (ts *service) CreateTag(ctx context.Context, t Tag) (repository.Tag, error) {
+	t.Name = NormalizeName(t.Name)
+	if err := ts.Validate.Struct(t); err != nil {
+		return repository.Tag{}, fmt.Errorf("failed to validate: %v because %w", t, ErrTaxonomyIsntValidate)
+	}
+
+	tx, err := ts.DB.Begin()
+	if err != nil {
+		return repository.Tag{}, fmt.Errorf("failed to begin transaction for: %v because %w", t, err)
+	}
+	defer tx.Rollback()
+
+	tag, err := ts.TagRepository.Create(ctx, tx, repository.Tag{Name: t.Name})
+	if err != nil {
+		return repository.Tag{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return repository.Tag{}, fmt.Errorf("failed to commit transaction: %v because %w", tag, err)
+	}
+
+	return tag, nil
+}
+
+// UpdateTag rename the tag, post that is already indexed keep the old name until it's reindexed
+func (ts *service) UpdateTag(ctx context.Context, id int64, t Tag) (repository.Tag, error) {
+	t.Name = NormalizeName(t.Name)
+	if err := ts.Validate.Struct(t); err != nil {
+		return repository.Tag{}, fmt.Errorf("failed to validate: %v because %w", t, ErrTaxonomyIsntValidate)
+	}
+
+	tx, err := ts.DB.Begin()
+	if err != nil {
+		return repository.Tag{}, fmt.Errorf("failed to begin transaction for: %v because %w", t, err)
+	}
+	defer tx.Rollback()
+
+	tag, err := ts.TagRepository.Update(ctx, tx, repository.Tag{ID: id, Name: t.Name})
+	if err != nil {
+		return repository.Tag{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return repository.Tag{}, fmt.Errorf("failed to commit transaction: %v because %w", tag, err)
+	}
+
+	return tag, nil
+}
+
+func (ts *service) DeleteTag(ctx context.Context, id int64) error {
+	tx, err := ts.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for tag: %d because %w", id, err)
+	}
+	defer tx.Rollback()
+
+	if err := ts.TagRepository.Delete(ctx, tx, id); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction for tag: %d because %w", id, err)
+	}
+
+	return nil
+}
+
+func (ts *service) FindTags(ctx context.Context) ([]repository.Tag, error) {
+	tx, err := ts.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction for finding tags because: %w", err)
+	}
+	defer tx.Rollback()
+
+	tags, err := ts.TagRepository.FindAll(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction for finding tags because: %w", err)
+	}
+
+	return tags, nil
+}
+
+func (ts *service) CreateCategory(ctx context.Context, c Category) (repository.Category, error) {
+	c.Name = strings.TrimSpace(c.Name)
+	if err := ts.Validate.Struct(c); err != nil {
+		return repository.Category{}, fmt.Errorf("failed to validate: %v because %w", c, ErrTaxonomyIsntValidate)
+	}
+
+	tx, err := ts.DB.Begin()
+	if err != nil {
+		return repository.Category{}, fmt.Errorf("failed to begin transaction for: %v because %w", c, err)
+	}
+	defer tx.Rollback()
+
+	if c.ParentID != nil {
+		if _, err := ts.CategoryRepository.FindByID(ctx, tx, *c.ParentID); err != nil {
+			return repository.Category{}, err
+		}
+	}
+
+	category, err := ts.CategoryRepository.Create(ctx, tx, repository.Category{Name: c.Name, ParentID: c.ParentID})
+	if err != nil {
+		return repository.Category{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return repository.Category{}, fmt.Errorf("failed to commit transaction: %v because %w", category, err)
+	}
+
+	return category, nil
+}
+
+// UpdateCategory rename or move the category, it can't be moved under itself or its own descendant
+func (ts *service) UpdateCategory(ctx context.Context, id int64, c Category) (repository.Category, error) {
+	c.Name = strings.TrimSpace(c.Name)
+	if err := ts.Validate.Struct(c); err != nil {
+		return repository.Category{}, fmt.Errorf("failed to validate: %v because %w", c, ErrTaxonomyIsntValidate)
+	}
+
+	tx, err := ts.DB.Begin()
+	if err != nil {
+		return repository.Category{}, fmt.Errorf("failed to begin transaction for: %v because %w", c, err)
+	}
+	defer tx.Rollback()
+
+	if c.ParentID != nil {
+		cycle, err := ts.CategoryRepository.IsDescendant(ctx, tx, *c.ParentID, id)
+		if err != nil {
+			return repository.Category{}, err
+		}
+		if cycle {
+			return repository.Category{}, repository.ErrCategoryCycle
+		}
+	}
+
+	category, err := ts.CategoryRepository.Update(ctx, tx, repository.Category{ID: id, Name: c.Name, ParentID: c.ParentID})
+	if err != nil {
+		return repository.Category{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return repository.Category{}, fmt.Errorf("failed to commit transaction: %v because %w", category, err)
+	}
+
+	return category, nil
+}
+
+func (ts *service) DeleteCategory(ctx context.Context, id int64) error {
+	tx, err := ts.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for category: %d because %w", id, err)
+	}
+	defer tx.Rollback()
+
+	if err := ts.CategoryRepository.Delete(ctx, tx, id); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction for category: %d because %w", id, err)
+	}
+
+	return nil
+}
+
+func (ts *service) FindCategories(ctx context.Context) ([]*CategoryNode, error) {
+	tx, err := ts.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction for finding categories because: %w", err)
+	}
+	defer tx.Rollback()
+
+	categories, err := ts.CategoryRepository.FindAll(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction for finding categories because: %w", err)
+	}
+
+	return buildTree(categories), nil
+}
+
+// buildTree nest the flat categories under their parent, keeping the order of the slice
+func buildTree(categories []repository.Category) []*CategoryNode {
+	nodes := make(map[int64]*CategoryNode, len(categories))
+	for _, category := range categories {
+		nodes[category.ID] = &CategoryNode{Category: category, Children: []*CategoryNode{}}
+	}
+
+	roots := []*CategoryNode{}
+	for _, category := range categories {
+		node := nodes[category.ID]
+		if category.ParentID == nil || nodes[*category.ParentID] == nil {
+			roots = append(roots, node)
+			continue
+		}
+		parent := nodes[*category.ParentID]
+		parent.Children = append(parent.Children, node)
+	}
+
+	return roots
+}
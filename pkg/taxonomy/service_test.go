@@ -0,0 +1,33 @@
+package taxonomy
+
+import (
+	"testing"
+
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeNames(t *testing.T) {
+	names := NormalizeNames([]string{" Go  Lang", "go lang", "", "Echo"})
+	assert.Equal(t, []string{"go lang", "echo"}, names)
+}
+
+func TestBuildTree(t *testing.T) {
+	parent := int64(1)
+	child := int64(2)
+	categories := []repository.Category{
+		{ID: 1, Name: "Programming"},
+		{ID: 2, Name: "Go", ParentID: &parent},
+		{ID: 3, Name: "Concurrency", ParentID: &child},
+		{ID: 4, Name: "Travel"},
+	}
+
+	tree := buildTree(categories)
+
+	assert.Len(t, tree, 2)
+	assert.Equal(t, "Programming", tree[0].Name)
+	assert.Equal(t, "Go", tree[0].Children[0].Name)
+	assert.Equal(t, "Concurrency", tree[0].Children[0].Children[0].Name)
+	assert.Equal(t, "Travel", tree[1].Name)
+	assert.Empty(t, tree[1].Children)
+}
@@ -0,0 +1,44 @@
+package taxonomy
+
+import (
+	"strings"
+
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+)
+
+type Tag struct {
+	Name string `json:"name" validate:"required,max=64"`
+}
+
+type Category struct {
+	Name     string `json:"name" validate:"required,max=64"`
+	ParentID *int64 `json:"parent_id"`
+}
+
+// CategoryNode is a category with its children, used to return the whole tree
+type CategoryNode struct {
+	repository.Category
+	Children []*CategoryNode `json:"children"`
+}
+
+// NormalizeName lowercase the tag name and collapse its spaces, so "Go  Lang" and "go lang" is the same tag
+func NormalizeName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// NormalizeNames normalize every name and drop the empty and duplicate one
+func NormalizeNames(names []string) []string {
+	seen := make(map[string]bool)
+	result := []string{}
+
+	for _, name := range names {
+		name = NormalizeName(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		result = append(result, name)
+	}
+
+	return result
+}
@@ -0,0 +1,97 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	caching "github.com/izzanzahrial/blog-api-echo/pkg/redis"
+)
+
+// Denylist keep the revoked access tokens in the cache until they would have expired anyway,
+// the jwt middleware only check the signature so every request must also be checked against it
+type Denylist struct {
+	Cache caching.Cache
+	// AccessTTL is how long the access token last, once it's over a revoked user doesn't need to be remembered
+	AccessTTL time.Duration
+}
+
+func NewDenylist(cache caching.Cache, accessTTL time.Duration) *Denylist {
+	if accessTTL <= 0 {
+		accessTTL = DefaultAccessTTL
+	}
+
+	return &Denylist{
+		Cache:     cache,
+		AccessTTL: accessTTL,
+	}
+}
+
+// RevokeToken deny the access token, an expired token doesn't need to be denied
+func (d *Denylist) RevokeToken(ctx context.Context, claims *JWTClaims) error {
+	ttl := time.Until(time.Unix(claims.ExpiresAt, 0))
+	if claims.Id == "" || ttl <= 0 {
+		return nil
+	}
+
+	return d.Cache.Set(ctx, caching.RevokedTokenKey(claims.Id), 1, ttl).Err()
+}
+
+// RevokeSession deny every access token of the session, whatever their jti, the session can't get another one
+func (d *Denylist) RevokeSession(ctx context.Context, sessionID string) error {
+	if sessionID == "" {
+		return nil
+	}
+
+	return d.Cache.Set(ctx, caching.RevokedSessionKey(sessionID), 1, d.AccessTTL).Err()
+}
+
+// RevokeUser deny every access token of the user issued until revokedAt, to the nanosecond
+func (d *Denylist) RevokeUser(ctx context.Context, userID int64, revokedAt time.Time) error {
+	return d.Cache.Set(ctx, caching.RevokedUserKey(userID), revokedAt.UnixNano(), d.AccessTTL).Err()
+}
+
+// IsRevoked report whether the access token was revoked by itself, along with its session
+// or along with every token of the user
+func (d *Denylist) IsRevoked(ctx context.Context, claims *JWTClaims) (bool, error) {
+	keys := []string{}
+	if claims.Id != "" {
+		keys = append(keys, caching.RevokedTokenKey(claims.Id))
+	}
+	if claims.SessionID != "" {
+		keys = append(keys, caching.RevokedSessionKey(claims.SessionID))
+	}
+
+	for _, key := range keys {
+		err := d.Cache.Get(ctx, key).Err()
+		if err == nil {
+			return true, nil
+		}
+		if !errors.Is(err, redis.Nil) {
+			return false, err
+		}
+	}
+
+	value, err := d.Cache.Get(ctx, caching.RevokedUserKey(claims.ID)).Result()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	revokedAt, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return false, err
+	}
+
+	// a token without iat_ns only carry the second, it's denied if it could be issued before the revocation
+	issuedAt := claims.IssuedAtNano
+	if issuedAt == 0 {
+		issuedAt = time.Unix(claims.IssuedAt, 0).UnixNano()
+	}
+
+	return issuedAt <= revokedAt, nil
+}
@@ -0,0 +1,133 @@
+package user
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+// parseClaims return the claims of the access token
+func parseClaims(t *testing.T, token string) *JWTClaims {
+	claims := &JWTClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+		return testTokenConfig.SigningKey, nil
+	})
+	assert.NoError(t, err)
+
+	return claims
+}
+
+func TestDenylist(t *testing.T) {
+	us, _, _, tokens := newMemoryService(t)
+	claims := parseClaims(t, tokens.AccessToken)
+	assert.NotEmpty(t, claims.Id)
+
+	revoked, err := us.Denylist.IsRevoked(context.Background(), claims)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	assert.NoError(t, us.Denylist.RevokeToken(context.Background(), claims))
+	revoked, err = us.Denylist.IsRevoked(context.Background(), claims)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	// another token of the same user isn't revoked along
+	other := *claims
+	other.Id = "other"
+	revoked, err = us.Denylist.IsRevoked(context.Background(), &other)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	// revoking the user deny the token issued until then but not the later one, even in the same second
+	revokedAt := time.Unix(0, other.IssuedAtNano)
+	assert.NoError(t, us.Denylist.RevokeUser(context.Background(), claims.ID, revokedAt))
+	revoked, err = us.Denylist.IsRevoked(context.Background(), &other)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	other.IssuedAtNano++
+	revoked, err = us.Denylist.IsRevoked(context.Background(), &other)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	// a token that only carry the second is denied through the whole second
+	other.IssuedAtNano = 0
+	revoked, err = us.Denylist.IsRevoked(context.Background(), &other)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+}
+
+func TestServiceLoginAfterLogoutAll(t *testing.T) {
+	us, _, u, _ := newMemoryService(t)
+
+	// the login right after is in the same second as the revocation most of the time
+	assert.NoError(t, us.LogoutAll(context.Background(), u.ID))
+	_, tokens, err := us.Login(context.Background(), u.Email, "password")
+	assert.NoError(t, err)
+
+	revoked, err := us.Denylist.IsRevoked(context.Background(), parseClaims(t, tokens.AccessToken))
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+}
+
+func TestServiceLogout(t *testing.T) {
+	us, _, u, tokens := newMemoryService(t)
+
+	familyID, err := randomToken()
+	assert.NoError(t, err)
+	other, err := us.issueTokens(context.Background(), u, familyID)
+	assert.NoError(t, err)
+
+	claims := parseClaims(t, tokens.AccessToken)
+	assert.NoError(t, us.Logout(context.Background(), claims))
+
+	revoked, err := us.Denylist.IsRevoked(context.Background(), claims)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	_, err = us.Refresh(context.Background(), tokens.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	// the login on another device go on
+	revoked, err = us.Denylist.IsRevoked(context.Background(), parseClaims(t, other.AccessToken))
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	_, err = us.Refresh(context.Background(), other.RefreshToken)
+	assert.NoError(t, err)
+}
+
+func TestServiceLogoutAll(t *testing.T) {
+	us, _, u, tokens := newMemoryService(t)
+
+	familyID, err := randomToken()
+	assert.NoError(t, err)
+	other, err := us.issueTokens(context.Background(), u, familyID)
+	assert.NoError(t, err)
+
+	assert.NoError(t, us.LogoutAll(context.Background(), u.ID))
+
+	for _, tokens := range []Tokens{tokens, other} {
+		revoked, err := us.Denylist.IsRevoked(context.Background(), parseClaims(t, tokens.AccessToken))
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+
+		_, err = us.Refresh(context.Background(), tokens.RefreshToken)
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	}
+}
+
+func TestServiceDeleteRevoke(t *testing.T) {
+	us, _, u, tokens := newMemoryService(t)
+
+	err := us.Delete(context.Background(), repository.User{Email: u.Email, Password: "password"})
+	assert.NoError(t, err)
+
+	revoked, err := us.Denylist.IsRevoked(context.Background(), parseClaims(t, tokens.AccessToken))
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+}
@@ -0,0 +1,135 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/izzanzahrial/blog-api-echo/pkg/mailer"
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+)
+
+const DefaultResetTTL = time.Hour
+
+// ForgotPassword mail the user a link to reset their password, the link carry a token that can be used once
+// until it expire. An unknown email isn't reported so the form can't tell who has an account
+func (us *userService) ForgotPassword(ctx context.Context, email string) error {
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+
+	var user repository.User
+	var found bool
+	err = us.Transactor.Run(ctx, func(ctx context.Context, tx repository.Tx) error {
+		var err error
+		user, err = us.UserRepository.FindByEmail(ctx, tx, email)
+		if errors.Is(err, repository.ErrUserNotFound) {
+			found = false
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		found = true
+
+		now := time.Now()
+		_, err = us.PasswordResetRepository.Create(ctx, tx, repository.PasswordReset{
+			UserID:    user.ID,
+			TokenHash: hashToken(token),
+			CreatedAt: now,
+			ExpiresAt: now.Add(us.Token.ResetTTL),
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		return nil
+	}
+
+	link, err := tokenLink(us.Token.ResetURL, token)
+	if err != nil {
+		return err
+	}
+
+	return us.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nfollow the link to choose a new password, it expire in %s:\n\n%s\n\n"+
+			"If you didn't ask for it, ignore this mail, your password isn't changed.", user.Name, us.Token.ResetTTL, link),
+	})
+}
+
+// ResetPassword consume the token of the link and change the password, every token mailed to the user is used up
+// and every session of the user is revoked since whoever knew the old password may be logged in
+func (us *userService) ResetPassword(ctx context.Context, token string, newPass string) error {
+	if newPass == "" {
+		return ErrUserIsntValidate
+	}
+
+	// hashing is slow, it's done before the token is locked
+	hashPass, err := hashPassword(newPass)
+	if err != nil {
+		return err
+	}
+
+	var userID int64
+	err = us.Transactor.Run(ctx, func(ctx context.Context, tx repository.Tx) error {
+		r, err := us.PasswordResetRepository.FindByHash(ctx, tx, hashToken(token))
+		if errors.Is(err, repository.ErrPasswordResetNotFound) {
+			return ErrInvalidResetToken
+		}
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if r.UsedAt != nil || !now.Before(r.ExpiresAt) {
+			return ErrInvalidResetToken
+		}
+
+		if err := us.PasswordResetRepository.MarkUsed(ctx, tx, r.UserID, now); err != nil {
+			return err
+		}
+
+		user, err := us.UserRepository.FindByID(ctx, tx, r.UserID)
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return ErrInvalidResetToken
+		}
+		if err != nil {
+			return err
+		}
+		userID = user.ID
+
+		user.Password = hashPass
+		if _, err := us.UserRepository.UpdatePassword(ctx, tx, user); err != nil {
+			return err
+		}
+
+		return us.RefreshTokenRepository.RevokeUser(ctx, tx, user.ID, now)
+	})
+	if err != nil {
+		return err
+	}
+
+	return us.Denylist.RevokeUser(ctx, userID, time.Now())
+}
+
+// tokenLink add the token to the query of the page the mailed link open
+func tokenLink(pageURL string, token string) (string, error) {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse url: %s because %w", pageURL, err)
+	}
+
+	q := u.Query()
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
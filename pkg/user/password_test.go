@@ -0,0 +1,163 @@
+package user
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/izzanzahrial/blog-api-echo/pkg/mailer"
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// passwordRecorder keep the user whose password was updated, the repository doesn't return the password
+type passwordRecorder struct {
+	repository.UserRepository
+	updated repository.User
+}
+
+func (r *passwordRecorder) UpdatePassword(ctx context.Context, tx repository.Tx, u repository.User) (repository.User, error) {
+	r.updated = u
+	return r.UserRepository.UpdatePassword(ctx, tx, u)
+}
+
+// mailedToken return the token of the link in the one mail sent by send
+func mailedToken(t *testing.T, us *userService, pageURL string, send func() error) (mailer.Message, string) {
+	m := &mailer.MockMailer{}
+	us.Mailer = m
+
+	var msg mailer.Message
+	m.On("Send", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		msg = args.Get(1).(mailer.Message)
+	}).Return(nil).Once()
+
+	assert.NoError(t, send())
+	m.AssertExpectations(t)
+
+	start := strings.Index(msg.Body, pageURL)
+	if !assert.NotEqual(t, -1, start) {
+		return msg, ""
+	}
+	link, err := url.Parse(strings.Fields(msg.Body[start:])[0])
+	assert.NoError(t, err)
+
+	return msg, link.Query().Get("token")
+}
+
+// forgotPassword ask for the reset link of the user and return the token of the mailed link
+func forgotPassword(t *testing.T, us *userService, u repository.User) string {
+	msg, token := mailedToken(t, us, testTokenConfig.ResetURL, func() error {
+		return us.ForgotPassword(context.Background(), u.Email)
+	})
+	assert.Equal(t, u.Email, msg.To)
+
+	return token
+}
+
+func TestServiceForgotPasswordUnknownEmail(t *testing.T) {
+	us, _, _, _ := newMemoryService(t)
+
+	m := &mailer.MockMailer{}
+	us.Mailer = m
+
+	assert.NoError(t, us.ForgotPassword(context.Background(), "unknown@example.com"))
+	m.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+}
+
+func TestServiceResetPassword(t *testing.T) {
+	us, _, u, tokens := newMemoryService(t)
+	users := &passwordRecorder{UserRepository: us.UserRepository}
+	us.UserRepository = users
+
+	token := forgotPassword(t, us, u)
+	other := forgotPassword(t, us, u)
+	assert.NotEmpty(t, token)
+
+	assert.NoError(t, us.ResetPassword(context.Background(), token, "new password"))
+
+	assert.Equal(t, u.ID, users.updated.ID)
+	assert.True(t, CheckPasswordHash("new password", users.updated.Password))
+
+	_, _, err := us.Login(context.Background(), u.Email, "new password")
+	assert.NoError(t, err)
+	_, _, err = us.Login(context.Background(), u.Email, "password")
+	assert.ErrorIs(t, err, ErrUnauthorizedUser)
+
+	// every session is logged out
+	revoked, err := us.Denylist.IsRevoked(context.Background(), parseClaims(t, tokens.AccessToken))
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	_, err = us.Refresh(context.Background(), tokens.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	// the link can't be followed twice and the other link mailed before is used up too
+	assert.ErrorIs(t, us.ResetPassword(context.Background(), token, "another password"), ErrInvalidResetToken)
+	assert.ErrorIs(t, us.ResetPassword(context.Background(), other, "another password"), ErrInvalidResetToken)
+}
+
+func TestServiceResetPasswordInvalid(t *testing.T) {
+	subtest := []struct {
+		name   string
+		change func(r *repository.PasswordReset)
+		token  string
+	}{
+		{
+			name:  "Unknown",
+			token: "unknown",
+		},
+		{
+			name: "Expired",
+			change: func(r *repository.PasswordReset) {
+				r.ExpiresAt = time.Now().Add(-time.Minute)
+			},
+		},
+		{
+			name: "Used",
+			change: func(r *repository.PasswordReset) {
+				usedAt := time.Now()
+				r.UsedAt = &usedAt
+			},
+		},
+	}
+
+	for _, test := range subtest {
+		t.Run(test.name, func(t *testing.T) {
+			us, store, u, tokens := newMemoryService(t)
+
+			token := test.token
+			if test.change != nil {
+				token = "changed"
+				r := repository.PasswordReset{
+					UserID:    u.ID,
+					TokenHash: hashToken(token),
+					CreatedAt: time.Now(),
+					ExpiresAt: time.Now().Add(time.Hour),
+				}
+				test.change(&r)
+
+				tx, err := store.Begin()
+				assert.NoError(t, err)
+				_, err = us.PasswordResetRepository.Create(context.Background(), tx, r)
+				assert.NoError(t, err)
+				assert.NoError(t, tx.Commit())
+			}
+
+			err := us.ResetPassword(context.Background(), token, "new password")
+			assert.ErrorIs(t, err, ErrInvalidResetToken)
+
+			// the session isn't revoked by an invalid token
+			_, err = us.Refresh(context.Background(), tokens.RefreshToken)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestTokenLink(t *testing.T) {
+	link, err := tokenLink("https://example.com/reset?lang=en", "abc")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/reset?lang=en&token=abc", link)
+}
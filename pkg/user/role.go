@@ -0,0 +1,99 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+)
+
+// FindUsers paginate every user for the admin
+func (us *userService) FindUsers(ctx context.Context, from int, size int) ([]repository.User, error) {
+	var users []repository.User
+	err := us.Transactor.ReadOnly(ctx, func(ctx context.Context, tx repository.Tx) error {
+		var err error
+		users, err = us.UserRepository.FindAll(ctx, tx, from, size)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// ChangeRole give the user another role, their access tokens are revoked so the next refresh
+// embed the permissions of the new role
+func (us *userService) ChangeRole(ctx context.Context, id int64, role string) (repository.User, error) {
+	var user repository.User
+	err := us.Transactor.Run(ctx, func(ctx context.Context, tx repository.Tx) error {
+		if _, err := us.RoleRepository.FindPermissions(ctx, tx, role); err != nil {
+			return err
+		}
+
+		if err := us.UserRepository.UpdateRole(ctx, tx, id, role); err != nil {
+			return err
+		}
+
+		var err error
+		user, err = us.UserRepository.FindByID(ctx, tx, id)
+		return err
+	})
+	if err != nil {
+		return repository.User{}, err
+	}
+
+	if err := us.Denylist.RevokeUser(ctx, id, time.Now()); err != nil {
+		return repository.User{}, err
+	}
+
+	return user, nil
+}
+
+// Suspend log the user out of every device and refuse their login until the suspension is lifted
+func (us *userService) Suspend(ctx context.Context, id int64) (repository.User, error) {
+	now := time.Now()
+
+	var user repository.User
+	err := us.Transactor.Run(ctx, func(ctx context.Context, tx repository.Tx) error {
+		if err := us.UserRepository.Suspend(ctx, tx, id, &now); err != nil {
+			return err
+		}
+
+		if err := us.RefreshTokenRepository.RevokeUser(ctx, tx, id, now); err != nil {
+			return err
+		}
+
+		var err error
+		user, err = us.UserRepository.FindByID(ctx, tx, id)
+		return err
+	})
+	if err != nil {
+		return repository.User{}, err
+	}
+
+	if err := us.Denylist.RevokeUser(ctx, id, now); err != nil {
+		return repository.User{}, err
+	}
+
+	return user, nil
+}
+
+// Unsuspend lift the suspension, the user has to login again
+func (us *userService) Unsuspend(ctx context.Context, id int64) (repository.User, error) {
+	var user repository.User
+	err := us.Transactor.Run(ctx, func(ctx context.Context, tx repository.Tx) error {
+		if err := us.UserRepository.Suspend(ctx, tx, id, nil); err != nil {
+			return err
+		}
+
+		var err error
+		user, err = us.UserRepository.FindByID(ctx, tx, id)
+		return err
+	})
+	if err != nil {
+		return repository.User{}, err
+	}
+
+	return user, nil
+}
@@ -0,0 +1,105 @@
+package user
+
+import (
+	"context"
+	"testing"
+
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceTokenPermissions(t *testing.T) {
+	_, _, _, tokens := newMemoryService(t)
+
+	claims := parseClaims(t, tokens.AccessToken)
+	assert.Equal(t, repository.RoleAuthor, claims.Role)
+	assert.False(t, claims.Admin)
+	assert.True(t, claims.Can(repository.PermissionPostCreate))
+	assert.False(t, claims.Can(repository.PermissionPostEditAny))
+}
+
+func TestServiceChangeRole(t *testing.T) {
+	us, _, u, tokens := newMemoryService(t)
+
+	changed, err := us.ChangeRole(context.Background(), u.ID, repository.RoleEditor)
+	assert.NoError(t, err)
+	assert.Equal(t, repository.RoleEditor, changed.Role)
+
+	// the token of the old role is refused, the refreshed one carry the new role
+	revoked, err := us.Denylist.IsRevoked(context.Background(), parseClaims(t, tokens.AccessToken))
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	refreshed, err := us.Refresh(context.Background(), tokens.RefreshToken)
+	assert.NoError(t, err)
+
+	claims := parseClaims(t, refreshed.AccessToken)
+	assert.Equal(t, repository.RoleEditor, claims.Role)
+	assert.True(t, claims.Can(repository.PermissionPostEditAny))
+	assert.False(t, claims.Can(repository.PermissionPostDeleteAny))
+
+	_, err = us.ChangeRole(context.Background(), u.ID, "owner")
+	assert.ErrorIs(t, err, repository.ErrRoleNotFound)
+
+	_, err = us.ChangeRole(context.Background(), u.ID+1, repository.RoleAdmin)
+	assert.ErrorIs(t, err, repository.ErrUserNotFound)
+}
+
+func TestServiceSuspend(t *testing.T) {
+	us, _, u, tokens := newMemoryService(t)
+
+	suspended, err := us.Suspend(context.Background(), u.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, suspended.SuspendedAt)
+
+	revoked, err := us.Denylist.IsRevoked(context.Background(), parseClaims(t, tokens.AccessToken))
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	_, err = us.Refresh(context.Background(), tokens.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	// a token issued while suspended can't be refreshed either
+	familyID, err := randomToken()
+	assert.NoError(t, err)
+	other, err := us.issueTokens(context.Background(), u, familyID)
+	assert.NoError(t, err)
+
+	_, err = us.Refresh(context.Background(), other.RefreshToken)
+	assert.ErrorIs(t, err, ErrUserSuspended)
+
+	unsuspended, err := us.Unsuspend(context.Background(), u.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, unsuspended.SuspendedAt)
+
+	_, err = us.Refresh(context.Background(), other.RefreshToken)
+	assert.NoError(t, err)
+}
+
+func TestServiceFindUsers(t *testing.T) {
+	us, store, u, _ := newMemoryService(t)
+
+	tx, err := store.Begin()
+	assert.NoError(t, err)
+	other, err := us.UserRepository.Create(context.Background(), tx, repository.User{
+		Email:    "other@example.com",
+		Username: "other",
+		Name:     "Other",
+		Password: "password",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, tx.Commit())
+
+	users, err := us.FindUsers(context.Background(), 0, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, users, 2) {
+		assert.Equal(t, u.ID, users[0].ID)
+		assert.Empty(t, users[0].Password)
+	}
+
+	users, err = us.FindUsers(context.Background(), 1, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, users, 1) {
+		assert.Equal(t, other.ID, users[0].ID)
+	}
+}
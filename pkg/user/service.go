@@ -2,14 +2,16 @@ package user
 
 import (
 	"context"
-	"database/sql"
 	"errors"
+	"log"
 	"net/mail"
-	"os"
+	"strings"
 	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/golang-jwt/jwt"
+	"github.com/izzanzahrial/blog-api-echo/pkg/mailer"
+	caching "github.com/izzanzahrial/blog-api-echo/pkg/redis"
 	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -20,6 +22,13 @@ var (
 	ErrFailedToCommitTransaction = errors.New("failed to commit transaction to the repository")
 	ErrFailedToGeneratePassword  = errors.New("failed to generate password")
 	ErrUnauthorizedUser          = errors.New("unathorized user")
+	ErrInvalidRefreshToken       = errors.New("the refresh token is unknown, expired or revoked")
+	ErrRefreshTokenReused        = errors.New("the refresh token was already used, every token of its login is revoked")
+	ErrUserSuspended             = errors.New("the user is suspended")
+	ErrInvalidResetToken         = errors.New("the password reset token is unknown, expired or used")
+	ErrInvalidVerifyToken        = errors.New("the email verification token is unknown, expired or used")
+	ErrEmailAlreadyVerified      = errors.New("the email of the user is already verified")
+	ErrVerifyRateLimited         = errors.New("a verification mail was sent too recently")
 )
 
 type UserService interface {
@@ -27,51 +36,113 @@ type UserService interface {
 	UpdateUser(ctx context.Context, u repository.User) (repository.User, error)
 	UpdatePassword(ctx context.Context, u repository.User, newPass string) (repository.User, error)
 	Delete(ctx context.Context, u repository.User) error
-	Login(ctx context.Context, emailOrUname string, pass string) (repository.User, string, error)
+	Login(ctx context.Context, emailOrUname string, pass string) (repository.User, Tokens, error)
+	Refresh(ctx context.Context, refreshToken string) (Tokens, error)
+	Logout(ctx context.Context, claims *JWTClaims) error
+	LogoutAll(ctx context.Context, userID int64) error
+	FindUsers(ctx context.Context, from int, size int) ([]repository.User, error)
+	ChangeRole(ctx context.Context, id int64, role string) (repository.User, error)
+	Suspend(ctx context.Context, id int64) (repository.User, error)
+	Unsuspend(ctx context.Context, id int64) (repository.User, error)
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token string, newPass string) error
+	VerifyEmail(ctx context.Context, token string) (repository.User, error)
+	ResendVerification(ctx context.Context, userID int64) error
 }
 
 type userService struct {
-	UserRepository repository.UserRepository
-	DB             *sql.DB
-	Validate       *validator.Validate
+	UserRepository              repository.UserRepository
+	FavouriteRepository         repository.FavouriteRepository
+	RefreshTokenRepository      repository.RefreshTokenRepository
+	RoleRepository              repository.RoleRepository
+	PasswordResetRepository     repository.PasswordResetRepository
+	EmailVerificationRepository repository.EmailVerificationRepository
+	Transactor                  repository.Transactor
+	Validate                    *validator.Validate
+	Cache                       caching.Cache
+	Mailer                      mailer.Mailer
+	Token                       TokenConfig
+	Denylist                    *Denylist
 }
 
-func NewUserService(ur repository.UserRepository, db *sql.DB, val *validator.Validate) UserService {
+func NewUserService(ur repository.UserRepository, fr repository.FavouriteRepository, rr repository.RefreshTokenRepository, rl repository.RoleRepository, pr repository.PasswordResetRepository, ev repository.EmailVerificationRepository, tr repository.Transactor, val *validator.Validate, cache caching.Cache, ml mailer.Mailer, tc TokenConfig) UserService {
+	if tc.SigningMethod == "" {
+		tc.SigningMethod = jwt.SigningMethodHS256.Alg()
+	}
+	if tc.AccessTTL <= 0 {
+		tc.AccessTTL = DefaultAccessTTL
+	}
+	if tc.RefreshTTL <= 0 {
+		tc.RefreshTTL = DefaultRefreshTTL
+	}
+	if tc.ResetTTL <= 0 {
+		tc.ResetTTL = DefaultResetTTL
+	}
+	if tc.VerifyTTL <= 0 {
+		tc.VerifyTTL = DefaultVerifyTTL
+	}
+	if tc.ResendInterval <= 0 {
+		tc.ResendInterval = DefaultResendInterval
+	}
+
 	return &userService{
-		UserRepository: ur,
-		DB:             db,
-		Validate:       val,
+		UserRepository:              ur,
+		FavouriteRepository:         fr,
+		RefreshTokenRepository:      rr,
+		RoleRepository:              rl,
+		PasswordResetRepository:     pr,
+		EmailVerificationRepository: ev,
+		Transactor:                  tr,
+		Validate:                    val,
+		Cache:                       cache,
+		Mailer:                      ml,
+		Token:                       tc,
+		Denylist:                    NewDenylist(cache, tc.AccessTTL),
 	}
 }
 
+// Create sign the user up and mail them the link to verify their email, the user is created even though
+// the mail failed since they can ask for another one
 func (us *userService) Create(ctx context.Context, u User) (repository.User, error) {
 	err := us.Validate.Struct(u)
 	if err != nil {
 		return repository.User{}, ErrUserIsntValidate
 	}
 
-	tx, err := us.DB.Begin()
+	token, err := randomToken()
+	if err != nil {
+		return repository.User{}, err
+	}
+
+	hashPass, err := hashPassword(u.Password)
 	if err != nil {
-		return repository.User{}, ErrFailedToBeginTransaction
+		return repository.User{}, err
 	}
-	defer tx.Rollback()
 
 	user := repository.User{
 		Email:    u.Email,
 		Username: u.Username,
 		Name:     u.Name,
-		Password: u.Password,
+		Password: hashPass,
 	}
 
-	user, err = us.UserRepository.Create(ctx, tx, user)
+	err = us.Transactor.Run(ctx, func(ctx context.Context, tx repository.Tx) error {
+		user, err = us.UserRepository.Create(ctx, tx, user)
+		if err != nil {
+			return err
+		}
+
+		return us.createVerification(ctx, tx, user.ID, token)
+	})
 	if err != nil {
 		return repository.User{}, err
 	}
 
-	if err := tx.Commit(); err != nil {
-		return repository.User{}, ErrFailedToCommitTransaction
+	if err := us.sendVerification(ctx, user, token); err != nil {
+		log.Printf("failed to send verification mail to user with id: %d because %v", user.ID, err)
 	}
 
+	user.Password = ""
 	return user, nil
 }
 
@@ -80,30 +151,33 @@ func (us *userService) UpdateUser(ctx context.Context, u repository.User) (repos
 		return repository.User{}, ErrUserIsntValidate
 	}
 
-	tx, err := us.DB.Begin()
-	if err != nil {
-		return repository.User{}, ErrFailedToBeginTransaction
-	}
-	defer tx.Rollback()
-
-	user, err := us.UserRepository.FindByEmail(ctx, tx, u.Email)
-	if err != nil {
-		return repository.User{}, err
-	}
+	var newUser repository.User
+	err := us.Transactor.Run(ctx, func(ctx context.Context, tx repository.Tx) error {
+		// the user is found by the id of the one logged in, the email is changed like the username and name
+		user, err := us.UserRepository.FindByID(ctx, tx, u.ID)
+		if err != nil {
+			return err
+		}
 
-	user.Email = u.Email
-	user.Username = u.Username
-	user.Name = u.Name
+		// a field left empty keep its value, a new email isn't verified yet
+		if u.Email != "" && !strings.EqualFold(user.Email, u.Email) {
+			user.Email = u.Email
+			user.VerifiedAt = nil
+		}
+		if u.Username != "" {
+			user.Username = u.Username
+		}
+		if u.Name != "" {
+			user.Name = u.Name
+		}
 
-	newUser, err := us.UserRepository.UpdateUser(ctx, tx, user)
+		newUser, err = us.UserRepository.UpdateUser(ctx, tx, user)
+		return err
+	})
 	if err != nil {
 		return repository.User{}, err
 	}
 
-	if err := tx.Commit(); err != nil {
-		return repository.User{}, ErrFailedToCommitTransaction
-	}
-
 	return newUser, nil
 }
 
@@ -112,102 +186,127 @@ func (us *userService) UpdatePassword(ctx context.Context, u repository.User, ne
 		return repository.User{}, ErrUserIsntValidate
 	}
 
-	tx, err := us.DB.Begin()
-	if err != nil {
-		return repository.User{}, ErrFailedToBeginTransaction
-	}
-	defer tx.Rollback()
+	var newUser repository.User
+	err := us.Transactor.Run(ctx, func(ctx context.Context, tx repository.Tx) error {
+		user, err := us.UserRepository.LoginByEmail(ctx, tx, u.Email)
+		if err != nil {
+			return err
+		}
 
-	user, err := us.UserRepository.FindByEmail(ctx, tx, u.Email)
-	if err != nil {
-		return repository.User{}, err
-	}
+		if ok := CheckPasswordHash(u.Password, user.Password); !ok {
+			return bcrypt.ErrMismatchedHashAndPassword
+		}
 
-	if ok := CheckPasswordHash(u.Password, user.Password); !ok {
-		return repository.User{}, bcrypt.ErrMismatchedHashAndPassword
-	}
+		hashPass, err := hashPassword(newPass)
+		if err != nil {
+			return bcrypt.ErrHashTooShort
+		}
 
-	hashPass, err := hashPassword(newPass)
-	if err != nil {
-		return repository.User{}, bcrypt.ErrHashTooShort
-	}
+		user.Password = hashPass
 
-	user.Password = hashPass
+		newUser, err = us.UserRepository.UpdatePassword(ctx, tx, user)
+		if err != nil {
+			return err
+		}
 
-	newUser, err := us.UserRepository.UpdatePassword(ctx, tx, user)
+		// whoever else knew the old password is logged out
+		return us.RefreshTokenRepository.RevokeUser(ctx, tx, user.ID, time.Now())
+	})
 	if err != nil {
 		return repository.User{}, err
 	}
 
-	if err := tx.Commit(); err != nil {
-		return repository.User{}, ErrFailedToCommitTransaction
+	if err := us.Denylist.RevokeUser(ctx, newUser.ID, time.Now()); err != nil {
+		return repository.User{}, err
 	}
 
+	newUser.Password = ""
 	return newUser, nil
 }
 
 func (us *userService) Delete(ctx context.Context, u repository.User) error {
-	tx, err := us.DB.Begin()
-	if err != nil {
-		return ErrFailedToBeginTransaction
-	}
-	defer tx.Rollback()
+	var userID int64
+	var favourites []int64
+	err := us.Transactor.Run(ctx, func(ctx context.Context, tx repository.Tx) error {
+		user, err := us.UserRepository.LoginByEmail(ctx, tx, u.Email)
+		if err != nil {
+			return err
+		}
+
+		if ok := CheckPasswordHash(u.Password, user.Password); !ok {
+			return ErrUnauthorizedUser
+		}
+		userID = user.ID
+
+		// the user favourites is deleted along with the user, the cached count of those post must go too
+		favourites, err = us.FavouriteRepository.FindPostIDsByUser(ctx, tx, user.ID)
+		if err != nil {
+			return err
+		}
 
-	user, err := us.UserRepository.LoginByEmail(ctx, tx, u.Email, u.Password)
+		return us.UserRepository.Delete(ctx, tx, user)
+	})
 	if err != nil {
 		return err
 	}
 
-	if err := us.UserRepository.Delete(ctx, tx, user); err != nil {
+	// the refresh tokens are deleted along with the user, the access tokens must be denied until they expire
+	if err := us.Denylist.RevokeUser(ctx, userID, time.Now()); err != nil {
 		return err
 	}
 
-	if err := tx.Commit(); err != nil {
-		return ErrFailedToCommitTransaction
+	if len(favourites) == 0 {
+		return nil
 	}
 
-	return nil
+	keys := make([]string, 0, len(favourites))
+	for _, id := range favourites {
+		keys = append(keys, caching.FavouriteKey(id))
+	}
+
+	return us.Cache.Del(context.Background(), keys...).Err()
 }
 
-func (us *userService) Login(ctx context.Context, emailOrUname string, pass string) (repository.User, string, error) {
-	tx, err := us.DB.Begin()
-	if err != nil {
-		return repository.User{}, "", ErrFailedToBeginTransaction
+// Login start a new family of refresh tokens along with the access token, an unknown user
+// and a wrong password are both reported as ErrUnauthorizedUser
+func (us *userService) Login(ctx context.Context, emailOrUname string, pass string) (repository.User, Tokens, error) {
+	var user repository.User
+	err := us.Transactor.ReadOnly(ctx, func(ctx context.Context, tx repository.Tx) error {
+		var err error
+		if ok := validateEmail(emailOrUname); !ok {
+			user, err = us.UserRepository.LoginByUsername(ctx, tx, emailOrUname)
+		} else {
+			user, err = us.UserRepository.LoginByEmail(ctx, tx, emailOrUname)
+		}
+		return err
+	})
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return repository.User{}, Tokens{}, ErrUnauthorizedUser
 	}
-	defer tx.Rollback()
-
-	hashPass, err := hashPassword(pass)
 	if err != nil {
-		return repository.User{}, "", err
+		return repository.User{}, Tokens{}, err
 	}
 
-	var user repository.User
-	if ok := validateEmail(emailOrUname); !ok {
-		user, err = us.UserRepository.LoginByUsername(ctx, tx, emailOrUname, hashPass)
-		if err != nil {
-			return repository.User{}, "", err
-		}
-	} else {
-		user, err = us.UserRepository.LoginByEmail(ctx, tx, emailOrUname, hashPass)
-		if err != nil {
-			return repository.User{}, "", err
-		}
+	if ok := CheckPasswordHash(pass, user.Password); !ok {
+		return repository.User{}, Tokens{}, ErrUnauthorizedUser
 	}
+	user.Password = ""
 
-	if ok := CheckPasswordHash(hashPass, user.Password); !ok {
-		return repository.User{}, "", ErrUnauthorizedUser
+	if user.SuspendedAt != nil {
+		return repository.User{}, Tokens{}, ErrUserSuspended
 	}
 
-	if err := tx.Commit(); err != nil {
-		return repository.User{}, "", ErrFailedToCommitTransaction
+	familyID, err := randomToken()
+	if err != nil {
+		return repository.User{}, Tokens{}, err
 	}
 
-	token, err := createJWTToken(false, user.ID, user.Email, user.Username, user.Name)
+	tokens, err := us.issueTokens(ctx, user, familyID)
 	if err != nil {
-		return repository.User{}, token, err
+		return repository.User{}, Tokens{}, err
 	}
 
-	return user, token, nil
+	return user, tokens, nil
 }
 
 type JWTClaims struct {
@@ -216,29 +315,29 @@ type JWTClaims struct {
 	Username string `json:"username"`
 	Name     string `json:"name"`
 	Admin    bool   `json:"admin"`
+	Role     string `json:"role"`
+	// Verified is whether the email was verified when the token was issued
+	Verified bool `json:"verified"`
+	// Permissions is what the role was permitted when the token was issued, a changed role
+	// is only seen by the next token
+	Permissions []string `json:"permissions"`
+	// SessionID is the family of the refresh tokens of the login, the jti of the token is StandardClaims.Id
+	SessionID string `json:"sid"`
+	// IssuedAtNano is the iat in nanoseconds, the iat only carry the second and a token issued right
+	// after the user was revoked would be denied with the ones before it
+	IssuedAtNano int64 `json:"iat_ns"`
 	jwt.StandardClaims
 }
 
-func createJWTToken(admin bool, id int64, email, username, name string) (string, error) {
-	claims := JWTClaims{
-		id,
-		email,
-		username,
-		name,
-		admin,
-		jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(time.Hour * 3600).Unix(),
-		},
-	}
-
-	rawToken := jwt.NewWithClaims(jwt.SigningMethodES512, claims)
-
-	token, err := rawToken.SignedString([]byte(os.Getenv("tokenSign")))
-	if err != nil {
-		return "", err
+// Can report whether the role of the user was permitted the permission
+func (c *JWTClaims) Can(permission string) bool {
+	for _, p := range c.Permissions {
+		if p == permission {
+			return true
+		}
 	}
 
-	return token, nil
+	return false
 }
 
 func hashPassword(password string) (string, error) {
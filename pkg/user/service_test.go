@@ -0,0 +1,139 @@
+package user
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceLogin(t *testing.T) {
+	subtest := []struct {
+		name         string
+		emailOrUname string
+		password     string
+		expected     error
+	}{
+		{
+			name:         "Email",
+			emailOrUname: "IZZAN@example.com",
+			password:     "password",
+		},
+		{
+			name:         "Username",
+			emailOrUname: "izzan",
+			password:     "password",
+		},
+		{
+			name:         "Wrong Password",
+			emailOrUname: "izzan@example.com",
+			password:     "wrong password",
+			expected:     ErrUnauthorizedUser,
+		},
+		{
+			name:         "Unknown User",
+			emailOrUname: "unknown",
+			password:     "password",
+			expected:     ErrUnauthorizedUser,
+		},
+	}
+
+	for _, test := range subtest {
+		t.Run(test.name, func(t *testing.T) {
+			us, _, u, _ := newMemoryService(t)
+
+			user, tokens, err := us.Login(context.Background(), test.emailOrUname, test.password)
+			assert.ErrorIs(t, err, test.expected)
+			if test.expected != nil {
+				return
+			}
+
+			assert.Equal(t, u.ID, user.ID)
+			assert.Empty(t, user.Password)
+			assert.Equal(t, u.ID, parseClaims(t, tokens.AccessToken).ID)
+
+			_, err = us.Refresh(context.Background(), tokens.RefreshToken)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestServiceLoginSuspended(t *testing.T) {
+	us, store, u, _ := newMemoryService(t)
+
+	suspendedAt := time.Now()
+	tx, err := store.Begin()
+	assert.NoError(t, err)
+	assert.NoError(t, us.UserRepository.Suspend(context.Background(), tx, u.ID, &suspendedAt))
+	assert.NoError(t, tx.Commit())
+
+	_, _, err = us.Login(context.Background(), u.Email, "password")
+	assert.ErrorIs(t, err, ErrUserSuspended)
+
+	_, _, err = us.Login(context.Background(), u.Email, "wrong password")
+	assert.ErrorIs(t, err, ErrUnauthorizedUser)
+}
+
+func TestServiceCreateLogin(t *testing.T) {
+	us, _, _, _ := newMemoryService(t)
+
+	// the password is stored hashed and the login check it against the hash
+	created, err := us.Create(context.Background(), User{
+		Email:    "zahrial@example.com",
+		Username: "zahrial",
+		Name:     "Zahrial",
+		Password: "secret password",
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, created.Password)
+
+	user, _, err := us.Login(context.Background(), "zahrial", "secret password")
+	assert.NoError(t, err)
+	assert.Equal(t, created.ID, user.ID)
+}
+
+func TestServiceDeleteWrongPassword(t *testing.T) {
+	us, store, u, _ := newMemoryService(t)
+
+	err := us.Delete(context.Background(), repository.User{Email: u.Email, Password: "wrong password"})
+	assert.ErrorIs(t, err, ErrUnauthorizedUser)
+
+	tx, err := store.Begin()
+	assert.NoError(t, err)
+	defer tx.Rollback()
+	_, err = us.UserRepository.FindByID(context.Background(), tx, u.ID)
+	assert.NoError(t, err)
+}
+
+func TestServiceUpdateUser(t *testing.T) {
+	us, store, u, _ := newMemoryService(t)
+
+	other, err := us.Create(context.Background(), User{
+		Email:    "zahrial@example.com",
+		Username: "zahrial",
+		Name:     "Zahrial",
+		Password: "secret password",
+	})
+	assert.NoError(t, err)
+
+	// the email of another user doesn't make their account the one updated
+	_, err = us.UpdateUser(context.Background(), repository.User{ID: u.ID, Email: other.Email, Username: "hijacked", Name: "Hijacked"})
+	assert.Error(t, err)
+
+	// a field left empty keep its value
+	updated, err := us.UpdateUser(context.Background(), repository.User{ID: u.ID, Name: "Izzan Zahrial"})
+	assert.NoError(t, err)
+	assert.Equal(t, u.Email, updated.Email)
+	assert.Equal(t, u.Username, updated.Username)
+	assert.Equal(t, "Izzan Zahrial", updated.Name)
+
+	tx, err := store.Begin()
+	assert.NoError(t, err)
+	defer tx.Rollback()
+	found, err := us.UserRepository.FindByID(context.Background(), tx, other.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "zahrial", found.Username)
+	assert.Equal(t, "Zahrial", found.Name)
+}
@@ -0,0 +1,229 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+)
+
+const (
+	DefaultAccessTTL  = 15 * time.Minute
+	DefaultRefreshTTL = 30 * 24 * time.Hour
+)
+
+// TokenConfig is how the access token is signed and how long the tokens last, it must match the
+// config of the jwt middleware that check the access token
+type TokenConfig struct {
+	// SigningMethod is the jwt alg, default to HS256 like the middleware
+	SigningMethod string
+	SigningKey    []byte
+	AccessTTL     time.Duration
+	RefreshTTL    time.Duration
+	// ResetTTL is how long the link of a password reset last, the link is ResetURL with the token
+	// in the token query param
+	ResetTTL time.Duration
+	ResetURL string
+	// VerifyTTL is how long the link of an email verification last, the link is VerifyURL with the token
+	// in the token query param. Another verification mail can only be asked for every ResendInterval
+	VerifyTTL      time.Duration
+	VerifyURL      string
+	ResendInterval time.Duration
+}
+
+// Refresh exchange the refresh token for new tokens of the same family, the refresh token can only be used once.
+// A used token presented again means it was stolen, so the whole family and the access tokens of its session
+// are revoked and the thief and the user both have to login again
+func (us *userService) Refresh(ctx context.Context, refreshToken string) (Tokens, error) {
+	var tokens Tokens
+	var reused string
+	err := us.Transactor.Run(ctx, func(ctx context.Context, tx repository.Tx) error {
+		reused = ""
+
+		t, err := us.RefreshTokenRepository.FindByHash(ctx, tx, hashToken(refreshToken))
+		if errors.Is(err, repository.ErrRefreshTokenNotFound) {
+			return ErrInvalidRefreshToken
+		}
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if t.RevokedAt != nil {
+			return ErrInvalidRefreshToken
+		}
+
+		// the revocation must be committed, so it's reported once the transaction is over
+		if t.UsedAt != nil {
+			reused = t.FamilyID
+			return us.RefreshTokenRepository.RevokeFamily(ctx, tx, t.FamilyID, now)
+		}
+
+		if !now.Before(t.ExpiresAt) {
+			return ErrInvalidRefreshToken
+		}
+
+		if err := us.RefreshTokenRepository.MarkUsed(ctx, tx, t.ID, now); err != nil {
+			return err
+		}
+
+		user, err := us.UserRepository.FindByID(ctx, tx, t.UserID)
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return ErrInvalidRefreshToken
+		}
+		if err != nil {
+			return err
+		}
+
+		if user.SuspendedAt != nil {
+			return ErrUserSuspended
+		}
+
+		tokens, err = us.issueTokens(ctx, user, t.FamilyID)
+		return err
+	})
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	// the family is the session of the access tokens
+	if reused != "" {
+		if err := us.Denylist.RevokeSession(ctx, reused); err != nil {
+			return Tokens{}, err
+		}
+		return Tokens{}, ErrRefreshTokenReused
+	}
+
+	return tokens, nil
+}
+
+// issueTokens sign the access token of the user with the permissions of their role
+// and store the hash of a new refresh token in the family
+func (us *userService) issueTokens(ctx context.Context, u repository.User, familyID string) (Tokens, error) {
+	refreshToken, err := randomToken()
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	var permissions []string
+	now := time.Now()
+	err = us.Transactor.Run(ctx, func(ctx context.Context, tx repository.Tx) error {
+		var err error
+		permissions, err = us.RoleRepository.FindPermissions(ctx, tx, u.Role)
+		if err != nil {
+			return err
+		}
+
+		_, err = us.RefreshTokenRepository.Create(ctx, tx, repository.RefreshToken{
+			UserID:    u.ID,
+			FamilyID:  familyID,
+			TokenHash: hashToken(refreshToken),
+			CreatedAt: now,
+			ExpiresAt: now.Add(us.Token.RefreshTTL),
+		})
+		return err
+	})
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	accessToken, expiresAt, err := us.createJWTToken(u, permissions, familyID)
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	return Tokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresAt:    expiresAt,
+	}, nil
+}
+
+// createJWTToken sign the access token of the session, the jti let the token be revoked alone
+func (us *userService) createJWTToken(u repository.User, permissions []string, sessionID string) (string, time.Time, error) {
+	method := jwt.GetSigningMethod(us.Token.SigningMethod)
+	if method == nil {
+		return "", time.Time{}, fmt.Errorf("unknown jwt signing method: %s", us.Token.SigningMethod)
+	}
+
+	jti, err := randomToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(us.Token.AccessTTL)
+	claims := JWTClaims{
+		ID:           u.ID,
+		Email:        u.Email,
+		Username:     u.Username,
+		Name:         u.Name,
+		Admin:        u.Role == repository.RoleAdmin,
+		Role:         u.Role,
+		Verified:     u.VerifiedAt != nil,
+		Permissions:  permissions,
+		SessionID:    sessionID,
+		IssuedAtNano: now.UnixNano(),
+		StandardClaims: jwt.StandardClaims{
+			Id:        jti,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: expiresAt.Unix(),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(method, claims).SignedString(us.Token.SigningKey)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, expiresAt, nil
+}
+
+// randomToken return 32 random bytes encoded for an url
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random token because %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken is what's stored of the refresh token, the token is random enough that a plain sha256 is safe
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Logout revoke the access token and the refresh tokens of its session, the other sessions of the user go on
+func (us *userService) Logout(ctx context.Context, claims *JWTClaims) error {
+	if claims.SessionID != "" {
+		err := us.Transactor.Run(ctx, func(ctx context.Context, tx repository.Tx) error {
+			return us.RefreshTokenRepository.RevokeFamily(ctx, tx, claims.SessionID, time.Now())
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return us.Denylist.RevokeToken(ctx, claims)
+}
+
+// LogoutAll revoke every session of the user on every device
+func (us *userService) LogoutAll(ctx context.Context, userID int64) error {
+	err := us.Transactor.Run(ctx, func(ctx context.Context, tx repository.Tx) error {
+		return us.RefreshTokenRepository.RevokeUser(ctx, tx, userID, time.Now())
+	})
+	if err != nil {
+		return err
+	}
+
+	return us.Denylist.RevokeUser(ctx, userID, time.Now())
+}
@@ -0,0 +1,214 @@
+package user
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/golang-jwt/jwt"
+	"github.com/izzanzahrial/blog-api-echo/pkg/mailer"
+	caching "github.com/izzanzahrial/blog-api-echo/pkg/redis"
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var testTokenConfig = TokenConfig{
+	SigningKey: []byte("secret"),
+	ResetURL:   "https://example.com/password/reset",
+	VerifyURL:  "https://example.com/api/v1/user/verify",
+}
+
+// newMemoryService return the service over the memory storage with a user who's already logged in
+func newMemoryService(t *testing.T) (*userService, *repository.MemoryStore, repository.User, Tokens) {
+	store := repository.NewMemoryStore()
+	us := NewUserService(
+		repository.NewUserMemory(store),
+		repository.NewFavouriteMemory(store),
+		repository.NewRefreshTokenMemory(store),
+		repository.NewRoleMemory(store),
+		repository.NewPasswordResetMemory(store),
+		repository.NewEmailVerificationMemory(store),
+		repository.NewTransactor(store),
+		validator.New(),
+		caching.NewMemory(),
+		mailer.NewLog(io.Discard),
+		testTokenConfig,
+	).(*userService)
+
+	// the cheapest cost keep the tests fast, the hash is checked the same way
+	hashPass, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	assert.NoError(t, err)
+
+	tx, err := store.Begin()
+	assert.NoError(t, err)
+	u, err := us.UserRepository.Create(context.Background(), tx, repository.User{
+		Email:    "izzan@example.com",
+		Username: "izzan",
+		Name:     "Izzan",
+		Password: string(hashPass),
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, tx.Commit())
+
+	familyID, err := randomToken()
+	assert.NoError(t, err)
+	tokens, err := us.issueTokens(context.Background(), u, familyID)
+	assert.NoError(t, err)
+
+	return us, store, u, tokens
+}
+
+// findToken return the stored refresh token
+func findToken(t *testing.T, store *repository.MemoryStore, token string) repository.RefreshToken {
+	tx, err := store.Begin()
+	assert.NoError(t, err)
+	defer tx.Rollback()
+
+	rt, err := repository.NewRefreshTokenMemory(store).FindByHash(context.Background(), tx, hashToken(token))
+	assert.NoError(t, err)
+
+	return rt
+}
+
+func TestServiceRefresh(t *testing.T) {
+	us, store, u, tokens := newMemoryService(t)
+
+	refreshed, err := us.Refresh(context.Background(), tokens.RefreshToken)
+	assert.NoError(t, err)
+	assert.NotEqual(t, tokens.RefreshToken, refreshed.RefreshToken)
+	assert.WithinDuration(t, time.Now().Add(DefaultAccessTTL), refreshed.ExpiresAt, time.Minute)
+
+	claims := &JWTClaims{}
+	_, err = jwt.ParseWithClaims(refreshed.AccessToken, claims, func(token *jwt.Token) (interface{}, error) {
+		return testTokenConfig.SigningKey, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, u.ID, claims.ID)
+	assert.Equal(t, u.Username, claims.Username)
+
+	// the rotated token stay in the family and only the new one can be used
+	used := findToken(t, store, tokens.RefreshToken)
+	next := findToken(t, store, refreshed.RefreshToken)
+	assert.NotNil(t, used.UsedAt)
+	assert.Nil(t, next.UsedAt)
+	assert.Equal(t, used.FamilyID, next.FamilyID)
+	assert.WithinDuration(t, time.Now().Add(DefaultRefreshTTL), next.ExpiresAt, time.Minute)
+}
+
+func TestServiceRefreshReused(t *testing.T) {
+	us, store, _, tokens := newMemoryService(t)
+
+	refreshed, err := us.Refresh(context.Background(), tokens.RefreshToken)
+	assert.NoError(t, err)
+
+	_, err = us.Refresh(context.Background(), tokens.RefreshToken)
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+
+	// the replay revoke the token the user got from the rotation too
+	assert.NotNil(t, findToken(t, store, refreshed.RefreshToken).RevokedAt)
+
+	// and the access tokens of the session, the first one and the one of the rotation
+	for _, accessToken := range []string{tokens.AccessToken, refreshed.AccessToken} {
+		revoked, err := us.Denylist.IsRevoked(context.Background(), parseClaims(t, accessToken))
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+	}
+
+	_, err = us.Refresh(context.Background(), refreshed.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestServiceRefreshOtherFamily(t *testing.T) {
+	us, _, u, tokens := newMemoryService(t)
+
+	familyID, err := randomToken()
+	assert.NoError(t, err)
+	other, err := us.issueTokens(context.Background(), u, familyID)
+	assert.NoError(t, err)
+
+	_, err = us.Refresh(context.Background(), tokens.RefreshToken)
+	assert.NoError(t, err)
+	_, err = us.Refresh(context.Background(), tokens.RefreshToken)
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+
+	// the login on another device isn't revoked
+	revoked, err := us.Denylist.IsRevoked(context.Background(), parseClaims(t, other.AccessToken))
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	_, err = us.Refresh(context.Background(), other.RefreshToken)
+	assert.NoError(t, err)
+}
+
+func TestServiceRefreshInvalid(t *testing.T) {
+	subtest := []struct {
+		name   string
+		change func(rt *repository.RefreshToken)
+		token  string
+	}{
+		{
+			name:  "Unknown",
+			token: "unknown",
+		},
+		{
+			name: "Expired",
+			change: func(rt *repository.RefreshToken) {
+				rt.ExpiresAt = time.Now().Add(-time.Minute)
+			},
+		},
+		{
+			name: "Revoked",
+			change: func(rt *repository.RefreshToken) {
+				revokedAt := time.Now()
+				rt.RevokedAt = &revokedAt
+			},
+		},
+	}
+
+	for _, test := range subtest {
+		t.Run(test.name, func(t *testing.T) {
+			us, store, u, tokens := newMemoryService(t)
+
+			token := test.token
+			if test.change != nil {
+				token = "changed"
+				rt := repository.RefreshToken{
+					UserID:    u.ID,
+					FamilyID:  findToken(t, store, tokens.RefreshToken).FamilyID,
+					TokenHash: hashToken(token),
+					CreatedAt: time.Now(),
+					ExpiresAt: time.Now().Add(time.Hour),
+				}
+				test.change(&rt)
+
+				tx, err := store.Begin()
+				assert.NoError(t, err)
+				_, err = us.RefreshTokenRepository.Create(context.Background(), tx, rt)
+				assert.NoError(t, err)
+				assert.NoError(t, tx.Commit())
+			}
+
+			_, err := us.Refresh(context.Background(), token)
+			assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+			// an invalid token isn't a replay, the login still work
+			_, err = us.Refresh(context.Background(), tokens.RefreshToken)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestServiceRefreshDeletedUser(t *testing.T) {
+	us, store, u, tokens := newMemoryService(t)
+
+	tx, err := store.Begin()
+	assert.NoError(t, err)
+	assert.NoError(t, us.UserRepository.Delete(context.Background(), tx, u))
+	assert.NoError(t, tx.Commit())
+
+	_, err = us.Refresh(context.Background(), tokens.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
@@ -1,8 +1,18 @@
 package user
 
+import "time"
+
 type User struct {
-	Email    string `json:"email"`
-	Username string `json:"username"`
+	Email    string `json:"email" validate:"required,email"`
+	Username string `json:"username" validate:"required"`
 	Name     string `json:"name"`
-	Password string `json:"password"`
+	Password string `json:"password" validate:"required"`
+}
+
+// Tokens is what a login or a refresh hand to the user, the access token is the jwt sent with every request
+// until ExpiresAt and the refresh token is exchanged for the next Tokens
+type Tokens struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
 }
@@ -0,0 +1,131 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/izzanzahrial/blog-api-echo/pkg/mailer"
+	caching "github.com/izzanzahrial/blog-api-echo/pkg/redis"
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+)
+
+const (
+	DefaultVerifyTTL      = 24 * time.Hour
+	DefaultResendInterval = time.Minute
+)
+
+// VerifyEmail consume the token of the mailed link and mark the email of the user as verified, their access
+// tokens are revoked so the next refresh carry the verified claim
+func (us *userService) VerifyEmail(ctx context.Context, token string) (repository.User, error) {
+	var user repository.User
+	err := us.Transactor.Run(ctx, func(ctx context.Context, tx repository.Tx) error {
+		v, err := us.EmailVerificationRepository.FindByHash(ctx, tx, hashToken(token))
+		if errors.Is(err, repository.ErrEmailVerificationNotFound) {
+			return ErrInvalidVerifyToken
+		}
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if v.UsedAt != nil || !now.Before(v.ExpiresAt) {
+			return ErrInvalidVerifyToken
+		}
+
+		if err := us.EmailVerificationRepository.MarkUsed(ctx, tx, v.UserID, now); err != nil {
+			return err
+		}
+
+		user, err = us.UserRepository.FindByID(ctx, tx, v.UserID)
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return ErrInvalidVerifyToken
+		}
+		if err != nil {
+			return err
+		}
+
+		if user.VerifiedAt != nil {
+			return nil
+		}
+
+		if err := us.UserRepository.Verify(ctx, tx, user.ID, now); err != nil {
+			return err
+		}
+		user.VerifiedAt = &now
+
+		return nil
+	})
+	if err != nil {
+		return repository.User{}, err
+	}
+
+	if err := us.Denylist.RevokeUser(ctx, user.ID, time.Now()); err != nil {
+		return repository.User{}, err
+	}
+
+	return user, nil
+}
+
+// ResendVerification mail the user another verification link, only one mail is sent every ResendInterval
+func (us *userService) ResendVerification(ctx context.Context, userID int64) error {
+	ok, err := us.Cache.SetNX(ctx, caching.VerificationResendKey(userID), time.Now().Unix(), us.Token.ResendInterval).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrVerifyRateLimited
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+
+	var user repository.User
+	err = us.Transactor.Run(ctx, func(ctx context.Context, tx repository.Tx) error {
+		var err error
+		user, err = us.UserRepository.FindByID(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+
+		if user.VerifiedAt != nil {
+			return ErrEmailAlreadyVerified
+		}
+
+		return us.createVerification(ctx, tx, user.ID, token)
+	})
+	if err != nil {
+		return err
+	}
+
+	return us.sendVerification(ctx, user, token)
+}
+
+// createVerification store the hash of the token mailed to the user
+func (us *userService) createVerification(ctx context.Context, tx repository.Tx, userID int64, token string) error {
+	now := time.Now()
+	_, err := us.EmailVerificationRepository.Create(ctx, tx, repository.EmailVerification{
+		UserID:    userID,
+		TokenHash: hashToken(token),
+		CreatedAt: now,
+		ExpiresAt: now.Add(us.Token.VerifyTTL),
+	})
+
+	return err
+}
+
+func (us *userService) sendVerification(ctx context.Context, u repository.User, token string) error {
+	link, err := tokenLink(us.Token.VerifyURL, token)
+	if err != nil {
+		return err
+	}
+
+	return us.Mailer.Send(ctx, mailer.Message{
+		To:      u.Email,
+		Subject: "Verify your email",
+		Body:    fmt.Sprintf("Hi %s,\n\nfollow the link to verify your email, it expire in %s:\n\n%s", u.Name, us.Token.VerifyTTL, link),
+	})
+}
@@ -0,0 +1,164 @@
+package user
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/izzanzahrial/blog-api-echo/pkg/mailer"
+	"github.com/izzanzahrial/blog-api-echo/pkg/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// signUp create the user through the service and return them with the token of the mailed verification link
+func signUp(t *testing.T, us *userService) (repository.User, string) {
+	var u repository.User
+	msg, token := mailedToken(t, us, testTokenConfig.VerifyURL, func() error {
+		var err error
+		u, err = us.Create(context.Background(), User{
+			Email:    "zahrial@example.com",
+			Username: "zahrial",
+			Name:     "Zahrial",
+			Password: "password",
+		})
+		return err
+	})
+	assert.Equal(t, "zahrial@example.com", msg.To)
+	assert.Nil(t, u.VerifiedAt)
+
+	return u, token
+}
+
+func TestServiceCreateInvalidEmail(t *testing.T) {
+	us, _, _, _ := newMemoryService(t)
+
+	_, err := us.Create(context.Background(), User{
+		Username: "zahrial",
+		Name:     "Zahrial",
+		Password: "password",
+	})
+	assert.ErrorIs(t, err, ErrUserIsntValidate)
+}
+
+func TestServiceCreateMailFailed(t *testing.T) {
+	us, _, _, _ := newMemoryService(t)
+
+	m := &mailer.MockMailer{}
+	m.On("Send", mock.Anything, mock.Anything).Return(mailer.ErrInvalidHeader)
+	us.Mailer = m
+
+	// the user can ask for another mail
+	u, err := us.Create(context.Background(), User{
+		Email:    "zahrial@example.com",
+		Username: "zahrial",
+		Password: "password",
+	})
+	assert.NoError(t, err)
+	assert.NotZero(t, u.ID)
+}
+
+func TestServiceVerifyEmail(t *testing.T) {
+	us, _, _, _ := newMemoryService(t)
+	u, token := signUp(t, us)
+
+	familyID, err := randomToken()
+	assert.NoError(t, err)
+	tokens, err := us.issueTokens(context.Background(), u, familyID)
+	assert.NoError(t, err)
+	assert.False(t, parseClaims(t, tokens.AccessToken).Verified)
+
+	verified, err := us.VerifyEmail(context.Background(), token)
+	assert.NoError(t, err)
+	assert.Equal(t, u.ID, verified.ID)
+	assert.NotNil(t, verified.VerifiedAt)
+
+	// the token without the verified claim is revoked, the refreshed one carry it
+	revoked, err := us.Denylist.IsRevoked(context.Background(), parseClaims(t, tokens.AccessToken))
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	refreshed, err := us.Refresh(context.Background(), tokens.RefreshToken)
+	assert.NoError(t, err)
+	assert.True(t, parseClaims(t, refreshed.AccessToken).Verified)
+
+	_, err = us.VerifyEmail(context.Background(), token)
+	assert.ErrorIs(t, err, ErrInvalidVerifyToken)
+
+	assert.ErrorIs(t, us.ResendVerification(context.Background(), u.ID), ErrEmailAlreadyVerified)
+}
+
+func TestServiceVerifyEmailInvalid(t *testing.T) {
+	subtest := []struct {
+		name   string
+		change func(v *repository.EmailVerification)
+		token  string
+	}{
+		{
+			name:  "Unknown",
+			token: "unknown",
+		},
+		{
+			name: "Expired",
+			change: func(v *repository.EmailVerification) {
+				v.ExpiresAt = time.Now().Add(-time.Minute)
+			},
+		},
+		{
+			name: "Used",
+			change: func(v *repository.EmailVerification) {
+				usedAt := time.Now()
+				v.UsedAt = &usedAt
+			},
+		},
+	}
+
+	for _, test := range subtest {
+		t.Run(test.name, func(t *testing.T) {
+			us, store, u, _ := newMemoryService(t)
+
+			token := test.token
+			if test.change != nil {
+				token = "changed"
+				v := repository.EmailVerification{
+					UserID:    u.ID,
+					TokenHash: hashToken(token),
+					CreatedAt: time.Now(),
+					ExpiresAt: time.Now().Add(time.Hour),
+				}
+				test.change(&v)
+
+				tx, err := store.Begin()
+				assert.NoError(t, err)
+				_, err = us.EmailVerificationRepository.Create(context.Background(), tx, v)
+				assert.NoError(t, err)
+				assert.NoError(t, tx.Commit())
+			}
+
+			_, err := us.VerifyEmail(context.Background(), token)
+			assert.ErrorIs(t, err, ErrInvalidVerifyToken)
+		})
+	}
+}
+
+func TestServiceResendVerification(t *testing.T) {
+	us, _, _, _ := newMemoryService(t)
+	u, first := signUp(t, us)
+
+	_, second := mailedToken(t, us, testTokenConfig.VerifyURL, func() error {
+		return us.ResendVerification(context.Background(), u.ID)
+	})
+	assert.NotEqual(t, first, second)
+
+	// another mail is held back until the interval is over
+	m := &mailer.MockMailer{}
+	us.Mailer = m
+	assert.ErrorIs(t, us.ResendVerification(context.Background(), u.ID), ErrVerifyRateLimited)
+	m.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+
+	// either link verify the email and use up the other
+	_, err := us.VerifyEmail(context.Background(), first)
+	assert.NoError(t, err)
+	_, err = us.VerifyEmail(context.Background(), second)
+	assert.ErrorIs(t, err, ErrInvalidVerifyToken)
+}